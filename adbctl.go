@@ -5,8 +5,11 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -19,13 +22,29 @@ type DeviceInfo struct {
 	Value    string
 }
 
+// Version is set by build.sh with -ldflags "-X main.Version=...".
+var Version = "dev"
+
 var isDebug bool
 var showIcons bool
 
+// screenReader switches every flow to linear, plain-text output: no colors or
+// box drawing, explicit labels and a fixed ordering.
+var screenReader bool
+
 func init() {
 	isDebug = os.Getenv("DEBUG") != ""
 	//showIcons = os.Getenv("SHOW_ICONS") != "false"
 	showIcons = false
+	screenReader = os.Getenv("ADBCTL_SCREEN_READER") != ""
+}
+
+// rule returns a horizontal separator line, omitted in screen-reader mode.
+func rule(char string, width int) string {
+	if screenReader {
+		return ""
+	}
+	return strings.Repeat(char, width) + "\n"
 }
 
 func debugPrint(format string, a ...interface{}) {
@@ -34,12 +53,15 @@ func debugPrint(format string, a ...interface{}) {
 	}
 }
 
+// runAdbCommand runs a read-only shell probe, retrying it like
+// adbShellIdempotent, and returns its output or "n/a".
 func runAdbCommand(deviceID, command string, timeout time.Duration) string {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "adb", "-s", deviceID, "shell", command)
-	output, err := cmd.CombinedOutput()
+	timeout = scaledTimeout(deviceID, timeout)
+	output, err := adbRetry.run(deviceID, func() ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return exec.CommandContext(ctx, adbPath, "-s", deviceID, "shell", command).CombinedOutput()
+	})
 	if err != nil {
 		debugPrint("Error executing command '%s': %v\n", command, err)
 		return "n/a"
@@ -47,9 +69,208 @@ func runAdbCommand(deviceID, command string, timeout time.Duration) string {
 	return strings.TrimSpace(string(output))
 }
 
+// adbShell runs a shell command on the device and returns its trimmed output,
+// surfacing failures to the caller instead of collapsing them to "n/a". It
+// runs the command once: it may change device state, and after a dropped
+// connection there is no telling whether it ran. Read-only probes use
+// adbShellIdempotent instead.
+func adbShell(deviceID string, args ...string) (string, error) {
+	output, err := exec.Command(adbPath, append([]string{"-s", deviceID, "shell"}, args...)...).CombinedOutput()
+	if err != nil {
+		return strings.TrimSpace(string(output)), normalizeAdbError(string(output), fmt.Errorf("adb shell %s: %v", strings.Join(args, " "), err))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// adbShellIdempotent is adbShell for commands that only read device state,
+// which are retried on transport errors and resumed once a device that
+// dropped off (adbd restarting) is back.
+func adbShellIdempotent(deviceID string, args ...string) (string, error) {
+	cmdArgs := append([]string{"-s", deviceID, "shell"}, args...)
+	output, err := adbRetry.run(deviceID, func() ([]byte, error) {
+		return exec.Command(adbPath, cmdArgs...).CombinedOutput()
+	})
+	if err != nil {
+		return strings.TrimSpace(string(output)), normalizeAdbError(string(output), fmt.Errorf("adb shell %s: %v", strings.Join(args, " "), err))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// adbStream runs `adb -s deviceID args...` and calls onLine for each line of
+// output (stdout and stderr) as it arrives, instead of buffering it all. It
+// returns when the command exits or ctx is cancelled.
+func adbStream(ctx context.Context, deviceID string, onLine func(string), args ...string) error {
+	cmd := exec.CommandContext(ctx, adbPath, append([]string{"-s", deviceID}, args...)...)
+	reader, writer := io.Pipe()
+	cmd.Stdout, cmd.Stderr = writer, writer
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		writer.CloseWithError(cmd.Wait())
+	}()
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var last string
+	for scanner.Scan() {
+		last = scanner.Text()
+		onLine(last)
+	}
+	err := scanner.Err()
+	if err == nil || ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return normalizeAdbError(last, fmt.Errorf("adb %s: %w", strings.Join(args, " "), err))
+}
+
+// adbExecOut runs a command with `adb exec-out`, which has no pty, and copies
+// its raw stdout to w byte for byte. Use it for binary data; adb shell output
+// may have line endings rewritten.
+func adbExecOut(deviceID string, w io.Writer, args ...string) error {
+	return adbExecOutWithin(deviceID, 0, w, args...)
+}
+
+// adbExecOutWithin is adbExecOut with a time budget; 0 means none.
+func adbExecOutWithin(deviceID string, budget time.Duration, w io.Writer, args ...string) error {
+	ctx, cancel := budgetContext(budget)
+	defer cancel()
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, adbPath, append([]string{"-s", deviceID, "exec-out"}, args...)...)
+	cmd.Stdout, cmd.Stderr = w, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("adb exec-out %s: no result within %v", strings.Join(args, " "), budget.Round(time.Second))
+		}
+		return normalizeAdbError(stderr.String(), fmt.Errorf("adb exec-out %s: %w", strings.Join(args, " "), err))
+	}
+	return nil
+}
+
+// budgetContext returns a context that expires after budget, or never when
+// budget is 0.
+func budgetContext(budget time.Duration) (context.Context, context.CancelFunc) {
+	if budget <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), budget)
+}
+
+// adbPush copies a local file or directory to the device. A copy cut short
+// by the device going offline is started again once it is back.
+func adbPush(deviceID, local, remote string) error {
+	output, err := adbRetry.run(deviceID, func() ([]byte, error) {
+		return exec.Command(adbPath, "-s", fastTransport(deviceID), "push", local, remote).CombinedOutput()
+	})
+	if err != nil {
+		return normalizeAdbError(string(output), fmt.Errorf("adb push %s: %v: %s", local, err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// adbPull copies a file or directory from the device, starting again like
+// adbPush when the device drops off mid-copy.
+func adbPull(deviceID, remote, local string) error {
+	budget := pullBudget(deviceID, remote)
+	output, err := adbRetry.run(deviceID, func() ([]byte, error) {
+		ctx, cancel := budgetContext(budget)
+		defer cancel()
+		output, err := exec.CommandContext(ctx, adbPath, "-s", fastTransport(deviceID), "pull", remote, local).CombinedOutput()
+		if ctx.Err() != nil {
+			return output, fmt.Errorf("no result within %v", budget.Round(time.Second))
+		}
+		return output, err
+	})
+	if err != nil {
+		return normalizeAdbError(string(output), fmt.Errorf("adb pull %s: %v: %s", remote, err, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// remoteFileSize returns the size of remote when it is a regular file, or -1
+// for a directory, a missing file or a device whose stat lacks -c.
+func remoteFileSize(deviceID, remote string) int64 {
+	fields := strings.Fields(runAdbCommand(deviceID, "stat -c '%F %s' "+shellQuote(remote)+" 2>/dev/null", 10*time.Second))
+	if len(fields) < 3 || fields[0] != "regular" {
+		return -1
+	}
+	size, err := strconv.ParseInt(fields[len(fields)-1], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// adbPullFile copies one regular file of the given size over exec-out, so
+// the bytes can be counted into p; adb pull reports no progress to a pipe.
+// It writes next to the destination and renames once complete, and starts
+// again like adbPull when the device drops off mid-copy.
+func adbPullFile(deviceID, remote, local string, size int64, p *progress) error {
+	if info, err := os.Stat(local); err == nil && info.IsDir() {
+		local = filepath.Join(local, path.Base(remote))
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(local), "."+filepath.Base(local)+".part-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	_, err = adbRetry.run(deviceID, func() ([]byte, error) {
+		tmp.Truncate(0)
+		tmp.Seek(0, io.SeekStart)
+		p.Set(0)
+		// Errors on stderr would land in the file; the size check catches them.
+		return nil, adbExecOutWithin(fastTransport(deviceID), transferBudget(deviceID, size), io.MultiWriter(tmp, p), "cat "+shellQuote(remote)+" 2>/dev/null")
+	})
+	if err != nil {
+		return err
+	}
+	if got := p.done.Load(); got != size {
+		return fmt.Errorf("adb pull %s: got %s of %s (is the file readable?)", remote, formatBytes(got), formatBytes(size))
+	}
+	// CreateTemp makes the file private; give it the usual mode.
+	if err := tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), local)
+}
+
+// adbPushFile copies one local file to remote over exec-in, counting the
+// bytes into p like adbPullFile, and checks the size that arrived.
+func adbPushFile(deviceID, local, remote string, p *progress) error {
+	info, err := os.Stat(local)
+	if err != nil {
+		return err
+	}
+	output, err := adbRetry.run(deviceID, func() ([]byte, error) {
+		file, err := os.Open(local)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		p.Set(0)
+		cmd := exec.Command(adbPath, "-s", fastTransport(deviceID), "exec-in", "cat > "+shellQuote(remote))
+		cmd.Stdin = io.TeeReader(file, p)
+		return cmd.CombinedOutput()
+	})
+	if err != nil {
+		return normalizeAdbError(string(output), fmt.Errorf("adb push %s: %v: %s", local, err, strings.TrimSpace(string(output))))
+	}
+	if size := remoteFileSize(deviceID, remote); size != info.Size() {
+		return fmt.Errorf("adb push %s: %s did not arrive complete (%s of %s): %s", local, remote,
+			formatBytes(max(size, 0)), formatBytes(info.Size()), strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
 func getConnectedDevices() []string {
-	cmd := exec.Command("adb", "devices", "-l")
+	cmd := exec.Command(adbPath, "devices", "-l")
 	output, err := cmd.Output()
+	if err != nil && ciMode {
+		ciFail(exitDevice, "adb-unavailable", err)
+	}
 	if err != nil {
 		fmt.Println("Error running adb devices:", err)
 		os.Exit(1)
@@ -69,19 +290,38 @@ func getConnectedDevices() []string {
 }
 
 func selectDevice(devices []string) string {
+	if ciMode && len(devices) == 0 {
+		ciFail(exitDevice, "no-device", fmt.Errorf("no devices connected"))
+	}
 	if len(devices) == 0 {
 		fmt.Println("No devices connected.")
 		fmt.Println("Please connect a device using 'adb connect <ip:port>' or ensure USB debugging is enabled.")
 		fmt.Println("After connecting, run this tool again.")
 		os.Exit(1)
 	}
+	devices = preferTaggedDevices(dedupeDevices(devices))
 	if len(devices) == 1 {
-		return strings.Fields(devices[0])[0]
+		return withTransport(strings.Fields(devices[0])[0])
 	}
 
+	if ciMode {
+		ciFail(exitDevice, "ambiguous-device", fmt.Errorf("%d devices connected; set ADBCTL_DEVICE", len(devices)))
+	}
 	fmt.Println("Multiple devices found. Please select a device:")
+	summaries := deviceSummaries(devices)
 	for i, device := range devices {
+		if screenReader {
+			description := describeDeviceLine(device)
+			if summaries[i] != "" {
+				description += ", " + strings.ReplaceAll(summaries[i], " · ", ", ")
+			}
+			fmt.Printf("Device %d of %d: %s\n", i+1, len(devices), description)
+			continue
+		}
 		fmt.Printf("%d. %s\n", i+1, device)
+		if summaries[i] != "" {
+			fmt.Printf("   %s\n", summaries[i])
+		}
 	}
 
 	reader := bufio.NewReader(os.Stdin)
@@ -92,18 +332,45 @@ func selectDevice(devices []string) string {
 		index := 0
 		_, err := fmt.Sscanf(input, "%d", &index)
 		if err == nil && index > 0 && index <= len(devices) {
-			return strings.Fields(devices[index-1])[0]
+			return withTransport(strings.Fields(devices[index-1])[0])
 		}
 		fmt.Println("Invalid selection. Please try again.")
 	}
 }
 
+// confirm asks the user to type "yes" before a destructive action.
+func confirm(prompt string) bool {
+	if ciMode {
+		fmt.Fprintln(os.Stderr, "Confirmation required but prompting is disabled in CI mode; pass --yes.")
+		return false
+	}
+	fmt.Print(prompt)
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(input), "yes")
+}
+
+// describeDeviceLine turns an `adb devices -l` line into "serial X, model Y".
+func describeDeviceLine(line string) string {
+	fields := strings.Fields(line)
+	description := "serial " + fields[0]
+	for _, field := range fields[1:] {
+		if model, found := strings.CutPrefix(field, "model:"); found {
+			description += ", model " + strings.ReplaceAll(model, "_", " ")
+		}
+	}
+	return description
+}
+
 func checkDeviceConnectivity(deviceID string, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "adb", "-s", deviceID, "shell", "echo", "connected")
-	err := cmd.Run()
+	// The caller's timeout is the point of a connectivity check, so it
+	// doesn't wait for an offline device to come back.
+	_, err := adbRetry.run("", func() ([]byte, error) {
+		return exec.CommandContext(ctx, adbPath, "-s", deviceID, "shell", "echo", "connected").CombinedOutput()
+	})
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return fmt.Errorf("device connection timed out after %v", timeout)
@@ -132,6 +399,20 @@ func parseMemInfo(meminfo string) string {
 	return fmt.Sprintf("%.2f GB / %d kB (%.2f GB used, %.2f GB free)", totalGB, totalKB, usedGB, freeGB)
 }
 
+// parseMemInfoFields parses /proc/meminfo into a map of field name to kB.
+func parseMemInfoFields(meminfo string) map[string]int {
+	memData := make(map[string]int)
+	for _, line := range strings.Split(meminfo, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) >= 2 {
+			key := strings.TrimSuffix(parts[0], ":")
+			value, _ := strconv.Atoi(parts[1])
+			memData[key] = value
+		}
+	}
+	return memData
+}
+
 func parseCPUInfo(cpuinfo, cpuUsage string) string {
 	lines := strings.Split(cpuinfo, "\n")
 	var totalCores int
@@ -259,6 +540,8 @@ func getDeviceInfo(deviceID string) []DeviceInfo {
 		{"CPU ABI", mapCPUABI(runAdbCommand(deviceID, "getprop ro.product.cpu.abi", timeout))},
 		{"Manufacturer", runAdbCommand(deviceID, "getprop ro.product.manufacturer", timeout)},
 		{"Build Number", runAdbCommand(deviceID, "getprop ro.build.display.id", timeout)},
+		{"SELinux", selinuxMode(deviceID)},
+		{"Root", rootStatus(deviceID)},
 		{"Memory", parseMemInfo(runAdbCommand(deviceID, "cat /proc/meminfo", timeout))},
 		{"CPU", parseCPUInfo(runAdbCommand(deviceID, "cat /proc/cpuinfo", timeout), runAdbCommand(deviceID, "top -n 1 | grep 'CPU:'", timeout))},
 		{"Storage", parseStorageInfo(runAdbCommand(deviceID, "df -k /data", timeout))},
@@ -267,9 +550,11 @@ func getDeviceInfo(deviceID string) []DeviceInfo {
 		{"Battery Level", runAdbCommand(deviceID, "dumpsys battery | grep level | awk '{print $2}'", timeout)},
 		{"Fire OS Version", runAdbCommand(deviceID, "getprop ro.build.version.name", timeout)},
 		{"Fire OS Build Number", runAdbCommand(deviceID, "getprop ro.build.version.number", timeout)},
-		{"IP Address", runAdbCommand(deviceID, "ip addr show wlan0 | grep 'inet ' | awk '{print $2}' | cut -d/ -f1", timeout)},
-		{"WiFi SSID", runAdbCommand(deviceID, "dumpsys wifi | grep 'mWifiInfo' | grep -o 'SSID:.*' | awk -F', ' '{print $1}' | sed 's/SSID: //'", timeout)},
+		{"IP Address", wlanIP(deviceID)},
 	}
+	info = append(info, getWifiLinkInfo(deviceID)...)
+	info = append(info, getMediaCapabilities(deviceID)...)
+	info = append(info, getDRMInfo(deviceID)...)
 
 	return info
 }
@@ -280,31 +565,50 @@ func formatOutput(info []DeviceInfo) string {
 
 	// Title
 	color.New(color.FgCyan, color.Bold).Fprintln(&output, "Device Information")
-	output.WriteString(strings.Repeat("=", maxWidth) + "\n\n")
+	output.WriteString(rule("=", maxWidth) + "\n")
 
-	// Group information
-	groups := map[string][]string{
-		"Device": {
+	// Group information, in a fixed order
+	groups := []struct {
+		Name       string
+		Properties []string
+	}{
+		{"Device", []string{
 			"Model", "Manufacturer", "Android Version", "API Level",
-			"Build Number", "Fire OS Version", "Fire OS Build Number",
-			"IP Address", "WiFi SSID",
-		},
-		"Hardware": {
+			"Build Number", "Fire OS Version", "Fire OS Build Number", "SELinux", "Root",
+		}},
+		{"Network", []string{
+			"IP Address", "WiFi SSID", "WiFi BSSID", "WiFi RSSI", "WiFi Link Speed", "WiFi Frequency",
+		}},
+		{"Hardware", []string{
 			"CPU", "CPU ABI", "Memory", "Storage", "Free Storage",
-		},
-		"Display": {
+		}},
+		{"Display", []string{
 			"Screen Resolution", "Screen Density",
-		},
-		"Other": {
+		}},
+		{"Media Capabilities", []string{
+			"HDR Types", "Max Luminance", "Dolby Vision Decoder",
+		}},
+		{"DRM", []string{
+			"Widevine", "PlayReady", "HDCP",
+		}},
+		{"Other", []string{
 			"Battery Level",
-		},
+		}},
 	}
 
-	for groupName, properties := range groups {
-		color.New(color.FgYellow, color.Bold).Fprintf(&output, "[ %s ]\n", groupName)
-		for _, property := range properties {
+	for _, group := range groups {
+		if screenReader {
+			fmt.Fprintf(&output, "Section: %s\n", group.Name)
+		} else {
+			color.New(color.FgYellow, color.Bold).Fprintf(&output, "[ %s ]\n", group.Name)
+		}
+		for _, property := range group.Properties {
 			for _, item := range info {
 				if item.Property == property {
+					if screenReader {
+						fmt.Fprintf(&output, "%s: %s\n", property, item.Value)
+						break
+					}
 					icon := getIcon(property)
 					color.New(color.FgGreen).Fprintf(&output, "%-3s %-20s : ", icon, property)
 					color.New(color.FgWhite).Fprintln(&output, item.Value)
@@ -330,6 +634,8 @@ func getIcon(property string) string {
 		"Build Number":         "🏗️",
 		"Fire OS Version":      "🔥",
 		"Fire OS Build Number": "🔥",
+		"SELinux":              "🛡️",
+		"Root":                 "🔓",
 		"CPU":                  "💻",
 		"CPU ABI":              "🧮",
 		"Memory":               "💾",
@@ -351,6 +657,16 @@ func measureTime(start time.Time, name string) {
 	fmt.Printf("%s took %s\n", name, elapsed)
 }
 
+// formatKB renders a size in kB using the largest fitting unit.
+func formatKB(kb int) string {
+	if kb > 1048576 {
+		return fmt.Sprintf("%.2f GB", float64(kb)/1048576)
+	} else if kb > 1024 {
+		return fmt.Sprintf("%.2f MB", float64(kb)/1024)
+	}
+	return fmt.Sprintf("%d KB", kb)
+}
+
 func getDetailedMemoryInfo(deviceID string) string {
 	timeout := 5 * time.Second
 	meminfo := runAdbCommand(deviceID, "cat /proc/meminfo", timeout)
@@ -358,16 +674,7 @@ func getDetailedMemoryInfo(deviceID string) string {
 
 	var output strings.Builder
 	color.New(color.FgCyan, color.Bold).Fprintln(&output, "Detailed Memory Information")
-	output.WriteString(strings.Repeat("=", 30) + "\n\n")
-
-	formatSize := func(kb int) string {
-		if kb > 1048576 {
-			return fmt.Sprintf("%.2f GB", float64(kb)/1048576)
-		} else if kb > 1024 {
-			return fmt.Sprintf("%.2f MB", float64(kb)/1024)
-		}
-		return fmt.Sprintf("%d KB", kb)
-	}
+	output.WriteString(rule("=", 30) + "\n")
 
 	memData := make(map[string]int)
 	for _, line := range lines {
@@ -393,7 +700,7 @@ func getDetailedMemoryInfo(deviceID string) string {
 	for _, field := range highlightedFields {
 		if value, ok := memData[field.key]; ok {
 			color.New(color.FgYellow, color.Bold).Fprintf(&output, "%-20s : ", field.description)
-			color.New(color.FgWhite).Fprintln(&output, formatSize(value))
+			color.New(color.FgWhite).Fprintln(&output, formatKB(value))
 		}
 	}
 
@@ -402,15 +709,15 @@ func getDetailedMemoryInfo(deviceID string) string {
 	// Calculate and display used memory
 	usedMem := memData["MemTotal"] - memData["MemAvailable"]
 	color.New(color.FgRed, color.Bold).Fprintf(&output, "%-20s : ", "Used RAM")
-	color.New(color.FgWhite).Fprintln(&output, formatSize(usedMem))
+	color.New(color.FgWhite).Fprintln(&output, formatKB(usedMem))
 
 	// Calculate and display used swap
 	usedSwap := memData["SwapTotal"] - memData["SwapFree"]
 	color.New(color.FgMagenta, color.Bold).Fprintf(&output, "%-20s : ", "Used Swap")
-	color.New(color.FgWhite).Fprintln(&output, formatSize(usedSwap))
+	color.New(color.FgWhite).Fprintln(&output, formatKB(usedSwap))
 
 	output.WriteString("\nOther Memory Information:\n")
-	output.WriteString(strings.Repeat("-", 25) + "\n")
+	output.WriteString(rule("-", 25))
 	for _, line := range lines {
 		parts := strings.Fields(line)
 		if len(parts) >= 2 {
@@ -419,7 +726,7 @@ func getDetailedMemoryInfo(deviceID string) string {
 				value, err := strconv.Atoi(parts[1])
 				if err == nil {
 					color.New(color.FgGreen).Fprintf(&output, "%-20s : ", key)
-					color.New(color.FgWhite).Fprintln(&output, formatSize(value))
+					color.New(color.FgWhite).Fprintln(&output, formatKB(value))
 				}
 			}
 		}
@@ -437,29 +744,78 @@ func contains(fields []struct{ key, description string }, key string) bool {
 	return false
 }
 
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+var menuOptions = []string{
+	"Show General Device Information",
+	"Show Detailed Memory Information",
+	"Reboot Device",
+	"Start Application",
+	"List Installed Applications",
+	"Exit",
+	"Command Palette (p, or Ctrl+P then Enter)",
+}
+
 func showInformationMenu(deviceID string) {
+	// One reader for the whole session, so piped answers aren't lost to a
+	// discarded buffer.
+	reader := bufio.NewReader(os.Stdin)
 	for {
 		fmt.Println("\nWhat action would you like to perform?")
-		fmt.Println("1. Show General Device Information")
-		fmt.Println("2. Show Detailed Memory Information")
-		fmt.Println("3. Reboot Device")
-		fmt.Println("4. Start Application")
-		fmt.Println("5. List Installed Applications")
-		fmt.Println("6. Exit")
-
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("Enter your choice (1-6): ")
-		input, _ := reader.ReadString('\n')
+		for i, option := range menuOptions {
+			if screenReader {
+				fmt.Printf("Option %d of %d: %s\n", i+1, len(menuOptions), option)
+			} else {
+				fmt.Printf("%d. %s\n", i+1, option)
+			}
+		}
+
+		fmt.Printf("Enter your choice (1-%d): ", len(menuOptions))
+		input, err := reader.ReadString('\n')
 		input = strings.TrimSpace(input)
+		if err != nil && input == "" {
+			// stdin closed: nothing more will be chosen.
+			fmt.Println()
+			return
+		}
 
+		if input >= "1" && input <= "5" {
+			// The device may have dropped off while the menu was open.
+			delete(pingedDevices, deviceID)
+			if !ensureResponsive(deviceID) {
+				continue
+			}
+		}
 		switch input {
 		case "1":
+			start := time.Now()
 			info := getDeviceInfo(deviceID)
+			recordAudit("info", deviceID, start, nil)
 			fmt.Print(formatOutput(info))
 		case "2":
 			fmt.Print(getDetailedMemoryInfo(deviceID))
 		case "3":
-			rebootDevice(deviceID)
+			if err := rebootDevice(deviceID, ""); err != nil {
+				printError(err)
+			}
 		case "4":
 			startApplication(deviceID)
 		case "5":
@@ -467,21 +823,28 @@ func showInformationMenu(deviceID string) {
 		case "6":
 			fmt.Println("Exiting. Goodbye!")
 			return
+		case "7", "p", "P", "\x10": // Ctrl+P
+			commandPalette(reader, deviceID)
 		default:
 			fmt.Println("Invalid choice. Please try again.")
 		}
 	}
 }
 
-func rebootDevice(deviceID string) {
+// rebootDevice reboots normally (mode "") or into recovery, bootloader,
+// sideload or userspace (a soft restart of Android without the kernel).
+func rebootDevice(deviceID, mode string) error {
 	fmt.Println("Rebooting device...")
-	cmd := exec.Command("adb", "-s", deviceID, "reboot")
-	err := cmd.Run()
+	args := []string{"-s", deviceID, "reboot"}
+	if mode != "" {
+		args = append(args, mode)
+	}
+	output, err := exec.Command(adbPath, args...).CombinedOutput()
 	if err != nil {
-		fmt.Printf("Error rebooting device: %v\n", err)
-	} else {
-		fmt.Println("Device is rebooting. Please wait...")
+		return normalizeAdbError(string(output), fmt.Errorf("adb reboot %s: %v: %s", mode, err, strings.TrimSpace(string(output))))
 	}
+	fmt.Println("Device is rebooting. Please wait...")
+	return nil
 }
 
 func startApplication(deviceID string) {
@@ -490,7 +853,7 @@ func startApplication(deviceID string) {
 	packageName, _ := reader.ReadString('\n')
 	packageName = strings.TrimSpace(packageName)
 
-	cmd := exec.Command("adb", "-s", deviceID, "shell", "monkey", "-p", packageName, "-c", "android.intent.category.LAUNCHER", "1")
+	cmd := exec.Command(adbPath, "-s", deviceID, "shell", "monkey", "-p", packageName, "-c", "android.intent.category.LAUNCHER", "1")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		fmt.Printf("Error starting application: %v\n", err)
@@ -501,7 +864,7 @@ func startApplication(deviceID string) {
 }
 
 func listInstalledApps(deviceID string) {
-	cmd := exec.Command("adb", "-s", deviceID, "shell", "pm", "list", "packages")
+	cmd := exec.Command(adbPath, "-s", deviceID, "shell", "pm", "list", "packages")
 	output, err := cmd.Output()
 	if err != nil {
 		fmt.Printf("Error listing installed applications: %v\n", err)
@@ -518,12 +881,59 @@ func listInstalledApps(deviceID string) {
 }
 
 func main() {
-	fmt.Println("Welcome to abdctl - Your Android Device Management Companion")
+	fixtureAdbMain()
 	memoryFlag := flag.Bool("memory", false, "Show detailed memory information")
+	flag.BoolVar(&screenReader, "screen-reader", screenReader, "Screen-reader friendly output: no colors or box drawing, explicit labels")
+	flag.BoolVar(&screenReader, "no-tui", screenReader, "Linear plain-text output (same as -screen-reader)")
+	flag.StringVar(&forcedTransport, "transport", forcedTransport, "Force the usb or wifi transport when a device is connected both ways")
+	flag.IntVar(&adbRetry.Attempts, "retries", adbRetry.Attempts, "Attempts for adb commands that fail because the device went offline")
+	flag.DurationVar(&adbRetry.Backoff, "retry-backoff", adbRetry.Backoff, "Wait before the first retry; doubles on each further retry")
+	flag.DurationVar(&adbRetry.ReconnectWait, "reconnect-wait", adbRetry.ReconnectWait, "How long to wait for a device that drops off mid-command (adbd restart) before failing; 0 disables")
+	adbPathFlag := flag.String("adb-path", "", "adb binary to use (default: ADBCTL_ADB, 'config adb-path', PATH, then the Android SDK)")
+	flag.BoolVar(&ignoreProject, "no-project", false, "Ignore the .adbctl.yaml project profile")
+	flag.BoolVar(&ciMode, "ci", ciMode, "Never prompt; configure from ADBCTL_DEVICE, ADBCTL_TIMEOUT and ADBCTL_OUTPUT")
 	flag.Parse()
+	if ciMode {
+		// Child processes (matrix runs) inherit CI mode.
+		os.Setenv("ADBCTL_CI", "1")
+		color.NoColor = true
+	} else {
+		fmt.Println("Welcome to abdctl - Your Android Device Management Companion")
+	}
+	if err := validateRetryPolicy(adbRetry); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if forcedTransport != "" && forcedTransport != "usb" && forcedTransport != "wifi" {
+		fmt.Println("-transport must be usb or wifi")
+		os.Exit(2)
+	}
+	if screenReader {
+		color.NoColor = true
+	}
+	if path, err := resolveAdbPath(*adbPathFlag); err == nil {
+		adbPath = path
+		// Child processes (fleet and matrix runs) use the same adb.
+		os.Setenv("ADBCTL_ADB", adbPath)
+	} else if command := flag.Arg(0); command != "help" && command != "config" && command != "alias" && command != "store" &&
+		!(command == "debug" && flag.Arg(1) == "replay") {
+		if ciMode {
+			ciFail(exitDevice, "adb-unavailable", err)
+		}
+		printError(err)
+		os.Exit(exitError)
+	}
+
+	if flag.NArg() > 0 {
+		runCommand(flag.Arg(0), flag.Args()[1:])
+		return
+	}
+	if ciMode && !*memoryFlag {
+		ciFail(exitUsage, "usage", fmt.Errorf("a command is required in CI mode"))
+	}
 
-	devices := getConnectedDevices()
-	selectedDevice := selectDevice(devices)
+	// ADBCTL_DEVICE skips the prompt here too, which debug capture relies on.
+	selectedDevice := pickDevice()
 
 	if *memoryFlag {
 		fmt.Print(getDetailedMemoryInfo(selectedDevice))
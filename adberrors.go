@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// adbError is a failure reported by adb or the device, normalized so the
+// same problem reads the same way across adb versions and device builds.
+type adbError struct {
+	Kind        string // stable identifier, e.g. "unauthorized"
+	Explanation string
+	Suggestion  string
+	Raw         string // the original message
+	Err         error  // the failure it explains, naming the command; may be nil
+}
+
+func (e *adbError) Error() string {
+	if e.Err == nil || e.Err.Error() == e.Raw {
+		return e.Kind + ": " + e.Raw
+	}
+	return e.Kind + ": " + e.Err.Error() + ": " + e.Raw
+}
+
+func (e *adbError) Unwrap() error { return e.Err }
+
+// adbErrorRule maps any of Patterns (matched case-insensitively) to an error.
+type adbErrorRule struct {
+	Patterns    []string
+	Kind        string
+	Explanation string
+	Suggestion  string
+}
+
+// anchoredAdbErrors also select a rule by kind. Their messages are too
+// common to match anywhere in the output, so they are only recognized where
+// the shell or adb itself reports them; a log line or file name that happens
+// to contain the words is not mistaken for the failure.
+var anchoredAdbErrors = map[string]*regexp.Regexp{
+	"permission-denied": regexp.MustCompile(`(?im)^(?:/system/bin/sh|sh|adb|adb: error|error): (?:.*: )?permission denied\s*$`),
+	"command-not-found": regexp.MustCompile(`(?im)^(?:/system/bin/sh|sh): (?:.*: )?(?:inaccessible or )?not found\s*$`),
+}
+
+var adbErrorRules = []adbErrorRule{
+	{[]string{"unauthorized"}, "unauthorized",
+		"The device has not accepted this computer's RSA key.",
+		"Accept the 'Allow USB debugging?' prompt on the device, or revoke authorizations in Developer options and reconnect."},
+	{[]string{"device offline", "error: closed", "device still connecting"}, "offline",
+		"The adb connection dropped or the device is still starting.",
+		"Run 'adb reconnect' or 'adb kill-server', then retry; for network devices run 'adb connect <ip:port>' again."},
+	{[]string{"no devices/emulators found", "device not found", "error: device '"}, "no-device",
+		"adb cannot see the selected device.",
+		"Check the cable or network, then run 'adb devices'."},
+	{[]string{"more than one device"}, "ambiguous-device",
+		"Several devices are connected and none was selected.",
+		"Set ADBCTL_DEVICE=<serial>."},
+	{[]string{"failed to connect", "connection refused", "cannot connect to"}, "connection-refused",
+		"Nothing is listening for adb at that address.",
+		"Enable network debugging on the device, or run 'adbctl wireless enable' while on USB."},
+	{[]string{"protocol fault", "connection reset"}, "protocol-fault",
+		"The adb server and device disagreed mid-transfer.",
+		"Run 'adb kill-server' and retry; prefer USB for large transfers."},
+	{[]string{"install_failed_insufficient_storage"}, "install-insufficient-storage",
+		"The device does not have enough free space for the APK.",
+		"Free space with 'adbctl du' and uninstall unused apps."},
+	{[]string{"install_failed_version_downgrade"}, "install-downgrade",
+		"A newer version of the app is already installed.",
+		"Uninstall it first, or pass -d to allow a downgrade on debuggable builds."},
+	{[]string{"install_failed_update_incompatible"}, "install-signature-mismatch",
+		"The installed app is signed with a different key.",
+		"Uninstall the existing app (its data is lost) and install again."},
+	{[]string{"install_failed_already_exists"}, "install-already-exists",
+		"The package is already installed.",
+		"Reinstall with -r."},
+	{[]string{"install_failed_older_sdk", "install_failed_newer_sdk"}, "install-sdk-mismatch",
+		"The APK's minSdkVersion or maxSdkVersion excludes this device's Android version.",
+		"Build for this API level; 'adbctl prop list --filter ro.build.version.sdk' shows it."},
+	{[]string{"install_failed_no_matching_abis", "install_failed_cpu_abi_incompatible"}, "install-abi-mismatch",
+		"The APK has no native libraries for this device's CPU.",
+		"Build for the ABI shown by 'adbctl prop list --filter ro.product.cpu.abi' (Fire TV devices are mostly armeabi-v7a)."},
+	{[]string{"install_failed_test_only"}, "install-test-only",
+		"The APK is marked android:testOnly.",
+		"Install with -t."},
+	{[]string{"install_failed_", "install_parse_failed_"}, "install-failed",
+		"The package manager rejected the APK.",
+		"See the INSTALL_* code in the message for the reason."},
+	{[]string{"securityexception", "permission denial"}, "permission-denied",
+		"The shell user is not allowed to do this on this build.",
+		"Some operations need 'adb root' (userdebug builds) or a device-owner app."},
+	{nil, "command-not-found",
+		"The command is not available on this device's Android version.",
+		"Check the API level with 'adbctl prop list --filter ro.build.version.sdk'."},
+}
+
+// normalizeAdbError returns an *adbError for known failures in output, or
+// err unchanged. A nil err is returned as is.
+func normalizeAdbError(output string, err error) error {
+	if err == nil {
+		return nil
+	}
+	text := strings.ToLower(output + "\n" + err.Error())
+	for _, rule := range adbErrorRules {
+		anchored, ok := anchoredAdbErrors[rule.Kind]
+		matched := ok && anchored.MatchString(text)
+		for _, pattern := range rule.Patterns {
+			matched = matched || strings.Contains(text, pattern)
+		}
+		if matched {
+			raw := strings.TrimSpace(output)
+			if raw == "" {
+				raw = err.Error()
+			}
+			return &adbError{rule.Kind, rule.Explanation, rule.Suggestion, raw, err}
+		}
+	}
+	return err
+}
+
+// errorKind returns the normalized kind of err, or fallback.
+func errorKind(err error, fallback string) string {
+	var adbErr *adbError
+	if errors.As(err, &adbErr) {
+		return adbErr.Kind
+	}
+	return fallback
+}
+
+// printError prints err with the explanation and next step for known adb
+// failures.
+func printError(err error) {
+	fmt.Printf("Error: %v\n", err)
+	var adbErr *adbError
+	if errors.As(err, &adbErr) {
+		fmt.Printf("  Why: %s\n", adbErr.Explanation)
+		fmt.Printf("  Try: %s\n", adbErr.Suggestion)
+	}
+}
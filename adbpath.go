@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// adbFile stores the adb binary chosen with `adbctl config adb-path`.
+const adbFile = "adb.json"
+
+const platformToolsURL = "https://developer.android.com/tools/releases/platform-tools"
+
+// adbPath is the adb binary every command runs. main resolves it from
+// -adb-path, ADBCTL_ADB, adb.json, PATH and finally the Android SDK.
+var adbPath = "adb"
+
+type adbConfig struct {
+	Path string `json:"path"`
+}
+
+// resolveAdbPath returns the adb binary to use, or an error explaining where
+// it looked and how to install platform-tools.
+func resolveAdbPath(flagValue string) (string, error) {
+	if flagValue != "" {
+		return checkAdbBinary(flagValue, "-adb-path")
+	}
+	if path := os.Getenv("ADBCTL_ADB"); path != "" {
+		return checkAdbBinary(path, "ADBCTL_ADB")
+	}
+	var config adbConfig
+	if err := loadConfigJSON(adbFile, &config); err != nil {
+		return "", err
+	}
+	if config.Path != "" {
+		return checkAdbBinary(config.Path, filepath.Join(configDir(), adbFile))
+	}
+	if path, err := exec.LookPath("adb"); err == nil {
+		return path, nil
+	}
+
+	searched := []string{"PATH"}
+	for _, sdk := range androidSDKDirs() {
+		candidate := filepath.Join(sdk, "platform-tools", adbExecutable())
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		searched = append(searched, filepath.Dir(candidate))
+	}
+	return "", &adbError{
+		Kind:        "adb-unavailable",
+		Explanation: "adb was not found in " + strings.Join(searched, ", ") + ".",
+		Suggestion:  fmt.Sprintf("Install Android SDK Platform-Tools from %s, then add it to PATH, set ANDROID_HOME, or run 'adbctl config adb-path <path to adb>'.", platformToolsURL),
+		Raw:         "adb not found",
+	}
+}
+
+func checkAdbBinary(path, source string) (string, error) {
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", fmt.Errorf("adb binary %q from %s does not exist; platform-tools can be downloaded from %s", path, source, platformToolsURL)
+	}
+	return path, nil
+}
+
+// androidSDKDirs lists SDK locations from the environment and the default
+// install directories of Android Studio.
+func androidSDKDirs() []string {
+	var dirs []string
+	for _, env := range []string{"ANDROID_HOME", "ANDROID_SDK_ROOT"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "darwin":
+		dirs = append(dirs, filepath.Join(home, "Library", "Android", "sdk"))
+	case "windows":
+		dirs = append(dirs, filepath.Join(os.Getenv("LOCALAPPDATA"), "Android", "Sdk"))
+	default:
+		dirs = append(dirs, filepath.Join(home, "Android", "Sdk"))
+	}
+	return dirs
+}
+
+func adbExecutable() string {
+	if runtime.GOOS == "windows" {
+		return "adb.exe"
+	}
+	return "adb"
+}
+
+// configAdbPath shows the adb binary in use, or saves path for later runs.
+func configAdbPath(args []string) error {
+	if len(args) == 0 {
+		path, err := resolveAdbPath("")
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	}
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err := checkAdbBinary(path, "the command line"); err != nil {
+		return err
+	}
+	if err := saveConfigJSON(adbFile, adbConfig{Path: path}); err != nil {
+		return err
+	}
+	fmt.Printf("adbctl will use %s\n", path)
+	return nil
+}
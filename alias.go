@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// aliasesFile maps alias names to the command line they stand for, e.g.
+// "fire-logs": "logcat --package com.example --save ./logs".
+const aliasesFile = "aliases.json"
+
+// deviceAliasesFile maps device serials to names shown in the device picker,
+// e.g. "G070VM1234567890": "living-room stick".
+const deviceAliasesFile = "device-aliases.json"
+
+// runAliasCommand is runAlias, assigned in init: runAlias reads the commands
+// table through validateAlias, so the table's entry can't name it directly
+// without an initialization cycle.
+var runAliasCommand func(args []string) error
+
+func init() {
+	runAliasCommand = runAlias
+}
+
+func runAlias(args []string) error {
+	if len(args) > 0 && (args[0] == "--device" || args[0] == "-device" || args[0] == "--remove-device" || args[0] == "-remove-device") {
+		return runDeviceAlias(args)
+	}
+	aliases := map[string]string{}
+	if err := loadConfigJSON(aliasesFile, &aliases); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		if len(aliases) == 0 {
+			fmt.Printf("No aliases defined in %s\n", documentPath(aliasesFile))
+			return nil
+		}
+		color.New(color.FgCyan, color.Bold).Println("Aliases")
+		for _, name := range sortedKeys(aliases) {
+			fmt.Printf("  %-15s %s\n", name, aliases[name])
+		}
+		return nil
+	}
+	if args[0] == "--remove" || args[0] == "-remove" {
+		if len(args) != 2 {
+			return fmt.Errorf("usage: alias --remove <name>")
+		}
+		if _, ok := aliases[args[1]]; !ok {
+			return fmt.Errorf("no alias named %q", args[1])
+		}
+		delete(aliases, args[1])
+		if err := saveConfigJSON(aliasesFile, aliases); err != nil {
+			return err
+		}
+		fmt.Printf("Removed alias %s\n", args[1])
+		return nil
+	}
+
+	// Accept both "alias name = cmd ..." and "alias name=cmd ..." (and the
+	// whole definition quoted as one argument).
+	definition := strings.Join(args, " ")
+	name, expansion, found := strings.Cut(definition, "=")
+	name, expansion = strings.TrimSpace(name), strings.TrimSpace(expansion)
+	if !found {
+		if target, ok := aliases[name]; ok {
+			fmt.Printf("%s = %s\n", name, target)
+			return nil
+		}
+		return fmt.Errorf("usage: alias <name> = <command> [args...]")
+	}
+	if err := validateAlias(name, expansion); err != nil {
+		return err
+	}
+	aliases[name] = expansion
+	if err := saveConfigJSON(aliasesFile, aliases); err != nil {
+		return err
+	}
+	fmt.Printf("Saved alias %s = %s\n", name, expansion)
+	return nil
+}
+
+// validateAlias rejects names that would shadow a command and expansions that
+// don't start with one. Aliases never refer to other aliases, so expansion
+// can't loop.
+func validateAlias(name, expansion string) error {
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid alias name %q", name)
+	}
+	if _, ok := findCommand(name); ok {
+		return fmt.Errorf("%q is a built-in command and can't be an alias", name)
+	}
+	fields := splitCommandLine(expansion)
+	if len(fields) == 0 {
+		return fmt.Errorf("alias %s needs a command", name)
+	}
+	if _, ok := findCommand(fields[0]); !ok {
+		return fmt.Errorf("alias %s: unknown command %q", name, fields[0])
+	}
+	return nil
+}
+
+// expandAlias replaces an alias with its command and arguments; extra
+// arguments given on the command line follow the alias's own. ok is false
+// when name is not an alias.
+func expandAlias(name string, args []string) (string, []string, bool) {
+	aliases := map[string]string{}
+	if err := loadConfigJSON(aliasesFile, &aliases); err != nil {
+		debugPrint("aliases: %v\n", err)
+		return name, args, false
+	}
+	expansion, ok := aliases[name]
+	if !ok {
+		return name, args, false
+	}
+	fields := splitCommandLine(expansion)
+	if len(fields) == 0 {
+		return name, args, false
+	}
+	return fields[0], append(fields[1:], args...), true
+}
+
+func runDeviceAlias(args []string) error {
+	aliases := loadDeviceAliases()
+	remove := strings.TrimLeft(args[0], "-") == "remove-device"
+	switch {
+	case remove && len(args) == 2:
+		if _, ok := aliases[args[1]]; !ok {
+			return fmt.Errorf("no alias for device %s", args[1])
+		}
+		delete(aliases, args[1])
+		if err := saveConfigJSON(deviceAliasesFile, aliases); err != nil {
+			return err
+		}
+		fmt.Printf("Removed the alias of %s\n", args[1])
+		return nil
+	case remove:
+		return fmt.Errorf("usage: alias --remove-device <serial>")
+	case len(args) == 1:
+		if len(aliases) == 0 {
+			fmt.Printf("No device aliases defined in %s\n", documentPath(deviceAliasesFile))
+			return nil
+		}
+		color.New(color.FgCyan, color.Bold).Println("Device aliases")
+		for _, serial := range sortedKeys(aliases) {
+			fmt.Printf("  %-22s %s\n", serial, aliases[serial])
+		}
+		return nil
+	case len(args) >= 3:
+		name := strings.TrimSpace(strings.Join(args[2:], " "))
+		if name == "" {
+			return fmt.Errorf("usage: alias --device <serial> <name>")
+		}
+		aliases[args[1]] = name
+		if err := saveConfigJSON(deviceAliasesFile, aliases); err != nil {
+			return err
+		}
+		fmt.Printf("Saved alias %q for %s\n", name, args[1])
+		return nil
+	}
+	return fmt.Errorf("usage: alias --device [<serial> <name>]")
+}
+
+// loadDeviceAliases returns the device aliases, or none when they can't be
+// read.
+func loadDeviceAliases() map[string]string {
+	aliases := map[string]string{}
+	if err := loadConfigJSON(deviceAliasesFile, &aliases); err != nil {
+		debugPrint("device aliases: %v\n", err)
+	}
+	return aliases
+}
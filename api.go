@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	adbctlv1 "adb-info/gen/adbctl/v1"
+	"adb-info/gen/adbctl/v1/adbctlv1connect"
+
+	"connectrpc.com/connect"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+//go:generate protoc --go_out=. --go_opt=module=adb-info --connect-go_out=. --connect-go_opt=module=adb-info adbctl.proto
+
+// apiProto is the service definition clients generate their stubs from.
+//
+//go:embed adbctl.proto
+var apiProto string
+
+var logLinePattern = regexp.MustCompile(`^(\d\d-\d\d \d\d:\d\d:\d\d\.\d{3})\s+(\d+)\s+(\d+)\s+([VDIWEFA])\s+(.*?)\s*: (.*)$`)
+
+func apiErrorf(code connect.Code, format string, a ...interface{}) *connect.Error {
+	return connect.NewError(code, fmt.Errorf(format, a...))
+}
+
+func runAPI(args []string) error {
+	if len(args) > 0 && args[0] == "proto" {
+		fmt.Print(apiProto)
+		return nil
+	}
+	if len(args) == 0 || args[0] != "serve" {
+		return fmt.Errorf("usage: api serve [--listen 127.0.0.1:8751] | api proto")
+	}
+	fs := flag.NewFlagSet("api serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8751", "Address to listen on")
+	parseArgs(fs, args[1:])
+	token := os.Getenv("ADBCTL_API_TOKEN")
+	if host, _, err := net.SplitHostPort(*listen); token == "" && (err != nil || !isLoopbackHost(host)) {
+		fmt.Println("Warning: ADBCTL_API_TOKEN is not set, so anyone who can reach this port can run commands on your devices.")
+	}
+	fmt.Printf("Serving the DeviceFarm API (adbctl.v1 over gRPC, gRPC-Web and Connect) on %s\n", *listen)
+	fmt.Println("Generate clients from the service definition printed by 'adbctl api proto'.")
+	return http.ListenAndServe(*listen, apiHandler(token))
+}
+
+// apiHandler serves the DeviceFarm service. gRPC needs HTTP/2, which h2c
+// provides without TLS; Connect and gRPC-Web clients may use HTTP/1.1.
+func apiHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(adbctlv1connect.NewDeviceFarmHandler(deviceFarm{},
+		connect.WithInterceptors(apiAuthInterceptor{token})))
+	return h2c.NewHandler(mux, &http2.Server{})
+}
+
+// apiAuthInterceptor requires ADBCTL_API_TOKEN as a bearer token, when set.
+type apiAuthInterceptor struct {
+	token string
+}
+
+func (a apiAuthInterceptor) authorize(header http.Header) error {
+	if a.token == "" {
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(header.Get("Authorization")), []byte("Bearer "+a.token)) != 1 {
+		return apiErrorf(connect.CodeUnauthenticated, "missing or wrong bearer token")
+	}
+	return nil
+}
+
+func (a apiAuthInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if err := a.authorize(req.Header()); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
+
+func (a apiAuthInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (a apiAuthInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := a.authorize(conn.RequestHeader()); err != nil {
+			return err
+		}
+		return next(ctx, conn)
+	}
+}
+
+// apiRequireDevice checks that serial names a device adb can use.
+func apiRequireDevice(serial string) error {
+	if serial == "" {
+		return apiErrorf(connect.CodeInvalidArgument, "serial is required")
+	}
+	switch status := adbDeviceStatus(serial); status {
+	case "device":
+		return nil
+	case "":
+		return apiErrorf(connect.CodeNotFound, "device %s is not connected", serial)
+	default:
+		return apiErrorf(connect.CodeUnavailable, "device %s is %s", serial, status)
+	}
+}
+
+// deviceFarm implements the DeviceFarm service.
+type deviceFarm struct{}
+
+func (deviceFarm) ListDevices(ctx context.Context, req *connect.Request[adbctlv1.ListDevicesRequest]) (*connect.Response[adbctlv1.ListDevicesResponse], error) {
+	output, err := exec.CommandContext(ctx, adbPath, "devices", "-l").Output()
+	if err != nil {
+		return nil, apiErrorf(connect.CodeUnavailable, "adb devices: %v", err)
+	}
+	var devices []*adbctlv1.Device
+	var usable []string
+	for _, line := range strings.Split(string(output), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		device := &adbctlv1.Device{Serial: fields[0], State: fields[1]}
+		for _, field := range fields[2:] {
+			if model, found := strings.CutPrefix(field, "model:"); found {
+				device.Model = strings.ReplaceAll(model, "_", " ")
+			}
+		}
+		devices = append(devices, device)
+		if device.State == "device" {
+			usable = append(usable, line)
+		}
+	}
+	summaries := deviceSummaries(usable)
+	for i, j := 0, 0; i < len(devices); i++ {
+		if devices[i].State == "device" {
+			devices[i].Summary = summaries[j]
+			j++
+		}
+	}
+	return connect.NewResponse(&adbctlv1.ListDevicesResponse{Devices: devices}), nil
+}
+
+func (deviceFarm) GetDeviceInfo(ctx context.Context, req *connect.Request[adbctlv1.GetDeviceInfoRequest]) (*connect.Response[adbctlv1.DeviceInfo], error) {
+	if err := apiRequireDevice(req.Msg.Serial); err != nil {
+		return nil, err
+	}
+	row := collectFleetInventoryRow(req.Msg.Serial)
+	return connect.NewResponse(&adbctlv1.DeviceInfo{
+		Serial:      row.Serial,
+		Model:       row.Model,
+		FireOs:      row.FireOS,
+		Android:     row.Android,
+		PatchLevel:  row.PatchLevel,
+		Battery:     row.Battery,
+		FreeStorage: row.FreeStorage,
+		Ip:          row.IP,
+	}), nil
+}
+
+func (deviceFarm) RunCommand(ctx context.Context, req *connect.Request[adbctlv1.RunCommandRequest]) (*connect.Response[adbctlv1.RunCommandResponse], error) {
+	if err := apiRequireDevice(req.Msg.Serial); err != nil {
+		return nil, err
+	}
+	if req.Msg.Command == "" {
+		return nil, apiErrorf(connect.CodeInvalidArgument, "command is required")
+	}
+	timeout := time.Minute
+	if req.Msg.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.Msg.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, adbPath, "-s", req.Msg.Serial, "shell", req.Msg.Command).CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, connect.NewError(connect.CodeDeadlineExceeded, ctx.Err())
+	}
+	var exitErr *exec.ExitError
+	exitCode := 0
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return nil, apiErrorf(connect.CodeUnavailable, "%v", err)
+	}
+	return connect.NewResponse(&adbctlv1.RunCommandResponse{Output: string(output), ExitCode: int32(exitCode)}), nil
+}
+
+func (deviceFarm) StreamShell(ctx context.Context, req *connect.Request[adbctlv1.RunCommandRequest], stream *connect.ServerStream[adbctlv1.ShellOutput]) error {
+	if err := apiRequireDevice(req.Msg.Serial); err != nil {
+		return err
+	}
+	if req.Msg.Command == "" {
+		return apiErrorf(connect.CodeInvalidArgument, "command is required")
+	}
+	if req.Msg.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Msg.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+	err := adbStream(ctx, req.Msg.Serial, func(line string) {
+		stream.Send(&adbctlv1.ShellOutput{Line: line})
+	}, "shell", req.Msg.Command)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	exitCode := 0
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return apiErrorf(connect.CodeUnavailable, "%v", err)
+	}
+	return stream.Send(&adbctlv1.ShellOutput{Exited: true, ExitCode: int32(exitCode)})
+}
+
+func (deviceFarm) StreamLogcat(ctx context.Context, req *connect.Request[adbctlv1.StreamLogcatRequest], stream *connect.ServerStream[adbctlv1.LogLine]) error {
+	if err := apiRequireDevice(req.Msg.Serial); err != nil {
+		return err
+	}
+	err := streamLogcat(ctx, req.Msg.Serial, req.Msg.FilterSpec, func(line string) {
+		stream.Send(parseLogLine(line))
+	})
+	if err != nil && ctx.Err() == nil {
+		return apiErrorf(connect.CodeUnavailable, "%v", err)
+	}
+	return err
+}
+
+// parseLogLine splits a threadtime logcat line into its fields.
+func parseLogLine(line string) *adbctlv1.LogLine {
+	m := logLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return &adbctlv1.LogLine{Line: line}
+	}
+	pid, _ := strconv.Atoi(m[2])
+	tid, _ := strconv.Atoi(m[3])
+	return &adbctlv1.LogLine{Line: line, Timestamp: m[1], Pid: int32(pid), Tid: int32(tid), Level: m[4], Tag: m[5], Message: m[6]}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var badgingValuePattern = regexp.MustCompile(`(\w+)='([^']*)'`)
+
+// sensitivePermissions are runtime ("dangerous") permissions and special
+// access whose addition deserves a reviewer's attention.
+var sensitivePermissions = []string{"CAMERA", "RECORD_AUDIO", "READ_CONTACTS", "WRITE_CONTACTS", "GET_ACCOUNTS",
+	"ACCESS_FINE_LOCATION", "ACCESS_COARSE_LOCATION", "ACCESS_BACKGROUND_LOCATION", "READ_PHONE_STATE", "CALL_PHONE",
+	"READ_SMS", "SEND_SMS", "READ_CALENDAR", "WRITE_CALENDAR", "BODY_SENSORS", "READ_EXTERNAL_STORAGE",
+	"WRITE_EXTERNAL_STORAGE", "READ_MEDIA_IMAGES", "READ_MEDIA_VIDEO", "READ_MEDIA_AUDIO", "MANAGE_EXTERNAL_STORAGE",
+	"SYSTEM_ALERT_WINDOW", "REQUEST_INSTALL_PACKAGES", "QUERY_ALL_PACKAGES", "BLUETOOTH_SCAN", "NEARBY_WIFI_DEVICES",
+	"PACKAGE_USAGE_STATS", "WRITE_SETTINGS", "BIND_ACCESSIBILITY_SERVICE", "READ_LOGS"}
+
+// apkSummary is what apk diff compares between two builds.
+type apkSummary struct {
+	Package     string
+	Version     string
+	MinSDK      string
+	TargetSDK   string
+	Permissions []string
+	Features    []string
+	Libraries   []string // uses-library
+	NativeLibs  []string // lib/<abi>/<name>.so
+	Components  []string // "kind name", with "(exported)" for exported ones
+}
+
+func runApk(args []string) error {
+	if len(args) != 3 || args[0] != "diff" {
+		return fmt.Errorf("usage: apk diff <old.apk> <new.apk>")
+	}
+	old, err := summarizeAPK(args[1])
+	if err != nil {
+		return err
+	}
+	updated, err := summarizeAPK(args[2])
+	if err != nil {
+		return err
+	}
+	if old.Package != updated.Package {
+		color.New(color.FgYellow).Printf("Package names differ: %s and %s\n\n", old.Package, updated.Package)
+	}
+
+	color.New(color.FgCyan, color.Bold).Printf("%s: %s -> %s\n", updated.Package, old.Version, updated.Version)
+	fmt.Print(rule("=", 60))
+	changes := 0
+	for _, field := range [][3]string{{"Min SDK", old.MinSDK, updated.MinSDK}, {"Target SDK", old.TargetSDK, updated.TargetSDK}} {
+		if field[1] != field[2] {
+			fmt.Printf("%-12s %s -> %s\n", field[0]+":", field[1], field[2])
+			changes++
+		}
+	}
+	changes += printSetDiff("Permissions", old.Permissions, updated.Permissions, isSensitivePermission)
+	changes += printSetDiff("Features", old.Features, updated.Features, nil)
+	changes += printSetDiff("Libraries", old.Libraries, updated.Libraries, nil)
+	changes += printSetDiff("Native libraries", old.NativeLibs, updated.NativeLibs, nil)
+	changes += printSetDiff("Components", old.Components, updated.Components, func(c string) bool {
+		return strings.HasSuffix(c, "(exported)")
+	})
+	if changes == 0 {
+		fmt.Println("No differences in permissions, components, SDK levels or libraries.")
+	}
+	return nil
+}
+
+// summarizeAPK reads a local APK with aapt2 (badging and manifest tree) and
+// its zip listing for native libraries.
+func summarizeAPK(path string) (apkSummary, error) {
+	aapt2 := findBuildTool("aapt2")
+	if aapt2 == "" {
+		return apkSummary{}, fmt.Errorf("aapt2 not found; install the SDK build-tools or add aapt2 to PATH")
+	}
+	badging, err := exec.Command(aapt2, "dump", "badging", path).Output()
+	if err != nil {
+		return apkSummary{}, fmt.Errorf("aapt2 dump badging %s: %v", path, err)
+	}
+	summary := parseBadging(string(badging))
+
+	tree, err := exec.Command(aapt2, "dump", "xmltree", "--file", "AndroidManifest.xml", path).Output()
+	if err != nil {
+		return apkSummary{}, fmt.Errorf("aapt2 dump xmltree %s: %v", path, err)
+	}
+	_, components := parseManifestTree(string(tree))
+	for _, c := range components {
+		entry := c.Kind + " " + c.Name
+		if c.Exported {
+			entry += " (exported)"
+		}
+		summary.Components = append(summary.Components, entry)
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return apkSummary{}, err
+	}
+	defer archive.Close()
+	for _, f := range archive.File {
+		if strings.HasPrefix(f.Name, "lib/") && strings.HasSuffix(f.Name, ".so") {
+			summary.NativeLibs = append(summary.NativeLibs, f.Name)
+		}
+	}
+	return summary, nil
+}
+
+func parseBadging(badging string) apkSummary {
+	var summary apkSummary
+	for _, line := range strings.Split(badging, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		values := map[string]string{}
+		for _, m := range badgingValuePattern.FindAllStringSubmatch(value, -1) {
+			values[m[1]] = m[2]
+		}
+		plain := strings.Trim(strings.TrimSpace(value), "'")
+		switch key {
+		case "package":
+			summary.Package = values["name"]
+			summary.Version = fmt.Sprintf("%s (%s)", values["versionName"], values["versionCode"])
+		case "sdkVersion", "minSdkVersion":
+			summary.MinSDK = plain
+		case "targetSdkVersion":
+			summary.TargetSDK = plain
+		case "uses-permission", "uses-permission-sdk-23":
+			summary.Permissions = append(summary.Permissions, values["name"])
+		case "uses-feature":
+			summary.Features = append(summary.Features, values["name"])
+		case "uses-feature-not-required":
+			summary.Features = append(summary.Features, values["name"]+" (optional)")
+		case "uses-library":
+			summary.Libraries = append(summary.Libraries, plain)
+		case "uses-library-not-required":
+			summary.Libraries = append(summary.Libraries, plain+" (optional)")
+		}
+	}
+	return summary
+}
+
+// printSetDiff prints what was added to and removed from a list, marking
+// additions notable reports as noteworthy. It returns the number of changes.
+func printSetDiff(title string, old, updated []string, notable func(string) bool) int {
+	before, after := map[string]bool{}, map[string]bool{}
+	for _, v := range old {
+		before[v] = true
+	}
+	for _, v := range updated {
+		after[v] = true
+	}
+	var added, removed []string
+	for v := range after {
+		if !before[v] {
+			added = append(added, v)
+		}
+	}
+	for v := range before {
+		if !after[v] {
+			removed = append(removed, v)
+		}
+	}
+	if len(added)+len(removed) == 0 {
+		return 0
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	fmt.Printf("\n%s:\n", title)
+	for _, v := range added {
+		if notable != nil && notable(v) {
+			color.New(color.FgRed, color.Bold).Printf("  + %s  !\n", v)
+		} else {
+			color.New(color.FgGreen).Printf("  + %s\n", v)
+		}
+	}
+	for _, v := range removed {
+		color.New(color.FgYellow).Printf("  - %s\n", v)
+	}
+	return len(added) + len(removed)
+}
+
+func isSensitivePermission(permission string) bool {
+	return containsString(sensitivePermissions, strings.TrimPrefix(permission, "android.permission."))
+}
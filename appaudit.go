@@ -0,0 +1,377 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// aapt2 xmltree lines: "E: activity (line=12)" and
+// "A: http://schemas.android.com/apk/res/android:exported(0x01010010)=true".
+var (
+	xmltreeElementPattern   = regexp.MustCompile(`^(\s*)E: ([\w-]+)`)
+	xmltreeAttributePattern = regexp.MustCompile(`^(\s*)A: (?:http://schemas.android.com/apk/res/android:)?(\w+)(?:\(0x[0-9a-f]+\))?="?([^"]*?)"?(?: \(Raw: .*\))?$`)
+)
+
+var auditComponentKinds = []string{"activity", "activity-alias", "service", "receiver", "provider"}
+
+type intentFilter struct {
+	Actions    []string
+	Categories []string
+	Data       []string // scheme://host/path
+}
+
+type appComponent struct {
+	Kind       string
+	Name       string
+	Exported   bool
+	Permission string
+	Attributes map[string]string
+	Filters    []intentFilter
+}
+
+type auditFinding struct {
+	Severity string // HIGH, MEDIUM or INFO
+	Subject  string
+	Message  string
+}
+
+func runApp(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: app info [package] | app audit <package> [--fuzz]")
+	}
+	switch args[0] {
+	case "info":
+		return runAppInfo(args[1:])
+	case "audit":
+		return runAppAudit(args[1:])
+	default:
+		return fmt.Errorf("unknown app subcommand %q", args[0])
+	}
+}
+
+func runAppAudit(args []string) error {
+	fs := flag.NewFlagSet("app audit", flag.ExitOnError)
+	fuzz := fs.Bool("fuzz", false, "Fire benign intents at exported components and report crashes")
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt for --fuzz")
+	args = parseArgs(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("usage: app audit <package> [--fuzz] [--yes]")
+	}
+	pkg, err := appPackage(args, "", "")
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	application, components, err := readManifest(deviceID, pkg)
+	if err != nil {
+		return err
+	}
+	findings := auditComponents(application, components)
+
+	color.New(color.FgCyan, color.Bold).Printf("Exported components of %s\n", pkg)
+	fmt.Print(rule("=", 60))
+	exported := 0
+	for _, c := range components {
+		if !c.Exported {
+			continue
+		}
+		exported++
+		permission := "no permission"
+		if c.Permission != "" {
+			permission = c.Permission
+		}
+		fmt.Printf("%-15s %s (%s)\n", c.Kind, c.Name, permission)
+		for _, filter := range c.Filters {
+			fmt.Printf("  %-13s %s\n", "filter:", strings.Join(append(append(shortNames(filter.Actions), shortNames(filter.Categories)...), filter.Data...), " "))
+		}
+	}
+	if exported == 0 {
+		fmt.Println("No exported components.")
+	}
+
+	fmt.Println()
+	if len(findings) == 0 {
+		color.New(color.FgGreen).Println("No risky exports found.")
+	}
+	for _, f := range findings {
+		c := color.New(color.FgYellow)
+		switch f.Severity {
+		case "HIGH":
+			c = color.New(color.FgRed, color.Bold)
+		case "INFO":
+			c = color.New(color.FgCyan)
+		}
+		c.Printf("%-7s", f.Severity)
+		fmt.Printf(" %s: %s\n", f.Subject, f.Message)
+	}
+
+	if !*fuzz {
+		return nil
+	}
+	if !*yes && !confirm(fmt.Sprintf("Type 'yes' to send test intents to %d exported component(s) of %s on %s: ", exported, pkg, deviceID)) {
+		return fmt.Errorf("fuzzing cancelled")
+	}
+	return fuzzComponents(deviceID, pkg, components)
+}
+
+// readManifest pulls the app's base APK and reads its compiled manifest with
+// aapt2, which unlike dumpsys shows exported flags and permissions.
+func readManifest(deviceID, pkg string) (map[string]string, []appComponent, error) {
+	aapt2 := findBuildTool("aapt2")
+	if aapt2 == "" {
+		return nil, nil, fmt.Errorf("aapt2 not found; install the SDK build-tools or add aapt2 to PATH")
+	}
+	var apk string
+	for _, line := range strings.Split(runAdbCommand(deviceID, "pm path "+pkg, 5*time.Second), "\n") {
+		// Split installs list the base APK first; the manifest is in it.
+		if path, found := strings.CutPrefix(strings.TrimSpace(line), "package:"); found && (apk == "" || strings.HasSuffix(path, "/base.apk")) {
+			apk = path
+		}
+	}
+	if apk == "" {
+		return nil, nil, fmt.Errorf("%s is not installed", pkg)
+	}
+	tmp, err := os.MkdirTemp("", "adbctl-audit")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(tmp)
+	local := filepath.Join(tmp, "base.apk")
+	if err := adbPull(deviceID, apk, local); err != nil {
+		return nil, nil, err
+	}
+	output, err := exec.Command(aapt2, "dump", "xmltree", "--file", "AndroidManifest.xml", local).Output()
+	if err != nil {
+		return nil, nil, fmt.Errorf("aapt2 dump xmltree: %v", err)
+	}
+	application, components := parseManifestTree(string(output))
+	return application, components, nil
+}
+
+// parseManifestTree walks aapt2's indented element tree, returning the
+// <application> attributes and every component with its intent filters.
+func parseManifestTree(tree string) (map[string]string, []appComponent) {
+	application := map[string]string{}
+	var components []appComponent
+	var current *appComponent
+	var filter *intentFilter
+	type element struct {
+		name   string
+		indent int
+	}
+	var stack []element
+	for _, line := range strings.Split(tree, "\n") {
+		if m := xmltreeElementPattern.FindStringSubmatch(line); m != nil {
+			indent := len(m[1])
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			stack = append(stack, element{m[2], indent})
+			switch {
+			case containsString(auditComponentKinds, m[2]):
+				components = append(components, appComponent{Kind: m[2], Attributes: map[string]string{}})
+				current, filter = &components[len(components)-1], nil
+			case m[2] == "intent-filter" && current != nil && len(stack) > 1 && containsString(auditComponentKinds, stack[len(stack)-2].name):
+				current.Filters = append(current.Filters, intentFilter{})
+				filter = &current.Filters[len(current.Filters)-1]
+			}
+			continue
+		}
+		m := xmltreeAttributePattern.FindStringSubmatch(line)
+		if m == nil || len(stack) == 0 {
+			continue
+		}
+		key, value := m[2], m[3]
+		switch owner := stack[len(stack)-1].name; {
+		case owner == "application":
+			application[key] = value
+		case containsString(auditComponentKinds, owner) && current != nil:
+			current.Attributes[key] = value
+		case filter != nil && key == "name" && owner == "action":
+			filter.Actions = append(filter.Actions, value)
+		case filter != nil && key == "name" && owner == "category":
+			filter.Categories = append(filter.Categories, value)
+		case filter != nil && owner == "data":
+			filter.Data = mergeFilterData(filter.Data, key, value)
+		}
+	}
+
+	for i := range components {
+		c := &components[i]
+		c.Name = c.Attributes["name"]
+		c.Permission = c.Attributes["permission"]
+		if c.Kind == "provider" && c.Permission == "" {
+			c.Permission = c.Attributes["readPermission"]
+		}
+		// Without android:exported, components with intent filters are exported
+		// (apps targeting Android 12+ must set it explicitly).
+		if exported, ok := c.Attributes["exported"]; ok {
+			c.Exported = exported == "true" || exported == "0xffffffff"
+		} else {
+			c.Exported = len(c.Filters) > 0
+		}
+		if c.Attributes["enabled"] == "false" {
+			c.Exported = false
+		}
+	}
+	return application, components
+}
+
+// mergeFilterData accumulates <data> attributes into scheme://host/path URIs;
+// a new scheme starts a new URI.
+func mergeFilterData(data []string, key, value string) []string {
+	switch key {
+	case "scheme":
+		return append(data, value+"://")
+	case "host":
+		if n := len(data); n > 0 {
+			data[n-1] += value
+		} else {
+			data = append(data, "*://"+value)
+		}
+	case "path", "pathPrefix", "pathPattern":
+		if n := len(data); n > 0 {
+			data[n-1] += value
+		}
+	case "mimeType":
+		data = append(data, value)
+	}
+	return data
+}
+
+// auditComponents flags the obviously risky parts of a manifest.
+func auditComponents(application map[string]string, components []appComponent) []auditFinding {
+	var findings []auditFinding
+	isTrue := func(v string) bool { return v == "true" || v == "0xffffffff" }
+	if isTrue(application["debuggable"]) {
+		findings = append(findings, auditFinding{"HIGH", "application", "android:debuggable is set; any app with run-as can read its data"})
+	}
+	if isTrue(application["usesCleartextTraffic"]) {
+		findings = append(findings, auditFinding{"MEDIUM", "application", "cleartext (http) traffic is allowed"})
+	}
+	if v, ok := application["allowBackup"]; !ok || isTrue(v) {
+		findings = append(findings, auditFinding{"INFO", "application", "adb backup of app data is allowed"})
+	}
+	for _, c := range components {
+		if !c.Exported {
+			continue
+		}
+		launcher := false
+		for _, f := range c.Filters {
+			if containsString(f.Categories, "android.intent.category.LAUNCHER") || containsString(f.Categories, "android.intent.category.LEANBACK_LAUNCHER") {
+				launcher = true
+			}
+			if containsString(f.Categories, "android.intent.category.BROWSABLE") {
+				findings = append(findings, auditFinding{"INFO", c.Name, "reachable from web links: " + strings.Join(f.Data, ", ")})
+			}
+		}
+		switch {
+		case c.Permission != "":
+		case c.Kind == "provider":
+			severity := "HIGH"
+			if isTrue(c.Attributes["grantUriPermissions"]) {
+				severity = "MEDIUM"
+			}
+			findings = append(findings, auditFinding{severity, c.Name, "exported content provider without read/write permissions"})
+		case c.Kind == "service":
+			findings = append(findings, auditFinding{"HIGH", c.Name, "exported service without a permission; any app can bind to or start it"})
+		case c.Kind == "receiver":
+			findings = append(findings, auditFinding{"MEDIUM", c.Name, "exported receiver without a permission; any app can send it broadcasts"})
+		case !launcher && len(c.Filters) == 0:
+			findings = append(findings, auditFinding{"MEDIUM", c.Name, "explicitly exported without intent filters or a permission"})
+		}
+	}
+	order := map[string]int{"HIGH": 0, "MEDIUM": 1, "INFO": 2}
+	sort.SliceStable(findings, func(i, j int) bool { return order[findings[i].Severity] < order[findings[j].Severity] })
+	return findings
+}
+
+// fuzzComponents starts every exported, unprotected component with no extras
+// and with each action and data URI it declares, checking the log for a crash
+// of the app after each intent.
+func fuzzComponents(deviceID, pkg string, components []appComponent) error {
+	crashes := 0
+	sent := 0
+	for _, c := range components {
+		if !c.Exported || c.Permission != "" || c.Kind == "provider" {
+			continue
+		}
+		component := pkg + "/" + c.Name
+		intents := [][]string{{}}
+		for _, f := range c.Filters {
+			for _, action := range f.Actions {
+				intents = append(intents, []string{"-a", action})
+				for _, data := range f.Data {
+					if strings.Contains(data, "://") {
+						intents = append(intents, []string{"-a", action, "-d", data})
+					}
+				}
+			}
+		}
+		for _, intent := range intents {
+			adbShell(deviceID, "logcat", "-c")
+			amArgs := []string{"am"}
+			switch c.Kind {
+			case "service":
+				amArgs = append(amArgs, "startservice")
+			case "receiver":
+				amArgs = append(amArgs, "broadcast")
+			default:
+				amArgs = append(amArgs, "start")
+			}
+			// Names, actions and URIs come from the app's manifest; quote them
+			// so the device shell passes them to am untouched ($ in inner
+			// class names, & in URIs, * in path patterns).
+			for i, arg := range intent {
+				if i%2 == 1 {
+					arg = shellQuote(arg)
+				}
+				amArgs = append(amArgs, arg)
+			}
+			amArgs = append(amArgs, "-n", shellQuote(component))
+			adbShell(deviceID, amArgs...)
+			sent++
+			time.Sleep(time.Second)
+			log, _ := adbShellIdempotent(deviceID, "logcat", "-d", "-b", "crash")
+			description := strings.Join(append([]string{c.Kind, c.Name}, intent...), " ")
+			if strings.Contains(log, "Process: "+pkg+",") {
+				crashes++
+				color.New(color.FgRed).Printf("CRASH   %s\n", description)
+				for _, line := range strings.Split(log, "\n") {
+					if strings.Contains(line, "Exception") || strings.Contains(line, "Error") {
+						fmt.Printf("        %s\n", strings.TrimSpace(line))
+						break
+					}
+				}
+			} else {
+				fmt.Printf("ok      %s\n", description)
+			}
+		}
+	}
+	adbShell(deviceID, "am", "force-stop", shellQuote(pkg))
+	adbShell(deviceID, "input", "keyevent", "KEYCODE_HOME")
+	fmt.Printf("\nSent %d intent(s), %d crash(es).\n", sent, crashes)
+	if crashes > 0 {
+		return fmt.Errorf("%s crashed on %d test intent(s)", pkg, crashes)
+	}
+	return nil
+}
+
+// shortNames drops the common android.intent.* prefixes for display.
+func shortNames(names []string) []string {
+	short := make([]string, len(names))
+	for i, name := range names {
+		short[i] = strings.TrimPrefix(strings.TrimPrefix(name, "android.intent.action."), "android.intent.category.")
+	}
+	return short
+}
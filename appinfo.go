@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var dumpsysPackageFieldPattern = regexp.MustCompile(`\b(versionName|versionCode|minSdk|targetSdk|firstInstallTime|lastUpdateTime|installerPackageName)=(\d{4}-\d\d-\d\d \d\d:\d\d:\d\d|\S+)`)
+
+// appInfoFields are the dumpsys package fields shown, with their labels.
+var appInfoFields = [][2]string{{"versionName", "Version"}, {"versionCode", "Version code"}, {"minSdk", "Min SDK"},
+	{"targetSdk", "Target SDK"}, {"installerPackageName", "Installer"}, {"firstInstallTime", "First installed"},
+	{"lastUpdateTime", "Last updated"}}
+
+func runAppInfo(args []string) error {
+	fs := flag.NewFlagSet("app-info", flag.ExitOnError)
+	flavor := fs.String("flavor", "", "Show this flavor's package from the project profile")
+	variant := fs.String("variant", "", "In a Gradle project, show this build variant's application id (default debug)")
+	args = parseArgs(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("usage: app-info [package] [--flavor name | --variant name]")
+	}
+	pkg, err := appPackage(args, *flavor, *variant)
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	output, err := adbShellIdempotent(deviceID, "dumpsys", "package", shellQuote(pkg))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(output, "Package ["+pkg+"]") {
+		return fmt.Errorf("%s is not installed", pkg)
+	}
+	fields := map[string]string{}
+	for _, m := range dumpsysPackageFieldPattern.FindAllStringSubmatch(output, -1) {
+		if _, seen := fields[m[1]]; !seen {
+			fields[m[1]] = m[2]
+		}
+	}
+
+	color.New(color.FgCyan, color.Bold).Println(pkg)
+	fmt.Print(rule("=", 40))
+	for _, field := range appInfoFields {
+		value := fields[field[0]]
+		if value == "" {
+			value = "n/a"
+		}
+		fmt.Printf("%-18s %s\n", field[1]+":", value)
+	}
+
+	framework, engine := appFramework(deviceID, pkg)
+	if framework == "" {
+		return nil
+	}
+	fmt.Printf("%-18s %s\n", "Framework:", framework)
+	if engine != "" {
+		fmt.Printf("%-18s %s\n", "Engine:", engine)
+	}
+	if project := findFrameworkProject(); project != nil && project.Framework == framework {
+		for _, version := range frameworkVersions(project) {
+			fmt.Printf("%-18s %s\n", version[0]+":", version[1])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func runAppLinks(args []string) error {
+	fs := flag.NewFlagSet("applinks", flag.ExitOnError)
+	reverify := fs.Bool("reverify", false, "Re-run domain verification before reporting")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: applinks <package> [--reverify]")
+	}
+	pkg := positional[0]
+	deviceID := pickDevice()
+
+	sdk, _ := strconv.Atoi(runAdbCommand(deviceID, "getprop ro.build.version.sdk", 5*time.Second))
+	if sdk < 31 {
+		// Before Android 12 only the intent-filter verification summary exists.
+		output, err := adbShellIdempotent(deviceID, "dumpsys", "package", "domain-preferred-apps")
+		if err != nil {
+			return err
+		}
+		printAppLinksLegacy(output, pkg)
+		return nil
+	}
+
+	if *reverify {
+		if output, err := adbShell(deviceID, "pm", "verify-app-links", "--re-verify", shellQuote(pkg)); err != nil {
+			return fmt.Errorf("re-verification failed: %s", output)
+		}
+		fmt.Println("Re-verification requested, waiting for the verifier...")
+		time.Sleep(5 * time.Second)
+	}
+
+	output, err := adbShellIdempotent(deviceID, "pm", "get-app-links", shellQuote(pkg))
+	if err != nil || output == "" {
+		return fmt.Errorf("no app-links information for %s: %s", pkg, output)
+	}
+	states := parseAppLinkStates(output)
+	color.New(color.FgCyan, color.Bold).Printf("Domain verification for %s\n", pkg)
+	fmt.Print(rule("=", 40))
+	if len(states) == 0 {
+		fmt.Println("No autoVerify domains declared.")
+	}
+	for _, s := range states {
+		c := color.New(color.FgYellow)
+		if s[1] == "verified" || s[1] == "approved" {
+			c = color.New(color.FgGreen)
+		}
+		fmt.Printf("  %-40s ", s[0])
+		c.Println(s[1])
+	}
+	return nil
+}
+
+// parseAppLinkStates extracts "domain: state" pairs from the
+// "Domain verification state:" block of `pm get-app-links`.
+func parseAppLinkStates(output string) [][2]string {
+	var states [][2]string
+	inBlock := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Domain verification state:") {
+			inBlock = true
+			continue
+		}
+		if !inBlock {
+			continue
+		}
+		domain, state, found := strings.Cut(trimmed, ": ")
+		if !found || strings.HasSuffix(trimmed, ":") {
+			inBlock = false
+			continue
+		}
+		states = append(states, [2]string{domain, state})
+	}
+	return states
+}
+
+func printAppLinksLegacy(output, pkg string) {
+	color.New(color.FgCyan, color.Bold).Printf("Domain preferences for %s\n", pkg)
+	fmt.Print(rule("=", 40))
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		if strings.Contains(line, pkg) {
+			fmt.Println(strings.TrimSpace(line))
+			found = true
+		}
+	}
+	if !found {
+		fmt.Println("No domain verification entries found.")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var assertionPattern = regexp.MustCompile(`^\s*([\w.\-]+)\s*(==|!=|>=|<=|>|<|=~|contains)\s*(.*?)\s*$`)
+
+type assertion struct {
+	Expr     string
+	Path     string
+	Op       string
+	Expected string
+}
+
+func runAssert(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(`usage: assert "meminfo.MemAvailable > 500000" "props.ro.build.version.sdk >= 30" ...`)
+	}
+
+	var assertions []assertion
+	var namespaces []string
+	for _, expr := range args {
+		m := assertionPattern.FindStringSubmatch(expr)
+		if m == nil {
+			return fmt.Errorf("cannot parse assertion %q", expr)
+		}
+		a := assertion{expr, m[1], m[2], strings.Trim(m[3], `"'`)}
+		ns, _, _ := strings.Cut(a.Path, ".")
+		if _, ok := schemaCollectors[ns]; !ok {
+			return fmt.Errorf("unknown namespace %q in %q", ns, expr)
+		}
+		if !containsString(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+		assertions = append(assertions, a)
+	}
+
+	schema := collectDeviceSchema(pickDevice(), namespaces...)
+	failed := 0
+	for _, a := range assertions {
+		actual, found := schema.lookup(a.Path)
+		ok, err := evaluateAssertion(actual, a.Op, a.Expected)
+		switch {
+		case !found:
+			failed++
+			color.New(color.FgRed).Printf("FAIL  %s (%s is not set)\n", a.Expr, a.Path)
+		case err != nil:
+			failed++
+			color.New(color.FgRed).Printf("FAIL  %s (%v)\n", a.Expr, err)
+		case !ok:
+			failed++
+			color.New(color.FgRed).Printf("FAIL  %s (actual: %s)\n", a.Expr, actual)
+		default:
+			color.New(color.FgGreen).Printf("PASS  %s\n", a.Expr)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d assertions failed", failed, len(assertions))
+	}
+	return nil
+}
+
+// evaluateAssertion compares numerically when both sides are numbers and as
+// strings otherwise; ordering operators require numbers.
+func evaluateAssertion(actual, op, expected string) (bool, error) {
+	switch op {
+	case "contains":
+		return strings.Contains(actual, expected), nil
+	case "=~":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(actual), nil
+	}
+
+	a, errA := strconv.ParseFloat(actual, 64)
+	e, errE := strconv.ParseFloat(expected, 64)
+	numeric := errA == nil && errE == nil
+	switch op {
+	case "==":
+		if numeric {
+			return a == e, nil
+		}
+		return actual == expected, nil
+	case "!=":
+		if numeric {
+			return a != e, nil
+		}
+		return actual != expected, nil
+	}
+	if !numeric {
+		return false, fmt.Errorf("%q and %q are not both numbers", actual, expected)
+	}
+	switch op {
+	case ">":
+		return a > e, nil
+	case ">=":
+		return a >= e, nil
+	case "<":
+		return a < e, nil
+	default:
+		return a <= e, nil
+	}
+}
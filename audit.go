@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// auditEntry is one line of the local audit log. Nothing is recorded unless
+// the user opted in with `adbctl stats enable`, and nothing leaves the host.
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Device     string    `json:"device,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// lastPickedDevice is the device chosen by pickDevice, recorded with the command.
+var lastPickedDevice string
+
+func auditLogPath() string {
+	return filepath.Join(configDir(), "audit.log")
+}
+
+// recordAudit appends an entry when the audit log exists (i.e. stats are enabled).
+func recordAudit(command, device string, start time.Time, err error) {
+	f, openErr := os.OpenFile(auditLogPath(), os.O_WRONLY|os.O_APPEND, 0644)
+	if openErr != nil {
+		return
+	}
+	defer f.Close()
+	entry := auditEntry{Time: start, Command: command, Device: device, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	data, _ := json.Marshal(entry)
+	f.Write(append(data, '\n'))
+}
+
+func readAuditLog() ([]auditEntry, error) {
+	f, err := os.Open(auditLogPath())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry auditEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// BatteryManager constants as printed by `dumpsys battery`.
+var batteryStatusNames = map[string]string{"1": "Unknown", "2": "Charging", "3": "Discharging", "4": "Not charging", "5": "Full"}
+var batteryHealthNames = map[string]string{"1": "Unknown", "2": "Good", "3": "Overheat", "4": "Dead", "5": "Over voltage", "6": "Failure", "7": "Cold"}
+
+var (
+	batteryDrainerPattern = regexp.MustCompile(`^\s*(?:Uid|UID) (\S+): ([\d.]+)`)
+	batteryDurationMsec   = regexp.MustCompile(`\s*\d+ms$`)
+)
+
+type batteryDrainer struct {
+	Name string
+	MAh  float64
+}
+
+func runBattery(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "history":
+			return runBatteryHistory(args[1:])
+		case "simulate":
+			return runBatterySimulate(args[1:])
+		case "reset":
+			if output, err := adbShell(pickDevice(), "dumpsys", "battery", "reset"); err != nil {
+				return fmt.Errorf("resetting the battery simulation failed: %s", output)
+			}
+			fmt.Println("Battery reporting is back to the real hardware state.")
+			return nil
+		}
+	}
+	fs := flag.NewFlagSet("battery", flag.ExitOnError)
+	top := fs.Int("top", 5, "Number of top drainers to show")
+	positional := parseArgs(fs, args)
+	if len(positional) > 0 {
+		return fmt.Errorf("unknown battery action %q", positional[0])
+	}
+	deviceID := pickDevice()
+	return showBattery(deviceID, *top)
+}
+
+func showBattery(deviceID string, top int) error {
+	output, err := adbShellIdempotent(deviceID, "dumpsys", "battery")
+	if err != nil {
+		return err
+	}
+	fields := parseKeyValueLines(output)
+	color.New(color.FgCyan, color.Bold).Println("Battery")
+	fmt.Print(rule("=", 40))
+	if fields["present"] == "false" {
+		fmt.Println("No battery (mains-powered device).")
+		return nil
+	}
+
+	rows := [][2]string{
+		{"Level", withUnit(fields["level"], "%")},
+		{"Status", lookupOr(batteryStatusNames, fields["status"])},
+		{"Health", lookupOr(batteryHealthNames, fields["health"])},
+		{"Power Source", batteryPowerSource(fields)},
+		{"Temperature", tenthsCelsius(fields["temperature"])},
+		{"Voltage", withUnit(fields["voltage"], " mV")},
+		{"Technology", fields["technology"]},
+	}
+	if counter, err := strconv.Atoi(fields["Charge counter"]); err == nil && counter > 0 {
+		rows = append(rows, [2]string{"Charge Counter", fmt.Sprintf("%d mAh", counter/1000)})
+	}
+	if cycles := batteryCycleCount(deviceID, fields); cycles != "" {
+		rows = append(rows, [2]string{"Charge Cycles", cycles})
+	}
+	if capacity := batteryCapacityHealth(deviceID); capacity != "" {
+		rows = append(rows, [2]string{"Capacity vs Design", capacity})
+	}
+
+	stats, _ := adbShellIdempotent(deviceID, "dumpsys", "batterystats", "--charged")
+	onBattery, screenOn, drainers := parseBatteryStatsSummary(stats)
+	rows = append(rows, [2]string{"On Battery Since Charge", onBattery}, [2]string{"Screen On Time", screenOn})
+	for _, row := range rows {
+		value := row[1]
+		if value == "" {
+			value = "n/a"
+		}
+		color.New(color.FgGreen).Printf("%-24s : ", row[0])
+		fmt.Println(value)
+	}
+
+	if len(drainers) > 0 {
+		color.New(color.FgCyan, color.Bold).Println("\nTop drainers since last charge")
+		names := packagesByUID(deviceID)
+		for i, d := range drainers {
+			if i == top {
+				break
+			}
+			fmt.Printf("  %-45s %8.1f mAh\n", batteryDrainerName(d.Name, names), d.MAh)
+		}
+	}
+	return nil
+}
+
+// runBatterySimulate overrides what BatteryManager reports, so low-battery
+// and charging UI can be tested without draining the device. The override
+// lasts until `battery reset` or a reboot.
+func runBatterySimulate(args []string) error {
+	fs := flag.NewFlagSet("battery simulate", flag.ExitOnError)
+	level := fs.Int("level", -1, "Battery level to report (0-100)")
+	unplugged := fs.Bool("unplugged", false, "Report no charger, even while connected over USB")
+	status := fs.String("status", "", "Charging status: charging, discharging, not-charging or full")
+	parseArgs(fs, args)
+	if *level < -1 || *level > 100 {
+		return fmt.Errorf("--level must be between 0 and 100")
+	}
+
+	var commands [][]string
+	if *unplugged {
+		commands = append(commands, []string{"dumpsys", "battery", "unplug"})
+	}
+	if *level >= 0 {
+		commands = append(commands, []string{"dumpsys", "battery", "set", "level", strconv.Itoa(*level)})
+	}
+	if *status != "" {
+		code := ""
+		for value, name := range batteryStatusNames {
+			if strings.EqualFold(strings.ReplaceAll(name, " ", "-"), *status) {
+				code = value
+			}
+		}
+		if code == "" {
+			return fmt.Errorf("unknown --status %q", *status)
+		}
+		commands = append(commands, []string{"dumpsys", "battery", "set", "status", code})
+	}
+	if len(commands) == 0 {
+		return fmt.Errorf("usage: battery simulate [--level n] [--unplugged] [--status discharging]")
+	}
+
+	deviceID := pickDevice()
+	for _, command := range commands {
+		if output, err := adbShell(deviceID, command...); err != nil {
+			return fmt.Errorf("%s failed: %s", strings.Join(command, " "), output)
+		}
+	}
+	fmt.Println("Battery simulation active; run 'adbctl battery reset' to restore real values.")
+	return nil
+}
+
+// parseKeyValueLines reads "key: value" lines, as printed by dumpsys battery.
+func parseKeyValueLines(output string) map[string]string {
+	fields := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, found := strings.Cut(strings.TrimSpace(line), ": "); found {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+func withUnit(value, unit string) string {
+	if value == "" {
+		return ""
+	}
+	return value + unit
+}
+
+func lookupOr(names map[string]string, value string) string {
+	if name, ok := names[value]; ok {
+		return name
+	}
+	return value
+}
+
+func batteryPowerSource(fields map[string]string) string {
+	var sources []string
+	for _, source := range []string{"AC", "USB", "Wireless", "Dock"} {
+		if fields[source+" powered"] == "true" {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return "Battery"
+	}
+	return strings.Join(sources, ", ")
+}
+
+func tenthsCelsius(value string) string {
+	tenths, err := strconv.Atoi(value)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%.1f°C", float64(tenths)/10)
+}
+
+// batteryCycleCount prefers dumpsys (Android 14 reports "Charge cycles" or
+// "cycle count") and falls back to the power supply driver.
+func batteryCycleCount(deviceID string, fields map[string]string) string {
+	for _, key := range []string{"Charge cycles", "cycle count"} {
+		if fields[key] != "" {
+			return fields[key]
+		}
+	}
+	output := runAdbCommand(deviceID, "cat /sys/class/power_supply/battery/cycle_count 2>/dev/null", 5*time.Second)
+	if _, err := strconv.Atoi(output); err != nil {
+		return ""
+	}
+	return output
+}
+
+// batteryCapacityHealth compares the learned full charge with the design
+// capacity, where the driver exposes both.
+func batteryCapacityHealth(deviceID string) string {
+	output := runAdbCommand(deviceID, "cat /sys/class/power_supply/battery/charge_full /sys/class/power_supply/battery/charge_full_design 2>/dev/null", 5*time.Second)
+	values := strings.Fields(output)
+	if len(values) != 2 {
+		return ""
+	}
+	full, err1 := strconv.Atoi(values[0])
+	design, err2 := strconv.Atoi(values[1])
+	if err1 != nil || err2 != nil || design <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%% (%d of %d mAh)", 100*float64(full)/float64(design), full/1000, design/1000)
+}
+
+// parseBatteryStatsSummary extracts the time on battery, screen-on time and
+// per-UID estimated power use (highest first) from `dumpsys batterystats`.
+func parseBatteryStatsSummary(output string) (string, string, []batteryDrainer) {
+	var onBattery, screenOn string
+	var drainers []batteryDrainer
+	inPowerUse := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case onBattery == "" && strings.HasPrefix(trimmed, "Time on battery: "):
+			onBattery = batteryStatsDuration(strings.TrimPrefix(trimmed, "Time on battery: "))
+		case screenOn == "" && strings.HasPrefix(trimmed, "Screen on: "):
+			screenOn = batteryStatsDuration(strings.TrimPrefix(trimmed, "Screen on: "))
+		case strings.HasPrefix(trimmed, "Estimated power use (mAh)"):
+			inPowerUse = true
+		case inPowerUse && trimmed == "":
+			inPowerUse = false
+		case inPowerUse:
+			if m := batteryDrainerPattern.FindStringSubmatch(line); m != nil {
+				mAh, _ := strconv.ParseFloat(m[2], 64)
+				drainers = append(drainers, batteryDrainer{m[1], mAh})
+			}
+		}
+	}
+	sort.SliceStable(drainers, func(i, j int) bool { return drainers[i].MAh > drainers[j].MAh })
+	return onBattery, screenOn, drainers
+}
+
+// batteryStatsDuration turns "1h 2m 3s 456ms (12.3%) realtime, ..." into "1h 2m 3s".
+func batteryStatsDuration(value string) string {
+	value, _, _ = strings.Cut(value, "(")
+	return batteryDurationMsec.ReplaceAllString(strings.TrimSpace(value), "")
+}
+
+// batteryDrainerName resolves a batterystats UID such as "u0a123" (app 123 of
+// user 0) or "1000" to package names.
+func batteryDrainerName(token string, names map[int][]string) string {
+	var user, app int
+	if _, err := fmt.Sscanf(token, "u%da%d", &user, &app); err == nil {
+		return uidName(10000+app, names)
+	}
+	if uid, err := strconv.Atoi(token); err == nil {
+		return uidName(uid%100000, names)
+	}
+	return token
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// batteryHistoryEvent is one line of `dumpsys batterystats --history` with
+// the battery state carried forward, so every row can be plotted on its own.
+type batteryHistoryEvent struct {
+	Time        time.Time `json:"time"`
+	Level       int       `json:"level"`
+	Status      string    `json:"status,omitempty"`
+	Plug        string    `json:"plug,omitempty"`
+	Temperature float64   `json:"temperature_c,omitempty"`
+	VoltageMV   int       `json:"voltage_mv,omitempty"`
+	Changes     string    `json:"changes,omitempty"`
+}
+
+var (
+	historyLinePattern   = regexp.MustCompile(`^\s*(0|\+[\dhms]+) \(\d+\) (\d{3})\s*(.*)$`)
+	historyTimePattern   = regexp.MustCompile(`TIME:\s*(\d{4}-\d{2}-\d{2}-\d{2}-\d{2}-\d{2})`)
+	historyOffsetPattern = regexp.MustCompile(`(\d+)(ms|d|h|m|s)`)
+)
+
+func runBatteryHistory(args []string) error {
+	fs := flag.NewFlagSet("battery history", flag.ExitOnError)
+	since := fs.Duration("since", 24*time.Hour, "Only events newer than this")
+	output := fs.String("o", "", "Write to a .csv or .json file instead of CSV on stdout")
+	reset := fs.Bool("reset", false, "Clear battery statistics (for a fresh test run) instead of exporting")
+	parseArgs(fs, args)
+
+	deviceID := pickDevice()
+	if *reset {
+		if out, err := adbShell(deviceID, "dumpsys", "batterystats", "--reset"); err != nil {
+			return fmt.Errorf("resetting battery stats failed: %s", out)
+		}
+		fmt.Println("Battery statistics reset. Unplug the device (or run 'dumpsys battery unplug') to start recording.")
+		return nil
+	}
+
+	history, err := adbShellIdempotent(deviceID, "dumpsys", "batterystats", "--history")
+	if err != nil {
+		return err
+	}
+	events := parseBatteryHistory(history)
+	cutoff := time.Now().Add(-*since)
+	var recent []batteryHistoryEvent
+	for _, e := range events {
+		if e.Time.After(cutoff) {
+			recent = append(recent, e)
+		}
+	}
+	if len(recent) == 0 {
+		return fmt.Errorf("no battery history in the last %v (%d events in total)", *since, len(events))
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if strings.EqualFold(filepath.Ext(*output), ".json") {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(recent)
+	} else {
+		err = writeBatteryHistoryCSV(w, recent)
+	}
+	if err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Printf("Wrote %d events to %s\n", len(recent), *output)
+	}
+	return nil
+}
+
+func writeBatteryHistoryCSV(w io.Writer, events []batteryHistoryEvent) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "level", "status", "plug", "temperature_c", "voltage_mv", "changes"})
+	for _, e := range events {
+		cw.Write([]string{e.Time.Format(time.RFC3339), strconv.Itoa(e.Level), e.Status, e.Plug,
+			strconv.FormatFloat(e.Temperature, 'f', 1, 64), strconv.Itoa(e.VoltageMV), e.Changes})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseBatteryHistory converts the history lines to absolute times. Offsets
+// such as "+1m02s005ms" count from the start of the history; TIME: markers
+// (at the start and after a reset or clock change) anchor them to the wall
+// clock, in the device's local time zone as printed.
+func parseBatteryHistory(output string) []batteryHistoryEvent {
+	var events []batteryHistoryEvent
+	var state batteryHistoryEvent
+	var base time.Time
+	var baseOffset time.Duration
+	for _, line := range strings.Split(output, "\n") {
+		if t := historyTimePattern.FindStringSubmatch(line); t != nil {
+			if parsed, err := time.ParseInLocation("2006-01-02-15-04-05", t[1], time.Local); err == nil {
+				base, baseOffset = parsed, parseHistoryOffset(strings.Fields(line)[0])
+			}
+			continue
+		}
+		m := historyLinePattern.FindStringSubmatch(line)
+		if m == nil || base.IsZero() {
+			continue
+		}
+		offset := parseHistoryOffset(m[1])
+		state.Time = base.Add(offset - baseOffset)
+		state.Level, _ = strconv.Atoi(m[2])
+		var changes []string
+		for _, token := range strings.Fields(m[3]) {
+			key, value, found := strings.Cut(token, "=")
+			switch {
+			case found && key == "status":
+				state.Status = value
+			case found && key == "plug":
+				state.Plug = value
+			case found && key == "temp":
+				tenths, _ := strconv.Atoi(value)
+				state.Temperature = float64(tenths) / 10
+			case found && key == "volt":
+				state.VoltageMV, _ = strconv.Atoi(value)
+			default:
+				changes = append(changes, token)
+			}
+		}
+		state.Changes = strings.Join(changes, " ")
+		events = append(events, state)
+	}
+	return events
+}
+
+func parseHistoryOffset(value string) time.Duration {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "h": time.Hour, "m": time.Minute, "s": time.Second, "ms": time.Millisecond}
+	var offset time.Duration
+	for _, m := range historyOffsetPattern.FindAllStringSubmatch(value, -1) {
+		n, _ := strconv.Atoi(m[1])
+		offset += time.Duration(n) * units[m[2]]
+	}
+	return offset
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Command timeouts in the code are budgets for a USB link. Wireless devices
+// get them scaled by how much slower their link measured, so dumpsys output
+// is not cut off over a weak WiFi connection. ADBCTL_TIMEOUT_SCALE fixes the
+// factor for every device instead.
+const (
+	budgetBaseLatency    = 50 * time.Millisecond
+	budgetBaseThroughput = 4.0 // MB/s
+	maxTimeoutScale      = 6.0
+
+	// screencapBudget bounds one screencap -p, a few MB of PNG at 4K.
+	screencapBudget = 15 * time.Second
+	// transferBaseBudget is the slack added to a pull's size-based budget.
+	transferBaseBudget = 30 * time.Second
+)
+
+// linkScale is a device's timeout factor, measured once.
+type linkScale struct {
+	once  sync.Once
+	scale float64
+}
+
+var (
+	timeoutScalesMu sync.Mutex // guards the map only; measuring happens outside it
+	timeoutScales   = map[string]*linkScale{}
+)
+
+// scaledTimeout returns the budget for base on deviceID, measuring the link
+// the first time a wireless device is used.
+func scaledTimeout(deviceID string, base time.Duration) time.Duration {
+	return time.Duration(float64(base) * timeoutScale(deviceID))
+}
+
+func timeoutScale(deviceID string) float64 {
+	if scale, err := strconv.ParseFloat(os.Getenv("ADBCTL_TIMEOUT_SCALE"), 64); err == nil && scale > 0 {
+		return scale
+	}
+	if !isWirelessSerial(deviceID) {
+		return 1
+	}
+	timeoutScalesMu.Lock()
+	link, ok := timeoutScales[deviceID]
+	if !ok {
+		link = &linkScale{}
+		timeoutScales[deviceID] = link
+	}
+	timeoutScalesMu.Unlock()
+	// Other devices don't wait for this one's measurement; concurrent
+	// callers for the same device wait for the single sample.
+	link.once.Do(func() {
+		link.scale = measureTimeoutScale(deviceID)
+		debugPrint("Link to %s: timeouts scaled by %.1f\n", deviceID, link.scale)
+	})
+	return link.scale
+}
+
+// transferBudget is the time to move size bytes at the USB baseline
+// throughput plus transferBaseBudget, scaled for deviceID's link.
+func transferBudget(deviceID string, size int64) time.Duration {
+	perByte := float64(time.Second) / (budgetBaseThroughput * 1e6)
+	return scaledTimeout(deviceID, transferBaseBudget+time.Duration(float64(size)*perByte))
+}
+
+// pullBudget is the transferBudget for what lies under remote, or 0 (no
+// limit) when the device can't say how big it is.
+func pullBudget(deviceID, remote string) time.Duration {
+	fields := strings.Fields(runAdbCommand(deviceID, "du -sk "+shellQuote(remote)+" 2>/dev/null", 10*time.Second))
+	if len(fields) == 0 {
+		return 0
+	}
+	kb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return transferBudget(deviceID, kb*1024)
+}
+
+// measureTimeoutScale takes one small link sample (1 MB) and compares it with
+// the USB baseline. A link too slow to finish the sample gets the maximum.
+func measureTimeoutScale(deviceID string) float64 {
+	result := make(chan linkSample, 1)
+	go func() {
+		if sample, err := measureLink(deviceID, 1); err == nil {
+			result <- sample
+		}
+	}()
+	select {
+	case sample := <-result:
+		scale := 1.0
+		if byLatency := float64(sample.Latency) / float64(budgetBaseLatency); byLatency > scale {
+			scale = byLatency
+		}
+		if sample.Throughput > 0 {
+			if byThroughput := budgetBaseThroughput / sample.Throughput; byThroughput > scale {
+				scale = byThroughput
+			}
+		}
+		if scale > maxTimeoutScale {
+			scale = maxTimeoutScale
+		}
+		return scale
+	case <-time.After(10 * time.Second):
+		return maxTimeoutScale
+	}
+}
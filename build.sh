@@ -36,11 +36,25 @@ do
 
     # Build
     echo "Building $OUTPUT_NAME..."
-    GOOS=$GOOS GOARCH=$GOARCH go build -o build/$OUTPUT_NAME -ldflags="-X main.Version=$VERSION" .
+    # Release binaries are built without cgo, so they have no sqlite store
+    GOOS=$GOOS GOARCH=$GOARCH CGO_ENABLED=0 go build -o build/$OUTPUT_NAME -ldflags="-X main.Version=$VERSION" .
     if [ $? -ne 0 ]; then
         echo 'An error has occurred! Aborting the script execution...'
         exit 1
     fi
 done
 
+# Build the companion APK with the same version and ship it next to the
+# binaries; it needs the Android SDK
+if [ -n "$ANDROID_HOME$ANDROID_SDK_ROOT" ]; then
+    echo "Building $APP_NAME-companion_$VERSION.apk..."
+    if ! ./companion/build.sh "$VERSION"; then
+        echo 'An error has occurred! Aborting the script execution...'
+        exit 1
+    fi
+    cp companion/$APP_NAME-companion.apk build/$APP_NAME'-companion_'$VERSION'.apk'
+else
+    echo "ANDROID_HOME is not set, skipping the companion APK"
+fi
+
 echo "Build completed!"
\ No newline at end of file
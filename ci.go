@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ciMode makes adbctl pipeline-safe: it never prompts, takes device selection,
+// timeout and output format from ADBCTL_DEVICE, ADBCTL_TIMEOUT and
+// ADBCTL_OUTPUT, and reports errors in a machine-readable form on stderr.
+var ciMode = os.Getenv("ADBCTL_CI") != ""
+
+// Exit codes used in CI mode.
+const (
+	exitError   = 1
+	exitUsage   = 2
+	exitDevice  = 3
+	exitTimeout = 124
+)
+
+// ciOutput is ADBCTL_OUTPUT: "text" (default) or "json".
+func ciOutput() string {
+	if os.Getenv("ADBCTL_OUTPUT") == "json" {
+		return "json"
+	}
+	return "text"
+}
+
+// ciFail reports an error and exits. kind is a stable identifier scripts can
+// match on, e.g. "no-device" or "timeout".
+func ciFail(code int, kind string, err error) {
+	var adbErr *adbError
+	errors.As(err, &adbErr)
+	if ciOutput() == "json" {
+		report := struct {
+			Error       string `json:"error"`
+			Kind        string `json:"kind"`
+			Command     string `json:"command,omitempty"`
+			Device      string `json:"device,omitempty"`
+			Code        int    `json:"exit_code"`
+			Explanation string `json:"explanation,omitempty"`
+			Suggestion  string `json:"suggestion,omitempty"`
+		}{Error: err.Error(), Kind: kind, Command: currentCommand, Device: lastPickedDevice, Code: code}
+		if adbErr != nil {
+			report.Explanation, report.Suggestion = adbErr.Explanation, adbErr.Suggestion
+		}
+		data, _ := json.Marshal(report)
+		fmt.Fprintln(os.Stderr, string(data))
+	} else {
+		fmt.Fprintf(os.Stderr, "adbctl: %s: %v\n", kind, err)
+		if adbErr != nil {
+			fmt.Fprintf(os.Stderr, "adbctl: hint: %s\n", adbErr.Suggestion)
+		}
+	}
+	os.Exit(code)
+}
+
+// currentCommand is the subcommand being run, for error reports.
+var currentCommand string
+
+// startCITimeout aborts the whole command once ADBCTL_TIMEOUT elapses.
+func startCITimeout() {
+	value := os.Getenv("ADBCTL_TIMEOUT")
+	if value == "" {
+		return
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		ciFail(exitUsage, "usage", fmt.Errorf("invalid ADBCTL_TIMEOUT %q", value))
+	}
+	time.AfterFunc(timeout, func() {
+		ciFail(exitTimeout, "timeout", fmt.Errorf("%s did not finish within %v", currentCommand, timeout))
+	})
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	codecMimePattern    = regexp.MustCompile(`^Media type '(.+)':`)
+	codecHeaderPattern  = regexp.MustCompile(`^(Decoder|Encoder) "(.+?)" supports`)
+	codecProfilePattern = regexp.MustCompile(`\(([\w\-]+)/[\w.\-]+\)`)
+	codecSizePattern    = regexp.MustCompile(`"size-range": "[\dx]+-(\d+x\d+)"`)
+)
+
+type codecInfo struct {
+	Kind     string // Decoder or Encoder
+	Name     string
+	Mime     string
+	Hardware bool
+	Secure   bool
+	MaxSize  string
+	Profiles []string
+}
+
+func runCodecs(args []string) error {
+	fs := flag.NewFlagSet("codecs", flag.ExitOnError)
+	mime := fs.String("mime", "", "Only show codecs whose MIME type starts with this, e.g. video/hevc")
+	kind := fs.String("type", "", "Only show decoders or encoders")
+	fs.Parse(args)
+
+	deviceID := pickDevice()
+	dump, _ := adbShellIdempotent(deviceID, "dumpsys", "media.player")
+	codecs := parseCodecDump(dump)
+	if len(codecs) == 0 {
+		// Older releases don't list codecs in dumpsys; read the platform XML instead.
+		xmlFiles, _ := adbShell(deviceID, "ls /vendor/etc/media_codecs*.xml /system/etc/media_codecs*.xml 2>/dev/null")
+		for _, file := range strings.Fields(xmlFiles) {
+			content, err := adbShellIdempotent(deviceID, "cat", file)
+			if err == nil {
+				codecs = append(codecs, parseCodecXML(content)...)
+			}
+		}
+	}
+	if len(codecs) == 0 {
+		return fmt.Errorf("no codec information available on this device")
+	}
+
+	sort.SliceStable(codecs, func(i, j int) bool {
+		if codecs[i].Mime != codecs[j].Mime {
+			return codecs[i].Mime < codecs[j].Mime
+		}
+		return codecs[i].Kind < codecs[j].Kind
+	})
+
+	color.New(color.FgCyan, color.Bold).Printf("%-8s %-45s %-28s %-4s %-10s %-6s %s\n", "TYPE", "NAME", "MIME", "HW", "MAX", "SECURE", "PROFILES")
+	for _, c := range codecs {
+		if *mime != "" && !strings.HasPrefix(c.Mime, *mime) {
+			continue
+		}
+		if *kind != "" && !strings.EqualFold(strings.TrimSuffix(*kind, "s"), c.Kind) {
+			continue
+		}
+		hw := "sw"
+		if c.Hardware {
+			hw = "hw"
+		}
+		secure := ""
+		if c.Secure {
+			secure = "yes"
+		}
+		fmt.Printf("%-8s %-45s %-28s %-4s %-10s %-6s %s\n", c.Kind, c.Name, c.Mime, hw, c.MaxSize, secure, strings.Join(c.Profiles, ","))
+	}
+	return nil
+}
+
+// parseCodecDump parses the codec list printed by `dumpsys media.player`.
+func parseCodecDump(dump string) []codecInfo {
+	var codecs []codecInfo
+	current := -1 // index into codecs; a pointer would dangle after append
+	mime := ""
+	for _, line := range strings.Split(dump, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := codecMimePattern.FindStringSubmatch(trimmed); m != nil {
+			mime = m[1]
+			continue
+		}
+		if m := codecHeaderPattern.FindStringSubmatch(trimmed); m != nil {
+			codecs = append(codecs, codecInfo{
+				Kind:     m[1],
+				Name:     m[2],
+				Mime:     mime,
+				Hardware: !isSoftwareCodec(m[2]),
+				Secure:   strings.HasSuffix(m[2], ".secure"),
+			})
+			current = len(codecs) - 1
+			continue
+		}
+		if current < 0 {
+			continue
+		}
+		c := &codecs[current]
+		switch {
+		case strings.Contains(trimmed, "software-only: 1"):
+			c.Hardware = false
+		case strings.Contains(trimmed, "hw-accelerated: 1"):
+			c.Hardware = true
+		case strings.Contains(trimmed, "feature-secure-playback"):
+			c.Secure = true
+		}
+		if m := codecSizePattern.FindStringSubmatch(trimmed); m != nil {
+			c.MaxSize = m[1]
+		}
+		for _, p := range codecProfilePattern.FindAllStringSubmatch(trimmed, -1) {
+			if !containsString(c.Profiles, p[1]) {
+				c.Profiles = append(c.Profiles, p[1])
+			}
+		}
+	}
+	return codecs
+}
+
+func isSoftwareCodec(name string) bool {
+	return strings.HasPrefix(name, "OMX.google.") || strings.HasPrefix(name, "c2.android.")
+}
+
+type mediaCodecsXML struct {
+	Decoders []xmlCodec `xml:"Decoders>MediaCodec"`
+	Encoders []xmlCodec `xml:"Encoders>MediaCodec"`
+}
+
+type xmlCodec struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Types []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"Type"`
+	Limits []struct {
+		Name string `xml:"name,attr"`
+		Max  string `xml:"max,attr"`
+	} `xml:"Limit"`
+	Features []struct {
+		Name string `xml:"name,attr"`
+	} `xml:"Feature"`
+}
+
+// parseCodecXML reads a media_codecs*.xml file as shipped in /vendor/etc.
+func parseCodecXML(content string) []codecInfo {
+	var doc mediaCodecsXML
+	if err := xml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil
+	}
+	var codecs []codecInfo
+	add := func(kind string, list []xmlCodec) {
+		for _, c := range list {
+			info := codecInfo{Kind: kind, Name: c.Name, Hardware: !isSoftwareCodec(c.Name), Secure: strings.HasSuffix(c.Name, ".secure")}
+			for _, l := range c.Limits {
+				if l.Name == "size" {
+					info.MaxSize = l.Max
+				}
+			}
+			for _, f := range c.Features {
+				if f.Name == "secure-playback" {
+					info.Secure = true
+				}
+			}
+			mimes := []string{c.Type}
+			for _, t := range c.Types {
+				mimes = append(mimes, t.Name)
+			}
+			for _, mime := range mimes {
+				if mime != "" {
+					info.Mime = mime
+					codecs = append(codecs, info)
+				}
+			}
+		}
+	}
+	add("Decoder", doc.Decoders)
+	add("Encoder", doc.Encoders)
+	return codecs
+}
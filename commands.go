@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Command is a non-interactive subcommand, e.g. `adbctl warmup --packages a,b`.
+type Command struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(args []string) error
+}
+
+var commands = []Command{
+	{"warmup", "warmup --packages a,b,c", "Free memory and pre-launch apps before a demo", runWarmup},
+	{"du", "du [path] [--top n]", "Show the largest directories on the device", runDu},
+	{"kiosk", "kiosk set <package> | kiosk clear", "Lock the device to a single app", runKiosk},
+	{"dpm", "dpm set-owner|set-admin|remove-admin <component> | status", "Provision device owner/admin test apps", runDpm},
+	{"seed", "seed --media dir/ --contacts file.vcf", "Populate a demo device with sample content", runSeed},
+	{"media", "media rescan [path] | list [--type video] | caps | status", "Media scans, MediaStore queries, HDR and playback status", runMedia},
+	{"settings", "settings get|put|list|apply|export [namespace]", "Read and write Android settings", runSettings},
+	{"applinks", "applinks <package> [--reverify]", "Show deep-link domain verification state", runAppLinks},
+	{"prop", "prop list|get|set [--filter prefix]", "Browse, export and set system properties", runProp},
+	{"defaults", "defaults [resolve --action VIEW --data uri | set <role> <pkg>]", "Inspect default apps and intent handlers", runDefaults},
+	{"dev", "dev animations [0|0.5|1] | frametime on|off", "Developer toggles such as animation scales and frame time overlays", runDev},
+	{"matrix", "matrix run --locales a,b --densities 1.0,1.3 -- <cmd>", "Run a command under several device configurations", runMatrix},
+	{"logcat", "logcat [--package pkg | --flavor name] [--unity [--symbols dir]] [--all-devices --merge] [--filter serial=spec]", "Tail logs from one or all devices", runLogcat},
+	{"power", "power status | stay-awake on|off | timeout 30m", "Control stay-awake and screen timeout", runPower},
+	{"demo", "demo on|off [--clock hhmm]", "Toggle System UI demo mode for clean screenshots", runDemo},
+	{"trap", "trap --on pattern --do screenshot,bugreport", "Capture artifacts when a logcat pattern appears", runTrap},
+	{"wait-for", "wait-for boot|state <level>|package-foreground <pkg>|property k=v|port <n> [--serial s]", "Block until a device condition is met", unprobed(runWaitFor)},
+	{"display", "display [size WxH | density dpi | reset | modes [--set id] | --preset name]", "Override screen size, density and display mode", runDisplay},
+	{"assert", "assert \"<namespace.key> <op> <value>\" ...", "Check device state in CI, exiting non-zero on failure", runAssert},
+	{"config", "config path | adb-path [path] | export <file> | import <file> [--force]", "Show, set or move adbctl configuration", runConfig},
+	{"codecs", "codecs [--mime video/hevc] [--type decoder]", "List hardware and software codecs", runCodecs},
+	{"stats", "stats [enable|disable] [--since 720h]", "Summarize your own usage from the local audit log", runStats},
+	{"link", "link [--rounds 5] [--size 8]", "Measure adb latency and throughput and classify the link", runLink},
+	{"wifi", "wifi connect --ssid <name> [--pass <p>] | status | forget <ssid> | monitor", "Move a device between WiFi networks and watch signal quality", runWifi},
+	{"net", "net usage [--window 24h] | test", "Per-app network usage and device-side connectivity checks", runNet},
+	{"screenshot", "screenshot [-o file.png] [--scroll] [--method swipe|dpad]", "Capture the screen, optionally stitching a scrolling page", runScreenshot},
+	{"record", "record [-o file] [--max 30s] [--gif|--webp] [--fps 10]", "Record the screen, optionally as a small GIF or WebP", runRecord},
+	{"wireless", "wireless enable [--port 5555]", "Switch a USB-connected device to adb over WiFi", runWireless},
+	{"pair", "pair <ip:port> <code> | pair --qr", "Pair with a device for wireless debugging (Android 11+)", runPair},
+	{"connect", "connect [host | ip:port | --recent]", "Connect to a paired device, refreshing its port via mDNS", runConnect},
+	{"services", "services [--package pkg]", "Running services and boot receivers, flagging third-party auto-starters", runServices},
+	{"report", "report [-o file.html] [--email] [--at HH:MM] [--upload dest]", "HTML fleet health report, optionally emailed nightly", runReport},
+	{"discover", "discover", "Find adb devices advertised over mDNS and connect", runDiscover},
+	{"monitor", "monitor [--interval 30s] [--mqtt] [--ha]", "Sample device state, optionally publishing to MQTT", runMonitor},
+	{"scan", "scan <cidr> [--port 5555]", "Find adb-over-network devices on a subnet", runScan},
+	{"keepalive", "keepalive [ip:port...] [--interval 15s] [--notify]", "Keep wireless devices connected, reconnecting when they drop", runKeepalive},
+	{"current-app", "current-app [--watch] [--interval 1s]", "Print the foreground package/activity", runCurrentApp},
+	{"launch-shortcut", "launch-shortcut [name] [--user id]", "Launch an app or intent configured in shortcuts.json", runLaunchShortcut},
+	{"identify", "identify [n] [--duration 10s]", "Flash a device so you can tell which physical unit it is", runIdentify},
+	{"companion", "companion install|status|uninstall|clipboard [text]|sensor <type>", "Manage the on-device helper app and use its clipboard and sensor access", runCompanion},
+	{"shell", "shell [--timeout 1m] -- <command>", "Run a shell command, streaming its output live", runShell},
+	{"reboot", "reboot [recovery|bootloader|sideload|userspace] [--wait]", "Reboot normally or into another mode", unprobed(runReboot)},
+	{"pull", "pull <remote> [local dir] [--compress]", "Copy files from the device, optionally gzip-compressed in transit", runPull},
+	{"sideload", "sideload <update.zip> [--reboot]", "Install an OTA package through recovery sideload", unprobed(runSideload)},
+	{"battery", "battery [--top 5] | history [--since 24h] [-o file.csv] [--reset] | simulate --level 15 --unplugged | reset", "Battery health, history export and simulated battery states", runBattery},
+	{"install", "install <app.apk|app.apks|app.aab> | --variant debug [--obb main.obb,patch.obb] [--user id]", "Install an app with its OBB files or asset packs", runInstall},
+	{"users", "users", "List device users and profiles", runUsers},
+	{"uninstall", "uninstall <package> [--user id] [--keep-data]", "Remove an app for all users or one user", runUninstall},
+	{"thermal", "thermal [--watch] [--interval 5s]", "Temperatures, throttling status and CPU frequency caps", runThermal},
+	{"alias", "alias [<name> = <command> [args...]] | --remove <name> | --device [<serial> <name>] | --remove-device <serial>", "Define shortcuts for long command lines", func(args []string) error { return runAliasCommand(args) }},
+	{"gfx", "gfx [package] [--reset] [--watch] [--interval 2s] | monitor [package] [--alert 10]", "Frame count, jank percentage and frame time percentiles, or a live frame-drop monitor", runGfx},
+	{"start", "start [package] [--flavor name | --variant name] [--restart]", "Launch an app and report its launch time", runStart},
+	{"smoke", "smoke [package] [--flavor name | --variant name] [--duration 10s] [--upload dest]", "Cold-start an app and check it stays up without crashing", runSmoke},
+	{"app-info", "app-info [package] [--flavor name | --variant name]", "Version, SDK levels and framework of an installed app", runAppInfo},
+	{"framework", "framework forward [--port 8081] | logs [--all]", "Flutter and React Native helpers: dev server ports and framework logs", runFramework},
+	{"ps", "ps [--sort cpu|mem|pid|name] [--filter name] [--top n] [--json]", "Process table with memory and CPU, sortable and filterable", runPs},
+	{"symbolize", "symbolize <log|tombstone|-> --symbols <dir>", "Resolve il2cpp/native crash frames in a saved log", runSymbolize},
+	{"app", "app info [package] | audit <package> [--fuzz]", "App details and an exported component security audit", runApp},
+	{"kill", "kill <name|pid> [--signal TERM] [--yes]", "Send a signal to processes by name or pid", runKill},
+	{"dmesg", "dmesg [--follow] [--grep pattern] [-o file]", "Kernel log, for driver issues (USB, HDMI, WiFi)", runDmesg},
+	{"apk", "apk diff <old.apk> <new.apk>", "Compare permissions, components, SDK levels and libraries of two builds", runApk},
+	{"mitm", "mitm start [--proxy host:port] | stop | status | check [package] [--duration 20s]", "Route device traffic through a proxy and check whether an app is pinned", runMitm},
+	{"selinux", "selinux denials [--source context] [--allow]", "Recent SELinux denials grouped by source context", runSelinux},
+	{"input", "input raw --record <file> [--device name] | --play <file> [--map old=new] [--speed 1]", "Record and replay kernel input events (getevent/sendevent)", runInput},
+	{"remote", "remote status [--json]", "Battery level and firmware version of the paired Bluetooth remote", runRemote},
+	{"inventory", "inventory [-o file.json] | inventory --all [-o fleet.csv] [--upload dest]", "Full device snapshot (props, packages, partitions, features, display) as JSON", runInventory},
+	{"store", "store [bolt | sqlite | file | remote <url> | serve [--listen :8750]]", "Keep the device registry in a local database or on a shared lab server", runStore},
+	{"api", "api serve [--listen 127.0.0.1:8751] | proto", "Typed device farm API (gRPC and Connect) with streaming logcat and shell", runAPI},
+	{"lib", "lib push [--all] | status | list | run <script> [args...] | remove", "Install the on-device helper scripts that probes run", runLib},
+	{"web", "web [--listen 127.0.0.1:8752]", "Local web dashboard: device cards, screenshots, reboot, install and live logcat", runWeb},
+	{"debug", "debug capture [-o dir] [-- <command>] | replay <dir> [-- <command>]", "Record a device's adb output, or rerun adbctl against a recording", runDebug},
+}
+
+func findCommand(name string) (Command, bool) {
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+func printCommands() {
+	fmt.Println("Available commands:")
+	for _, c := range commands {
+		fmt.Printf("  %-40s %s\n", c.Usage, c.Description)
+	}
+}
+
+func runCommand(name string, args []string) {
+	c, ok := findCommand(name)
+	if !ok {
+		if command, expanded, isAlias := expandAlias(name, args); isAlias {
+			debugPrint("alias %s -> %s %s\n", name, command, strings.Join(expanded, " "))
+			name, args = command, expanded
+			c, ok = findCommand(name)
+		}
+	}
+	currentCommand = name
+	if !ok && ciMode {
+		ciFail(exitUsage, "usage", fmt.Errorf("unknown command %q", name))
+	}
+	if !ok {
+		fmt.Printf("Unknown command: %s\n\n", name)
+		printCommands()
+		os.Exit(2)
+	}
+	if ciMode {
+		startCITimeout()
+	}
+	run := c.Run
+	if !containsString(nativeFleetCommands, name) {
+		if rest, opts := extractFleetFlags(args); opts.AllDevices {
+			args = rest
+			run = func(args []string) error { return runFleetCommand(name, args, opts) }
+		}
+	}
+	start := time.Now()
+	err := run(args)
+	recordAudit(name, lastPickedDevice, start, err)
+	if err == nil && lastPickedDevice != "" {
+		recordConnection(lastPickedDevice)
+	}
+	if err != nil && ciMode {
+		ciFail(exitCode(err), errorKind(err, "command-failed"), err)
+	}
+	if err != nil {
+		printError(err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// probeDevice is cleared for commands wrapped in unprobed, which work with
+// devices that have no shell yet (booting, in recovery or sideload mode) and
+// handle an unreachable device themselves.
+var probeDevice = true
+
+// unprobed marks a command in the table as one that pickDevice should not
+// health-check.
+func unprobed(run func(args []string) error) func(args []string) error {
+	return func(args []string) error {
+		probeDevice = false
+		return run(args)
+	}
+}
+
+// pickDevice resolves the device to operate on: ADBCTL_DEVICE wins, otherwise
+// the user is prompted when several are connected.
+func pickDevice() string {
+	lastPickedDevice = os.Getenv("ADBCTL_DEVICE")
+	if lastPickedDevice == "" {
+		lastPickedDevice = selectDevice(getConnectedDevices())
+	} else {
+		lastPickedDevice = withTransport(lastPickedDevice)
+	}
+	if probeDevice && !ensureResponsive(lastPickedDevice) {
+		os.Exit(exitDevice)
+	}
+	return lastPickedDevice
+}
+
+// parseArgs parses flags that may appear before, between or after positional
+// arguments (the standard flag package stops at the first positional one) and
+// returns the positional arguments in order. Everything after "--" is
+// positional, flags included.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			return positional
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// connectedSerials returns the serial of every online device.
+func connectedSerials() []string {
+	var serials []string
+	for _, line := range getConnectedDevices() {
+		serials = append(serials, strings.Fields(line)[0])
+	}
+	return serials
+}
+
+// multiFlag collects a flag that may be repeated, e.g. --filter a --filter b.
+type multiFlag []string
+
+func (m *multiFlag) String() string { return strings.Join(*m, ",") }
+
+func (m *multiFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
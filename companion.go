@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// The companion is a small helper app for what the shell cannot do: a full
+// screen number for identify, the clipboard on old builds and raw sensor
+// readings. Its source is in companion/; build.sh builds and signs it with
+// the same version as the binary and ships it alongside, so a CLI only talks
+// to its own companion.
+const (
+	companionPackage   = "dev.adbctl.companion"
+	companionAPKName   = "adbctl-companion.apk"
+	companionIdentify  = companionPackage + "/.IdentifyActivity"
+	companionClipboard = companionPackage + "/.ClipboardReceiver"
+	companionSensor    = companionPackage + "/.SensorReceiver"
+)
+
+// sensorTypes maps names to android.hardware.Sensor TYPE_* constants.
+var sensorTypes = map[string]int{
+	"accelerometer": 1, "magnetic-field": 2, "gyroscope": 4, "light": 5,
+	"pressure": 6, "proximity": 8, "gravity": 9, "linear-acceleration": 10,
+	"rotation-vector": 11, "humidity": 12, "ambient-temperature": 13,
+}
+
+func runCompanion(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: companion install|status|uninstall|clipboard [text]|sensor <type>")
+	}
+	deviceID := pickDevice()
+	switch args[0] {
+	case "install":
+		return installCompanion(deviceID)
+	case "status":
+		return companionStatus(deviceID)
+	case "uninstall":
+		if err := uninstallCompanion(deviceID); err != nil {
+			return err
+		}
+		fmt.Println("Companion removed.")
+		return nil
+	case "clipboard":
+		if len(args) > 1 {
+			_, err := companionBroadcast(deviceID, companionClipboard, "CLIPBOARD_SET", "--es", "text", shellQuote(strings.Join(args[1:], " ")))
+			return err
+		}
+		text, err := companionBroadcast(deviceID, companionClipboard, "CLIPBOARD_GET")
+		if err != nil {
+			return err
+		}
+		fmt.Println(text)
+		return nil
+	case "sensor":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: companion sensor <type>")
+		}
+		sensorType, ok := sensorTypes[args[1]]
+		if !ok {
+			n, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("unknown sensor %q; use a Sensor.TYPE_* number or accelerometer, gyroscope, light, pressure, proximity, ...", args[1])
+			}
+			sensorType = n
+		}
+		values, err := companionBroadcast(deviceID, companionSensor, "SENSOR", "--ei", "type", strconv.Itoa(sensorType))
+		if err != nil {
+			return err
+		}
+		fmt.Println(values)
+		return nil
+	default:
+		return fmt.Errorf("unknown companion action %q", args[0])
+	}
+}
+
+// companionAPK finds the APK: ADBCTL_COMPANION_APK, then next to the
+// executable (as laid out by build.sh), then the config directory.
+func companionAPK() (string, error) {
+	if path := os.Getenv("ADBCTL_COMPANION_APK"); path != "" {
+		return path, nil
+	}
+	var candidates []string
+	if exe, err := os.Executable(); err == nil {
+		dir := filepath.Dir(exe)
+		candidates = append(candidates,
+			filepath.Join(dir, fmt.Sprintf("adbctl-companion_%s.apk", Version)),
+			filepath.Join(dir, companionAPKName))
+	}
+	candidates = append(candidates, filepath.Join(configDir(), companionAPKName))
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("companion APK not found; looked for %s (set ADBCTL_COMPANION_APK to its path)", strings.Join(candidates, ", "))
+}
+
+func installCompanion(deviceID string) error {
+	apk, err := companionAPK()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Installing %s...\n", apk)
+	// -r replaces an installed copy, -g grants runtime permissions.
+	output, err := exec.Command(adbPath, "-s", fastTransport(deviceID), "install", "-r", "-g", apk).CombinedOutput()
+	if err == nil && strings.Contains(string(output), "Success") {
+		return companionStatus(deviceID)
+	}
+	// An older adbctl brings an older companion, and a self-built one is
+	// signed with another key; neither can replace the installed copy. The
+	// companion keeps no data, so it is simply removed first.
+	if !strings.Contains(string(output), "INSTALL_FAILED_VERSION_DOWNGRADE") && !strings.Contains(string(output), "INSTALL_FAILED_UPDATE_INCOMPATIBLE") {
+		return normalizeAdbError(string(output), fmt.Errorf("install failed: %s", strings.TrimSpace(string(output))))
+	}
+	fmt.Printf("Replacing companion %s...\n", companionVersion(deviceID))
+	if err := uninstallCompanion(deviceID); err != nil {
+		return err
+	}
+	output, err = exec.Command(adbPath, "-s", fastTransport(deviceID), "install", "-g", apk).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Success") {
+		return normalizeAdbError(string(output), fmt.Errorf("install failed: %s", strings.TrimSpace(string(output))))
+	}
+	return companionStatus(deviceID)
+}
+
+func uninstallCompanion(deviceID string) error {
+	output, err := exec.Command(adbPath, "-s", deviceID, "uninstall", companionPackage).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Success") {
+		return normalizeAdbError(string(output), fmt.Errorf("uninstall failed: %s", strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// companionBroadcast sends action to one of the companion's receivers and
+// returns the result data it answered with. extras must already be quoted
+// for the device shell.
+func companionBroadcast(deviceID, receiver, action string, extras ...string) (string, error) {
+	if !companionReady(deviceID) {
+		return "", fmt.Errorf("the companion is not installed or does not match adbctl %s; run 'adbctl companion install'", Version)
+	}
+	args := append([]string{"am", "broadcast", "-n", receiver, "-a", companionPackage + "." + action}, extras...)
+	output, err := adbShell(deviceID, args...)
+	if err != nil {
+		return "", err
+	}
+	// Broadcast completed: result=-1, data="..."; the data may span lines.
+	_, result, found := strings.Cut(output, "Broadcast completed: result=")
+	if !found {
+		return "", fmt.Errorf("unexpected broadcast output: %s", output)
+	}
+	code, rest, _ := strings.Cut(result, ",")
+	data := ""
+	if _, quoted, found := strings.Cut(rest, `data="`); found {
+		data = strings.TrimSuffix(strings.TrimSpace(quoted), `"`)
+	}
+	if code != "-1" {
+		if data == "" {
+			data = "no result"
+		}
+		return "", fmt.Errorf("companion %s failed: %s", strings.ToLower(action), data)
+	}
+	return data, nil
+}
+
+// companionVersion returns the installed companion's versionName, or "" when
+// it is not installed.
+func companionVersion(deviceID string) string {
+	output, err := adbShellIdempotent(deviceID, "dumpsys", "package", companionPackage)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if version, found := strings.CutPrefix(strings.TrimSpace(line), "versionName="); found {
+			return version
+		}
+	}
+	return ""
+}
+
+// companionReady reports whether a companion matching this CLI is installed.
+// Development builds accept any version.
+func companionReady(deviceID string) bool {
+	version := companionVersion(deviceID)
+	return version != "" && (Version == "dev" || version == Version)
+}
+
+func companionStatus(deviceID string) error {
+	version := companionVersion(deviceID)
+	color.New(color.FgGreen).Printf("%-20s : ", "Companion")
+	switch {
+	case version == "":
+		fmt.Println("not installed (run 'adbctl companion install')")
+	case Version != "dev" && version != Version:
+		color.New(color.FgYellow).Printf("%s, but adbctl is %s; run 'adbctl companion install' to update\n", version, Version)
+	default:
+		fmt.Println(version)
+	}
+	return nil
+}
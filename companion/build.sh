@@ -0,0 +1,36 @@
+#!/bin/bash
+
+# Builds and signs the companion APK with the Android SDK command-line tools
+# (no Gradle needed): companion/build.sh <version>
+# ANDROID_HOME points at the SDK. Release builds set COMPANION_KEYSTORE and
+# COMPANION_KEYSTORE_PASS; otherwise the debug keystore is used.
+
+set -e
+
+VERSION=${1:?usage: companion/build.sh <version>}
+SDK=${ANDROID_HOME:-$ANDROID_SDK_ROOT}
+BUILD_TOOLS=$(ls -d "$SDK"/build-tools/* | sort -V | tail -1)
+PLATFORM=$(ls -d "$SDK"/platforms/android-* | sort -V | tail -1)
+
+# The version code only has to grow from release to release; the commit count
+# does. adbctl itself matches on the version name.
+VERSION_CODE=$(git rev-list --count HEAD 2>/dev/null || echo 1)
+
+cd "$(dirname "$0")"
+rm -rf out
+mkdir -p out/classes
+
+"$BUILD_TOOLS/aapt2" link -o out/unsigned.apk \
+    --manifest AndroidManifest.xml -I "$PLATFORM/android.jar" \
+    --min-sdk-version 21 --target-sdk-version 34 \
+    --version-code "$VERSION_CODE" --version-name "$VERSION"
+javac --release 8 -classpath "$PLATFORM/android.jar" -d out/classes $(find src -name '*.java')
+"$BUILD_TOOLS/d8" --min-api 21 --lib "$PLATFORM/android.jar" --output out $(find out/classes -name '*.class')
+(cd out && zip -q unsigned.apk classes.dex)
+"$BUILD_TOOLS/zipalign" -f 4 out/unsigned.apk out/aligned.apk
+"$BUILD_TOOLS/apksigner" sign \
+    --ks "${COMPANION_KEYSTORE:-$HOME/.android/debug.keystore}" \
+    --ks-pass "pass:${COMPANION_KEYSTORE_PASS:-android}" \
+    --out adbctl-companion.apk out/aligned.apk
+
+echo "Built companion/adbctl-companion.apk ($VERSION)"
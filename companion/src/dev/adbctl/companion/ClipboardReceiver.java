@@ -0,0 +1,34 @@
+package dev.adbctl.companion;
+
+import android.app.Activity;
+import android.content.BroadcastReceiver;
+import android.content.ClipData;
+import android.content.ClipboardManager;
+import android.content.Context;
+import android.content.Intent;
+
+/**
+ * Reads or sets the clipboard, which the shell has no command for on most
+ * builds. The text travels as the broadcast's result data:
+ * am broadcast -n dev.adbctl.companion/.ClipboardReceiver -a dev.adbctl.companion.CLIPBOARD_GET
+ * am broadcast -n dev.adbctl.companion/.ClipboardReceiver -a dev.adbctl.companion.CLIPBOARD_SET --es text 'hello'
+ */
+public class ClipboardReceiver extends BroadcastReceiver {
+    @Override
+    public void onReceive(Context context, Intent intent) {
+        ClipboardManager clipboard = (ClipboardManager) context.getSystemService(Context.CLIPBOARD_SERVICE);
+        if ("dev.adbctl.companion.CLIPBOARD_SET".equals(intent.getAction())) {
+            String text = intent.getStringExtra("text");
+            clipboard.setPrimaryClip(ClipData.newPlainText("adbctl", text == null ? "" : text));
+            setResultCode(Activity.RESULT_OK);
+            return;
+        }
+        ClipData clip = clipboard.getPrimaryClip();
+        if (clip == null || clip.getItemCount() == 0) {
+            setResult(Activity.RESULT_CANCELED, "the clipboard is empty or, on Android 10+, hidden from background apps", null);
+            return;
+        }
+        CharSequence text = clip.getItemAt(0).coerceToText(context);
+        setResult(Activity.RESULT_OK, text == null ? "" : text.toString(), null);
+    }
+}
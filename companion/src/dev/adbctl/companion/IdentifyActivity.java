@@ -0,0 +1,44 @@
+package dev.adbctl.companion;
+
+import android.app.Activity;
+import android.graphics.Color;
+import android.os.Bundle;
+import android.os.Handler;
+import android.os.Looper;
+import android.view.Gravity;
+import android.view.WindowManager;
+import android.widget.TextView;
+
+/**
+ * Shows the device number full screen for adbctl identify:
+ * am start -n dev.adbctl.companion/.IdentifyActivity --ei number 2 --el duration_ms 10000
+ */
+public class IdentifyActivity extends Activity {
+    private final Handler handler = new Handler(Looper.getMainLooper());
+
+    @Override
+    protected void onCreate(Bundle savedInstanceState) {
+        super.onCreate(savedInstanceState);
+        getWindow().addFlags(WindowManager.LayoutParams.FLAG_KEEP_SCREEN_ON
+                | WindowManager.LayoutParams.FLAG_SHOW_WHEN_LOCKED
+                | WindowManager.LayoutParams.FLAG_TURN_SCREEN_ON);
+
+        TextView number = new TextView(this);
+        number.setText(String.valueOf(getIntent().getIntExtra("number", 1)));
+        number.setTextColor(Color.BLACK);
+        number.setBackgroundColor(Color.YELLOW);
+        number.setGravity(Gravity.CENTER);
+        // Large enough to read from across a rack of devices.
+        number.setTextSize(Math.min(getResources().getConfiguration().screenHeightDp, 400) * 0.6f);
+        setContentView(number);
+
+        long durationMs = getIntent().getLongExtra("duration_ms", 10000);
+        handler.postDelayed(this::finish, durationMs);
+    }
+
+    @Override
+    protected void onDestroy() {
+        handler.removeCallbacksAndMessages(null);
+        super.onDestroy();
+    }
+}
@@ -0,0 +1,87 @@
+package dev.adbctl.companion;
+
+import android.app.Activity;
+import android.content.BroadcastReceiver;
+import android.content.Context;
+import android.content.Intent;
+import android.hardware.Sensor;
+import android.hardware.SensorEvent;
+import android.hardware.SensorEventListener;
+import android.hardware.SensorManager;
+import android.os.Handler;
+import android.os.HandlerThread;
+
+/**
+ * Returns one raw reading of a sensor, at the precision the driver reports,
+ * as comma-separated values in the broadcast's result data:
+ * am broadcast -n dev.adbctl.companion/.SensorReceiver -a dev.adbctl.companion.SENSOR --ei type 1
+ * The type is a Sensor.TYPE_* constant; without a reading within two seconds
+ * the result is cancelled.
+ */
+public class SensorReceiver extends BroadcastReceiver {
+    private static final long TIMEOUT_MS = 2000;
+
+    @Override
+    public void onReceive(Context context, Intent intent) {
+        SensorManager manager = (SensorManager) context.getSystemService(Context.SENSOR_SERVICE);
+        Sensor sensor = manager.getDefaultSensor(intent.getIntExtra("type", Sensor.TYPE_ACCELEROMETER));
+        if (sensor == null) {
+            setResult(Activity.RESULT_CANCELED, "no such sensor", null);
+            return;
+        }
+
+        HandlerThread thread = new HandlerThread("adbctl-sensor");
+        thread.start();
+        Handler handler = new Handler(thread.getLooper());
+        Reading reading = new Reading(manager, goAsync(), thread, handler);
+        manager.registerListener(reading, sensor, SensorManager.SENSOR_DELAY_FASTEST, handler);
+        handler.postDelayed(() -> reading.finish(Activity.RESULT_CANCELED, "no reading"), TIMEOUT_MS);
+    }
+
+    /**
+     * Answers the broadcast with the first reading. The reading and the
+     * timeout both run on the handler thread, so whichever comes first wins.
+     */
+    private static class Reading implements SensorEventListener {
+        private final SensorManager manager;
+        private final PendingResult result;
+        private final HandlerThread thread;
+        private final Handler handler;
+        private boolean done;
+
+        Reading(SensorManager manager, PendingResult result, HandlerThread thread, Handler handler) {
+            this.manager = manager;
+            this.result = result;
+            this.thread = thread;
+            this.handler = handler;
+        }
+
+        @Override
+        public void onSensorChanged(SensorEvent event) {
+            StringBuilder values = new StringBuilder();
+            for (float value : event.values) {
+                if (values.length() > 0) {
+                    values.append(',');
+                }
+                values.append(value);
+            }
+            finish(Activity.RESULT_OK, values.toString());
+        }
+
+        @Override
+        public void onAccuracyChanged(Sensor sensor, int accuracy) {
+        }
+
+        void finish(int code, String data) {
+            if (done) {
+                return;
+            }
+            done = true;
+            manager.unregisterListener(this);
+            handler.removeCallbacksAndMessages(null);
+            result.setResult(code, data, null);
+            result.finish();
+            thread.quitSafely();
+        }
+    }
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// configDir is where adbctl keeps its configuration and device registry
+// (aliases, tags, golden profiles, provisioning definitions, history).
+// ADBCTL_CONFIG_DIR overrides the platform default.
+func configDir() string {
+	if dir := os.Getenv("ADBCTL_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = "."
+	}
+	return filepath.Join(base, "adbctl")
+}
+
+// loadConfigJSON reads the named document from its store (configDir()/name
+// unless a remote store is configured) into v. A missing document leaves v
+// untouched and is not an error.
+func loadConfigJSON(name string, v any) error {
+	data, err := storeFor(name).Load(name)
+	if err != nil || data == nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// saveConfigJSON writes v to the named document in its store.
+func saveConfigJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storeFor(name).Save(name, append(data, '\n'))
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: config path | config adb-path [path] | config export <file.tar.gz> | config import <file.tar.gz> [--force]")
+	}
+	switch args[0] {
+	case "path":
+		fmt.Println(configDir())
+		return nil
+	case "adb-path":
+		return configAdbPath(args[1:])
+	case "export":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: config export <file.tar.gz>")
+		}
+		count, err := exportConfig(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d file(s) from %s to %s\n", count, configDir(), args[1])
+		return nil
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: config import <file.tar.gz> [--force]")
+		}
+		force := len(args) > 2 && args[2] == "--force"
+		count, err := importConfig(args[1], force)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Imported %d file(s) into %s\n", count, configDir())
+		return nil
+	default:
+		return fmt.Errorf("unknown config action %q", args[0])
+	}
+}
+
+func exportConfig(archivePath string) (int, error) {
+	root := configDir()
+	if _, err := os.Stat(root); err != nil {
+		return 0, fmt.Errorf("nothing to export: %v", err)
+	}
+	return writeTarGz(root, archivePath)
+}
+
+// writeTarGz archives the files below root, with paths relative to it, and
+// returns how many it wrote.
+func writeTarGz(root, archivePath string) (int, error) {
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	gz := gzip.NewWriter(out)
+	defer gz.Close()
+	tw := tar.NewWriter(gz)
+	defer tw.Close()
+
+	count := 0
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: filepath.ToSlash(rel), Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(tw, f); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// importConfig unpacks an exported archive into the config directory. Existing
+// files are only replaced with force, after copying the old directory aside.
+func importConfig(archivePath string, force bool) (int, error) {
+	root := configDir()
+	if entries, _ := os.ReadDir(root); len(entries) > 0 {
+		if !force {
+			return 0, fmt.Errorf("%s is not empty; pass --force to replace it (a backup is kept)", root)
+		}
+		backup := root + ".bak-" + time.Now().Format("20060102-150405")
+		if err := os.Rename(root, backup); err != nil {
+			return 0, err
+		}
+		fmt.Printf("Previous configuration moved to %s\n", backup)
+	}
+
+	in, err := os.Open(archivePath)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a config archive: %v", archivePath, err)
+	}
+	tr := tar.NewReader(gz)
+
+	count := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		target := filepath.Join(root, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(root)+string(os.PathSeparator)) {
+			return count, fmt.Errorf("refusing to extract %q outside the config directory", header.Name)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return count, err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0777)
+		if err != nil {
+			return count, err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// shortcutsFile maps shortcut names to apps or intents for launch-shortcut.
+const shortcutsFile = "shortcuts.json"
+
+// launchShortcut is either a package to launch from its launcher icon, or an
+// intent: Component (package/.Activity) and/or Action, Data and Category,
+// using the same short action and category names as defaults resolve.
+type launchShortcut struct {
+	Package   string `json:"package,omitempty"`
+	Component string `json:"component,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Data      string `json:"data,omitempty"`
+	Category  string `json:"category,omitempty"`
+}
+
+func runCurrentApp(args []string) error {
+	fs := flag.NewFlagSet("current-app", flag.ExitOnError)
+	watch := fs.Bool("watch", false, "Keep running and print each change of foreground app")
+	interval := fs.Duration("interval", time.Second, "Polling interval with --watch")
+	parseArgs(fs, args)
+
+	deviceID := pickDevice()
+	if !*watch {
+		pkg, activity := foregroundActivity(deviceID)
+		if pkg == "" {
+			return fmt.Errorf("could not determine the foreground app")
+		}
+		fmt.Printf("%s/%s\n", pkg, activity)
+		return nil
+	}
+
+	var last string
+	for {
+		pkg, activity := foregroundActivity(deviceID)
+		current := pkg + "/" + activity
+		if pkg != "" && current != last {
+			fmt.Printf("%s %s\n", time.Now().Format("15:04:05"), current)
+			last = current
+		}
+		time.Sleep(*interval)
+	}
+}
+
+func runLaunchShortcut(args []string) error {
+	fs := flag.NewFlagSet("launch-shortcut", flag.ExitOnError)
+	user := fs.String("user", "", "Launch as this user id (see 'adbctl users')")
+	args = parseArgs(fs, args)
+	shortcuts := map[string]launchShortcut{}
+	if err := loadConfigJSON(shortcutsFile, &shortcuts); err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		if len(shortcuts) == 0 {
+			return fmt.Errorf("usage: launch-shortcut <name>; no shortcuts defined in %s", documentPath(shortcutsFile))
+		}
+		color.New(color.FgCyan, color.Bold).Println("Shortcuts")
+		for _, name := range sortedShortcutNames(shortcuts) {
+			fmt.Printf("  %-15s %s\n", name, shortcuts[name].describe())
+		}
+		return nil
+	}
+	shortcut, ok := shortcuts[args[0]]
+	if !ok {
+		return fmt.Errorf("no shortcut named %q in %s", args[0], documentPath(shortcutsFile))
+	}
+
+	deviceID := pickDevice()
+	if err := checkUser(deviceID, *user); err != nil {
+		return err
+	}
+	if shortcut.Component == "" && shortcut.Action == "" {
+		if shortcut.Package == "" {
+			return fmt.Errorf("shortcut %q needs a package, component or action", args[0])
+		}
+		if *user != "" {
+			// monkey always runs as the current user; start the launcher activity instead.
+			activity, err := resolveLauncherActivity(deviceID, shortcut.Package)
+			if err != nil {
+				return err
+			}
+			shortcut.Component = activity
+		}
+	}
+	if shortcut.Component == "" && shortcut.Action == "" {
+		output, err := adbShell(deviceID, "monkey", "-p", shortcut.Package, "-c", "android.intent.category.LAUNCHER", "1")
+		if err != nil || strings.Contains(output, "No activities found") {
+			return fmt.Errorf("failed to launch %s: %s", shortcut.Package, output)
+		}
+		fmt.Printf("Launched %s\n", shortcut.Package)
+		return nil
+	}
+
+	amArgs := []string{"am", "start"}
+	if *user != "" {
+		amArgs = append(amArgs, "--user", *user)
+	}
+	if shortcut.Action != "" || shortcut.Data != "" || shortcut.Category != "" {
+		action := shortcut.Action
+		if action == "" {
+			action = "VIEW"
+		}
+		amArgs = append(amArgs, intentArgs(action, shortcut.Data, shortcut.Category, "")...)
+	}
+	if shortcut.Component != "" {
+		amArgs = append(amArgs, "-n", shortcut.Component)
+	} else if shortcut.Package != "" {
+		amArgs = append(amArgs, "-p", shortcut.Package)
+	}
+	output, err := adbShell(deviceID, amArgs...)
+	if err != nil || strings.Contains(output, "Error:") {
+		return fmt.Errorf("failed to start %s: %s", args[0], output)
+	}
+	fmt.Printf("Started %s\n", args[0])
+	return nil
+}
+
+func (s launchShortcut) describe() string {
+	var parts []string
+	for _, field := range []string{s.Component, s.Action, s.Data, s.Category} {
+		if field != "" {
+			parts = append(parts, field)
+		}
+	}
+	if len(parts) == 0 {
+		return s.Package
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedShortcutNames(shortcuts map[string]launchShortcut) []string {
+	names := make([]string, 0, len(shortcuts))
+	for name := range shortcuts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
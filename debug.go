@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// debug capture runs adbctl with a copy of itself named adb in place of adb.
+// That copy passes every call on to the real adb and records its arguments,
+// output and exit status in the fixture directory; debug replay runs adbctl
+// against the same copy, which then answers from the recordings instead, so a
+// problem device's behaviour can be reproduced without the device.
+const (
+	fixtureDirEnv   = "ADBCTL_FIXTURE_DIR"
+	fixtureAdbEnv   = "ADBCTL_FIXTURE_ADB"   // the real adb, while capturing
+	fixtureStateEnv = "ADBCTL_FIXTURE_STATE" // replay's per-call counters
+
+	fixtureManifestFile = "manifest.json"
+	fixtureCallsFile    = "calls.jsonl"
+)
+
+// defaultCaptureInput drives the interactive menu through the device
+// information and memory views, then exits.
+const defaultCaptureInput = "1\n2\n6\n"
+
+type fixtureManifest struct {
+	Version  string       `json:"adbctl_version"`
+	Captured time.Time    `json:"captured"`
+	Serial   string       `json:"serial"`
+	Runs     []fixtureRun `json:"runs"`
+}
+
+// fixtureRun is one adbctl invocation made during capture.
+type fixtureRun struct {
+	Args   []string `json:"args"`
+	Stdin  string   `json:"stdin,omitempty"`
+	Output string   `json:"output"` // file with what it printed
+}
+
+// fixtureCall is one adb invocation and where its output was saved.
+type fixtureCall struct {
+	Args   []string `json:"args"`
+	Stdout string   `json:"stdout"`
+	Stderr string   `json:"stderr"`
+	Exit   int      `json:"exit"`
+	Millis int64    `json:"ms"`
+}
+
+func runDebug(args []string) error {
+	usage := fmt.Errorf("usage: debug capture [-o dir] [-- <adbctl command>] | debug replay <fixture-dir> [-- <adbctl command>]")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "capture":
+		fs := flag.NewFlagSet("debug capture", flag.ExitOnError)
+		output := fs.String("o", "", "Fixture directory; an existing one gets the new run added (default debug-capture-<time>)")
+		fs.Parse(args[1:])
+		return captureFixture(*output, fs.Args())
+	case "replay":
+		if len(args) < 2 {
+			return usage
+		}
+		fs := flag.NewFlagSet("debug replay", flag.ExitOnError)
+		fs.Parse(args[2:])
+		return replayFixture(args[1], fs.Args())
+	}
+	return usage
+}
+
+func captureFixture(dir string, command []string) error {
+	deviceID := pickDevice()
+	var err error
+	if dir == "" {
+		dir, err = artifactDir("debug-capture")
+	} else {
+		err = os.MkdirAll(dir, 0755)
+	}
+	if err != nil {
+		return err
+	}
+	manifest := fixtureManifest{Version: Version, Captured: time.Now().UTC().Truncate(time.Second), Serial: deviceID}
+	if data, err := os.ReadFile(filepath.Join(dir, fixtureManifestFile)); err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return fmt.Errorf("%s: %v", fixtureManifestFile, err)
+		}
+		if manifest.Serial != deviceID {
+			return fmt.Errorf("%s holds a capture of %s, not %s; use another directory", dir, manifest.Serial, deviceID)
+		}
+	}
+
+	run := fixtureRun{Args: command, Output: fmt.Sprintf("run-%d.txt", len(manifest.Runs)+1)}
+	if len(command) == 0 {
+		run.Stdin = defaultCaptureInput
+	}
+	fmt.Printf("Capturing adb traffic for %s into %s\n", deviceID, dir)
+	err = runWithFixture(dir, run, "", filepath.Join(dir, run.Output),
+		fixtureAdbEnv+"="+adbPath, "ADBCTL_DEVICE="+deviceID)
+	manifest.Runs = append(manifest.Runs, run)
+	data, _ := json.MarshalIndent(manifest, "", "  ")
+	if writeErr := os.WriteFile(filepath.Join(dir, fixtureManifestFile), append(data, '\n'), 0644); writeErr != nil {
+		return writeErr
+	}
+	calls, _ := loadFixtureCalls(dir)
+	fmt.Printf("\nCaptured %d adb call(s) in %s\n", len(calls), dir)
+	color.New(color.FgYellow).Println("The fixture holds everything adb returned (properties, package names, serials, IP addresses); review it before sharing.")
+	fmt.Printf("Reproduce with: adbctl debug replay %s\n", dir)
+	if err != nil {
+		return fmt.Errorf("the captured command failed: %v", err)
+	}
+	return nil
+}
+
+func replayFixture(dir string, command []string) error {
+	data, err := os.ReadFile(filepath.Join(dir, fixtureManifestFile))
+	if err != nil {
+		return fmt.Errorf("not a fixture directory: %v", err)
+	}
+	var manifest fixtureManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("%s: %v", fixtureManifestFile, err)
+	}
+	state, err := os.MkdirTemp("", "adbctl-replay")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(state)
+	env := []string{fixtureStateEnv + "=" + state, "ADBCTL_DEVICE=" + manifest.Serial}
+	if os.Getenv("ADBCTL_TIMEOUT_SCALE") == "" {
+		// Don't measure the link: there is none.
+		env = append(env, "ADBCTL_TIMEOUT_SCALE=1")
+	}
+	fmt.Printf("Replaying %s (%s, captured %s by adbctl %s)\n", dir, manifest.Serial, manifest.Captured.Local().Format("2006-01-02 15:04"), manifest.Version)
+
+	if len(command) > 0 {
+		return runWithFixture(dir, fixtureRun{Args: command}, state, "", env...)
+	}
+	for i, run := range manifest.Runs {
+		label := strings.Join(run.Args, " ")
+		if label == "" {
+			label = "(menu: " + strings.ReplaceAll(strings.TrimSpace(run.Stdin), "\n", ", ") + ")"
+		}
+		color.New(color.FgCyan, color.Bold).Printf("\n=== adbctl %s ===\n", label)
+		replayOutput := filepath.Join(state, "output.txt")
+		err := runWithFixture(dir, run, state, replayOutput, env...)
+		if err != nil {
+			fmt.Printf("Replay of run %d failed: %v\n", i+1, err)
+		}
+		captured, capErr := os.ReadFile(filepath.Join(dir, run.Output))
+		replayed, repErr := os.ReadFile(replayOutput)
+		if capErr == nil && repErr == nil {
+			if changed := differingLines(string(captured), string(replayed)); changed == 0 {
+				color.New(color.FgGreen).Println("Output matches the capture.")
+			} else {
+				kept := filepath.Join(dir, fmt.Sprintf("replay-%d.txt", i+1))
+				os.WriteFile(kept, replayed, 0644)
+				color.New(color.FgYellow).Printf("Output differs from the capture in %d line(s); compare %s with %s.\n",
+					changed, filepath.Join(dir, run.Output), kept)
+			}
+		}
+	}
+	return nil
+}
+
+// runWithFixture runs adbctl with the fixture's adb, teeing what it prints to
+// output when set. Without state it captures; with state it replays.
+func runWithFixture(dir string, run fixtureRun, state, output string, env ...string) error {
+	tmp, err := os.MkdirTemp("", "adbctl-fixture")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	fakeAdb, err := fixtureAdbBinary(tmp)
+	if err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(self, run.Args...)
+	cmd.Env = append(os.Environ(), append(env, "ADBCTL_ADB="+fakeAdb, fixtureDirEnv+"="+absDir)...)
+	cmd.Stdin = os.Stdin
+	if run.Stdin != "" {
+		cmd.Stdin = strings.NewReader(run.Stdin)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		cmd.Stdout, cmd.Stderr = io.MultiWriter(os.Stdout, f), io.MultiWriter(os.Stderr, f)
+	}
+	return cmd.Run()
+}
+
+// fixtureAdbBinary puts a copy of this executable named adb in dir.
+func fixtureAdbBinary(dir string) (string, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	name := "adb"
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	target := filepath.Join(dir, name)
+	if os.Link(self, target) == nil {
+		return target, nil
+	}
+	data, err := os.ReadFile(self)
+	if err != nil {
+		return "", err
+	}
+	return target, os.WriteFile(target, data, 0755)
+}
+
+// fixtureAdbMain turns this process into the fixture's adb when it was
+// started as one by runWithFixture. It doesn't return in that case.
+func fixtureAdbMain() {
+	dir := os.Getenv(fixtureDirEnv)
+	if dir == "" || strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") != "adb" {
+		return
+	}
+	if real := os.Getenv(fixtureAdbEnv); real != "" {
+		os.Exit(captureAdbCall(dir, real, os.Args[1:]))
+	}
+	os.Exit(replayAdbCall(dir, os.Getenv(fixtureStateEnv), os.Args[1:]))
+}
+
+// captureAdbCall runs the real adb, passing its output through while saving
+// it, and appends the call to calls.jsonl.
+func captureAdbCall(dir, realAdb string, args []string) int {
+	stdout, err := os.CreateTemp(dir, "call-*.stdout")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "debug capture:", err)
+		return 1
+	}
+	defer stdout.Close()
+	stderr, err := os.CreateTemp(dir, "call-*.stderr")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "debug capture:", err)
+		return 1
+	}
+	defer stderr.Close()
+
+	start := time.Now()
+	cmd := exec.Command(realAdb, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout, cmd.Stderr = io.MultiWriter(os.Stdout, stdout), io.MultiWriter(os.Stderr, stderr)
+	err = cmd.Run()
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		fmt.Fprintln(stderr, err)
+		fmt.Fprintln(os.Stderr, err)
+		code = 1
+	}
+
+	line, _ := json.Marshal(fixtureCall{args, filepath.Base(stdout.Name()), filepath.Base(stderr.Name()), code, time.Since(start).Milliseconds()})
+	// Calls run in parallel; a single small O_APPEND write keeps lines whole.
+	if f, err := os.OpenFile(filepath.Join(dir, fixtureCallsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err == nil {
+		f.Write(append(line, '\n'))
+		f.Close()
+	}
+	return code
+}
+
+// replayAdbCall answers a call from the recordings with the same arguments.
+// Repeated calls (polling) get the recordings in order, then the last again.
+func replayAdbCall(dir, state string, args []string) int {
+	calls, err := loadFixtureCalls(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "debug replay:", err)
+		return 1
+	}
+	var matches []fixtureCall
+	for _, call := range calls {
+		if slicesEqual(call.Args, args) {
+			matches = append(matches, call)
+		}
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(os.Stderr, "debug replay: nothing recorded for adb %s\n", strings.Join(args, " "))
+		return 1
+	}
+	call := matches[min(nextReplayIndex(state, args), len(matches)-1)]
+	for _, out := range []struct {
+		file string
+		w    io.Writer
+	}{{call.Stdout, os.Stdout}, {call.Stderr, os.Stderr}} {
+		if data, err := os.ReadFile(filepath.Join(dir, out.file)); err == nil {
+			out.w.Write(data)
+		}
+	}
+	return call.Exit
+}
+
+// nextReplayIndex counts the calls with args seen so far in this replay.
+func nextReplayIndex(state string, args []string) int {
+	if state == "" {
+		return 0
+	}
+	sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	counter := filepath.Join(state, hex.EncodeToString(sum[:8]))
+	data, _ := os.ReadFile(counter)
+	n, _ := strconv.Atoi(string(data))
+	os.WriteFile(counter, []byte(strconv.Itoa(n+1)), 0644)
+	return n
+}
+
+func loadFixtureCalls(dir string) ([]fixtureCall, error) {
+	data, err := os.ReadFile(filepath.Join(dir, fixtureCallsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var calls []fixtureCall
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var call fixtureCall
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &call) == nil {
+			calls = append(calls, call)
+		}
+	}
+	return calls, nil
+}
+
+func slicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// differingLines counts the lines that differ between two outputs, position
+// by position.
+func differingLines(a, b string) int {
+	linesA, linesB := strings.Split(a, "\n"), strings.Split(b, "\n")
+	changed := 0
+	for i := 0; i < max(len(linesA), len(linesB)); i++ {
+		if i >= len(linesA) || i >= len(linesB) || linesA[i] != linesB[i] {
+			changed++
+		}
+	}
+	return changed
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// defaultRoles maps the user-facing role names to RoleManager roles (Android 10+).
+var defaultRoles = map[string]string{
+	"browser":   "android.app.role.BROWSER",
+	"home":      "android.app.role.HOME",
+	"assistant": "android.app.role.ASSISTANT",
+	"dialer":    "android.app.role.DIALER",
+	"sms":       "android.app.role.SMS",
+}
+
+func runDefaults(args []string) error {
+	fs := flag.NewFlagSet("defaults", flag.ExitOnError)
+	action := fs.String("action", "VIEW", "Intent action; short names are expanded to android.intent.action.*")
+	data := fs.String("data", "", "Intent data URI")
+	category := fs.String("category", "", "Intent category")
+	mimeType := fs.String("type", "", "Intent MIME type")
+	positional := parseArgs(fs, args)
+
+	deviceID := pickDevice()
+	if len(positional) == 0 {
+		showDefaults(deviceID)
+		return nil
+	}
+	switch positional[0] {
+	case "resolve":
+		return resolveIntentHandlers(deviceID, intentArgs(*action, *data, *category, *mimeType))
+	case "set":
+		if len(positional) != 3 {
+			return fmt.Errorf("usage: defaults set browser|home|assistant <package or component>")
+		}
+		return setDefault(deviceID, positional[1], positional[2])
+	default:
+		return fmt.Errorf("unknown defaults action %q", positional[0])
+	}
+}
+
+func showDefaults(deviceID string) {
+	color.New(color.FgCyan, color.Bold).Println("Default Apps")
+	fmt.Print(rule("=", 30))
+
+	browser := roleHolder(deviceID, defaultRoles["browser"])
+	if browser == "" {
+		browser = resolveActivity(deviceID, "-a", "android.intent.action.VIEW", "-d", "https://example.com")
+	}
+	assistant := roleHolder(deviceID, defaultRoles["assistant"])
+	if assistant == "" {
+		assistant, _ = adbShellIdempotent(deviceID, "settings", "get", "secure", "assistant")
+	}
+
+	rows := [][2]string{
+		{"Browser", browser},
+		{"Launcher", resolveHomeActivity(deviceID)},
+		{"Assistant", assistant},
+	}
+	for _, row := range rows {
+		value := row[1]
+		if value == "" || value == "null" {
+			value = "n/a (chooser or none)"
+		}
+		color.New(color.FgGreen).Printf("%-20s : ", row[0])
+		fmt.Println(value)
+	}
+}
+
+func intentArgs(action, data, category, mimeType string) []string {
+	if !strings.Contains(action, ".") {
+		action = "android.intent.action." + strings.ToUpper(action)
+	}
+	args := []string{"-a", shellQuote(action)}
+	if data != "" {
+		args = append(args, "-d", shellQuote(data))
+	}
+	if category != "" {
+		if !strings.Contains(category, ".") {
+			category = "android.intent.category." + strings.ToUpper(category)
+		}
+		args = append(args, "-c", shellQuote(category))
+	}
+	if mimeType != "" {
+		args = append(args, "-t", shellQuote(mimeType))
+	}
+	return args
+}
+
+func resolveIntentHandlers(deviceID string, intent []string) error {
+	output, err := adbShellIdempotent(deviceID, append([]string{"cmd", "package", "query-activities", "--brief"}, intent...)...)
+	if err != nil {
+		return fmt.Errorf("query failed: %s", output)
+	}
+	preferred := resolveActivity(deviceID, intent...)
+
+	color.New(color.FgCyan, color.Bold).Printf("Handlers for %s\n", strings.Join(intent, " "))
+	for _, line := range strings.Split(output, "\n") {
+		component := strings.TrimSpace(line)
+		if !strings.Contains(component, "/") {
+			continue
+		}
+		if component == preferred {
+			color.New(color.FgGreen).Printf("  %s (default)\n", component)
+		} else {
+			fmt.Printf("  %s\n", component)
+		}
+	}
+	if !strings.Contains(preferred, "/") {
+		fmt.Println("No default handler; Android will show a chooser.")
+	}
+	return nil
+}
+
+func setDefault(deviceID, kind, target string) error {
+	if kind == "home" && strings.Contains(target, "/") {
+		if output, err := adbShell(deviceID, "cmd", "package", "set-home-activity", shellQuote(target)); err != nil {
+			return fmt.Errorf("failed to set launcher: %s", output)
+		}
+		fmt.Printf("Launcher set to %s\n", target)
+		return nil
+	}
+	role, ok := defaultRoles[kind]
+	if !ok {
+		return fmt.Errorf("unknown default %q", kind)
+	}
+	pkg := strings.SplitN(target, "/", 2)[0]
+	output, err := adbShell(deviceID, "cmd", "role", "add-role-holder", role, shellQuote(pkg))
+	if err != nil || strings.Contains(output, "Exception") {
+		return fmt.Errorf("this device does not allow changing the default %s: %s", kind, output)
+	}
+	fmt.Printf("Default %s set to %s\n", kind, pkg)
+	return nil
+}
+
+func roleHolder(deviceID, role string) string {
+	output, err := adbShellIdempotent(deviceID, "cmd", "role", "get-role-holders", role)
+	if err != nil || strings.Contains(output, "Unknown") || strings.Contains(output, "Exception") {
+		return ""
+	}
+	return output
+}
+
+// resolveActivity returns the component that would handle the intent without
+// a chooser, or "" when none (or several) match.
+func resolveActivity(deviceID string, intent ...string) string {
+	output, err := adbShellIdempotent(deviceID, append([]string{"cmd", "package", "resolve-activity", "--brief"}, intent...)...)
+	lines := strings.Split(output, "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if err != nil || !strings.Contains(last, "/") {
+		return ""
+	}
+	return last
+}
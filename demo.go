@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// demoStateFile keeps the sysui_demo_allowed value from before `demo on`.
+const demoStateFile = "/data/local/tmp/adbctl-demo-allowed"
+
+func runDemo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	clock := fs.String("clock", "1200", "Clock to show while in demo mode (hhmm)")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 || (positional[0] != "on" && positional[0] != "off") {
+		return fmt.Errorf("usage: demo on [--clock hhmm] | demo off")
+	}
+
+	deviceID := pickDevice()
+	if positional[0] == "off" {
+		return demoOff(deviceID)
+	}
+	return demoOn(deviceID, *clock)
+}
+
+func demoOn(deviceID, clock string) error {
+	// Only the first `demo on` records the original value; a repeated one
+	// would otherwise save the 1 set here. `demo off` removes the file.
+	if exists, _ := adbShellIdempotent(deviceID, "test", "-e", demoStateFile, "&&", "echo", "yes"); exists != "yes" {
+		previous, err := getSetting(deviceID, "global", "sysui_demo_allowed")
+		if err != nil {
+			return err
+		}
+		adbShell(deviceID, "echo", shellQuote(previous), ">", demoStateFile)
+	}
+	if err := putSetting(deviceID, "global", "sysui_demo_allowed", "1"); err != nil {
+		return err
+	}
+
+	commands := [][]string{
+		{"enter"},
+		{"clock", "-e", "hhmm", shellQuote(clock)},
+		{"battery", "-e", "level", "100", "-e", "plugged", "false"},
+		{"network", "-e", "wifi", "show", "-e", "level", "4"},
+		{"network", "-e", "mobile", "show", "-e", "datatype", "none", "-e", "level", "4"},
+		{"notifications", "-e", "visible", "false"},
+	}
+	for _, c := range commands {
+		if err := sendDemoCommand(deviceID, c[0], c[1:]...); err != nil {
+			return err
+		}
+	}
+	fmt.Println("Demo mode enabled. Run 'adbctl demo off' to restore the status bar.")
+	return nil
+}
+
+func demoOff(deviceID string) error {
+	if err := sendDemoCommand(deviceID, "exit"); err != nil {
+		return err
+	}
+	previous, err := adbShellIdempotent(deviceID, "cat", demoStateFile)
+	if err != nil || previous == "" || previous == "null" {
+		previous = "0"
+	}
+	if err := putSetting(deviceID, "global", "sysui_demo_allowed", previous); err != nil {
+		return err
+	}
+	adbShell(deviceID, "rm", "-f", demoStateFile)
+	fmt.Println("Demo mode disabled.")
+	return nil
+}
+
+func sendDemoCommand(deviceID, command string, extras ...string) error {
+	args := append([]string{"am", "broadcast", "-a", "com.android.systemui.demo", "-e", "command", command}, extras...)
+	if output, err := adbShell(deviceID, args...); err != nil {
+		return fmt.Errorf("demo %s failed: %s", command, output)
+	}
+	return nil
+}
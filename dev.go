@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var animationScaleSettings = []string{"window_animation_scale", "transition_animation_scale", "animator_duration_scale"}
+
+func runDev(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: dev animations [0|0.5|1] | dev frametime on|off")
+	}
+	switch args[0] {
+	case "animations":
+		deviceID := pickDevice()
+		if len(args) == 1 {
+			for _, key := range animationScaleSettings {
+				value, _ := getSetting(deviceID, "global", key)
+				fmt.Printf("%-30s : %s\n", key, value)
+			}
+			return nil
+		}
+		scale, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || scale < 0 || scale > 10 {
+			return fmt.Errorf("invalid animation scale %q (expected 0-10, e.g. 0, 0.5, 1)", args[1])
+		}
+		return setAnimationScale(deviceID, args[1])
+	case "frametime":
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			return fmt.Errorf("usage: dev frametime on|off")
+		}
+		return setFrameTimeOverlay(pickDevice(), args[1] == "on")
+	default:
+		return fmt.Errorf("unknown dev action %q", args[0])
+	}
+}
+
+// setAnimationScale sets all three animation scales at once; 0 disables
+// animations, which UI automation frameworks recommend.
+func setAnimationScale(deviceID, scale string) error {
+	for _, key := range animationScaleSettings {
+		if err := putSetting(deviceID, "global", key, scale); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Animation scales set to %s\n", scale)
+	return nil
+}
+
+// setFrameTimeOverlay toggles the profile GPU rendering bars, which cover apps
+// drawing through the UI toolkit, and SurfaceFlinger's refresh rate overlay
+// (Android 11+), which also covers games rendering to their own surface.
+func setFrameTimeOverlay(deviceID string, on bool) error {
+	profile, overlay := "false", "0"
+	if on {
+		profile, overlay = "visual_bars", "1"
+	}
+	if _, err := adbShell(deviceID, "setprop", "debug.hwui.profile", profile); err != nil {
+		return err
+	}
+	// Ask running apps to re-read system properties (SYSPROPS_TRANSACTION).
+	adbShell(deviceID, "service", "call", "activity", "1599295570")
+	output, _ := adbShell(deviceID, "service", "call", "SurfaceFlinger", "1034", "i32", overlay)
+	if on {
+		fmt.Println("Frame time bars on.")
+		if !strings.Contains(output, "Result: Parcel") {
+			fmt.Println("The refresh rate overlay needs Android 11 or later.")
+		}
+	} else {
+		fmt.Println("Frame time bars off.")
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+#!/system/bin/sh
+# root.sh: su binaries and the build properties that decide whether adb root works.
+echo "## su"
+for path in /system/bin/su /system/xbin/su /sbin/su /su/bin/su /system/sbin/su \
+	/vendor/bin/su /data/local/su /data/local/bin/su /data/local/xbin/su /debug_ramdisk/su; do
+	[ -e "$path" ] && echo "$path"
+done
+echo "## props"
+echo "debuggable=$(getprop ro.debuggable)"
+echo "secure=$(getprop ro.secure)"
+true
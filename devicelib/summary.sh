@@ -0,0 +1,12 @@
+#!/system/bin/sh
+# summary.sh: key fields for inventory --all and the API in one round trip.
+echo "## props"
+echo "model=$(getprop ro.product.model)"
+echo "fire_os=$(getprop ro.build.version.name)"
+echo "android=$(getprop ro.build.version.release)"
+echo "patch_level=$(getprop ro.build.version.security_patch)"
+echo "ip=$(ip addr show wlan0 2>/dev/null | grep 'inet ' | awk '{print $2}' | cut -d/ -f1)"
+echo "## df"
+df -k /data 2>/dev/null
+echo "## battery"
+dumpsys battery 2>/dev/null
@@ -0,0 +1,15 @@
+#!/system/bin/sh
+# thermal.sh: raw thermal zones, active cooling devices and CPU frequency caps.
+echo "## zones"
+for z in /sys/class/thermal/thermal_zone*; do
+	[ -r "$z/temp" ] && echo "$(cat "$z/type") $(cat "$z/temp")"
+done 2>/dev/null
+echo "## cooling"
+for c in /sys/class/thermal/cooling_device*; do
+	[ -r "$c/cur_state" ] && echo "$(cat "$c/type") $(cat "$c/cur_state") $(cat "$c/max_state")"
+done 2>/dev/null
+echo "## cpufreq"
+for p in /sys/devices/system/cpu/cpufreq/policy*; do
+	[ -r "$p/scaling_max_freq" ] && echo "$(cat "$p/scaling_max_freq") $(cat "$p/cpuinfo_max_freq")"
+done 2>/dev/null
+true
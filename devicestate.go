@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DeviceState is how far a device is on the way to being usable. Each level
+// implies all lower ones.
+type DeviceState int
+
+const (
+	StateDisconnected  DeviceState = iota // not listed by adb
+	StateConnected                        // listed, but offline or unauthorized
+	StateAuthorized                       // shell commands work
+	StateBooted                           // sys.boot_completed is set
+	StateLauncherReady                    // boot animation gone and an app has focus
+)
+
+var deviceStateNames = []string{"disconnected", "connected", "authorized", "booted", "launcher-ready"}
+
+func (s DeviceState) String() string {
+	return deviceStateNames[s]
+}
+
+func parseDeviceState(name string) (DeviceState, error) {
+	for i, n := range deviceStateNames {
+		if n == name {
+			return DeviceState(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown device state %q (want one of %s)", name, strings.Join(deviceStateNames, ", "))
+}
+
+// adbDeviceStatus returns the status column of `adb devices` for serial
+// ("device", "offline", "unauthorized", ...), or "" when it is not listed.
+func adbDeviceStatus(serial string) string {
+	output, err := exec.Command(adbPath, "devices").Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(output), "\n")[1:] {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == serial {
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// probeDeviceState checks each level in turn and returns the highest one the
+// device has reached.
+func probeDeviceState(serial string) DeviceState {
+	switch adbDeviceStatus(serial) {
+	case "":
+		return StateDisconnected
+	case "device":
+	default:
+		return StateConnected
+	}
+	if checkDeviceConnectivity(serial, 5*time.Second) != nil {
+		return StateConnected
+	}
+	if !isBootCompleted(serial) {
+		return StateAuthorized
+	}
+	if runAdbCommand(serial, "getprop init.svc.bootanim", 5*time.Second) == "running" {
+		return StateBooted
+	}
+	if pkg, _ := foregroundActivity(serial); pkg == "" {
+		return StateBooted
+	}
+	return StateLauncherReady
+}
+
+// waitForState polls until the device reaches at least target.
+func waitForState(serial string, target DeviceState, timeout time.Duration) error {
+	var state DeviceState
+	err := pollUntil(timeout, time.Second, func() bool {
+		state = probeDeviceState(serial)
+		return state >= target
+	})
+	if err != nil {
+		return fmt.Errorf("%s is %s, not %s: %v", serial, state, target, err)
+	}
+	return nil
+}
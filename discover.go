@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// mdnsService is one adb service advertised on the LAN.
+type mdnsService struct {
+	Name    string
+	Type    string // _adb-tls-connect._tcp (Android 11+ wireless debugging) or _adb._tcp
+	Address string
+}
+
+func runDiscover(args []string) error {
+	output, err := exec.Command(adbPath, "mdns", "services").CombinedOutput()
+	if err != nil {
+		return normalizeAdbError(string(output), fmt.Errorf("adb mdns services: %v (check 'adb mdns check')", err))
+	}
+	services := parseMDNSServices(string(output))
+	if len(services) == 0 {
+		fmt.Println("No adb devices advertised on this network.")
+		fmt.Println("Enable Wireless debugging on the device, or check 'adb mdns check'.")
+		return nil
+	}
+
+	connected := connectedSerials()
+	color.New(color.FgCyan, color.Bold).Println("Discovered Devices")
+	fmt.Print(rule("=", 60))
+	for i, s := range services {
+		status := ""
+		if containsString(connected, s.Address) {
+			status = " (connected)"
+		}
+		fmt.Printf("%d. %-30s %-22s %s%s\n", i+1, s.Name, s.Address, strings.TrimSuffix(s.Type, "."), status)
+	}
+	if ciMode {
+		return nil
+	}
+
+	fmt.Print("Number to connect (Enter to skip): ")
+	input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil
+	}
+	index := 0
+	if _, err := fmt.Sscanf(input, "%d", &index); err != nil || index < 1 || index > len(services) {
+		return fmt.Errorf("invalid selection %q", input)
+	}
+	chosen := services[index-1]
+	host, port, _ := net.SplitHostPort(chosen.Address)
+	endpoint := pairedEndpoint{Host: host, ConnectPort: port}
+	if err := connectEndpoint(&endpoint); err != nil {
+		if strings.Contains(chosen.Type, "tls") {
+			fmt.Println("Wireless debugging devices must be paired first: 'adbctl pair --qr'.")
+		}
+		return err
+	}
+	if known, _ := loadEndpoints(); containsEndpoint(known, host) {
+		return rememberEndpoint(endpoint)
+	}
+	return nil
+}
+
+// parseMDNSServices reads `adb mdns services`, whose lines are
+// "<name>\t<type>\t<ip:port>". Pairing services are left out.
+func parseMDNSServices(output string) []mdnsService {
+	var services []mdnsService
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.Contains(fields[1], "pairing") {
+			continue
+		}
+		if !strings.HasPrefix(fields[1], "_adb-tls-connect._tcp") && !strings.HasPrefix(fields[1], "_adb._tcp") {
+			continue
+		}
+		services = append(services, mdnsService{fields[0], fields[1], fields[len(fields)-1]})
+	}
+	return services
+}
+
+func containsEndpoint(endpoints []pairedEndpoint, host string) bool {
+	for _, e := range endpoints {
+		if e.Host == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var sizePattern = regexp.MustCompile(`^\d+x\d+$`)
+
+var displayPresets = map[string]struct {
+	Size    string
+	Density string
+}{
+	"720p-tv":      {"1280x720", "213"},
+	"1080p-tv":     {"1920x1080", "320"},
+	"4k-tv":        {"3840x2160", "640"},
+	"phone":        {"1080x1920", "420"},
+	"fire-hd8":     {"800x1280", "213"},
+	"fire-hd10":    {"1200x1920", "224"},
+	"tablet-large": {"1600x2560", "320"},
+}
+
+func runDisplay(args []string) error {
+	if len(args) > 0 && args[0] == "modes" {
+		return runDisplayModes(args[1:])
+	}
+
+	fs := flag.NewFlagSet("display", flag.ExitOnError)
+	preset := fs.String("preset", "", "Apply a named size/density preset (see 'display presets')")
+	positional := parseArgs(fs, args)
+
+	if len(positional) > 0 && positional[0] == "presets" {
+		names := make([]string, 0, len(displayPresets))
+		for name := range displayPresets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := displayPresets[name]
+			fmt.Printf("%-15s %-10s %s dpi\n", name, p.Size, p.Density)
+		}
+		return nil
+	}
+
+	deviceID := pickDevice()
+	if *preset != "" {
+		p, ok := displayPresets[*preset]
+		if !ok {
+			return fmt.Errorf("unknown preset %q, run 'adbctl display presets' to list them", *preset)
+		}
+		if err := wmSet(deviceID, "size", p.Size); err != nil {
+			return err
+		}
+		if err := wmSet(deviceID, "density", p.Density); err != nil {
+			return err
+		}
+		return showDisplay(deviceID)
+	}
+	if len(positional) == 0 {
+		return showDisplay(deviceID)
+	}
+
+	switch positional[0] {
+	case "size":
+		if len(positional) != 2 || !sizePattern.MatchString(positional[1]) {
+			return fmt.Errorf("usage: display size <width>x<height>")
+		}
+		if err := wmSet(deviceID, "size", positional[1]); err != nil {
+			return err
+		}
+	case "density":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: display density <dpi>")
+		}
+		if dpi, err := strconv.Atoi(positional[1]); err != nil || dpi <= 0 {
+			return fmt.Errorf("invalid density %q", positional[1])
+		}
+		if err := wmSet(deviceID, "density", positional[1]); err != nil {
+			return err
+		}
+	case "reset":
+		if err := wmSet(deviceID, "size", "reset"); err != nil {
+			return err
+		}
+		if err := wmSet(deviceID, "density", "reset"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown display action %q", positional[0])
+	}
+	return showDisplay(deviceID)
+}
+
+func wmSet(deviceID, kind, value string) error {
+	if output, err := adbShell(deviceID, "wm", kind, shellQuote(value)); err != nil {
+		return fmt.Errorf("wm %s %s failed: %s", kind, value, output)
+	}
+	return nil
+}
+
+func showDisplay(deviceID string) error {
+	size, err := adbShellIdempotent(deviceID, "wm", "size")
+	if err != nil {
+		return err
+	}
+	density, _ := adbShellIdempotent(deviceID, "wm", "density")
+	fmt.Println(size)
+	fmt.Println(density)
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	displayModePattern   = regexp.MustCompile(`\{id=(\d+), width=(\d+), height=(\d+), fps=([\d.]+)`)
+	activeModeIDPattern  = regexp.MustCompile(`(?:mActiveModeId=|modeId )(\d+)`)
+	defaultModeIDPattern = regexp.MustCompile(`(?:mDefaultModeId=|defaultModeId )(\d+)`)
+)
+
+type displayMode struct {
+	ID     int     `json:"id"`
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	FPS    float64 `json:"fps"`
+}
+
+func (m displayMode) String() string {
+	return fmt.Sprintf("%dx%d @ %.2f Hz", m.Width, m.Height, m.FPS)
+}
+
+func runDisplayModes(args []string) error {
+	fs := flag.NewFlagSet("display modes", flag.ExitOnError)
+	set := fs.Int("set", 0, "Switch to the mode with this id")
+	clear := fs.Bool("clear", false, "Clear the user-preferred mode and let the system decide")
+	fs.Parse(args)
+
+	deviceID := pickDevice()
+	dump, err := adbShellIdempotent(deviceID, "dumpsys", "display")
+	if err != nil {
+		return err
+	}
+	modes, active, defaultID := parseDisplayModes(dump)
+	if len(modes) == 0 {
+		return fmt.Errorf("no display modes found in dumpsys display")
+	}
+
+	if *clear {
+		if output, err := adbShell(deviceID, "cmd", "display", "clear-user-preferred-display-mode"); err != nil {
+			return fmt.Errorf("clearing the preferred mode failed: %s", output)
+		}
+		fmt.Println("User-preferred display mode cleared.")
+		return nil
+	}
+	if *set != 0 {
+		return setDisplayMode(deviceID, modes, *set)
+	}
+
+	color.New(color.FgCyan, color.Bold).Println("Display Modes")
+	fmt.Print(rule("=", 40))
+	for _, m := range modes {
+		marker := "  "
+		if m.ID == active {
+			marker = "* "
+		}
+		line := fmt.Sprintf("%s%3d  %s", marker, m.ID, m)
+		if m.ID == defaultID {
+			line += " (default)"
+		}
+		if m.ID == active {
+			color.New(color.FgGreen, color.Bold).Println(line)
+		} else {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// parseDisplayModes extracts the supported modes of the built-in display
+// along with the active and default mode ids.
+func parseDisplayModes(dump string) ([]displayMode, int, int) {
+	seen := make(map[int]bool)
+	var modes []displayMode
+	for _, m := range displayModePattern.FindAllStringSubmatch(dump, -1) {
+		id, _ := strconv.Atoi(m[1])
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		width, _ := strconv.Atoi(m[2])
+		height, _ := strconv.Atoi(m[3])
+		fps, _ := strconv.ParseFloat(m[4], 64)
+		modes = append(modes, displayMode{id, width, height, fps})
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i].ID < modes[j].ID })
+
+	active, defaultID := 0, 0
+	if m := activeModeIDPattern.FindStringSubmatch(dump); m != nil {
+		active, _ = strconv.Atoi(m[1])
+	}
+	if m := defaultModeIDPattern.FindStringSubmatch(dump); m != nil {
+		defaultID, _ = strconv.Atoi(m[1])
+	}
+	return modes, active, defaultID
+}
+
+// setDisplayMode switches modes via `cmd display`, available from Android 13.
+// Earlier releases only change modes on behalf of the foreground app.
+func setDisplayMode(deviceID string, modes []displayMode, id int) error {
+	for _, m := range modes {
+		if m.ID != id {
+			continue
+		}
+		output, err := adbShell(deviceID, "cmd", "display", "set-user-preferred-display-mode",
+			strconv.Itoa(m.Width), strconv.Itoa(m.Height), strconv.FormatFloat(m.FPS, 'f', -1, 64))
+		if err != nil || strings.Contains(output, "Unknown command") {
+			return fmt.Errorf("this device cannot switch display modes from adb (requires Android 13): %s", output)
+		}
+		time.Sleep(2 * time.Second)
+		fmt.Printf("Switched to mode %d: %s\n", m.ID, m)
+		return nil
+	}
+	return fmt.Errorf("no display mode with id %d", id)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// kernelLogSource is one way to read the kernel ring buffer; the shell user
+// is refused dmesg on most builds since Android 8, so root and logd's kernel
+// buffer are tried in turn.
+type kernelLogSource struct {
+	Name   string
+	Dump   []string
+	Follow []string
+}
+
+var kernelLogSources = []kernelLogSource{
+	{"dmesg", []string{"dmesg"}, []string{"dmesg", "-w"}},
+	{"su dmesg", []string{"su", "0", "dmesg"}, []string{"su", "0", "dmesg", "-w"}},
+	{"su -c dmesg", []string{"su", "-c", "dmesg"}, []string{"su", "-c", "dmesg -w"}},
+	{"logcat kernel buffer", []string{"logcat", "-d", "-b", "kernel"}, []string{"logcat", "-b", "kernel"}},
+}
+
+func runDmesg(args []string) error {
+	fs := flag.NewFlagSet("dmesg", flag.ExitOnError)
+	follow := fs.Bool("follow", false, "Keep printing new kernel messages")
+	grep := fs.String("grep", "", "Only show lines matching this regular expression (case-insensitive)")
+	output := fs.String("o", "", "Also write the lines shown to this file")
+	parseArgs(fs, args)
+	var pattern *regexp.Regexp
+	if *grep != "" {
+		var err error
+		if pattern, err = regexp.Compile("(?i)" + *grep); err != nil {
+			return fmt.Errorf("invalid --grep pattern: %v", err)
+		}
+	}
+
+	deviceID := pickDevice()
+	source, log, err := readKernelLog(deviceID)
+	if err != nil {
+		return err
+	}
+	debugPrint("kernel log from %s\n", source.Name)
+
+	var file *os.File
+	if *output != "" {
+		if file, err = os.Create(*output); err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+	show := func(line string) {
+		if pattern != nil && !pattern.MatchString(line) {
+			return
+		}
+		fmt.Println(line)
+		if file != nil {
+			fmt.Fprintln(file, line)
+		}
+	}
+	if !*follow {
+		for _, line := range strings.Split(log, "\n") {
+			show(line)
+		}
+		return nil
+	}
+	// The follow commands print the existing buffer first, then new lines.
+	return adbStream(context.Background(), deviceID, show, append([]string{"shell"}, source.Follow...)...)
+}
+
+// readKernelLog returns the first source that can read the ring buffer and
+// what it read.
+func readKernelLog(deviceID string) (kernelLogSource, string, error) {
+	for _, source := range kernelLogSources {
+		output, err := adbShellIdempotent(deviceID, source.Dump...)
+		if err == nil && output != "" && !kernelLogRefused(output) {
+			return source, output, nil
+		}
+		debugPrint("%s: %v %s\n", source.Name, err, strings.SplitN(output, "\n", 2)[0])
+	}
+	return kernelLogSource{}, "", &adbError{
+		Kind:        "permission-denied",
+		Explanation: "The shell user may not read the kernel log on this build, su is not available and logd keeps no kernel buffer.",
+		Suggestion:  "Run 'adb root' on a userdebug build; bug reports (adb bugreport) also include the kernel log.",
+		Raw:         "kernel log not readable",
+	}
+}
+
+func kernelLogRefused(output string) bool {
+	first := strings.ToLower(strings.SplitN(output, "\n", 2)[0])
+	for _, refusal := range []string{"permission denied", "not permitted", "not found", "unknown buffer", "su: ", "invalid"} {
+		if strings.Contains(first, refusal) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+func runDpm(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: dpm set-owner|set-admin|remove-admin <component> | dpm status")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("dpm "+action, flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt")
+	user := fs.String("user", "", "Target user id (defaults to the current user)")
+	positional := parseArgs(fs, args[1:])
+
+	if action == "status" {
+		return dpmStatus(pickDevice())
+	}
+	if len(positional) != 1 || !strings.Contains(positional[0], "/") {
+		return fmt.Errorf("usage: dpm %s <package/.AdminReceiver>", action)
+	}
+	component := positional[0]
+
+	var dpmArgs []string
+	switch action {
+	case "set-owner":
+		warning := color.New(color.FgRed, color.Bold)
+		warning.Println("WARNING: setting a device owner is effectively irreversible.")
+		fmt.Println("  - All accounts must be removed from the device first, or provisioning fails.")
+		fmt.Println("  - Only the owner app itself or a factory reset can remove it, unless the")
+		fmt.Println("    app is built with android:testOnly=\"true\".")
+		fmt.Println("  - Only use this on dedicated lab/test devices.")
+		if !*yes && !confirm("Type 'yes' to make "+component+" the device owner: ") {
+			return fmt.Errorf("aborted")
+		}
+		dpmArgs = []string{"dpm", "set-device-owner"}
+	case "set-admin":
+		dpmArgs = []string{"dpm", "set-active-admin"}
+	case "remove-admin":
+		if !*yes && !confirm("Type 'yes' to remove admin "+component+": ") {
+			return fmt.Errorf("aborted")
+		}
+		dpmArgs = []string{"dpm", "remove-active-admin"}
+	default:
+		return fmt.Errorf("unknown dpm action %q", action)
+	}
+	if *user != "" {
+		dpmArgs = append(dpmArgs, "--user", shellQuote(*user))
+	}
+	dpmArgs = append(dpmArgs, shellQuote(component))
+
+	output, err := adbShell(pickDevice(), dpmArgs...)
+	fmt.Println(output)
+	if err != nil || strings.Contains(output, "Exception") {
+		return fmt.Errorf("%s failed", dpmArgs[1])
+	}
+	return nil
+}
+
+func dpmStatus(deviceID string) error {
+	output, err := adbShellIdempotent(deviceID, "dumpsys", "device_policy")
+	if err != nil {
+		return err
+	}
+	color.New(color.FgCyan, color.Bold).Println("Device Policy")
+	fmt.Print(rule("=", 30))
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "Device Owner") || strings.HasPrefix(trimmed, "Profile Owner") ||
+			strings.HasPrefix(trimmed, "admin=") || strings.HasPrefix(trimmed, "ComponentInfo{") {
+			fmt.Println(trimmed)
+			found = true
+		}
+	}
+	if !found {
+		fmt.Println("No device owner, profile owner or active admins.")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// drmSecurityLevelPattern finds the Widevine security level in
+// `dumpsys media.drm`, which lists each plugin's properties on newer releases.
+var drmSecurityLevelPattern = regexp.MustCompile(`(?i)securityLevel\W+(L[123])\b`)
+
+// getDRMInfo reports Widevine, PlayReady and HDCP details. The Widevine level
+// comes from MediaDrm's securityLevel property where `dumpsys media.drm`
+// shows it; otherwise it is guessed from the presence of the OEMCrypto
+// library that L1 requires, and labelled as a guess.
+func getDRMInfo(deviceID string) []DeviceInfo {
+	timeout := 5 * time.Second
+	plugins := strings.ToLower(runAdbCommand(deviceID,
+		"ls /vendor/lib/mediadrm /vendor/lib64/mediadrm /system/lib/mediadrm /system/lib64/mediadrm /vendor/bin/hw 2>/dev/null; lshal 2>/dev/null | grep -i drm", timeout))
+
+	widevine := "Not present"
+	if m := drmSecurityLevelPattern.FindStringSubmatch(runAdbCommand(deviceID, "dumpsys media.drm", timeout)); m != nil {
+		widevine = strings.ToUpper(m[1])
+		switch widevine {
+		case "L1":
+			widevine += " (hardware-backed)"
+		case "L3":
+			widevine += " (software)"
+		}
+	} else if strings.Contains(plugins, "widevine") || strings.Contains(plugins, "wvdrm") {
+		widevine = "L3 (software, guessed: no liboemcrypto)"
+		oemcrypto := runAdbCommand(deviceID,
+			"ls /vendor/lib/liboemcrypto.so /vendor/lib64/liboemcrypto.so /system/vendor/lib/liboemcrypto.so 2>/dev/null", timeout)
+		if strings.Contains(oemcrypto, "liboemcrypto.so") {
+			widevine = "L1 (hardware-backed, guessed from liboemcrypto)"
+		}
+	}
+
+	playready := "No"
+	if strings.Contains(plugins, "playready") {
+		playready = "Yes"
+	}
+
+	return []DeviceInfo{
+		{"Widevine", widevine},
+		{"PlayReady", playready},
+		{"HDCP", getHDCPLevel(deviceID)},
+	}
+}
+
+// getHDCPLevel reads the negotiated HDCP version from the HDMI driver where
+// the SoC exposes it (Amlogic and MediaTek based Fire TV devices).
+func getHDCPLevel(deviceID string) string {
+	output := runAdbCommand(deviceID,
+		"cat /sys/class/amhdmitx/amhdmitx0/hdcp_mode /sys/class/amhdmitx/amhdmitx0/hdcp_ver /sys/class/hdmitx/hdmitx/hdcp_ver 2>/dev/null", 5*time.Second)
+	for _, field := range strings.Fields(output) {
+		switch field {
+		case "22", "2.2":
+			return "2.2"
+		case "14", "1.4":
+			return "1.4"
+		}
+	}
+	return "n/a"
+}
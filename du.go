@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type duEntry struct {
+	Path     string
+	SizeKB   int
+	Children []*duEntry
+}
+
+func runDu(args []string) error {
+	fs := flag.NewFlagSet("du", flag.ExitOnError)
+	top := fs.Int("top", 10, "Number of directories to show per level")
+	fs.Parse(args)
+
+	root := "/sdcard"
+	if fs.NArg() > 0 {
+		root = path.Clean(fs.Arg(0))
+	}
+	target := root
+	if root != "/" {
+		target += "/" // follow /sdcard style symlinks
+	}
+
+	deviceID := pickDevice()
+	output, err := adbShellIdempotent(deviceID, "du", "-k", "-d", "2", shellQuote(target))
+	if output == "" && err != nil {
+		return err
+	}
+
+	tree := parseDuOutput(output, root)
+	color.New(color.FgCyan, color.Bold).Printf("Disk usage of %s: %s\n", root, formatKB(tree.SizeKB))
+	fmt.Print(rule("=", 50))
+	printDuTree(tree.Children, "", *top)
+	return nil
+}
+
+// parseDuOutput builds a two-level tree from `du -d 2` output. du reports
+// permission errors inline, so unparsable lines are skipped rather than fatal.
+func parseDuOutput(output, root string) *duEntry {
+	tree := &duEntry{Path: root}
+	byPath := map[string]*duEntry{root: tree}
+
+	var entries []*duEntry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		size, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		p := path.Clean(fields[1])
+		if p == root {
+			tree.SizeKB = size
+			continue
+		}
+		entry := &duEntry{Path: p, SizeKB: size}
+		byPath[p] = entry
+		entries = append(entries, entry)
+	}
+
+	for _, entry := range entries {
+		if parent, ok := byPath[path.Dir(entry.Path)]; ok {
+			parent.Children = append(parent.Children, entry)
+		}
+	}
+	sortDuTree(tree)
+	return tree
+}
+
+func sortDuTree(entry *duEntry) {
+	sort.Slice(entry.Children, func(i, j int) bool {
+		return entry.Children[i].SizeKB > entry.Children[j].SizeKB
+	})
+	for _, child := range entry.Children {
+		sortDuTree(child)
+	}
+}
+
+func printDuTree(entries []*duEntry, indent string, top int) {
+	for i, entry := range entries {
+		if i >= top {
+			fmt.Printf("%s  ... %d more\n", indent, len(entries)-top)
+			break
+		}
+		color.New(color.FgYellow).Printf("%s%10s  ", indent, formatKB(entry.SizeKB))
+		fmt.Println(path.Base(entry.Path) + "/")
+		printDuTree(entry.Children, indent+"    ", top)
+	}
+}
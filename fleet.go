@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// exitPartialFailure is returned when a fleet operation failed on some but
+// not all devices; a total failure exits with exitError.
+const exitPartialFailure = 4
+
+// nativeFleetCommands implement --all-devices themselves; every other command
+// is run once per device by runFleetCommand.
+var nativeFleetCommands = []string{"logcat"}
+
+// fleetResult is the outcome of a fleet operation on one device.
+type fleetResult struct {
+	Device     string `json:"device"`
+	Status     string `json:"status"` // succeeded, failed or skipped
+	Reason     string `json:"reason,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type fleetOptions struct {
+	AllDevices      bool
+	ContinueOnError bool
+	ResultsPath     string
+}
+
+// exitCodeError makes runCommand exit with Code instead of the default 1.
+type exitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *exitCodeError) Error() string { return e.Err.Error() }
+
+// exitCode returns the process exit code for a command error.
+func exitCode(err error) int {
+	var coded *exitCodeError
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return exitError
+}
+
+// extractFleetFlags removes --all-devices, --continue-on-error and
+// --results <file> from args.
+func extractFleetFlags(args []string) ([]string, fleetOptions) {
+	var rest []string
+	var opts fleetOptions
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			// Arguments for a nested command (e.g. matrix run) are left alone.
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") {
+			name = ""
+		}
+		switch name {
+		case "all-devices":
+			opts.AllDevices = true
+		case "continue-on-error":
+			opts.ContinueOnError = true
+		case "results":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			opts.ResultsPath = value
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, opts
+}
+
+// fleetDevices returns the online serials, plus a skipped result for every
+// device adb lists that cannot be used (offline, unauthorized, ...).
+func fleetDevices() ([]string, []fleetResult) {
+	output, err := exec.Command(adbPath, "devices").Output()
+	if err != nil {
+		return nil, nil
+	}
+	var online []string
+	var skipped []fleetResult
+	for _, line := range strings.Split(string(output), "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		if fields[1] == "device" {
+			online = append(online, fields[0])
+		} else {
+			skipped = append(skipped, fleetResult{Device: fields[0], Status: "skipped", Reason: StateConnected.String() + " but " + fields[1]})
+		}
+	}
+	return online, skipped
+}
+
+// runFleetCommand runs an adbctl command once per online device, in a child
+// process with ADBCTL_DEVICE set, stopping at the first failure unless
+// ContinueOnError is set.
+func runFleetCommand(name string, args []string, opts fleetOptions) error {
+	serials, results := fleetDevices()
+	if len(serials) == 0 {
+		return fmt.Errorf("no devices connected")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	stopped := false
+	for _, serial := range serials {
+		if stopped {
+			results = append(results, fleetResult{Device: serial, Status: "skipped", Reason: "stopped after an earlier failure"})
+			continue
+		}
+		color.New(color.FgCyan, color.Bold).Printf("\n=== %s ===\n", serial)
+		start := time.Now()
+		cmd := exec.Command(self, append([]string{name}, args...)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		cmd.Env = append(os.Environ(), "ADBCTL_DEVICE="+serial)
+		result := fleetResult{Device: serial, Status: "succeeded"}
+		if err := cmd.Run(); err != nil {
+			result.Status, result.Reason = "failed", err.Error()
+			stopped = !opts.ContinueOnError
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+		results = append(results, result)
+	}
+	return reportFleet(results, opts.ResultsPath)
+}
+
+// reportFleet prints the per-device summary, optionally writes it as JSON,
+// and turns it into an error: nil when nothing failed, exitPartialFailure
+// when some devices succeeded, exitError when none did.
+func reportFleet(results []fleetResult, resultsPath string) error {
+	counts := map[string]int{}
+	color.New(color.FgCyan, color.Bold).Println("\nFleet Results")
+	fmt.Print(rule("=", 60))
+	for _, r := range results {
+		counts[r.Status]++
+		c := color.New(color.FgGreen)
+		switch r.Status {
+		case "failed":
+			c = color.New(color.FgRed)
+		case "skipped":
+			c = color.New(color.FgYellow)
+		}
+		fmt.Printf("%-30s ", r.Device)
+		c.Printf("%-10s", r.Status)
+		fmt.Println(" " + r.Reason)
+	}
+	fmt.Printf("\n%d succeeded, %d failed, %d skipped\n", counts["succeeded"], counts["failed"], counts["skipped"])
+
+	if resultsPath != "" {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		if err := os.WriteFile(resultsPath, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case counts["failed"] == 0 && counts["skipped"] == 0:
+		return nil
+	case counts["succeeded"] == 0:
+		return &exitCodeError{exitError, fmt.Errorf("failed on every device")}
+	default:
+		return &exitCodeError{exitPartialFailure, fmt.Errorf("failed or skipped on %d of %d devices",
+			counts["failed"]+counts["skipped"], len(results))}
+	}
+}
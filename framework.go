@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	frameworkFlutter     = "Flutter"
+	frameworkReactNative = "React Native"
+)
+
+// frameworkLogTags are the logcat tags each framework's own output uses.
+var frameworkLogTags = map[string][]string{
+	frameworkFlutter:     {"flutter", "FlutterJNI", "DartVM"},
+	frameworkReactNative: {"ReactNativeJS", "ReactNative", "ReactNativeJNI", "Hermes"},
+}
+
+var hermesEnabledPattern = regexp.MustCompile(`(?m)^hermesEnabled\s*=\s*(\w+)`)
+
+var dartVMServicePattern = regexp.MustCompile(`(?:Dart VM [Ss]ervice|Observatory)[^:]* listening on (https?://127\.0\.0\.1:(\d+)/\S*)`)
+
+// frameworkProject is a Flutter or React Native project around the working
+// directory.
+type frameworkProject struct {
+	Framework string
+	Root      string
+}
+
+func runFramework(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: framework forward [--port 8081] | logs [--all]")
+	}
+	switch args[0] {
+	case "forward":
+		return runFrameworkForward(args[1:])
+	case "logs":
+		return runFrameworkLogs(args[1:])
+	default:
+		return fmt.Errorf("unknown framework subcommand %q", args[0])
+	}
+}
+
+// findFrameworkProject looks upwards for a pubspec.yaml depending on Flutter or
+// a package.json depending on react-native.
+func findFrameworkProject() *frameworkProject {
+	if ignoreProject {
+		return nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	for {
+		if data, err := os.ReadFile(filepath.Join(dir, "pubspec.yaml")); err == nil {
+			var pubspec struct {
+				Dependencies map[string]any `yaml:"dependencies"`
+			}
+			if yaml.Unmarshal(data, &pubspec) == nil && pubspec.Dependencies["flutter"] != nil {
+				return &frameworkProject{frameworkFlutter, dir}
+			}
+		}
+		if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+			var pkg struct {
+				Dependencies map[string]string `json:"dependencies"`
+			}
+			if json.Unmarshal(data, &pkg) == nil && pkg.Dependencies["react-native"] != "" {
+				return &frameworkProject{frameworkReactNative, dir}
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
+
+func requireFrameworkProject() (*frameworkProject, error) {
+	project := findFrameworkProject()
+	if project == nil {
+		return nil, fmt.Errorf("no Flutter (pubspec.yaml) or React Native (package.json) project found in this directory or its parents")
+	}
+	return project, nil
+}
+
+// runFrameworkForward makes the development server reachable: React Native
+// apps load their bundle from Metro on the host (adb reverse), Flutter tools
+// attach to the Dart VM service on the device (adb forward).
+func runFrameworkForward(args []string) error {
+	fs := flag.NewFlagSet("framework forward", flag.ExitOnError)
+	port := fs.Int("port", 0, "Metro port for React Native (default RCT_METRO_PORT or 8081)")
+	parseArgs(fs, args)
+	project, err := requireFrameworkProject()
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	if project.Framework == frameworkReactNative {
+		if *port == 0 {
+			*port = 8081
+			fmt.Sscanf(os.Getenv("RCT_METRO_PORT"), "%d", port)
+		}
+		spec := fmt.Sprintf("tcp:%d", *port)
+		if err := adbHost(deviceID, "reverse", spec, spec); err != nil {
+			return err
+		}
+		fmt.Printf("Device port %d now reaches Metro on this machine.\n", *port)
+		return nil
+	}
+
+	url, devicePort := dartVMService(deviceID)
+	if url == "" {
+		return fmt.Errorf("no Dart VM service found in the log; start the app in debug or profile mode first")
+	}
+	spec := "tcp:" + devicePort
+	if err := adbHost(deviceID, "forward", spec, spec); err != nil {
+		return err
+	}
+	fmt.Printf("Dart VM service forwarded: %s\n", url)
+	fmt.Printf("Attach with: flutter attach --debug-url %s\n", url)
+	return nil
+}
+
+// dartVMService finds the most recent VM service URL the Flutter engine
+// logged, with its device port.
+func dartVMService(deviceID string) (string, string) {
+	output, _ := adbShellIdempotent(deviceID, "logcat", "-d", "-s", "flutter:I")
+	url, port := "", ""
+	for _, line := range strings.Split(output, "\n") {
+		if m := dartVMServicePattern.FindStringSubmatch(line); m != nil {
+			url, port = m[1], m[2]
+		}
+	}
+	return url, port
+}
+
+// adbHost runs an adb command that works on the host side of the connection,
+// such as forward and reverse. These are safe to repeat, so they are retried
+// across an adbd restart.
+func adbHost(deviceID string, args ...string) error {
+	output, err := adbRetry.run(deviceID, func() ([]byte, error) {
+		return exec.Command(adbPath, append([]string{"-s", fastTransport(deviceID)}, args...)...).CombinedOutput()
+	})
+	if err != nil {
+		return normalizeAdbError(string(output), fmt.Errorf("adb %s: %s", strings.Join(args, " "), strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+func runFrameworkLogs(args []string) error {
+	fs := flag.NewFlagSet("framework logs", flag.ExitOnError)
+	all := fs.Bool("all", false, "Show warnings and errors from every tag as well")
+	parseArgs(fs, args)
+	project, err := requireFrameworkProject()
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	var spec []string
+	for _, tag := range frameworkLogTags[project.Framework] {
+		spec = append(spec, tag+":V")
+	}
+	if *all {
+		spec = append(spec, "*:W")
+	} else {
+		spec = append(spec, "*:S")
+	}
+	fmt.Printf("Tailing %s logs; press Ctrl+C to stop.\n", project.Framework)
+	return streamLogcat(context.Background(), deviceID, spec, func(line string) {
+		if strings.Contains(line, " E ") {
+			color.New(color.FgRed).Println(line)
+		} else {
+			fmt.Println(line)
+		}
+	})
+}
+
+// appFramework tells from an installed app's native libraries whether it is
+// built with Flutter or React Native and which JavaScript engine it bundles.
+func appFramework(deviceID, pkg string) (framework, engine string) {
+	paths := runAdbCommand(deviceID, "pm path "+pkg, 5*time.Second)
+	var libs string
+	for _, line := range strings.Split(paths, "\n") {
+		apk, found := strings.CutPrefix(strings.TrimSpace(line), "package:")
+		if !found {
+			continue
+		}
+		dir := filepath.ToSlash(filepath.Dir(apk))
+		// Native libraries are extracted to lib/<abi>/ unless the app keeps them
+		// in the APK, in which case the archive listing has them.
+		libs += runAdbCommand(deviceID, fmt.Sprintf("ls %s/lib/*/ 2>/dev/null || unzip -l %s 2>/dev/null | grep '\\.so$'", dir, apk), 10*time.Second)
+	}
+	switch {
+	case strings.Contains(libs, "libflutter.so"):
+		return frameworkFlutter, "Dart"
+	case strings.Contains(libs, "libhermes.so") || strings.Contains(libs, "libhermes_executor"):
+		return frameworkReactNative, "Hermes"
+	case strings.Contains(libs, "libjsc.so"):
+		return frameworkReactNative, "JavaScriptCore"
+	case strings.Contains(libs, "libreactnativejni.so"):
+		return frameworkReactNative, ""
+	}
+	return "", ""
+}
+
+// frameworkVersions reads framework and engine versions from the project's
+// lock files and installed SDK.
+func frameworkVersions(project *frameworkProject) [][2]string {
+	var versions [][2]string
+	if project.Framework == frameworkReactNative {
+		var rn struct {
+			Version string `json:"version"`
+		}
+		if data, err := os.ReadFile(filepath.Join(project.Root, "node_modules", "react-native", "package.json")); err == nil && json.Unmarshal(data, &rn) == nil {
+			versions = append(versions, [2]string{"React Native", rn.Version})
+		}
+		if data, err := os.ReadFile(filepath.Join(project.Root, "node_modules", "react-native", "sdks", ".hermesversion")); err == nil {
+			versions = append(versions, [2]string{"Hermes", strings.TrimSpace(string(data))})
+		}
+		if data, err := os.ReadFile(filepath.Join(project.Root, "android", "gradle.properties")); err == nil {
+			if m := hermesEnabledPattern.FindSubmatch(data); m != nil {
+				versions = append(versions, [2]string{"Hermes enabled", string(m[1])})
+			}
+		}
+		return versions
+	}
+
+	// flutter --version --machine reports the SDK the project builds with.
+	if output, err := exec.Command("flutter", "--version", "--machine").Output(); err == nil {
+		var sdk struct {
+			FrameworkVersion string `json:"frameworkVersion"`
+			EngineRevision   string `json:"engineRevision"`
+			DartSdkVersion   string `json:"dartSdkVersion"`
+		}
+		if json.Unmarshal(output, &sdk) == nil {
+			versions = append(versions, [2]string{"Flutter", sdk.FrameworkVersion}, [2]string{"Flutter engine", sdk.EngineRevision},
+				[2]string{"Dart", sdk.DartSdkVersion})
+			return versions
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(project.Root, "pubspec.lock")); err == nil {
+		var lock struct {
+			SDKs map[string]string `yaml:"sdks"`
+		}
+		if yaml.Unmarshal(data, &lock) == nil {
+			for _, sdk := range []string{"flutter", "dart"} {
+				if constraint := lock.SDKs[sdk]; constraint != "" {
+					versions = append(versions, [2]string{upperFirst(sdk) + " constraint", constraint})
+				}
+			}
+		}
+	}
+	return versions
+}
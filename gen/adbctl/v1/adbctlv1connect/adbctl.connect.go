@@ -0,0 +1,243 @@
+// The device farm API served by `adbctl api serve`.
+//
+// adbctl serves this service over gRPC, gRPC-Web and the Connect protocol
+// (https://connectrpc.com/docs/protocol), with binary protobuf or JSON, so
+// clients generated by grpc-go, grpc-java, grpcurl, connect-go, connect-es
+// and the like work as they are. gRPC uses HTTP/2 without TLS (h2c).
+//
+// The Go code in gen/adbctl/v1 is generated from this file; run
+// `go generate` after changing it.
+
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: adbctl.proto
+
+package adbctlv1connect
+
+import (
+	v1 "adb-info/gen/adbctl/v1"
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// DeviceFarmName is the fully-qualified name of the DeviceFarm service.
+	DeviceFarmName = "adbctl.v1.DeviceFarm"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// DeviceFarmListDevicesProcedure is the fully-qualified name of the DeviceFarm's ListDevices RPC.
+	DeviceFarmListDevicesProcedure = "/adbctl.v1.DeviceFarm/ListDevices"
+	// DeviceFarmGetDeviceInfoProcedure is the fully-qualified name of the DeviceFarm's GetDeviceInfo
+	// RPC.
+	DeviceFarmGetDeviceInfoProcedure = "/adbctl.v1.DeviceFarm/GetDeviceInfo"
+	// DeviceFarmRunCommandProcedure is the fully-qualified name of the DeviceFarm's RunCommand RPC.
+	DeviceFarmRunCommandProcedure = "/adbctl.v1.DeviceFarm/RunCommand"
+	// DeviceFarmStreamShellProcedure is the fully-qualified name of the DeviceFarm's StreamShell RPC.
+	DeviceFarmStreamShellProcedure = "/adbctl.v1.DeviceFarm/StreamShell"
+	// DeviceFarmStreamLogcatProcedure is the fully-qualified name of the DeviceFarm's StreamLogcat RPC.
+	DeviceFarmStreamLogcatProcedure = "/adbctl.v1.DeviceFarm/StreamLogcat"
+)
+
+// DeviceFarmClient is a client for the adbctl.v1.DeviceFarm service.
+type DeviceFarmClient interface {
+	// ListDevices returns every device adb lists, usable or not.
+	ListDevices(context.Context, *connect.Request[v1.ListDevicesRequest]) (*connect.Response[v1.ListDevicesResponse], error)
+	// GetDeviceInfo returns the key fields of one device.
+	GetDeviceInfo(context.Context, *connect.Request[v1.GetDeviceInfoRequest]) (*connect.Response[v1.DeviceInfo], error)
+	// RunCommand runs a shell command and returns its output once it exits.
+	RunCommand(context.Context, *connect.Request[v1.RunCommandRequest]) (*connect.Response[v1.RunCommandResponse], error)
+	// StreamShell runs a shell command and streams its output as it is
+	// produced. The last message has exited set.
+	StreamShell(context.Context, *connect.Request[v1.RunCommandRequest]) (*connect.ServerStreamForClient[v1.ShellOutput], error)
+	// StreamLogcat tails the device log until the client cancels the call.
+	StreamLogcat(context.Context, *connect.Request[v1.StreamLogcatRequest]) (*connect.ServerStreamForClient[v1.LogLine], error)
+}
+
+// NewDeviceFarmClient constructs a client for the adbctl.v1.DeviceFarm service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewDeviceFarmClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) DeviceFarmClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	deviceFarmMethods := v1.File_adbctl_proto.Services().ByName("DeviceFarm").Methods()
+	return &deviceFarmClient{
+		listDevices: connect.NewClient[v1.ListDevicesRequest, v1.ListDevicesResponse](
+			httpClient,
+			baseURL+DeviceFarmListDevicesProcedure,
+			connect.WithSchema(deviceFarmMethods.ByName("ListDevices")),
+			connect.WithClientOptions(opts...),
+		),
+		getDeviceInfo: connect.NewClient[v1.GetDeviceInfoRequest, v1.DeviceInfo](
+			httpClient,
+			baseURL+DeviceFarmGetDeviceInfoProcedure,
+			connect.WithSchema(deviceFarmMethods.ByName("GetDeviceInfo")),
+			connect.WithClientOptions(opts...),
+		),
+		runCommand: connect.NewClient[v1.RunCommandRequest, v1.RunCommandResponse](
+			httpClient,
+			baseURL+DeviceFarmRunCommandProcedure,
+			connect.WithSchema(deviceFarmMethods.ByName("RunCommand")),
+			connect.WithClientOptions(opts...),
+		),
+		streamShell: connect.NewClient[v1.RunCommandRequest, v1.ShellOutput](
+			httpClient,
+			baseURL+DeviceFarmStreamShellProcedure,
+			connect.WithSchema(deviceFarmMethods.ByName("StreamShell")),
+			connect.WithClientOptions(opts...),
+		),
+		streamLogcat: connect.NewClient[v1.StreamLogcatRequest, v1.LogLine](
+			httpClient,
+			baseURL+DeviceFarmStreamLogcatProcedure,
+			connect.WithSchema(deviceFarmMethods.ByName("StreamLogcat")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// deviceFarmClient implements DeviceFarmClient.
+type deviceFarmClient struct {
+	listDevices   *connect.Client[v1.ListDevicesRequest, v1.ListDevicesResponse]
+	getDeviceInfo *connect.Client[v1.GetDeviceInfoRequest, v1.DeviceInfo]
+	runCommand    *connect.Client[v1.RunCommandRequest, v1.RunCommandResponse]
+	streamShell   *connect.Client[v1.RunCommandRequest, v1.ShellOutput]
+	streamLogcat  *connect.Client[v1.StreamLogcatRequest, v1.LogLine]
+}
+
+// ListDevices calls adbctl.v1.DeviceFarm.ListDevices.
+func (c *deviceFarmClient) ListDevices(ctx context.Context, req *connect.Request[v1.ListDevicesRequest]) (*connect.Response[v1.ListDevicesResponse], error) {
+	return c.listDevices.CallUnary(ctx, req)
+}
+
+// GetDeviceInfo calls adbctl.v1.DeviceFarm.GetDeviceInfo.
+func (c *deviceFarmClient) GetDeviceInfo(ctx context.Context, req *connect.Request[v1.GetDeviceInfoRequest]) (*connect.Response[v1.DeviceInfo], error) {
+	return c.getDeviceInfo.CallUnary(ctx, req)
+}
+
+// RunCommand calls adbctl.v1.DeviceFarm.RunCommand.
+func (c *deviceFarmClient) RunCommand(ctx context.Context, req *connect.Request[v1.RunCommandRequest]) (*connect.Response[v1.RunCommandResponse], error) {
+	return c.runCommand.CallUnary(ctx, req)
+}
+
+// StreamShell calls adbctl.v1.DeviceFarm.StreamShell.
+func (c *deviceFarmClient) StreamShell(ctx context.Context, req *connect.Request[v1.RunCommandRequest]) (*connect.ServerStreamForClient[v1.ShellOutput], error) {
+	return c.streamShell.CallServerStream(ctx, req)
+}
+
+// StreamLogcat calls adbctl.v1.DeviceFarm.StreamLogcat.
+func (c *deviceFarmClient) StreamLogcat(ctx context.Context, req *connect.Request[v1.StreamLogcatRequest]) (*connect.ServerStreamForClient[v1.LogLine], error) {
+	return c.streamLogcat.CallServerStream(ctx, req)
+}
+
+// DeviceFarmHandler is an implementation of the adbctl.v1.DeviceFarm service.
+type DeviceFarmHandler interface {
+	// ListDevices returns every device adb lists, usable or not.
+	ListDevices(context.Context, *connect.Request[v1.ListDevicesRequest]) (*connect.Response[v1.ListDevicesResponse], error)
+	// GetDeviceInfo returns the key fields of one device.
+	GetDeviceInfo(context.Context, *connect.Request[v1.GetDeviceInfoRequest]) (*connect.Response[v1.DeviceInfo], error)
+	// RunCommand runs a shell command and returns its output once it exits.
+	RunCommand(context.Context, *connect.Request[v1.RunCommandRequest]) (*connect.Response[v1.RunCommandResponse], error)
+	// StreamShell runs a shell command and streams its output as it is
+	// produced. The last message has exited set.
+	StreamShell(context.Context, *connect.Request[v1.RunCommandRequest], *connect.ServerStream[v1.ShellOutput]) error
+	// StreamLogcat tails the device log until the client cancels the call.
+	StreamLogcat(context.Context, *connect.Request[v1.StreamLogcatRequest], *connect.ServerStream[v1.LogLine]) error
+}
+
+// NewDeviceFarmHandler builds an HTTP handler from the service implementation. It returns the path
+// on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewDeviceFarmHandler(svc DeviceFarmHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	deviceFarmMethods := v1.File_adbctl_proto.Services().ByName("DeviceFarm").Methods()
+	deviceFarmListDevicesHandler := connect.NewUnaryHandler(
+		DeviceFarmListDevicesProcedure,
+		svc.ListDevices,
+		connect.WithSchema(deviceFarmMethods.ByName("ListDevices")),
+		connect.WithHandlerOptions(opts...),
+	)
+	deviceFarmGetDeviceInfoHandler := connect.NewUnaryHandler(
+		DeviceFarmGetDeviceInfoProcedure,
+		svc.GetDeviceInfo,
+		connect.WithSchema(deviceFarmMethods.ByName("GetDeviceInfo")),
+		connect.WithHandlerOptions(opts...),
+	)
+	deviceFarmRunCommandHandler := connect.NewUnaryHandler(
+		DeviceFarmRunCommandProcedure,
+		svc.RunCommand,
+		connect.WithSchema(deviceFarmMethods.ByName("RunCommand")),
+		connect.WithHandlerOptions(opts...),
+	)
+	deviceFarmStreamShellHandler := connect.NewServerStreamHandler(
+		DeviceFarmStreamShellProcedure,
+		svc.StreamShell,
+		connect.WithSchema(deviceFarmMethods.ByName("StreamShell")),
+		connect.WithHandlerOptions(opts...),
+	)
+	deviceFarmStreamLogcatHandler := connect.NewServerStreamHandler(
+		DeviceFarmStreamLogcatProcedure,
+		svc.StreamLogcat,
+		connect.WithSchema(deviceFarmMethods.ByName("StreamLogcat")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/adbctl.v1.DeviceFarm/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case DeviceFarmListDevicesProcedure:
+			deviceFarmListDevicesHandler.ServeHTTP(w, r)
+		case DeviceFarmGetDeviceInfoProcedure:
+			deviceFarmGetDeviceInfoHandler.ServeHTTP(w, r)
+		case DeviceFarmRunCommandProcedure:
+			deviceFarmRunCommandHandler.ServeHTTP(w, r)
+		case DeviceFarmStreamShellProcedure:
+			deviceFarmStreamShellHandler.ServeHTTP(w, r)
+		case DeviceFarmStreamLogcatProcedure:
+			deviceFarmStreamLogcatHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedDeviceFarmHandler returns CodeUnimplemented from all methods.
+type UnimplementedDeviceFarmHandler struct{}
+
+func (UnimplementedDeviceFarmHandler) ListDevices(context.Context, *connect.Request[v1.ListDevicesRequest]) (*connect.Response[v1.ListDevicesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("adbctl.v1.DeviceFarm.ListDevices is not implemented"))
+}
+
+func (UnimplementedDeviceFarmHandler) GetDeviceInfo(context.Context, *connect.Request[v1.GetDeviceInfoRequest]) (*connect.Response[v1.DeviceInfo], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("adbctl.v1.DeviceFarm.GetDeviceInfo is not implemented"))
+}
+
+func (UnimplementedDeviceFarmHandler) RunCommand(context.Context, *connect.Request[v1.RunCommandRequest]) (*connect.Response[v1.RunCommandResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("adbctl.v1.DeviceFarm.RunCommand is not implemented"))
+}
+
+func (UnimplementedDeviceFarmHandler) StreamShell(context.Context, *connect.Request[v1.RunCommandRequest], *connect.ServerStream[v1.ShellOutput]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("adbctl.v1.DeviceFarm.StreamShell is not implemented"))
+}
+
+func (UnimplementedDeviceFarmHandler) StreamLogcat(context.Context, *connect.Request[v1.StreamLogcatRequest], *connect.ServerStream[v1.LogLine]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("adbctl.v1.DeviceFarm.StreamLogcat is not implemented"))
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var gfxPercentilePattern = regexp.MustCompile(`^(\d+)th percentile: (\d+)ms`)
+
+// gfxJankReasons are the "Number ..." counters dumpsys gfxinfo explains janky
+// frames with, in the order it prints them.
+var gfxJankReasons = []string{"Missed Vsync", "High input latency", "Slow UI thread", "Slow bitmap uploads",
+	"Slow issue draw commands", "Frame deadline missed"}
+
+type gfxStats struct {
+	Package     string
+	TotalFrames int
+	JankyFrames int
+	Percentiles map[int]int // percentile -> frame time in ms
+	Reasons     map[string]int
+}
+
+func (s gfxStats) jankPercent() float64 {
+	if s.TotalFrames == 0 {
+		return 0
+	}
+	return float64(s.JankyFrames) * 100 / float64(s.TotalFrames)
+}
+
+func runGfx(args []string) error {
+	if len(args) > 0 && args[0] == "monitor" {
+		return runGfxMonitor(args[1:])
+	}
+	fs := flag.NewFlagSet("gfx", flag.ExitOnError)
+	reset := fs.Bool("reset", false, "Reset the statistics first, so they cover only what happens next")
+	watch := fs.Bool("watch", false, "Keep sampling and print one line per interval")
+	interval := fs.Duration("interval", 2*time.Second, "Sampling interval with --watch")
+	args = parseArgs(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("usage: gfx [package] [--reset] [--watch] [--interval 2s] | gfx monitor [package]")
+	}
+
+	deviceID := pickDevice()
+	pkg, err := gfxPackage(deviceID, args)
+	if err != nil {
+		return err
+	}
+	if *reset {
+		if _, err := readGfxStats(deviceID, pkg, true); err != nil {
+			return err
+		}
+		fmt.Printf("Reset frame statistics for %s\n", pkg)
+		if !*watch {
+			return nil
+		}
+	}
+	if !*watch {
+		stats, err := readGfxStats(deviceID, pkg, false)
+		if err != nil {
+			return err
+		}
+		printGfxStats(stats)
+		return nil
+	}
+
+	fmt.Printf("%-10s %8s %8s %8s %6s %6s %6s %6s\n", "Time", "Frames", "Janky", "Jank %", "p50", "p90", "p95", "p99")
+	for {
+		stats, err := readGfxStats(deviceID, pkg, false)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-10s %8d %8d %7.2f%% %6s %6s %6s %6s\n", time.Now().Format("15:04:05"), stats.TotalFrames,
+			stats.JankyFrames, stats.jankPercent(), stats.percentile(50), stats.percentile(90),
+			stats.percentile(95), stats.percentile(99))
+		time.Sleep(*interval)
+	}
+}
+
+// gfxPackage is the package argument or, without one, the foreground app.
+func gfxPackage(deviceID string, args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+	pkg, _ := foregroundActivity(deviceID)
+	if pkg == "" {
+		return "", fmt.Errorf("no foreground app found; pass a package name")
+	}
+	return pkg, nil
+}
+
+// readGfxStats runs dumpsys gfxinfo for pkg. With reset the statistics are
+// cleared after being read.
+func readGfxStats(deviceID, pkg string, reset bool) (gfxStats, error) {
+	dumpsysArgs := []string{"dumpsys", "gfxinfo", pkg}
+	if reset {
+		dumpsysArgs = append(dumpsysArgs, "reset")
+	}
+	output, err := adbShell(deviceID, dumpsysArgs...)
+	if err != nil {
+		return gfxStats{}, err
+	}
+	if strings.Contains(output, "No process found") {
+		return gfxStats{}, fmt.Errorf("%s is not running", pkg)
+	}
+	stats := parseGfxInfo(output)
+	stats.Package = pkg
+	return stats, nil
+}
+
+// parseGfxInfo reads the process-wide summary at the top of dumpsys gfxinfo;
+// only the first value of each field is used, later ones belong to
+// individual windows.
+func parseGfxInfo(output string) gfxStats {
+	stats := gfxStats{Percentiles: map[int]int{}, Reasons: map[string]int{}}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, found := strings.Cut(line, ":")
+		if !found || seen[key] {
+			continue
+		}
+		seen[key] = true
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "Total frames rendered":
+			stats.TotalFrames, _ = strconv.Atoi(value)
+		case key == "Janky frames":
+			if fields := strings.Fields(value); len(fields) > 0 {
+				stats.JankyFrames, _ = strconv.Atoi(fields[0])
+			}
+		case strings.HasPrefix(key, "Number "):
+			if count, err := strconv.Atoi(value); err == nil {
+				stats.Reasons[strings.TrimPrefix(key, "Number ")] = count
+			}
+		default:
+			if m := gfxPercentilePattern.FindStringSubmatch(line); m != nil {
+				p, _ := strconv.Atoi(m[1])
+				stats.Percentiles[p], _ = strconv.Atoi(m[2])
+			}
+		}
+	}
+	return stats
+}
+
+func (s gfxStats) percentile(p int) string {
+	if ms, ok := s.Percentiles[p]; ok {
+		return fmt.Sprintf("%dms", ms)
+	}
+	return "n/a"
+}
+
+func printGfxStats(s gfxStats) {
+	color.New(color.FgCyan, color.Bold).Printf("Frame statistics for %s\n", s.Package)
+	fmt.Print(rule("=", 40))
+	if s.TotalFrames == 0 {
+		fmt.Println("No frames rendered yet. Interact with the app and try again.")
+		return
+	}
+	fmt.Printf("%-28s %d\n", "Total frames:", s.TotalFrames)
+	jank := fmt.Sprintf("%d (%.2f%%)", s.JankyFrames, s.jankPercent())
+	switch {
+	case s.jankPercent() >= 10:
+		color.New(color.FgRed).Printf("%-28s %s\n", "Janky frames:", jank)
+	case s.jankPercent() >= 5:
+		color.New(color.FgYellow).Printf("%-28s %s\n", "Janky frames:", jank)
+	default:
+		fmt.Printf("%-28s %s\n", "Janky frames:", jank)
+	}
+	for _, p := range []int{50, 90, 95, 99} {
+		fmt.Printf("%-28s %s\n", fmt.Sprintf("%dth percentile:", p), s.percentile(p))
+	}
+	if len(s.Reasons) > 0 {
+		fmt.Println()
+		fmt.Println("Jank reasons:")
+		for _, reason := range gfxJankReasons {
+			if count, ok := s.Reasons[reason]; ok {
+				fmt.Printf("  %-26s %d\n", reason+":", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var surfaceFlingerMissedPattern = regexp.MustCompile(`Total missed frame count: (\d+)`)
+
+// gfxSample is the change in frame counters over one polling interval.
+type gfxSample struct {
+	Frames int
+	Janky  int
+}
+
+// runGfxMonitor polls gfxinfo while the device is used and prints one line per
+// interval with the frames rendered and dropped in it, the jank rate over the
+// rolling window, and SurfaceFlinger's device-wide missed frames, which also
+// covers apps that don't draw through the UI toolkit (video, games).
+func runGfxMonitor(args []string) error {
+	fs := flag.NewFlagSet("gfx monitor", flag.ExitOnError)
+	interval := fs.Duration("interval", time.Second, "Polling interval")
+	window := fs.Duration("window", 10*time.Second, "Period the rolling jank rate covers")
+	alert := fs.Float64("alert", 10, "Alert when the rolling jank rate exceeds this percentage")
+	minDropped := fs.Int("dropped", 5, "Alert when at least this many frames are janky within one interval")
+	args = parseArgs(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("usage: gfx monitor [package] [--interval 1s] [--window 10s] [--alert 10] [--dropped 5]")
+	}
+	if *interval <= 0 || *window < *interval {
+		return fmt.Errorf("--window must be at least --interval")
+	}
+
+	deviceID := pickDevice()
+	pkg, err := gfxPackage(deviceID, args)
+	if err != nil {
+		return err
+	}
+	previous, err := readGfxStats(deviceID, pkg, false)
+	if err != nil {
+		return err
+	}
+	previousMissed, haveMissed := surfaceFlingerMissedFrames(deviceID)
+
+	fmt.Printf("Monitoring frame drops in %s; press Ctrl+C to stop.\n", pkg)
+	fmt.Printf("%-10s %7s %7s %9s %10s\n", "Time", "Frames", "Janky", "Rolling", "SF missed")
+	size := int(*window / *interval)
+	var samples []gfxSample
+	for {
+		time.Sleep(*interval)
+		current, err := readGfxStats(deviceID, pkg, false)
+		if err != nil {
+			return err
+		}
+		sample := gfxSample{current.TotalFrames - previous.TotalFrames, current.JankyFrames - previous.JankyFrames}
+		if sample.Frames < 0 || sample.Janky < 0 {
+			// The app restarted or its statistics were reset.
+			sample = gfxSample{current.TotalFrames, current.JankyFrames}
+		}
+		previous = current
+
+		samples = append(samples, sample)
+		if len(samples) > size {
+			samples = samples[1:]
+		}
+		var windowFrames, windowJanky int
+		for _, s := range samples {
+			windowFrames += s.Frames
+			windowJanky += s.Janky
+		}
+		rolling := 0.0
+		if windowFrames > 0 {
+			rolling = float64(windowJanky) * 100 / float64(windowFrames)
+		}
+
+		missed := "n/a"
+		if count, ok := surfaceFlingerMissedFrames(deviceID); ok && haveMissed {
+			missed = strconv.Itoa(max(count-previousMissed, 0))
+			previousMissed = count
+		} else if ok {
+			previousMissed, haveMissed = count, true
+		}
+
+		line := fmt.Sprintf("%-10s %7d %7d %8.1f%% %10s", time.Now().Format("15:04:05"), sample.Frames, sample.Janky, rolling, missed)
+		switch {
+		case sample.Janky >= *minDropped:
+			color.New(color.FgRed).Printf("%s  ALERT: %d janky frames\a\n", line, sample.Janky)
+		case rolling > *alert && windowFrames > 0:
+			color.New(color.FgRed).Printf("%s  ALERT: jank rate above %.0f%%\a\n", line, *alert)
+		case sample.Janky > 0:
+			color.New(color.FgYellow).Println(line)
+		default:
+			fmt.Println(line)
+		}
+	}
+}
+
+// surfaceFlingerMissedFrames reads the device-wide missed frame counter
+// (Android 10 and later).
+func surfaceFlingerMissedFrames(deviceID string) (int, bool) {
+	output := runAdbCommand(deviceID, "dumpsys SurfaceFlinger | grep 'Total missed frame count'", 5*time.Second)
+	m := surfaceFlingerMissedPattern.FindStringSubmatch(output)
+	if m == nil {
+		return 0, false
+	}
+	count, err := strconv.Atoi(m[1])
+	return count, err == nil
+}
@@ -3,8 +3,18 @@ module adb-info
 go 1.22.5
 
 require (
-	github.com/fatih/color v1.17.0 // indirect
+	connectrpc.com/connect v1.18.1
+	github.com/fatih/color v1.17.0
+	github.com/mattn/go-sqlite3 v1.14.33
+	go.etcd.io/bbolt v1.3.11
+	golang.org/x/net v0.33.0
+	google.golang.org/protobuf v1.36.5
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.18.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
 )
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	gradleApplicationIDPattern = regexp.MustCompile(`(?m)^\s*applicationId\s*=?\s*["']([\w.]+)["']`)
+	gradleNamespacePattern     = regexp.MustCompile(`(?m)^\s*namespace\s*=?\s*["']([\w.]+)["']`)
+	gradleBuildTypePattern     = regexp.MustCompile(`(?m)^\s*(?:getByName\(\s*"(\w+)"\s*\)|create\(\s*"(\w+)"\s*\)|(\w+))\s*\{`)
+	gradleIDSuffixPattern      = regexp.MustCompile(`applicationIdSuffix\s*=?\s*["']([\w.]+)["']`)
+)
+
+var gradleBuildFiles = []string{"build.gradle", "build.gradle.kts"}
+
+// gradleOutputMetadata is the output-metadata.json the Android Gradle plugin
+// writes next to the APKs of each variant.
+type gradleOutputMetadata struct {
+	ApplicationID string `json:"applicationId"`
+	VariantName   string `json:"variantName"`
+	Elements      []struct {
+		OutputFile  string `json:"outputFile"`
+		VersionCode int    `json:"versionCode"`
+	} `json:"elements"`
+}
+
+// findGradleAppModule returns the directory of the Android application module
+// the working directory belongs to: the enclosing module if it applies the
+// com.android.application plugin, otherwise such a module (preferring app/)
+// below the enclosing Gradle project root.
+func findGradleAppModule() string {
+	if ignoreProject {
+		return ""
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	root := ""
+	for {
+		if isGradleAppModule(dir) {
+			return dir
+		}
+		// Flutter and React Native keep the Android app in android/app.
+		if isGradleAppModule(filepath.Join(dir, "android", "app")) {
+			return filepath.Join(dir, "android", "app")
+		}
+		for _, name := range append([]string{"settings.gradle", "settings.gradle.kts"}, gradleBuildFiles...) {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				root = dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	if root == "" {
+		return ""
+	}
+	if isGradleAppModule(filepath.Join(root, "app")) {
+		return filepath.Join(root, "app")
+	}
+	entries, _ := os.ReadDir(root)
+	for _, entry := range entries {
+		if entry.IsDir() && isGradleAppModule(filepath.Join(root, entry.Name())) {
+			return filepath.Join(root, entry.Name())
+		}
+	}
+	return ""
+}
+
+func isGradleAppModule(dir string) bool {
+	script := gradleBuildScript(dir)
+	return strings.Contains(script, "com.android.application") || strings.Contains(script, "android.application")
+}
+
+func gradleBuildScript(dir string) string {
+	for _, name := range gradleBuildFiles {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			return string(data)
+		}
+	}
+	return ""
+}
+
+// gradleVariantOutputs finds the output metadata of every built APK variant of
+// the module, keyed by lower-cased variant name.
+func gradleVariantOutputs(module string) map[string]string {
+	outputs := map[string]string{}
+	root := filepath.Join(module, "build", "outputs", "apk")
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != "output-metadata.json" {
+			return nil
+		}
+		var metadata gradleOutputMetadata
+		if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &metadata) == nil && metadata.VariantName != "" {
+			outputs[strings.ToLower(metadata.VariantName)] = path
+		}
+		return nil
+	})
+	return outputs
+}
+
+// gradleVariantAPK returns the built APK and application id of a variant
+// (e.g. debug or stagingRelease).
+func gradleVariantAPK(module, variant string) (string, string, error) {
+	outputs := gradleVariantOutputs(module)
+	path, ok := outputs[strings.ToLower(variant)]
+	if !ok {
+		built := sortedKeys(outputs)
+		hint := fmt.Sprintf("build it with ./gradlew %s:assemble%s", filepath.Base(module), upperFirst(variant))
+		if len(built) > 0 {
+			hint += " (built variants: " + strings.Join(built, ", ") + ")"
+		}
+		return "", "", fmt.Errorf("no %s APK in %s; %s", variant, filepath.Join(module, "build", "outputs", "apk"), hint)
+	}
+	var metadata gradleOutputMetadata
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return "", "", fmt.Errorf("%s: %v", path, err)
+	}
+	if len(metadata.Elements) == 0 {
+		return "", "", fmt.Errorf("%s lists no APK", path)
+	}
+	return filepath.Join(filepath.Dir(path), metadata.Elements[0].OutputFile), metadata.ApplicationID, nil
+}
+
+// gradleApplicationID resolves a variant's application id, from the build
+// outputs when the variant was built and otherwise from the build script:
+// applicationId (or namespace) plus the build type's applicationIdSuffix.
+// Product flavor ids and suffixes need a build.
+func gradleApplicationID(module, variant string) (string, error) {
+	if _, id, err := gradleVariantAPK(module, variant); err == nil && id != "" {
+		return id, nil
+	}
+	script := gradleBuildScript(module)
+	id := ""
+	if m := gradleApplicationIDPattern.FindStringSubmatch(script); m != nil {
+		id = m[1]
+	} else if m := gradleNamespacePattern.FindStringSubmatch(script); m != nil {
+		id = m[1]
+	}
+	if id == "" {
+		return "", fmt.Errorf("no applicationId in %s; build the app or pass a package", module)
+	}
+	return id + gradleBuildTypeSuffix(script, variant), nil
+}
+
+// gradleBuildTypeSuffix finds the applicationIdSuffix inside the buildTypes
+// block of the variant's build type (the last word of the variant name).
+func gradleBuildTypeSuffix(script, variant string) string {
+	start := strings.Index(script, "buildTypes")
+	if start < 0 {
+		return ""
+	}
+	block := matchingBlock(script[start:])
+	buildType := strings.ToLower(variant)
+	for i := len(variant) - 1; i > 0; i-- {
+		if unicode.IsUpper(rune(variant[i])) {
+			buildType = strings.ToLower(variant[i:])
+			break
+		}
+	}
+	for _, loc := range gradleBuildTypePattern.FindAllStringSubmatchIndex(block, -1) {
+		name := ""
+		for g := 1; g <= 3; g++ {
+			if loc[2*g] >= 0 {
+				name = block[loc[2*g]:loc[2*g+1]]
+			}
+		}
+		if strings.ToLower(name) != buildType {
+			continue
+		}
+		if m := gradleIDSuffixPattern.FindStringSubmatch(matchingBlock(block[loc[0]:])); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// matchingBlock returns the contents of the first {...} block in s.
+func matchingBlock(s string) string {
+	open := strings.Index(s, "{")
+	if open < 0 {
+		return ""
+	}
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[open+1 : i]
+			}
+		}
+	}
+	return s[open+1:]
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// pingTimeout bounds the health check run before each action, so an
+	// unresponsive device costs one short wait instead of a timeout per probe.
+	// It is not scaled for slow links: an echo is tiny even over weak WiFi.
+	pingTimeout = 3 * time.Second
+	// slowPing is the round trip above which the device is reported as slow.
+	slowPing = 500 * time.Millisecond
+)
+
+// pingedDevices remembers devices already checked by this process.
+var pingedDevices = map[string]bool{}
+
+// ensureResponsive checks that the device answers a shell command. A slow
+// device gets a warning; an unresponsive wireless one is offered a reconnect.
+// It returns false when the user chose not to go on with a dead device.
+func ensureResponsive(deviceID string) bool {
+	if pingedDevices[deviceID] {
+		return true
+	}
+	start := time.Now()
+	err := checkDeviceConnectivity(deviceID, pingTimeout)
+	latency := time.Since(start)
+	if err == nil {
+		pingedDevices[deviceID] = true
+		if latency > slowPing {
+			color.New(color.FgYellow).Fprintf(os.Stderr, "Device %s is slow to respond (%v); commands may time out.\n", deviceID, latency.Round(time.Millisecond))
+		}
+		return true
+	}
+	if ciMode {
+		ciFail(exitDevice, "device-unresponsive", fmt.Errorf("%s: %v", deviceID, err))
+	}
+
+	color.New(color.FgRed).Printf("Device %s is not responding: %v\n", deviceID, err)
+	if isWirelessSerial(deviceID) && askYesNo("Reconnect now? [Y/n] ", true) {
+		if err := keepaliveReconnect(deviceID); err != nil {
+			printError(err)
+		} else if checkDeviceConnectivity(deviceID, pingTimeout) == nil {
+			fmt.Println("Reconnected.")
+			pingedDevices[deviceID] = true
+			return true
+		}
+	}
+	return askYesNo("Continue anyway? [y/N] ", false)
+}
+
+// askYesNo reads a y/n answer; an empty answer means def. fmt.Scanln reads
+// stdin unbuffered, so nothing meant for a later prompt is swallowed.
+func askYesNo(prompt string, def bool) bool {
+	fmt.Print(prompt)
+	var input string
+	fmt.Scanln(&input)
+	switch strings.ToLower(input) {
+	case "":
+		return def
+	case "y", "yes":
+		return true
+	}
+	return false
+}
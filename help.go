@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fatih/color"
+)
+
+// helpTopics holds the longer `adbctl help <topic>` pages. Every command has
+// a page; the remaining entries cover cross-cutting topics.
+var helpTopics = map[string]struct {
+	Text     string
+	Examples []string
+}{
+	"warmup":          {"Force-stops background third-party apps, pre-launches the given apps so they are cached, then checks available memory and thermal headroom.", []string{"adbctl warmup --packages com.example.app,com.example.player", "adbctl warmup --packages com.example.app --min-free 800 --max-temp 55"}},
+	"du":              {"Summarizes `du -d 2` into the largest directories, two levels deep.", []string{"adbctl du", "adbctl du /sdcard/Android --top 5"}},
+	"kiosk":           {"Locks the device to one app using screen pinning, or by making it the home app when pinning is unavailable. `kiosk clear` restores the previous launcher.", []string{"adbctl kiosk set com.example.app", "adbctl kiosk set com.example.app --mode home", "adbctl kiosk clear"}},
+	"dpm":             {"Wraps `dpm` for provisioning device-owner and device-admin test apps. Setting a device owner is effectively irreversible; use lab devices only.", []string{"adbctl dpm status", "adbctl dpm set-owner com.example.dpc/.AdminReceiver"}},
+	"seed":            {"Pushes sample media (and triggers a media scan) and opens contact/calendar fixtures in their import flows.", []string{"adbctl seed --media ./fixtures/media", "adbctl seed --contacts contacts.vcf --calendar events.ics"}},
+	"media":           {"Triggers MediaStore rescans (of the given path, or of all shared storage where the device only allows that, which adbctl says when it happens), lists indexed media, reports HDR capabilities and shows active playback sessions with audio routing.", []string{"adbctl media rescan /sdcard/Movies", "adbctl media list --type video", "adbctl media caps", "adbctl media status"}},
+	"settings":        {"Reads and writes Android settings with type checks for well-known keys, and applies or exports whole bundles. A null value in a bundle deletes the key, which is how --save-rollback records keys that were not set.", []string{"adbctl settings list global --search anim", "adbctl settings put system screen_off_timeout 600000", "adbctl settings apply bundle.yaml --save-rollback old.yaml", "adbctl settings export -o device.yaml"}},
+	"applinks":        {"Shows App Links domain verification state, optionally re-running verification first.", []string{"adbctl applinks com.example.app", "adbctl applinks com.example.app --reverify"}},
+	"prop":            {"Lists, exports and sets system properties. Writing most properties requires `adb root`.", []string{"adbctl prop list --filter ro.build", "adbctl prop list -o props.json", "adbctl prop set debug.hwui.profile visual_bars"}},
+	"defaults":        {"Shows default browser/launcher/assistant, resolves intent handlers and changes defaults via RoleManager where allowed.", []string{"adbctl defaults", "adbctl defaults resolve --action VIEW --data https://example.com", "adbctl defaults set browser org.mozilla.firefox"}},
+	"dev":             {"Developer toggles. `frametime on` shows the profile GPU rendering bars and, on Android 11+, the SurfaceFlinger refresh rate overlay, which also works for games (Unity, Unreal) that render to their own surface.", []string{"adbctl dev animations 0", "adbctl dev animations", "adbctl dev frametime on"}},
+	"matrix":          {"Runs an adbctl command under every combination of locales, density scales and time zones, then restores the original configuration, including a density override that was set before. Each setting is checked after it is applied, and a configuration the device would not take is reported as SKIP instead of running the command: switching the system locale needs root on most builds, and time zones need Android 12 (cmd alarm set-timezone) or root.", []string{"adbctl matrix run --locales en-US,de-DE --densities 1.0,1.3 -- warmup --packages com.example.app"}},
+	"logcat":          {"Tails logcat from one device or from all devices at once, with per-device filters. --package (or --flavor) shows only that app's process; inside a project with an .adbctl.yaml, a bare `logcat` does this for the project's app. --unity shows the Unity, IL2CPP and native crash (CRASH, DEBUG) tags and folds stack traces to their first frame; with --symbols pointing at the unzipped symbols.zip of the build, frames in libil2cpp.so and libunity.so get their function and source line from llvm-addr2line (ADDR2LINE, PATH or the NDK in the SDK).", []string{"adbctl logcat '*:E'", "adbctl logcat --package com.example.app", "adbctl logcat --unity --symbols ./symbols", "adbctl logcat --all-devices --merge", "adbctl logcat --all-devices --save ./logs --filter emulator-5554='MyApp:V *:S'"}},
+	"power":           {"Controls stay-awake and screen timeout.", []string{"adbctl power status", "adbctl power stay-awake on", "adbctl power timeout 30m"}},
+	"demo":            {"Enables System UI demo mode for clean screenshots and restores the previous state afterwards.", []string{"adbctl demo on --clock 0900", "adbctl demo off"}},
+	"trap":            {"Watches logcat and captures artifacts as soon as a pattern appears.", []string{"adbctl trap --on 'FATAL EXCEPTION' --do screenshot,bugreport", "adbctl trap --on 'ANR in' --once"}},
+	"wait-for":        {"Blocks until a condition holds, for use in scripts. `state` waits for a readiness level: disconnected, connected (listed but offline or unauthorized), authorized, booted or launcher-ready. With --serial or ADBCTL_DEVICE the device need not be connected yet; adbctl waits for it to appear.", []string{"adbctl wait-for boot", "adbctl wait-for boot --serial emulator-5554 --timeout 5m", "adbctl wait-for state launcher-ready --timeout 5m", "adbctl wait-for package-foreground com.example.app --timeout 30s", "adbctl wait-for port 8080"}},
+	"display":         {"Overrides screen size and density, lists display modes and switches refresh rates.", []string{"adbctl display size 1920x1080", "adbctl display --preset 720p-tv", "adbctl display modes", "adbctl display reset"}},
+	"assert":          {"Evaluates expressions against the device schema (props, meminfo, battery, storage, global, system, secure) and exits non-zero on failure.", []string{`adbctl assert "meminfo.MemAvailable > 500000" "props.ro.build.version.sdk >= 30"`, `adbctl assert "battery.level >= 50"`}},
+	"config":          {"Exports and imports the adbctl configuration directory. `config adb-path` shows the adb binary in use or saves one. adb is looked up from -adb-path, ADBCTL_ADB, the saved path, PATH and finally $ANDROID_HOME/platform-tools or the default Android Studio SDK.", []string{"adbctl config path", "adbctl config adb-path ~/Android/Sdk/platform-tools/adb", "adbctl config export lab.tar.gz", "adbctl config import lab.tar.gz --force"}},
+	"codecs":          {"Lists decoders and encoders with profiles, maximum size and secure-decoder support.", []string{"adbctl codecs", "adbctl codecs --mime video/hevc --type decoder"}},
+	"stats":           {"Summarizes your own usage from the local audit log. Recording is opt-in and never leaves this machine.", []string{"adbctl stats enable", "adbctl stats --since 168h"}},
+	"link":            {"Measures adb round-trip latency and throughput repeatedly and classifies the link as USB2, USB3, WiFi-good or WiFi-poor, warning when wireless conditions will slow recording and large pushes.", []string{"adbctl link", "adbctl link --rounds 10 --size 16"}},
+	"wifi":            {"Connects to, inspects and forgets WiFi networks so headless devices can be moved between networks from the CLI. Uses `cmd wifi` on Android 11+ and wpa_cli (rooted shell) on older builds. `wifi monitor` streams RSSI and link speed to diagnose streaming buffering.", []string{"adbctl wifi connect --ssid Lab-5G --pass secret", "adbctl wifi status", "adbctl wifi forget Lab-2G", "adbctl wifi monitor --interval 2s --duration 5m"}},
+	"net":             {"Sums `dumpsys netstats` per app over a time window, sorted by total traffic. `net test` runs ping, DNS and HTTP HEAD checks from the device shell to tell device network problems from app problems.", []string{"adbctl net usage", "adbctl net usage --window 6h --top 5", "adbctl net test --http https://api.example.com/health"}},
+	"screenshot":      {"Saves a PNG of the screen. With --scroll it captures, scrolls (touch swipe or D-pad presses) and stitches the captures into one tall image, keeping fixed headers and footers once.", []string{"adbctl screenshot -o home.png", "adbctl screenshot --scroll --pages 6", "adbctl screenshot --scroll --method dpad --steps 4 -o settings.png"}},
+	"record":          {"Records the screen with screenrecord and pulls the MP4. --gif and --webp convert it locally with ffmpeg into a small animation for pull requests and chat.", []string{"adbctl record --max 1m -o demo.mp4", "adbctl record --gif --fps 10 --max 15s", "adbctl record --webp --width 360"}},
+	"wireless":        {"While connected over USB, restarts adbd in TCP mode, reads the wlan0 address, runs adb connect and verifies the new connection.", []string{"adbctl wireless enable", "adbctl wireless enable --port 5556"}},
+	"pair":            {"Pairs with a device using the code from Developer options > Wireless debugging > Pair device with pairing code, connects, and remembers the device for `adbctl connect`. With --qr a QR code is shown in the terminal for Pair device with QR code, and pairing completes over mDNS once the device scans it.", []string{"adbctl pair 192.168.1.20:37123 482913", "adbctl pair --qr"}},
+	"connect":         {"Connects to a device saved by `adbctl pair`. The wireless debugging port changes whenever it is toggled, so the current port is looked up with `adb mdns services`. With no argument, pick from the saved devices. Every ip:port used successfully is kept in history.json with its serial, model and last-seen time; --recent picks from that history.", []string{"adbctl connect", "adbctl connect 192.168.1.20", "adbctl connect 192.168.1.30:5555", "adbctl connect --recent"}},
+	"services":        {"Lists running services and BOOT_COMPLETED receivers per package, highlighting third-party apps that both start at boot and keep services running: the usual suspects when a fresh device feels sluggish.", []string{"adbctl services", "adbctl services --package com.example.app"}},
+	"report":          {"Writes an HTML fleet health report (state, battery, free storage, available memory, uptime and problems per device). --email sends it as an attachment using smtp.json in the config directory, with the password taken from ADBCTL_SMTP_PASSWORD; --at keeps running and sends it every day at that time. --upload copies the report to s3://bucket/path (aws CLI), gs://bucket/path (gcloud CLI) or an HTTP(S) URL with a PUT, such as a pre-signed upload URL, and prints a download link signed for 7 days where the CLI can sign one; a destination ending in / gets the file name appended.", []string{"adbctl report -o fleet.html", "adbctl report --email", "adbctl report --email --at 02:00", "adbctl report --upload s3://lab-reports/nightly/"}},
+	"discover":        {"Lists devices advertising adb over mDNS (_adb-tls-connect._tcp for Android 11+ wireless debugging, _adb._tcp for classic network adb) and connects to the one you pick. Uses the adb server's mDNS browser; see `adb mdns check`.", []string{"adbctl discover"}},
+	"monitor":         {"Samples state, battery, available memory (MB), temperature, screen state, free storage (MB), uptime (seconds), WiFi signal (dBm) and the foreground app at an interval. --all samples every connected device, picking up devices connected later, so one process can watch a whole lab or home. With --mqtt, samples and state/foreground change events are published using mqtt.json in the config directory, e.g. for Home Assistant; the password is taken from ADBCTL_MQTT_PASSWORD, and a \"devices\" object can give individual serials their own sample_topic, event_topic or command_topic. --ha also publishes Home Assistant discovery messages (power, state, current app, temperature, battery, memory, storage, uptime, WiFi signal, plus Wake and Launch app controls) and runs those controls while monitoring.", []string{"adbctl monitor", "adbctl monitor --interval 1m --mqtt", "adbctl monitor --all --ha"}},
+	"scan":            {"Probes the adb port concurrently across a subnet, connects to responding hosts to read their model, and keeps the ones you choose connected.", []string{"adbctl scan 192.168.1.0/24", "adbctl scan 10.0.0.0/22 --timeout 300ms --workers 128"}},
+	"keepalive":       {"Pings each ip:port (default: every device paired with adbctl pair) at an interval and re-runs adb connect when it drops. State changes are logged with a timestamp; --notify also shows desktop notifications (notify-send on Linux, osascript on macOS).", []string{"adbctl keepalive 192.168.1.20:5555 --interval 30s", "adbctl keepalive --notify"}},
+	"current-app":     {"Prints the foreground package/activity from a single dumpsys call, suitable for scripts. --watch keeps running and prints a timestamped line whenever the foreground app changes.", []string{"adbctl current-app", "adbctl current-app --watch --interval 500ms"}},
+	"launch-shortcut": {"Launches a named shortcut from shortcuts.json in the config directory. A shortcut is a package (started from its launcher icon) or an intent: component, action, data and category. Without a name the configured shortcuts are listed. --user launches in another user profile.", []string{"adbctl launch-shortcut", "adbctl launch-shortcut youtube"}},
+	"identify":        {"Wakes the device numbered n in the device selector, posts a notification with its number and blinks its screen (colour inversion) n times in a row for --duration. Without n every connected device blinks its own number at once. The original inversion setting is restored afterwards.", []string{"adbctl identify", "adbctl identify 2 --duration 30s"}},
+	"companion":       {"Installs, checks or removes the companion helper app used where the shell is not enough: identify shows its number full screen, clipboard reads or sets the clipboard (reading works before Android 10 only) and sensor prints one raw reading of a sensor, by name or Sensor.TYPE_* number. The APK is built from companion/ and shipped next to the adbctl binary as adbctl-companion_<version>.apk; it must match the CLI version, and install replaces a newer or differently signed copy. ADBCTL_COMPANION_APK points at another copy.", []string{"adbctl companion install", "adbctl companion status", "adbctl companion clipboard 'some text'", "adbctl companion sensor accelerometer", "adbctl companion uninstall"}},
+	"shell":           {"Runs a command in the device shell and prints each line as soon as it is produced, which suits long-running commands like top, monkey or dumpsys on a slow link. Ctrl+C or --timeout stops it; otherwise adbctl exits with the command's status.", []string{"adbctl shell -- top -d 2 -n 5", "adbctl shell --timeout 5m -- monkey -p com.example.app -v 5000"}},
+	"reboot":          {"Reboots the device, or into recovery, bootloader, sideload or userspace (a soft restart of Android that keeps the kernel running, Android 11+). With --wait adbctl blocks until the device is back: fully booted for a normal or userspace reboot, listed in recovery or sideload mode, or visible to fastboot for the bootloader.", []string{"adbctl reboot --wait", "adbctl reboot userspace --wait --timeout 2m", "adbctl reboot bootloader --wait"}},
+	"pull":            {"Copies a file or directory from the device. --compress packs it on the device with tar -z and streams it over exec-out, unpacking into the local directory; text logs typically shrink 5-10x, which matters over wireless adb. A tar failure on the device, such as an unreadable file, is reported after unpacking what arrived. Bugreports and adb backup streams need no flag as adb already delivers them compressed.", []string{"adbctl pull /sdcard/Download", "adbctl pull /data/local/tmp/logs ./logs --compress"}},
+	"sideload":        {"Reboots into sideload mode (skipped when the device is already there), streams the OTA package with a progress bar and reports whether recovery accepted it. Some devices only enter sideload from the recovery menu (Apply update from ADB). --reboot restarts into Android afterwards and waits for boot.", []string{"adbctl sideload update.zip", "adbctl sideload update.zip --reboot"}},
+	"battery":         {"Shows everything dumpsys battery reports (level, status, health, power source, temperature, voltage, charge counter) plus charge cycles and capacity against design where the driver exposes them, and from batterystats the time on battery, screen-on time and the apps that used the most power since the last charge. `battery history` exports the batterystats history (level, status, plug, temperature, voltage and state changes such as +screen or +wake_lock) as timestamped CSV, or JSON with -o file.json; --reset clears the statistics before a test run. `battery simulate` makes the device report a level, charging status or unplugged charger until `battery reset` or a reboot.", []string{"adbctl battery simulate --level 15 --unplugged", "adbctl battery reset", "adbctl battery", "adbctl battery --top 10", "adbctl battery history --since 24h -o history.csv", "adbctl battery history --reset"}},
+	"install":         {"Installs an APK, then pushes --obb expansion files to Android/obb/<package>/ renamed to main.<versionCode>.<package>.obb (and patch.… for the second). The package name is read with aapt2 from the SDK build-tools, or given with --package. .apks sets are installed with bundletool (BUNDLETOOL, PATH or bundletool.jar in the config directory); sets built with build-apks --local-testing include their Play Asset Delivery packs for local testing. An .aab is turned into the split set for the selected device (ABI, density, locale, SDK) with bundletool build-apks, signed with the debug keystore, and installed; --device-spec takes a saved device-spec JSON instead of reading it from the device, and --local-testing includes asset packs. --user installs an APK (and its OBBs) for one user only, such as a kids profile. Inside an Android Gradle project, `install --variant debug` (or no file at all) installs the APK the last build of that variant produced, found through build/outputs/apk/**/output-metadata.json of the application module.", []string{"adbctl install --variant debug", "adbctl install app.apk --user 10", "adbctl install game.apk --obb main.obb", "adbctl install game.apks", "adbctl install game.aab --device-spec auto --local-testing"}},
+	"users":           {"Lists the users and profiles on the device (for example Fire kids profiles) with their ids, marking the current and running ones. Pass an id to install --user, uninstall --user or launch-shortcut --user.", []string{"adbctl users"}},
+	"uninstall":       {"Removes an app. With --user only that user's copy is removed (pm uninstall --user); --keep-data keeps its data and cache.", []string{"adbctl uninstall com.example.app", "adbctl uninstall com.example.app --user 10 --keep-data"}},
+	"thermal":         {"Shows the thermal throttling status and sensor temperatures from dumpsys thermalservice (Android 10+), falling back to the kernel thermal zones, plus active cooling devices and how far CPU frequencies are capped. --watch prints one line per interval, so throttling under load becomes visible.", []string{"adbctl thermal", "adbctl thermal --watch --interval 2s"}},
+	"alias":           {"Lists, shows and saves command aliases (aliases.json in the config directory). An alias expands to a command and its arguments before they are parsed, and arguments given after the alias are appended, so teams can share standard invocations by sharing the file. Aliases can't shadow built-in commands or refer to other aliases. `alias --device <serial> <name>` names a device instead (device-aliases.json); the name is shown next to it when adbctl asks which device to use.", []string{"adbctl alias fire-logs = logcat --package com.example --save ./logs", "adbctl fire-logs --level E", "adbctl alias", "adbctl alias --remove fire-logs", "adbctl alias --device G070VM1234567890 living-room stick"}},
+	"gfx":             {"Summarises dumpsys gfxinfo for a package (the foreground app by default): total frames rendered, janky frames and their percentage, 50th/90th/95th/99th percentile frame times and the reasons frames were janky. --reset clears the statistics so the next reading covers only what happens after it, such as one navigation flow; --watch prints a line every interval. `gfx monitor` polls while you use the device and prints the frames rendered and janky in each interval, the jank rate over a rolling --window and the device-wide frames SurfaceFlinger missed (which also counts video and game surfaces); it alerts with a bell when an interval has --dropped janky frames or the rolling rate exceeds --alert percent.", []string{"adbctl gfx com.example.app", "adbctl gfx com.example.app --reset", "adbctl gfx --watch --interval 1s", "adbctl gfx monitor com.example.app --window 30s --alert 5"}},
+	"start":           {"Starts an app's launcher activity and prints the launch time. --restart force-stops it first for a cold start. Inside a project the package comes from .adbctl.yaml, found in the working directory or a parent: `package`, a default `flavor`, `flavors` mapping flavor names to application ids, `tags` matched against device serials, models and product names to pick a device, and an `artifacts` directory for screenshots, recordings and smoke results. Without a profile, inside an Android Gradle project the package is the application id of --variant (default debug): read from the build outputs, or from applicationId and the build type's applicationIdSuffix when the variant hasn't been built. -no-project ignores both.", []string{"adbctl start com.example.app", "adbctl start", "adbctl start --flavor staging --restart", "adbctl start --variant release"}},
+	"smoke":           {"Force-stops and cold-starts an app, lets it run for --duration, then checks that its process is alive, it is still in front and the log has no crash or ANR for it. The log and a screenshot are saved in a smoke-<time> directory (under the project's artifacts directory inside a project). The package defaults to the project's, as for start. --upload copies the artifacts, bundled as a .tar.gz, to s3://bucket/path (aws CLI), gs://bucket/path (gcloud CLI) or an HTTP(S) URL with a PUT, such as a pre-signed upload URL, and prints a download link signed for 7 days where the CLI can sign one; a destination ending in / gets the file name appended. The upload happens before the verdict, so failed runs are archived too.", []string{"adbctl smoke", "adbctl smoke com.example.app --duration 30s", "adbctl smoke --flavor release", "adbctl smoke --upload gs://ci-artifacts/smoke/"}},
+	"app-info":        {"Shows an installed app's version, SDK levels, installer and install dates, and whether it is built with Flutter or React Native (with Hermes or JavaScriptCore), judged from its native libraries. Inside a Flutter or React Native project the framework, engine and Dart/Hermes versions the project builds with are listed too. The package defaults to the project's, as for start. --upload copies the artifacts, bundled as a .tar.gz, to s3://bucket/path (aws CLI), gs://bucket/path (gcloud CLI) or an HTTP(S) URL with a PUT, such as a pre-signed upload URL, and prints a download link signed for 7 days where the CLI can sign one; a destination ending in / gets the file name appended. The upload happens before the verdict, so failed runs are archived too.", []string{"adbctl app-info com.example.app", "adbctl app-info"}},
+	"framework":       {"Helpers for the Flutter or React Native project in the working directory (found from pubspec.yaml or package.json). `forward` runs adb reverse for the Metro port (RCT_METRO_PORT or 8081) so a React Native app loads its bundle from this machine, or forwards the Dart VM service a debug or profile Flutter app logged and prints the URL for flutter attach. `logs` tails only the framework tags (ReactNativeJS, ReactNative, Hermes; or flutter, FlutterJNI); --all adds warnings and errors from everything else.", []string{"adbctl framework forward", "adbctl framework forward --port 8088", "adbctl framework logs --all"}},
+	"ps":              {"Lists processes as a table of pid, user, PSS, RSS, CPU% and name. PSS comes from dumpsys meminfo and is shown for the processes it tracks; memory sorting falls back to RSS for the rest. CPU% is the average since the process started, as ps reports it, and n/a before Android 8. --filter matches the name or user; --json prints the table as JSON.", []string{"adbctl ps", "adbctl ps --sort cpu --top 10", "adbctl ps --filter com.example --json"}},
+	"symbolize":       {"Appends function names and source lines to the native backtrace frames (#00 pc ...) of a saved log or tombstone, using the symbol files below --symbols (libil2cpp.sym.so, libunity.sym.so, *.dbg.so or unstripped *.so, per ABI directory) and llvm-addr2line. - reads standard input.", []string{"adbctl symbolize tombstone_03 --symbols ./symbols", "adb logcat -d | adbctl symbolize - --symbols ./symbols"}},
+	"app":             {"`app info` is app-info. `app audit` pulls the APK and reads its manifest with aapt2, lists the exported activities, services, receivers and providers with their permissions and intent filters, and flags risky ones: services, receivers and providers exported without a permission, explicit exports without filters, web-reachable deep links, and a debuggable build or cleartext traffic. --fuzz (after confirmation, or --yes) starts each exported component without a permission with no extras and with each action and data URI it declares, and reports crashes.", []string{"adbctl app audit com.example.app", "adbctl app audit com.example.app --fuzz"}},
+	"kill":            {"Resolves a pid, process name or package (including its :remote processes) to running processes, lists them and sends the signal after confirmation (--yes skips it). Useful for native daemons that force-stop doesn't cover. The shell user can only signal its own processes unless adbd runs as root; debuggable apps are signalled through run-as.", []string{"adbctl kill 1234", "adbctl kill com.example.app --signal KILL", "adbctl kill mediaserver --signal HUP --yes"}},
+	"dmesg":           {"Prints the kernel ring buffer. Since Android 8 the shell user usually may not read it, so dmesg falls back to su and then to logd's kernel buffer (logcat -b kernel). --grep filters with a case-insensitive regular expression, --follow keeps printing new messages and -o also saves the lines shown.", []string{"adbctl dmesg --grep hdmi", "adbctl dmesg --follow --grep \"usb|wlan\"", "adbctl dmesg -o kernel.log"}},
+	"apk":             {"`apk diff` compares two builds of an app with aapt2: min and target SDK, permissions, features, shared libraries, native libraries and manifest components. Added runtime permissions and special access (camera, location, overlay, install packages and similar) and newly exported components are highlighted with a !.", []string{"adbctl apk diff app-1.4.apk app-1.5.apk"}},
+	"mitm":            {"`mitm start` sets the device's global HTTP proxy (default 127.0.0.1:8080); a proxy on this machine, such as mitmproxy, is reached through adb reverse. `mitm stop` clears it. `mitm check` restarts the app with a relay in front of the proxy and reports, per host, whether the app sent requests through the proxy's certificate (intercepted) or aborted the TLS handshake (likely pinned), along with TLS errors from the log. If nothing reaches the proxy the app ignores the system proxy; pinned apps usually need Frida.", []string{"adbctl mitm start", "adbctl mitm check com.example.app --duration 30s", "adbctl mitm stop"}},
+	"selinux":         {"`selinux denials` prints the SELinux mode and the recent avc denials from logcat and the kernel log, grouped by source context type and counted, with target, class, command and file name. --source keeps only matching source contexts and --allow adds the allow rules that would silence them, as audit2allow prints them. The mode is also shown in the device information view (menu option 1).", []string{"adbctl selinux denials", "adbctl selinux denials --source untrusted_app --allow"}},
+	"input":           {"`input raw --record` saves the kernel input events (getevent) of every input device, or the one --device names, until Ctrl+C; the file lists the devices by name and then one event per line as seconds, device, type, code and value. `input raw --play` replays a recording with sendevent in a single script on the device so the timing holds, finding each device again by name after event nodes are renumbered (--map event3=event5 overrides it). --speed scales the timing.", []string{"adbctl input raw --record remote-bug.txt --device \"Fire TV Remote\"", "adbctl input raw --play remote-bug.txt", "adbctl input raw --play remote-bug.txt --map event3=event4"}},
+	"remote":          {"`remote status` lists the paired Bluetooth remotes from dumpsys bluetooth_manager and shows whether each is connected, its battery level and its firmware version. The battery comes from the kernel's HID battery node when there is one, otherwise from the Bluetooth dump or vendor remote services (dumpsys -l entries such as remote or rcu services), which are also searched for the firmware version. A battery at 20% or less is flagged. IR-only remotes report nothing.", []string{"adbctl remote status", "adbctl remote status --json"}},
+	"inventory":       {"Captures a full snapshot of the device into one JSON document for asset tracking: every system property, each installed package with its version name, version code and whether it is a system app, the mounted partitions from df (plus /dev/block/by-name when readable), the features from pm list features, and the display size, density and modes. Lists are sorted so snapshots of the same device taken at different times diff cleanly. `inventory --all` instead queries every connected device in parallel for the key lab fields (serial, model, Fire OS and Android version, security patch level, battery or power, free storage, IP) and prints one table, or writes it to a .csv or .json file with -o; devices adb can't use are listed with their state. --upload copies the JSON or CSV file to s3://bucket/path (aws CLI), gs://bucket/path (gcloud CLI) or an HTTP(S) URL with a PUT, such as a pre-signed upload URL, and prints a download link signed for 7 days where the CLI can sign one; a destination ending in / gets the file name appended.", []string{"adbctl inventory", "adbctl inventory -o living-room.json", "adbctl inventory --all -o fleet.csv", "adbctl inventory --upload s3://assets/inventory/"}},
+	"store":           {"Shows or switches where adbctl keeps its shared documents: paired endpoints, connection history, command and device aliases, shortcuts and the mail and MQTT settings. `store bolt` (the default) keeps them in a bbolt database, store.db in the config directory; `store sqlite` uses store.sqlite instead, for labs that want to query or back it up with SQLite tools (builds without cgo, such as the cross-compiled release binaries, lack it); `store file` keeps one JSON file per document. `store remote <url>` reads and writes them at <url>/<name> with GET and PUT, so a lab can keep one device registry for every laptop; `store serve` on the lab machine serves its own store that way, on 127.0.0.1:8750 unless --listen says otherwise; any other address needs ADBCTL_STORE_TOKEN. Switching copies over the documents the new store lacks. The databases read the JSON files in the config directory whenever one is newer than their copy, so smtp.json, mqtt.json, shortcuts.json and aliases.json can still be edited by hand (on the server, for a remote store). ADBCTL_STORE_TOKEN is sent and required as a bearer token when set, and ADBCTL_STORE (bolt, sqlite, file or a URL) overrides the setting. adb.json and store.json always stay local files.", []string{"adbctl store", "adbctl store sqlite", "ADBCTL_STORE_TOKEN=secret adbctl store serve --listen :8750", "adbctl store remote http://lab-server:8750/", "adbctl store bolt"}},
+	"api":             {"Serves the DeviceFarm service defined in adbctl.proto (print it with `api proto`): ListDevices, GetDeviceInfo, RunCommand, and the server-streaming StreamShell and StreamLogcat. It speaks gRPC (HTTP/2 without TLS), gRPC-Web and the Connect protocol, with binary protobuf or JSON, so stubs generated by protoc for any gRPC language, grpcurl with -plaintext -proto adbctl.proto, and connect-go or connect-es clients all work. Without a generated client, POST JSON to /adbctl.v1.DeviceFarm/<Method>. It listens on localhost unless --listen says otherwise; set ADBCTL_API_TOKEN to require that bearer token.", []string{"adbctl api proto > adbctl.proto", "grpcurl -plaintext -proto adbctl.proto localhost:8751 adbctl.v1.DeviceFarm/ListDevices", "ADBCTL_API_TOKEN=secret adbctl api serve --listen 0.0.0.0:8751", "curl -s -H \"Content-Type: application/json\" -d \"{}\" localhost:8751/adbctl.v1.DeviceFarm/ListDevices"}},
+	"lib":             {"adbctl bundles a few vetted shell scripts (toybox/mksh, no busybox needed) for probes that collect many values at once: summary.sh for inventory --all and the API, thermal.sh for thermal, root.sh for the Root row of the device information. `lib push` installs them in /data/local/tmp/adbctl/ with a VERSION file; probes also install or update them on first use, and fall back to sending the script over stdin when /data/local/tmp is not writable. `lib run <script>` runs one by hand and `lib remove` deletes the directory.", []string{"adbctl lib push --all", "adbctl lib status", "adbctl lib run summary.sh"}},
+	"web":             {"Serves a dashboard at http://127.0.0.1:8752/ with a card per device (model, Fire OS and Android versions, battery, free storage, IP and a screenshot thumbnail), buttons to take a fresh screenshot, reboot and install an APK, and a live logcat panel with a filter. The cards use the same collection as inventory --all. It listens on localhost; --listen 0.0.0.0:8752 shares it on the network, but it has no login, so only do that on a trusted lab network.", []string{"adbctl web", "adbctl web --listen 0.0.0.0:8752"}},
+	"debug":           {"Reproduces problems from a device you don't have. `debug capture` runs adbctl with every adb call recorded (arguments, output, exit status and timing) into a fixture directory with a manifest.json; by default it walks the menu through device information and memory, and `-- <command>` captures that command instead (again with -o to add it to the same fixture). `debug replay <dir>` reruns the captured commands with the recorded answers in place of adb and says whether the output still matches, with no device or adb needed; `-- <command>` runs another command against the recording, and calls that were never captured fail with \"nothing recorded\". The fixture holds everything adb returned, including serials, IP addresses and package names; review it before attaching it to a bug report.", []string{"adbctl debug capture", "adbctl debug capture -o fixture -- inventory", "adbctl debug replay fixture", "adbctl debug replay fixture -- inventory"}},
+	"devices":         {"When several devices are connected adbctl asks which one to use. Set ADBCTL_DEVICE=<serial> to skip the prompt. A device connected over both USB and WiFi is listed once; transfers use USB automatically, and -transport usb|wifi (or ADBCTL_TRANSPORT) forces one transport for everything. Read-only queries that fail because the device briefly went offline are retried -retries times (default 3, ADBCTL_RETRIES), waiting -retry-backoff (default 500ms, ADBCTL_RETRY_BACKOFF) and doubling the wait each time. If the device has dropped off adb altogether, as it does while adbd restarts after adb tcpip, adb root or during an OTA, adbctl instead waits up to -reconnect-wait (default 1m, ADBCTL_RECONNECT_WAIT; 0 disables) for it to return, reconnecting network devices, then repeats the query; pushes, pulls and port forwards are repeated the same way, and a running logcat picks up where it left off. Actions that change the device (starting apps, key presses, settings, installs) are never repeated, since they may already have run; they fail with the transport error instead. Over WiFi, command timeouts are scaled (up to 6x) by the latency and throughput measured on first use; ADBCTL_TIMEOUT_SCALE sets a fixed factor.", []string{"ADBCTL_DEVICE=192.168.1.20:5555 adbctl power status", "adbctl -transport wifi link", "adbctl -retries 5 -retry-backoff 1s", "adbctl -reconnect-wait 2m logcat"}},
+	"ci":              {"-ci (or ADBCTL_CI=1) never prompts. ADBCTL_DEVICE selects the device, ADBCTL_TIMEOUT aborts after a duration and ADBCTL_OUTPUT=json prints errors as JSON on stderr. Exit codes: 1 failed, 2 usage, 3 device, 124 timeout.", []string{"ADBCTL_DEVICE=emulator-5554 ADBCTL_TIMEOUT=5m adbctl -ci wait-for boot"}},
+	"fleet":           {"Any command accepts --all-devices to run once per connected device. Add --continue-on-error to keep going after a failure and --results <file> to save per-device results (succeeded/failed/skipped with reasons) as JSON. Exit code 0 means every device succeeded, 4 a partial failure and 1 a total failure.", []string{"adbctl power stay-awake on --all-devices --continue-on-error", "adbctl wait-for boot --all-devices --results boot.json"}},
+	"palette":         {"In the interactive menu type p (or Ctrl+P) and Enter, or choose option 7, to fuzzy-search all commands and recent actions. The chosen command runs as its own adbctl process against the menu's device, and the menu comes back when it finishes.", nil},
+}
+
+func init() {
+	// Registered here rather than in the commands table, which runHelp itself reads.
+	commands = append(commands, Command{"help", "help [topic]", "Show detailed help and examples", runHelp})
+}
+
+// paletteHistory holds command lines run from the palette in this session.
+var paletteHistory []string
+
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		printCommands()
+		fmt.Println("\nRun 'adbctl help <topic>' for details. Extra topics: devices, ci, fleet, palette")
+		return nil
+	}
+	topic, ok := helpTopics[args[0]]
+	if !ok {
+		fmt.Printf("No help for %q.", args[0])
+		if matches := fuzzyMatchCommands(args[0]); len(matches) > 0 {
+			fmt.Printf(" Did you mean '%s'?", matches[0])
+		}
+		fmt.Println()
+		return nil
+	}
+	color.New(color.FgCyan, color.Bold).Printf("adbctl %s\n", args[0])
+	fmt.Print(rule("=", 40))
+	if c, found := findCommand(args[0]); found {
+		fmt.Printf("Usage: adbctl %s\n\n", c.Usage)
+	}
+	fmt.Println(topic.Text)
+	if len(topic.Examples) > 0 {
+		color.New(color.FgYellow, color.Bold).Println("\nExamples:")
+		for _, example := range topic.Examples {
+			fmt.Printf("  %s\n", example)
+		}
+	}
+	return nil
+}
+
+// fuzzyScore returns a positive score when every rune of query appears in
+// candidate in order; consecutive and word-start matches score higher.
+func fuzzyScore(query, candidate string) int {
+	query, candidate = strings.ToLower(query), strings.ToLower(candidate)
+	if query == "" {
+		return 1
+	}
+	score, qi, prev := 0, 0, -2
+	runes := []rune(candidate)
+	q := []rune(query)
+	for i, r := range runes {
+		if qi < len(q) && r == q[qi] {
+			score++
+			if i == prev+1 {
+				score += 2
+			}
+			if i == 0 || !unicode.IsLetter(runes[i-1]) {
+				score += 3
+			}
+			prev = i
+			qi++
+		}
+	}
+	if qi < len(q) {
+		return 0
+	}
+	return score
+}
+
+func fuzzyMatchCommands(query string) []string {
+	type match struct {
+		Name  string
+		Score int
+	}
+	var matches []match
+	for _, c := range commands {
+		if score := fuzzyScore(query, c.Name)*2 + fuzzyScore(query, c.Description); score > 0 {
+			matches = append(matches, match{c.Name, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// commandPalette lets the user fuzzy-search commands and recent actions from
+// the interactive menu and runs the chosen one against deviceID. The command
+// runs as a separate adbctl process, so one that exits (as most do on an
+// error) returns to the menu instead of ending the session.
+func commandPalette(reader *bufio.Reader, deviceID string) {
+	fmt.Print("Search commands (empty for recent): ")
+	query, _ := reader.ReadString('\n')
+	query = strings.TrimSpace(query)
+
+	var entries []string
+	for i := len(paletteHistory) - 1; i >= 0; i-- {
+		if fuzzyScore(query, paletteHistory[i]) > 0 && !containsString(entries, paletteHistory[i]) {
+			entries = append(entries, paletteHistory[i])
+		}
+	}
+	for _, name := range fuzzyMatchCommands(query) {
+		if len(entries) >= 10 {
+			break
+		}
+		entries = append(entries, name)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No matching commands.")
+		return
+	}
+
+	for i, entry := range entries {
+		description := "recent"
+		if c, ok := findCommand(entry); ok {
+			description = c.Usage
+		}
+		if screenReader {
+			fmt.Printf("Result %d of %d: %s, %s\n", i+1, len(entries), entry, description)
+		} else {
+			fmt.Printf("%2d. %-20s %s\n", i+1, entry, description)
+		}
+	}
+	fmt.Print("Select a number: ")
+	input, _ := reader.ReadString('\n')
+	index, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || index < 1 || index > len(entries) {
+		fmt.Println("Invalid selection.")
+		return
+	}
+
+	line := entries[index-1]
+	if _, isCommand := findCommand(line); isCommand {
+		fmt.Printf("Arguments for '%s' (see 'adbctl help %s'): ", line, line)
+		extra, _ := reader.ReadString('\n')
+		if extra = strings.TrimSpace(extra); extra != "" {
+			line += " " + extra
+		}
+	}
+	paletteHistory = append(paletteHistory, line)
+
+	executable, err := os.Executable()
+	if err != nil {
+		printError(err)
+		return
+	}
+	cmd := exec.Command(executable, splitCommandLine(line)...)
+	cmd.Env = append(os.Environ(), "ADBCTL_DEVICE="+deviceID)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		printError(fmt.Errorf("%s: %v", line, err))
+	}
+}
+
+// splitCommandLine splits on whitespace, honouring single and double quotes.
+func splitCommandLine(line string) []string {
+	var fields []string
+	var current strings.Builder
+	inField := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\''):
+			quote = r
+			inField = true
+		case quote == 0 && unicode.IsSpace(r):
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyFile records every network endpoint adbctl has used successfully,
+// most recent first, for `adbctl connect --recent`.
+const historyFile = "history.json"
+
+const maxHistoryEntries = 50
+
+type historyEntry struct {
+	Serial   string    `json:"serial,omitempty"`
+	Address  string    `json:"address"`
+	Model    string    `json:"model,omitempty"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+func loadHistory() ([]historyEntry, error) {
+	var entries []historyEntry
+	err := loadConfigJSON(historyFile, &entries)
+	return entries, err
+}
+
+// recordConnection notes that address was just used. USB serials and mDNS
+// service names are skipped; only ip:port endpoints need retyping.
+func recordConnection(address string) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return
+	}
+	entries, err := loadHistory()
+	if err != nil {
+		debugPrint("history: %v\n", err)
+		return
+	}
+	entry := historyEntry{Address: address}
+	for i, e := range entries {
+		if e.Address == address {
+			entry = e
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if entry.Serial == "" || entry.Model == "" {
+		timeout := 5 * time.Second
+		entry.Serial = runAdbCommand(address, "getprop ro.serialno", timeout)
+		entry.Model = mapFireOSModel(runAdbCommand(address, "getprop ro.product.model", timeout))
+	}
+	entry.LastSeen = time.Now()
+	entries = append([]historyEntry{entry}, entries...)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[:maxHistoryEntries]
+	}
+	if err := saveConfigJSON(historyFile, entries); err != nil {
+		debugPrint("history: %v\n", err)
+	}
+}
+
+// connectRecent lets the user pick a previously used endpoint and connects.
+func connectRecent() error {
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no connection history yet; connect once with 'adbctl connect <ip:port>'")
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastSeen.After(entries[j].LastSeen) })
+
+	choice := entries[0]
+	if len(entries) > 1 {
+		if ciMode {
+			return fmt.Errorf("%d recent devices; name one with 'adbctl connect <ip:port>'", len(entries))
+		}
+		fmt.Println("Recent devices:")
+		for i, e := range entries {
+			fmt.Printf("%d. %-21s %-20s %-16s last seen %s\n", i+1, e.Address, e.Model, e.Serial, e.LastSeen.Format("2006-01-02 15:04"))
+		}
+		fmt.Print("Enter the number of the device to connect: ")
+		input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		index := 0
+		if _, err := fmt.Sscanf(strings.TrimSpace(input), "%d", &index); err != nil || index < 1 || index > len(entries) {
+			return fmt.Errorf("invalid selection")
+		}
+		choice = entries[index-1]
+	}
+	host, port, _ := net.SplitHostPort(choice.Address)
+	endpoint := pairedEndpoint{Host: host, ConnectPort: port}
+	return connectEndpoint(&endpoint)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// haEntity describes one Home Assistant MQTT discovery entity. Sensors read
+// the monitor sample topics; buttons and text entities publish to command
+// topics that adbctl subscribes to.
+type haEntity struct {
+	Component   string // sensor, binary_sensor, button, text
+	Key         string // sample metric or command name
+	Name        string
+	DeviceClass string
+	Unit        string
+	Icon        string
+}
+
+var haEntities = []haEntity{
+	{Component: "binary_sensor", Key: "screen_on", Name: "Power", DeviceClass: "power"},
+	{Component: "sensor", Key: "state", Name: "State", Icon: "mdi:cellphone-link"},
+	{Component: "sensor", Key: "foreground", Name: "Current app", Icon: "mdi:application"},
+	{Component: "sensor", Key: "temperature", Name: "Temperature", DeviceClass: "temperature", Unit: "°C"},
+	{Component: "sensor", Key: "battery", Name: "Battery", DeviceClass: "battery", Unit: "%"},
+	{Component: "sensor", Key: "mem_available", Name: "Available memory", DeviceClass: "data_size", Unit: "MB"},
+	{Component: "sensor", Key: "storage_free", Name: "Free storage", DeviceClass: "data_size", Unit: "MB"},
+	{Component: "sensor", Key: "uptime", Name: "Uptime", DeviceClass: "duration", Unit: "s"},
+	{Component: "sensor", Key: "wifi_rssi", Name: "WiFi signal", DeviceClass: "signal_strength", Unit: "dBm"},
+	{Component: "button", Key: "wake", Name: "Wake", Icon: "mdi:power"},
+	{Component: "text", Key: "launch", Name: "Launch app", Icon: "mdi:rocket-launch"},
+}
+
+var haObjectIDUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// publishHADiscovery announces the device's entities under the discovery
+// prefix. The messages are retained so Home Assistant picks them up after
+// either side restarts.
+func publishHADiscovery(sink *mqttSink, deviceID string) error {
+	objectID := "adbctl_" + strings.Trim(haObjectIDUnsafe.ReplaceAllString(deviceID, "_"), "_")
+	timeout := 5 * time.Second
+	model := runAdbCommand(deviceID, "getprop ro.product.model", timeout)
+	device := map[string]any{
+		"identifiers":  []string{objectID},
+		"name":         mapFireOSModel(model) + " (" + deviceID + ")",
+		"manufacturer": runAdbCommand(deviceID, "getprop ro.product.manufacturer", timeout),
+		"model":        model,
+		"sw_version":   "Android " + runAdbCommand(deviceID, "getprop ro.build.version.release", timeout),
+	}
+
+	for _, entity := range haEntities {
+		config := map[string]any{
+			"name":      entity.Name,
+			"unique_id": objectID + "_" + entity.Key,
+			"device":    device,
+		}
+		switch entity.Component {
+		case "button", "text":
+			config["command_topic"] = sink.commandTopic(deviceID, entity.Key)
+		default:
+			config["state_topic"] = sink.sampleTopic(deviceID, entity.Key)
+		}
+		if entity.Component == "binary_sensor" {
+			config["payload_on"], config["payload_off"] = "true", "false"
+		}
+		if entity.DeviceClass != "" {
+			config["device_class"] = entity.DeviceClass
+		}
+		if entity.Unit != "" {
+			config["unit_of_measurement"] = entity.Unit
+		}
+		if entity.Icon != "" {
+			config["icon"] = entity.Icon
+		}
+		payload, _ := json.Marshal(config)
+		topic := fmt.Sprintf("%s/%s/%s/%s/config", sink.config.DiscoveryPrefix, entity.Component, objectID, entity.Key)
+		if err := sink.PublishRetained(topic, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// haPackagePattern is what a launch payload must look like: an Android
+// package name and nothing the device shell would interpret.
+var haPackagePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z0-9_]+)+$`)
+
+// subscribeHACommands maps the Home Assistant command entities to adbctl
+// actions on deviceID.
+func subscribeHACommands(sink *mqttSink, deviceID string) error {
+	if err := sink.Subscribe(sink.commandTopic(deviceID, "wake"), func(string) {
+		fmt.Println("Home Assistant: wake")
+		if output, err := adbShell(deviceID, "input", "keyevent", "KEYCODE_WAKEUP"); err != nil {
+			fmt.Printf("Home Assistant: wake failed: %s\n", output)
+		}
+	}); err != nil {
+		return err
+	}
+	return sink.Subscribe(sink.commandTopic(deviceID, "launch"), func(payload string) {
+		pkg := strings.TrimSpace(payload)
+		if pkg == "" {
+			return
+		}
+		if !haPackagePattern.MatchString(pkg) {
+			fmt.Printf("Home Assistant: ignoring launch of %q: not a package name\n", pkg)
+			return
+		}
+		fmt.Printf("Home Assistant: launch %s\n", pkg)
+		if output, err := adbShell(deviceID, "monkey", "-p", shellQuote(pkg), "-c", "android.intent.category.LAUNCHER", "1"); err != nil {
+			fmt.Printf("Home Assistant: launching %s failed: %s\n", pkg, output)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runIdentify makes physical units recognisable: the device numbered n in the
+// device selector (every device when n is omitted) wakes up, posts a
+// notification with its number and inverts its display colours n times in a
+// row, repeating for the duration. Colour inversion is applied by the
+// compositor, so it is visible on HDMI sticks whose brightness is fixed. With
+// the companion installed it shows the number full screen instead.
+func runIdentify(args []string) error {
+	fs := flag.NewFlagSet("identify", flag.ExitOnError)
+	duration := fs.Duration("duration", 10*time.Second, "How long to keep flashing")
+	positional := parseArgs(fs, args)
+
+	devices := dedupeDevices(getConnectedDevices())
+	if len(devices) == 0 {
+		return fmt.Errorf("no devices connected")
+	}
+	targets := map[int]string{}
+	switch len(positional) {
+	case 0:
+		for i, device := range devices {
+			targets[i+1] = strings.Fields(device)[0]
+		}
+	case 1:
+		n, err := strconv.Atoi(positional[0])
+		if err != nil || n < 1 || n > len(devices) {
+			return fmt.Errorf("device number must be between 1 and %d", len(devices))
+		}
+		targets[n] = strings.Fields(devices[n-1])[0]
+	default:
+		return fmt.Errorf("usage: identify [n] [--duration 10s]")
+	}
+
+	var wg sync.WaitGroup
+	for n, serial := range targets {
+		fmt.Printf("%d. %s\n", n, describeDeviceLine(devices[n-1]))
+		wg.Add(1)
+		go func(n int, serial string) {
+			defer wg.Done()
+			identifyDevice(serial, n, *duration)
+		}(n, serial)
+	}
+	wg.Wait()
+	return nil
+}
+
+func identifyDevice(serial string, n int, duration time.Duration) {
+	adbShell(serial, "input", "keyevent", "KEYCODE_WAKEUP")
+	if companionReady(serial) {
+		// The companion draws the number full screen over whatever is showing.
+		if _, err := adbShell(serial, "am", "start", "-n", companionIdentify,
+			"--ei", "number", strconv.Itoa(n), "--el", "duration_ms", strconv.FormatInt(duration.Milliseconds(), 10)); err == nil {
+			time.Sleep(duration)
+			return
+		}
+	}
+	adbShell(serial, "cmd", "notification", "post", "-S", "bigtext", "-t",
+		shellQuote(fmt.Sprintf("adbctl device %d", n)), "adbctl-identify", shellQuote(fmt.Sprintf("This is device %d (%s)", n, serial)))
+	defer adbShell(serial, "cmd", "notification", "cancel", "adbctl-identify")
+
+	original, _ := adbShellIdempotent(serial, "settings", "get", "secure", "accessibility_display_inversion_enabled")
+	defer func() {
+		if original == "null" || original == "" {
+			adbShell(serial, "settings", "delete", "secure", "accessibility_display_inversion_enabled")
+		} else {
+			adbShell(serial, "settings", "put", "secure", "accessibility_display_inversion_enabled", original)
+		}
+	}()
+
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		for i := 0; i < n; i++ {
+			adbShell(serial, "settings", "put", "secure", "accessibility_display_inversion_enabled", "1")
+			time.Sleep(400 * time.Millisecond)
+			adbShell(serial, "settings", "put", "secure", "accessibility_display_inversion_enabled", "0")
+			time.Sleep(400 * time.Millisecond)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const inputRecordingHeader = "# adbctl input raw recording: <seconds> <device> <type> <code> <value>"
+
+var (
+	// getevent -t prints "[   1234.567890] /dev/input/event3: 0001 0067 00000001".
+	geteventLinePattern   = regexp.MustCompile(`^\[\s*(\d+\.\d+)\]\s+(/dev/input/event\d+):\s+([0-9a-f]{4})\s+([0-9a-f]{4})\s+([0-9a-f]{8})`)
+	geteventDevicePattern = regexp.MustCompile(`^add device \d+: (\S+)`)
+	geteventNamePattern   = regexp.MustCompile(`^\s+name:\s+"(.*)"`)
+	recordedDevicePattern = regexp.MustCompile(`^device (\S+) "(.*)"$`)
+)
+
+// rawInputEvent is one kernel input event, with its time from the start of
+// the recording.
+type rawInputEvent struct {
+	Seconds float64
+	Device  string
+	Type    int
+	Code    int
+	Value   int32
+}
+
+func runInput(args []string) error {
+	if len(args) == 0 || args[0] != "raw" {
+		return fmt.Errorf("usage: input raw --record <file> [--device name] | --play <file> [--map old=new] [--speed 1]")
+	}
+	fs := flag.NewFlagSet("input raw", flag.ExitOnError)
+	record := fs.String("record", "", "Record kernel input events to this file until Ctrl+C")
+	play := fs.String("play", "", "Replay the events in this file")
+	device := fs.String("device", "", "When recording, only this input device (path or part of its name)")
+	mapping := fs.String("map", "", "When playing, override device paths: event3=event5[,event4=event2]")
+	speed := fs.Float64("speed", 1, "Playback speed factor")
+	parseArgs(fs, args[1:])
+	if (*record == "") == (*play == "") {
+		return fmt.Errorf("choose one of --record and --play")
+	}
+	if *speed <= 0 {
+		return fmt.Errorf("--speed must be positive")
+	}
+
+	deviceID := pickDevice()
+	devices, err := inputDevices(deviceID)
+	if err != nil {
+		return err
+	}
+	if *record != "" {
+		return recordRawInput(deviceID, devices, *record, *device)
+	}
+	return playRawInput(deviceID, devices, *play, *mapping, *speed)
+}
+
+// inputDevices maps the device's input device paths to their names, as
+// getevent -p lists them.
+func inputDevices(deviceID string) (map[string]string, error) {
+	output, err := adbShellIdempotent(deviceID, "getevent", "-p")
+	if err != nil {
+		return nil, fmt.Errorf("getevent -p failed: %s", output)
+	}
+	devices := map[string]string{}
+	var path string
+	for _, line := range strings.Split(output, "\n") {
+		if m := geteventDevicePattern.FindStringSubmatch(line); m != nil {
+			path = m[1]
+			devices[path] = ""
+		} else if m := geteventNamePattern.FindStringSubmatch(line); m != nil && path != "" {
+			devices[path] = m[1]
+		}
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("no input devices readable by the shell user")
+	}
+	return devices, nil
+}
+
+// recordRawInput writes a header naming each device, so a replay can find it
+// again after a reboot renumbers the event nodes, followed by one event per
+// line in sendevent's decimal form.
+func recordRawInput(deviceID string, devices map[string]string, file, only string) error {
+	var paths []string
+	for path, name := range devices {
+		if only == "" || path == only || strings.Contains(strings.ToLower(name), strings.ToLower(only)) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no input device matches %q; devices: %s", only, strings.Join(sortedKeys(devices), ", "))
+	}
+
+	out, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	fmt.Fprintln(w, inputRecordingHeader)
+	for _, path := range sortedKeys(devices) {
+		if containsString(paths, path) {
+			fmt.Fprintf(w, "device %s %q\n", path, devices[path])
+		}
+	}
+
+	getevent := []string{"shell", "getevent", "-t"}
+	if len(paths) == 1 {
+		getevent = append(getevent, paths[0])
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("Recording input from %d device(s) to %s, press Ctrl+C to stop...\n", len(paths), file)
+	start, count := -1.0, 0
+	err = adbStream(ctx, deviceID, func(line string) {
+		event, ok := parseGeteventLine(line)
+		if !ok || !containsString(paths, event.Device) {
+			return
+		}
+		if start < 0 {
+			start = event.Seconds
+		}
+		fmt.Fprintf(w, "%.6f %s %d %d %d\n", event.Seconds-start, event.Device, event.Type, event.Code, event.Value)
+		count++
+		if event.Type == 1 && event.Value == 1 {
+			debugPrint("key %d down on %s\n", event.Code, event.Device)
+		}
+	}, getevent...)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	fmt.Printf("\nRecorded %d event(s).\n", count)
+	return nil
+}
+
+func parseGeteventLine(line string) (rawInputEvent, bool) {
+	m := geteventLinePattern.FindStringSubmatch(line)
+	if m == nil {
+		return rawInputEvent{}, false
+	}
+	seconds, _ := strconv.ParseFloat(m[1], 64)
+	typ, _ := strconv.ParseInt(m[3], 16, 32)
+	code, _ := strconv.ParseInt(m[4], 16, 32)
+	value, _ := strconv.ParseUint(m[5], 16, 32)
+	return rawInputEvent{Seconds: seconds, Device: m[2], Type: int(typ), Code: int(code), Value: int32(uint32(value))}, true
+}
+
+// playRawInput replays a recording with one shell script, as a round trip
+// per sendevent would stretch the timing that firmware bugs depend on.
+func playRawInput(deviceID string, devices map[string]string, file, mapping string, speed float64) error {
+	recorded, events, err := readRawInput(file)
+	if err != nil {
+		return err
+	}
+	paths, err := mapInputDevices(recorded, devices, mapping)
+	if err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	last := 0.0
+	for _, e := range events {
+		if gap := (e.Seconds - last) / speed; gap >= 0.001 {
+			fmt.Fprintf(&script, "sleep %.3f\n", gap)
+			last = e.Seconds
+		}
+		fmt.Fprintf(&script, "sendevent %s %d %d %d\n", paths[e.Device], e.Type, e.Code, e.Value)
+	}
+	tmp, err := os.MkdirTemp("", "adbctl-input")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	local := filepath.Join(tmp, "replay.sh")
+	if err := os.WriteFile(local, []byte(script.String()), 0644); err != nil {
+		return err
+	}
+	remote := "/data/local/tmp/adbctl-replay.sh"
+	if err := adbPush(deviceID, local, remote); err != nil {
+		return err
+	}
+	defer adbShell(deviceID, "rm", "-f", remote)
+
+	fmt.Printf("Replaying %d event(s) over %.1fs...\n", len(events), events[len(events)-1].Seconds/speed)
+	if output, err := adbShell(deviceID, "sh", remote); err != nil || strings.Contains(output, "sendevent:") {
+		return fmt.Errorf("replay failed: %s", strings.TrimSpace(output))
+	}
+	fmt.Println("Done.")
+	return nil
+}
+
+// readRawInput reads a recording's devices (path to name) and events.
+func readRawInput(file string) (map[string]string, []rawInputEvent, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	devices := map[string]string{}
+	var events []rawInputEvent
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := recordedDevicePattern.FindStringSubmatch(line); m != nil {
+			name, _ := strconv.Unquote(`"` + m[2] + `"`)
+			devices[m[1]] = name
+			continue
+		}
+		var e rawInputEvent
+		if _, err := fmt.Sscanf(line, "%f %s %d %d %d", &e.Seconds, &e.Device, &e.Type, &e.Code, &e.Value); err != nil {
+			return nil, nil, fmt.Errorf("%s:%d: %v", file, n, err)
+		}
+		if _, ok := devices[e.Device]; !ok {
+			devices[e.Device] = ""
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil, fmt.Errorf("%s has no events", file)
+	}
+	return devices, events, nil
+}
+
+// mapInputDevices finds the current path of each recorded device: an
+// explicit --map entry, then the device with the same name, then the same
+// path.
+func mapInputDevices(recorded, current map[string]string, mapping string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for _, pair := range strings.Split(mapping, ",") {
+		old, updated, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			if pair != "" {
+				return nil, fmt.Errorf("invalid --map entry %q (want old=new)", pair)
+			}
+			continue
+		}
+		overrides[inputDevicePath(old)] = inputDevicePath(updated)
+	}
+
+	paths := map[string]string{}
+	for path, name := range recorded {
+		if target, ok := overrides[path]; ok {
+			paths[path] = target
+			continue
+		}
+		if name != "" {
+			for currentPath, currentName := range current {
+				if currentName == name {
+					paths[path] = currentPath
+					break
+				}
+			}
+		}
+		if paths[path] == "" {
+			if _, ok := current[path]; !ok {
+				return nil, fmt.Errorf("recorded device %s (%q) is not on this device; use --map", path, name)
+			}
+			paths[path] = path
+		}
+		if paths[path] != path {
+			fmt.Printf("%s (%s) is now %s\n", path, name, paths[path])
+		}
+	}
+	return paths, nil
+}
+
+// inputDevicePath accepts "event3" as well as "/dev/input/event3".
+func inputDevicePath(s string) string {
+	if strings.HasPrefix(s, "/") {
+		return s
+	}
+	return "/dev/input/" + s
+}
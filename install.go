@@ -0,0 +1,279 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	obbNamePattern      = regexp.MustCompile(`^(main|patch)\.\d+\.[\w.]+\.obb$`)
+	badgingPackageRegex = regexp.MustCompile(`package: name='([^']+)'`)
+)
+
+// runInstall installs an APK (with optional OBB expansion files), an .apks
+// set built by bundletool, or an .aab app bundle. Sets built with
+// --local-testing carry their Play Asset Delivery packs, which bundletool
+// pushes for local testing.
+func runInstall(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ExitOnError)
+	obbs := fs.String("obb", "", "Comma-separated OBB files to push after installing (main first, then patch)")
+	pkg := fs.String("package", "", "Package name, needed for --obb when aapt2 is not available")
+	deviceSpec := fs.String("device-spec", "auto", "For .aab: a bundletool device-spec JSON, or auto to read it from the device")
+	localTesting := fs.Bool("local-testing", false, "For .aab: include Play Asset Delivery packs for local testing")
+	user := fs.String("user", "", "Install for this user id only (see 'adbctl users'); APKs only")
+	variant := fs.String("variant", "", "In a Gradle project, install this build variant's APK (default debug)")
+	positional := parseArgs(fs, args)
+	if len(positional) > 1 || (len(positional) == 1 && *variant != "") {
+		return fmt.Errorf("usage: install <app.apk|app.apks|app.aab> | --variant debug [--obb main.obb,patch.obb] [--package name]")
+	}
+	var file string
+	if len(positional) == 1 {
+		file = positional[0]
+	} else {
+		module := findGradleAppModule()
+		if module == "" {
+			return fmt.Errorf("usage: install <app.apk|app.apks|app.aab>; no Android Gradle project found to take the APK from")
+		}
+		if *variant == "" {
+			*variant = "debug"
+		}
+		apk, applicationID, err := gradleVariantAPK(module, *variant)
+		if err != nil {
+			return err
+		}
+		file = apk
+		if *pkg == "" {
+			*pkg = applicationID
+		}
+	}
+	if _, err := os.Stat(file); err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	if err := checkUser(deviceID, *user); err != nil {
+		return err
+	}
+	ext := strings.ToLower(filepath.Ext(file))
+	if *user != "" && ext != ".apk" {
+		return fmt.Errorf("--user is only supported for .apk files; bundletool installs for the current user")
+	}
+	switch ext {
+	case ".apk":
+		// adb install reports no byte count and much of its time goes into
+		// verifying the APK on the device, so this is a spinner.
+		p := startProgress("Installing "+filepath.Base(file), 0, progressTime)
+		err := adbInstall(deviceID, file, *user)
+		p.Finish(err)
+		if err != nil {
+			return err
+		}
+	case ".apks":
+		if err := runBundletool("install-apks", "--apks="+file, "--device-id="+fastTransport(deviceID), "--adb="+adbPath); err != nil {
+			return err
+		}
+	case ".aab":
+		if err := installBundle(deviceID, file, *deviceSpec, *localTesting); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%s is not an .apk, .apks or .aab file", file)
+	}
+	fmt.Printf("Installed %s\n", file)
+
+	if *obbs == "" {
+		return nil
+	}
+	if *pkg == "" {
+		name, err := apkPackageName(file)
+		if err != nil {
+			return err
+		}
+		*pkg = name
+	}
+	return pushOBBs(deviceID, *pkg, *user, splitList(*obbs))
+}
+
+// adbInstall installs or replaces apk, for every user or only for user.
+func adbInstall(deviceID, apk, user string) error {
+	args := []string{"-s", fastTransport(deviceID), "install", "-r"}
+	if user != "" {
+		args = append(args, "--user", user)
+	}
+	output, err := exec.Command(adbPath, append(args, apk)...).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Success") {
+		return normalizeAdbError(string(output), fmt.Errorf("adb install %s: %s", apk, strings.TrimSpace(string(output))))
+	}
+	return nil
+}
+
+// installBundle builds the split APKs an app bundle would deliver to this
+// device (ABI, density, locales, SDK) and installs them. bundletool signs
+// them with the debug keystore.
+func installBundle(deviceID, bundle, deviceSpec string, localTesting bool) error {
+	dir, err := os.MkdirTemp("", "adbctl-bundle-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	apks := filepath.Join(dir, strings.TrimSuffix(filepath.Base(bundle), filepath.Ext(bundle))+".apks")
+
+	serial := fastTransport(deviceID)
+	buildArgs := []string{"build-apks", "--bundle=" + bundle, "--output=" + apks}
+	if deviceSpec == "auto" {
+		buildArgs = append(buildArgs, "--connected-device", "--device-id="+serial, "--adb="+adbPath)
+	} else {
+		buildArgs = append(buildArgs, "--device-spec="+deviceSpec)
+	}
+	if localTesting {
+		buildArgs = append(buildArgs, "--local-testing")
+	}
+	fmt.Println("Building the split APKs for this device...")
+	if err := runBundletool(buildArgs...); err != nil {
+		return err
+	}
+	return runBundletool("install-apks", "--apks="+apks, "--device-id="+serial, "--adb="+adbPath)
+}
+
+// pushOBBs copies expansion files to Android/obb/<package>/ under the names
+// the Play Store would use: <main|patch>.<versionCode>.<package>.obb. Each
+// user has its own shared storage, so a user id selects /storage/emulated/<id>.
+func pushOBBs(deviceID, pkg, user string, obbs []string) error {
+	versionCode := ""
+	if output, err := adbShellIdempotent(deviceID, "dumpsys", "package", shellQuote(pkg)); err == nil {
+		for _, line := range strings.Split(output, "\n") {
+			for _, field := range strings.Fields(line) {
+				if value, found := strings.CutPrefix(field, "versionCode="); found && versionCode == "" {
+					versionCode = value
+				}
+			}
+		}
+	}
+	if versionCode == "" {
+		return fmt.Errorf("%s is not installed on the device; check --package", pkg)
+	}
+
+	dir := path.Join("/sdcard/Android/obb", pkg)
+	if user != "" {
+		dir = path.Join("/storage/emulated", user, "Android/obb", pkg)
+	}
+	if output, err := adbShell(deviceID, "mkdir", "-p", shellQuote(dir)); err != nil {
+		return fmt.Errorf("creating %s failed: %s", dir, output)
+	}
+	for i, obb := range obbs {
+		name := filepath.Base(obb)
+		if !obbNamePattern.MatchString(name) {
+			kind := "main"
+			if strings.HasPrefix(name, "patch") || (i > 0 && !strings.HasPrefix(name, "main")) {
+				kind = "patch"
+			}
+			name = fmt.Sprintf("%s.%s.%s.obb", kind, versionCode, pkg)
+		}
+		info, err := os.Stat(obb)
+		if err != nil {
+			return err
+		}
+		p := startProgress(fmt.Sprintf("Pushing %s as %s/%s", filepath.Base(obb), dir, name), info.Size(), progressBytes)
+		err = adbPushFile(deviceID, obb, path.Join(dir, name), p)
+		p.Finish(err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apkPackageName reads the package name with aapt2 (or aapt) from the SDK
+// build-tools.
+func apkPackageName(apk string) (string, error) {
+	if ext := strings.ToLower(filepath.Ext(apk)); ext == ".apks" || ext == ".aab" {
+		return "", fmt.Errorf("give the package name of an %s file with --package", ext)
+	}
+	for _, tool := range []string{"aapt2", "aapt"} {
+		binary := findBuildTool(tool)
+		if binary == "" {
+			continue
+		}
+		output, _ := exec.Command(binary, "dump", "badging", apk).Output()
+		if m := badgingPackageRegex.FindSubmatch(output); m != nil {
+			return string(m[1]), nil
+		}
+	}
+	return "", fmt.Errorf("could not read the package name of %s (aapt2 not found); pass --package", apk)
+}
+
+// findBuildTool looks for tool on PATH, then in the newest SDK build-tools.
+func findBuildTool(tool string) string {
+	if binary, err := exec.LookPath(tool); err == nil {
+		return binary
+	}
+	for _, sdk := range androidSDKDirs() {
+		versions, _ := filepath.Glob(filepath.Join(sdk, "build-tools", "*", tool+filepath.Ext(adbExecutable())))
+		if len(versions) > 0 {
+			sort.Slice(versions, func(i, j int) bool {
+				return buildToolsVersionLess(filepath.Base(filepath.Dir(versions[i])), filepath.Base(filepath.Dir(versions[j])))
+			})
+			return versions[len(versions)-1]
+		}
+	}
+	return ""
+}
+
+// buildToolsVersionLess orders build-tools directories such as 9.0.0,
+// 30.0.3 and 35.0.0-rc1 numerically, with a preview before its release.
+func buildToolsVersionLess(a, b string) bool {
+	aNumbers, aPreview, _ := strings.Cut(a, "-")
+	bNumbers, bPreview, _ := strings.Cut(b, "-")
+	aParts, bParts := strings.Split(aNumbers, "."), strings.Split(bNumbers, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x, _ = strconv.Atoi(aParts[i])
+		}
+		if i < len(bParts) {
+			y, _ = strconv.Atoi(bParts[i])
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	if (aPreview == "") != (bPreview == "") {
+		return aPreview != ""
+	}
+	return aPreview < bPreview
+}
+
+// runBundletool runs bundletool from BUNDLETOOL (a .jar or an executable),
+// PATH, or bundletool.jar in the config directory.
+func runBundletool(args ...string) error {
+	var cmd *exec.Cmd
+	jar := os.Getenv("BUNDLETOOL")
+	if jar == "" {
+		if binary, err := exec.LookPath("bundletool"); err == nil {
+			jar = binary
+		} else {
+			jar = filepath.Join(configDir(), "bundletool.jar")
+		}
+	}
+	if _, err := os.Stat(jar); err != nil {
+		return fmt.Errorf("bundletool not found; download bundletool-all.jar from https://github.com/google/bundletool/releases and set BUNDLETOOL to its path or save it as %s", filepath.Join(configDir(), "bundletool.jar"))
+	}
+	if strings.EqualFold(filepath.Ext(jar), ".jar") {
+		cmd = exec.Command("java", append([]string{"-jar", jar}, args...)...)
+	} else {
+		cmd = exec.Command(jar, args...)
+	}
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("bundletool %s failed: %v", args[0], err)
+	}
+	return nil
+}
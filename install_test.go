@@ -0,0 +1,17 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestBuildToolsVersionLess(t *testing.T) {
+	versions := []string{"35.0.0-rc1", "9.0.0", "30.0.3", "34.0.0", "35.0.0", "30.0.10", "35.0.0-rc2"}
+	sort.Slice(versions, func(i, j int) bool { return buildToolsVersionLess(versions[i], versions[j]) })
+	want := []string{"9.0.0", "30.0.3", "30.0.10", "34.0.0", "35.0.0-rc1", "35.0.0-rc2", "35.0.0"}
+	for i := range want {
+		if versions[i] != want[i] {
+			t.Fatalf("sorted %v, want %v", versions, want)
+		}
+	}
+}
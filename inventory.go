@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	packageBlockPattern   = regexp.MustCompile(`^\s*Package \[([^\]]+)\]`)
+	packageVersionPattern = regexp.MustCompile(`^\s*versionCode=(\d+)`)
+	packageNamePattern    = regexp.MustCompile(`^\s*versionName=(.*)$`)
+	packageFlagsPattern   = regexp.MustCompile(`^\s*pkgFlags=\[(.*)\]`)
+)
+
+// deviceInventory is a full snapshot of a device for asset tracking. Lists
+// are sorted so two snapshots of the same device diff cleanly.
+type deviceInventory struct {
+	Captured     time.Time            `json:"captured"`
+	Serial       string               `json:"serial"`
+	Model        string               `json:"model"`
+	Fingerprint  string               `json:"fingerprint"`
+	Properties   map[string]string    `json:"properties"`
+	Packages     []inventoryPackage   `json:"packages"`
+	Partitions   []inventoryPartition `json:"partitions"`
+	BlockDevices []string             `json:"block_devices,omitempty"` // /dev/block/by-name, when readable
+	Features     []string             `json:"features"`
+	Display      inventoryDisplay     `json:"display"`
+}
+
+type inventoryPackage struct {
+	Name        string `json:"name"`
+	VersionName string `json:"version_name"`
+	VersionCode int64  `json:"version_code"`
+	System      bool   `json:"system"`
+}
+
+type inventoryPartition struct {
+	Filesystem string `json:"filesystem"`
+	Mount      string `json:"mount"`
+	SizeKB     int64  `json:"size_kb"`
+	UsedKB     int64  `json:"used_kb"`
+	FreeKB     int64  `json:"free_kb"`
+}
+
+type inventoryDisplay struct {
+	Size       string        `json:"size"`
+	Density    string        `json:"density"`
+	Modes      []displayMode `json:"modes"`
+	ActiveMode int           `json:"active_mode"`
+}
+
+// fleetInventoryRow is one device's line in inventory --all.
+type fleetInventoryRow struct {
+	Serial      string `json:"serial"`
+	State       string `json:"state"`
+	Model       string `json:"model"`
+	FireOS      string `json:"fire_os"`
+	Android     string `json:"android"`
+	PatchLevel  string `json:"patch_level"`
+	Battery     string `json:"battery"`
+	FreeStorage string `json:"free_storage"`
+	IP          string `json:"ip"`
+}
+
+func runInventory(args []string) error {
+	fs := flag.NewFlagSet("inventory", flag.ExitOnError)
+	output := fs.String("o", "", "Output file (default inventory-<serial>-<date>.json; with --all a .csv or .json file instead of a table)")
+	all := fs.Bool("all", false, "Collect key fields from every connected device into one table")
+	upload := fs.String("upload", "", "Also upload the file to s3://, gs:// or an HTTP PUT URL and print a download link")
+	parseArgs(fs, args)
+	if *all {
+		if *upload != "" && *output == "" {
+			*output = artifactPath(fmt.Sprintf("fleet-inventory-%s.csv", time.Now().Format("20060102-150405")))
+		}
+		if err := runFleetInventory(*output); err != nil {
+			return err
+		}
+		return uploadAndReport(*output, *upload)
+	}
+
+	deviceID := pickDevice()
+	inventory, err := collectInventory(deviceID)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		*output = artifactPath(fmt.Sprintf("inventory-%s-%s.json", strings.ReplaceAll(inventory.Serial, ":", "_"), time.Now().Format("20060102-150405")))
+	}
+	data, err := json.MarshalIndent(inventory, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*output, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s: %d properties, %d packages, %d partitions, %d features\n",
+		*output, len(inventory.Properties), len(inventory.Packages), len(inventory.Partitions), len(inventory.Features))
+	return uploadAndReport(*output, *upload)
+}
+
+func collectInventory(deviceID string) (deviceInventory, error) {
+	props, err := getAllProps(deviceID)
+	if err != nil {
+		return deviceInventory{}, fmt.Errorf("getprop failed: %v", err)
+	}
+	inventory := deviceInventory{
+		Captured:    time.Now().UTC().Truncate(time.Second),
+		Serial:      props["ro.serialno"],
+		Model:       mapFireOSModel(props["ro.product.model"]),
+		Fingerprint: props["ro.build.fingerprint"],
+		Properties:  props,
+	}
+	if inventory.Serial == "" {
+		inventory.Serial = deviceID
+	}
+
+	packages, err := adbShellIdempotent(deviceID, "dumpsys", "package", "packages")
+	if err != nil {
+		return deviceInventory{}, fmt.Errorf("dumpsys package failed: %v", err)
+	}
+	inventory.Packages = parsePackageInventory(packages)
+
+	df, _ := adbShellIdempotent(deviceID, "df", "-k")
+	inventory.Partitions = parseDfPartitions(df)
+	if byName, err := adbShellIdempotent(deviceID, "ls", "/dev/block/by-name"); err == nil {
+		inventory.BlockDevices = strings.Fields(byName)
+		sort.Strings(inventory.BlockDevices)
+	}
+
+	features, _ := adbShellIdempotent(deviceID, "pm", "list", "features")
+	for _, line := range strings.Split(features, "\n") {
+		if feature, found := strings.CutPrefix(strings.TrimSpace(line), "feature:"); found {
+			inventory.Features = append(inventory.Features, feature)
+		}
+	}
+	sort.Strings(inventory.Features)
+
+	inventory.Display.Size = runAdbCommand(deviceID, "wm size", 5*time.Second)
+	inventory.Display.Density = runAdbCommand(deviceID, "wm density", 5*time.Second)
+	if dump, err := adbShellIdempotent(deviceID, "dumpsys", "display"); err == nil {
+		inventory.Display.Modes, inventory.Display.ActiveMode, _ = parseDisplayModes(dump)
+	}
+	return inventory, nil
+}
+
+// parsePackageInventory reads name, version and whether the app is part of
+// the system image from the per-package blocks of dumpsys package packages.
+func parsePackageInventory(dump string) []inventoryPackage {
+	var packages []inventoryPackage
+	var current *inventoryPackage
+	for _, line := range strings.Split(dump, "\n") {
+		if m := packageBlockPattern.FindStringSubmatch(line); m != nil {
+			packages = append(packages, inventoryPackage{Name: m[1]})
+			current = &packages[len(packages)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if m := packageVersionPattern.FindStringSubmatch(line); m != nil && current.VersionCode == 0 {
+			current.VersionCode, _ = strconv.ParseInt(m[1], 10, 64)
+		} else if m := packageNamePattern.FindStringSubmatch(line); m != nil && current.VersionName == "" {
+			current.VersionName = strings.TrimSpace(m[1])
+		} else if m := packageFlagsPattern.FindStringSubmatch(line); m != nil {
+			current.System = current.System || containsString(strings.Fields(m[1]), "SYSTEM")
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Name < packages[j].Name })
+	return packages
+}
+
+// parseDfPartitions reads every mount of df -k, skipping the header.
+func parseDfPartitions(df string) []inventoryPartition {
+	var partitions []inventoryPartition
+	for _, line := range strings.Split(df, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 || fields[0] == "Filesystem" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		used, _ := strconv.ParseInt(fields[2], 10, 64)
+		free, _ := strconv.ParseInt(fields[3], 10, 64)
+		partitions = append(partitions, inventoryPartition{Filesystem: fields[0], Mount: fields[5], SizeKB: size, UsedKB: used, FreeKB: free})
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i].Mount < partitions[j].Mount })
+	return partitions
+}
+
+// runFleetInventory prints or writes the key fields of every device.
+func runFleetInventory(output string) error {
+	rows := collectFleetInventory()
+	if len(rows) == 0 {
+		return fmt.Errorf("no devices connected")
+	}
+	if output == "" {
+		printFleetInventory(rows)
+		return nil
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(output), ".json") {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(rows)
+	} else {
+		err = writeFleetInventoryCSV(f, rows)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d device(s) to %s\n", len(rows), output)
+	return nil
+}
+
+// collectFleetInventory queries every device in parallel. Devices adb lists
+// but can't use are included with their state so the lab sheet is complete.
+func collectFleetInventory() []fleetInventoryRow {
+	serials, skipped := fleetDevices()
+	rows := make([]fleetInventoryRow, len(serials))
+	var wg sync.WaitGroup
+	for i, serial := range serials {
+		wg.Add(1)
+		go func(i int, serial string) {
+			defer wg.Done()
+			rows[i] = collectFleetInventoryRow(serial)
+		}(i, serial)
+	}
+	wg.Wait()
+	for _, s := range skipped {
+		rows = append(rows, fleetInventoryRow{Serial: s.Device, State: s.Reason})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Serial < rows[j].Serial })
+	return rows
+}
+
+func collectFleetInventoryRow(serial string) fleetInventoryRow {
+	row := fleetInventoryRow{Serial: serial, State: "online", Model: "n/a", FireOS: "n/a", Android: "n/a", PatchLevel: "n/a", Battery: "n/a", FreeStorage: "n/a", IP: "n/a"}
+	output, err := runLibScript(serial, "summary.sh", 10*time.Second)
+	if err != nil {
+		debugPrint("summary %s: %v\n", serial, err)
+		return row
+	}
+	sections := libSections(output)
+	props := libValues(sections["props"])
+	row.Model, _, _ = strings.Cut(mapFireOSModel(props["model"]), " (http")
+	row.FireOS, row.Android, row.PatchLevel, row.IP = props["fire_os"], props["android"], props["patch_level"], props["ip"]
+	row.Battery = powerSummary(parseKeyValueLines(sections["battery"]))
+	if df := parseDfFields(strings.TrimSpace(sections["df"])); df != nil {
+		free, _ := strconv.Atoi(df["free_kb"])
+		row.FreeStorage = formatKB(free)
+	}
+	return row
+}
+
+func printFleetInventory(rows []fleetInventoryRow) {
+	format := "%-22s %-28s %-14s %-8s %-11s %-22s %-10s %s\n"
+	color.New(color.FgCyan, color.Bold).Printf(format, "SERIAL", "MODEL", "FIRE OS", "ANDROID", "PATCH", "BATTERY", "FREE", "IP")
+	for _, r := range rows {
+		if r.State != "online" {
+			color.New(color.FgYellow).Printf("%-22s %s\n", r.Serial, r.State)
+			continue
+		}
+		fmt.Printf(format, r.Serial, r.Model, r.FireOS, r.Android, r.PatchLevel, r.Battery, r.FreeStorage, r.IP)
+	}
+}
+
+func writeFleetInventoryCSV(w io.Writer, rows []fleetInventoryRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"serial", "state", "model", "fire_os", "android", "patch_level", "battery", "free_storage", "ip"})
+	for _, r := range rows {
+		cw.Write([]string{r.Serial, r.State, r.Model, r.FireOS, r.Android, r.PatchLevel, r.Battery, r.FreeStorage, r.IP})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// runKeepalive keeps wireless devices connected: every interval each address
+// is pinged and, when it dropped, disconnected and connected again. State
+// changes are logged and, with --notify, shown as desktop notifications.
+func runKeepalive(args []string) error {
+	fs := flag.NewFlagSet("keepalive", flag.ExitOnError)
+	interval := fs.Duration("interval", 15*time.Second, "How often to check each device")
+	notify := fs.Bool("notify", false, "Show a desktop notification when a device drops or comes back")
+	addresses := parseArgs(fs, args)
+
+	if len(addresses) == 0 {
+		endpoints, err := loadEndpoints()
+		if err != nil {
+			return err
+		}
+		for _, e := range endpoints {
+			if e.ConnectPort != "" {
+				addresses = append(addresses, e.Address())
+			}
+		}
+		if len(addresses) == 0 {
+			return fmt.Errorf("usage: keepalive <ip:port>... (or pair devices first with 'adbctl pair')")
+		}
+	}
+	for i, address := range addresses {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			addresses[i] = net.JoinHostPort(address, "5555")
+		}
+	}
+
+	fmt.Printf("Keeping %d device(s) connected, checking every %v. Press Ctrl+C to stop.\n", len(addresses), *interval)
+	states := map[string]string{}
+	for {
+		for _, address := range addresses {
+			state := "connected"
+			if !keepalivePing(address) {
+				state = "disconnected"
+				if err := keepaliveReconnect(address); err == nil {
+					state = "reconnected"
+				} else {
+					debugPrint("keepalive: %s: %v\n", address, err)
+				}
+			}
+			previous, seen := states[address]
+			if !seen || (state != previous && !(state == "connected" && previous == "reconnected")) {
+				message := fmt.Sprintf("%s %s", address, state)
+				fmt.Printf("%s %s\n", time.Now().Format("2006-01-02 15:04:05"), message)
+				if *notify && seen {
+					desktopNotify("adbctl keepalive", message)
+				}
+			}
+			states[address] = state
+		}
+		time.Sleep(*interval)
+	}
+}
+
+// keepalivePing reports whether the device is listed as online and answers a
+// shell command.
+func keepalivePing(address string) bool {
+	if adbDeviceStatus(address) != "device" {
+		return false
+	}
+	_, err := exec.Command(adbPath, "-s", address, "shell", "true").CombinedOutput()
+	return err == nil
+}
+
+func keepaliveReconnect(address string) error {
+	// A stale offline entry makes `adb connect` report "already connected".
+	exec.Command(adbPath, "disconnect", address).Run()
+	output, err := exec.Command(adbPath, "connect", address).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "connected to") {
+		return normalizeAdbError(string(output), fmt.Errorf("adb connect %s: %s", address, strings.TrimSpace(string(output))))
+	}
+	return waitForState(address, StateAuthorized, 10*time.Second)
+}
+
+// desktopNotify shows a notification with notify-send (Linux) or osascript
+// (macOS). Failures are ignored; the log line is always printed.
+func desktopNotify(title, message string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf("display notification %q with title %q", message, title))
+	case "linux":
+		cmd = exec.Command("notify-send", title, message)
+	default:
+		return
+	}
+	cmd.Stderr = os.Stderr
+	cmd.Run()
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// appUserPattern matches app uids as ps shows them (u0_a123, u10_a45).
+var appUserPattern = regexp.MustCompile(`^u\d+_a\d+$`)
+
+var signalNumbers = map[string]int{"HUP": 1, "INT": 2, "QUIT": 3, "ABRT": 6, "KILL": 9, "USR1": 10, "USR2": 12,
+	"TERM": 15, "CONT": 18, "STOP": 19}
+
+func runKill(args []string) error {
+	fs := flag.NewFlagSet("kill", flag.ExitOnError)
+	signal := fs.String("signal", "TERM", "Signal name or number (TERM, KILL, HUP, INT, QUIT, ABRT, USR1, USR2, STOP, CONT)")
+	yes := fs.Bool("yes", false, "Skip the confirmation prompt")
+	args = parseArgs(fs, args)
+	if len(args) != 1 {
+		return fmt.Errorf("usage: kill <name|pid> [--signal TERM] [--yes]")
+	}
+	number, err := parseSignal(*signal)
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	processes, err := listProcesses(deviceID)
+	if err != nil {
+		return err
+	}
+	targets := matchProcesses(processes, args[0])
+	if len(targets) == 0 {
+		return fmt.Errorf("no process matches %q", args[0])
+	}
+
+	fmt.Printf("Processes to send signal %d to:\n", number)
+	for _, p := range targets {
+		fmt.Printf("  %7d  %-14s %s\n", p.PID, p.User, p.Name)
+	}
+	if !*yes && !confirm("Type 'yes' to continue: ") {
+		return fmt.Errorf("cancelled")
+	}
+
+	root := isShellRoot(deviceID)
+	failed := 0
+	for _, p := range targets {
+		if err := signalProcess(deviceID, p, number, root); err != nil {
+			color.New(color.FgRed).Printf("  %d %s: %v\n", p.PID, p.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("  signalled %d %s\n", p.PID, p.Name)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d process(es) could not be signalled", failed, len(targets))
+	}
+	return nil
+}
+
+func parseSignal(signal string) (int, error) {
+	if n, err := strconv.Atoi(signal); err == nil && n > 0 && n < 65 {
+		return n, nil
+	}
+	if n, ok := signalNumbers[strings.TrimPrefix(strings.ToUpper(signal), "SIG")]; ok {
+		return n, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", signal)
+}
+
+// matchProcesses resolves a pid, or a process or package name: exact name
+// matches win, otherwise the package's own processes ("pkg" and "pkg:remote")
+// and finally processes whose name contains it.
+func matchProcesses(processes []processInfo, target string) []processInfo {
+	if pid, err := strconv.Atoi(target); err == nil {
+		for _, p := range processes {
+			if p.PID == pid {
+				return []processInfo{p}
+			}
+		}
+		return nil
+	}
+	var exact, pkg, partial []processInfo
+	for _, p := range processes {
+		switch {
+		case p.Name == target || strings.HasSuffix(p.Name, "/"+target):
+			exact = append(exact, p)
+		case strings.HasPrefix(p.Name, target+":"):
+			pkg = append(pkg, p)
+		case strings.Contains(p.Name, target):
+			partial = append(partial, p)
+		}
+	}
+	if len(exact) > 0 {
+		return append(exact, pkg...)
+	}
+	if len(pkg) > 0 {
+		return pkg
+	}
+	return partial
+}
+
+// signalProcess sends the signal as the shell user, which may signal its own
+// processes (and everything when adbd runs as root), falling back to run-as
+// for processes of debuggable apps.
+func signalProcess(deviceID string, p processInfo, signal int, root bool) error {
+	pid := strconv.Itoa(p.PID)
+	output, err := adbShell(deviceID, "kill", "-"+strconv.Itoa(signal), pid)
+	if err == nil && !strings.Contains(output, "denied") && !strings.Contains(output, "not permitted") {
+		return nil
+	}
+	if !root && appUserPattern.MatchString(p.User) {
+		pkg, _, _ := strings.Cut(p.Name, ":")
+		runAs, runAsErr := adbShell(deviceID, "run-as", pkg, "kill", "-"+strconv.Itoa(signal), pid)
+		if runAsErr == nil && strings.TrimSpace(runAs) == "" {
+			return nil
+		}
+		return fmt.Errorf("permission denied; for a release app use 'am force-stop %s', otherwise run 'adb root'", pkg)
+	}
+	if output == "" && err != nil {
+		return err
+	}
+	return fmt.Errorf("%s", strings.TrimSpace(output))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// kioskStateFile remembers the launcher that was active before `kiosk set`
+// replaced it, so `kiosk clear` can restore it from any host.
+const kioskStateFile = "/data/local/tmp/adbctl-kiosk-home"
+
+var taskIDPattern = regexp.MustCompile(`(?:taskId|id)=(\d+)`)
+
+func runKiosk(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: kiosk set <package> [--mode pin|home] | kiosk clear")
+	}
+	switch args[0] {
+	case "set":
+		fs := flag.NewFlagSet("kiosk set", flag.ExitOnError)
+		mode := fs.String("mode", "auto", "Kiosk mechanism: auto, pin (screen pinning) or home (persistent launcher)")
+		fs.Parse(args[1:])
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: kiosk set <package> [--mode pin|home]")
+		}
+		return kioskSet(pickDevice(), fs.Arg(0), *mode)
+	case "clear":
+		return kioskClear(pickDevice())
+	default:
+		return fmt.Errorf("unknown kiosk action %q", args[0])
+	}
+}
+
+func kioskSet(deviceID, pkg, mode string) error {
+	activity, err := resolveLauncherActivity(deviceID, pkg)
+	if err != nil {
+		return err
+	}
+
+	if mode == "auto" || mode == "pin" {
+		err := pinApp(deviceID, pkg)
+		if err == nil {
+			fmt.Printf("%s is pinned to the screen. Run 'adbctl kiosk clear' to unpin.\n", pkg)
+			return nil
+		}
+		if mode == "pin" {
+			return err
+		}
+		fmt.Printf("Screen pinning unavailable (%v), falling back to persistent launcher.\n", err)
+	}
+
+	if current := resolveHomeActivity(deviceID); current != "" && current != activity {
+		adbShell(deviceID, "echo", shellQuote(current), ">", kioskStateFile)
+	}
+	if _, err := adbShell(deviceID, "cmd", "package", "set-home-activity", shellQuote(activity)); err != nil {
+		return fmt.Errorf("failed to make %s the home app: %v", activity, err)
+	}
+	adbShell(deviceID, "input", "keyevent", "KEYCODE_HOME")
+	fmt.Printf("%s is now the home app. Run 'adbctl kiosk clear' to restore the previous launcher.\n", pkg)
+	return nil
+}
+
+func pinApp(deviceID, pkg string) error {
+	adbShell(deviceID, "settings", "put", "system", "lock_to_app_enabled", "1")
+	if _, err := adbShell(deviceID, "monkey", "-p", shellQuote(pkg), "-c", "android.intent.category.LAUNCHER", "1"); err != nil {
+		return fmt.Errorf("failed to launch %s: %v", pkg, err)
+	}
+	time.Sleep(2 * time.Second)
+
+	taskID := findTaskID(deviceID, pkg)
+	if taskID == "" {
+		return fmt.Errorf("could not find a task for %s", pkg)
+	}
+	output, err := adbShell(deviceID, "am", "task", "lock", taskID)
+	if err != nil || strings.Contains(output, "Error") || strings.Contains(output, "Unknown") {
+		return fmt.Errorf("am task lock failed: %s", output)
+	}
+	return nil
+}
+
+func findTaskID(deviceID, pkg string) string {
+	for _, query := range [][]string{{"am", "stack", "list"}, {"cmd", "activity", "stack", "list"}} {
+		output, err := adbShellIdempotent(deviceID, query...)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if !strings.Contains(line, pkg+"/") {
+				continue
+			}
+			if m := taskIDPattern.FindStringSubmatch(line); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+func kioskClear(deviceID string) error {
+	adbShell(deviceID, "am", "task", "lock", "stop")
+	adbShell(deviceID, "settings", "put", "system", "lock_to_app_enabled", "0")
+
+	previous, err := adbShellIdempotent(deviceID, "cat", kioskStateFile)
+	if err == nil && previous != "" {
+		if _, err := adbShell(deviceID, "cmd", "package", "set-home-activity", shellQuote(previous)); err != nil {
+			return fmt.Errorf("failed to restore launcher %s: %v", previous, err)
+		}
+		adbShell(deviceID, "rm", kioskStateFile)
+		fmt.Printf("Restored launcher %s.\n", previous)
+	}
+	adbShell(deviceID, "input", "keyevent", "KEYCODE_HOME")
+	fmt.Println("Kiosk mode cleared.")
+	return nil
+}
+
+// resolveLauncherActivity returns the package/activity component started from the launcher.
+func resolveLauncherActivity(deviceID, pkg string) (string, error) {
+	activity := resolveActivity(deviceID, "-c", "android.intent.category.LAUNCHER", shellQuote(pkg))
+	if activity == "" {
+		return "", fmt.Errorf("no launcher activity found for %s", pkg)
+	}
+	return activity, nil
+}
+
+func resolveHomeActivity(deviceID string) string {
+	return resolveActivity(deviceID, "-a", "android.intent.action.MAIN", "-c", "android.intent.category.HOME")
+}
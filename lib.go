@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// deviceLib holds the helper scripts that probes run on the device. Keeping
+// multi-step collection in real shell files instead of command strings built
+// in Go avoids quoting bugs and gathers everything in one round trip.
+//
+//go:embed devicelib/*.sh
+var deviceLib embed.FS
+
+// libDir is where `lib push` installs the scripts on the device.
+const libDir = "/data/local/tmp/adbctl"
+
+var (
+	// libInstalled caches, per device, whether the current scripts are there.
+	libInstalled   = map[string]bool{}
+	libInstalledMu sync.Mutex
+)
+
+// libScripts returns the bundled script names, sorted.
+func libScripts() []string {
+	entries, _ := fs.ReadDir(deviceLib, "devicelib")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// libVersion identifies the bundled scripts; a device with another VERSION
+// file gets them pushed again.
+func libVersion() string {
+	hash := sha256.New()
+	for _, name := range libScripts() {
+		data, _ := deviceLib.ReadFile("devicelib/" + name)
+		fmt.Fprintf(hash, "%s\x00%s\x00", name, data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// installedLibVersion returns the VERSION on the device, or "".
+func installedLibVersion(deviceID string) string {
+	version, err := adbShellIdempotent(deviceID, "cat", libDir+"/VERSION", "2>/dev/null")
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+// pushLib installs the scripts, replacing whatever version is there.
+func pushLib(deviceID string) error {
+	tmp, err := os.MkdirTemp("", "adbctl-lib")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	local := filepath.Join(tmp, path.Base(libDir))
+	if err := os.Mkdir(local, 0755); err != nil {
+		return err
+	}
+	for _, name := range libScripts() {
+		data, _ := deviceLib.ReadFile("devicelib/" + name)
+		if err := os.WriteFile(filepath.Join(local, name), data, 0755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(local, "VERSION"), []byte(libVersion()+"\n"), 0644); err != nil {
+		return err
+	}
+	if _, err := adbShell(deviceID, "rm", "-rf", libDir); err != nil {
+		return err
+	}
+	if err := adbPush(deviceID, local, path.Dir(libDir)); err != nil {
+		return err
+	}
+	if _, err := adbShell(deviceID, "chmod", "755", libDir+"/*.sh"); err != nil {
+		return err
+	}
+	libInstalledMu.Lock()
+	libInstalled[deviceID] = true
+	libInstalledMu.Unlock()
+	return nil
+}
+
+// ensureLib pushes the scripts when the device lacks the current version,
+// once per device and process. It reports whether they are installed.
+func ensureLib(deviceID string) bool {
+	libInstalledMu.Lock()
+	installed, checked := libInstalled[deviceID]
+	libInstalledMu.Unlock()
+	if checked {
+		return installed
+	}
+	installed = installedLibVersion(deviceID) == libVersion()
+	if !installed {
+		if err := pushLib(deviceID); err != nil {
+			debugPrint("lib push %s: %v\n", deviceID, err)
+		} else {
+			installed = true
+		}
+	}
+	libInstalledMu.Lock()
+	libInstalled[deviceID] = installed
+	libInstalledMu.Unlock()
+	return installed
+}
+
+// runLibScript runs one of the helper scripts and returns its output. When
+// the scripts can't be installed (a full or read-only /data/local/tmp), the
+// script is fed to the device shell on stdin instead, so probes work either
+// way.
+func runLibScript(deviceID, name string, timeout time.Duration, args ...string) (string, error) {
+	script, err := deviceLib.ReadFile("devicelib/" + name)
+	if err != nil {
+		return "", fmt.Errorf("no helper script %q (have %s)", name, strings.Join(libScripts(), ", "))
+	}
+	timeout = scaledTimeout(deviceID, timeout)
+	installed := ensureLib(deviceID)
+	output, err := adbRetry.run(deviceID, func() ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if installed {
+			return exec.CommandContext(ctx, adbPath, append([]string{"-s", deviceID, "shell", "sh", libDir + "/" + name}, args...)...).CombinedOutput()
+		}
+		cmd := exec.CommandContext(ctx, adbPath, append([]string{"-s", deviceID, "shell", "sh", "-s"}, args...)...)
+		cmd.Stdin = strings.NewReader(string(script))
+		return cmd.CombinedOutput()
+	})
+	text := strings.ReplaceAll(string(output), "\r", "")
+	if err != nil {
+		return text, normalizeAdbError(text, fmt.Errorf("%s: %v", name, err))
+	}
+	return text, nil
+}
+
+// libSections splits script output into its "## name" sections.
+func libSections(output string) map[string]string {
+	sections := map[string]string{}
+	var name string
+	var body []string
+	flush := func() {
+		if name != "" {
+			sections[name] = strings.Join(body, "\n")
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if header, found := strings.CutPrefix(line, "## "); found {
+			flush()
+			name, body = strings.TrimSpace(header), nil
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	return sections
+}
+
+// libValues reads the key=value lines of a section.
+func libValues(section string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(section, "\n") {
+		if key, value, found := strings.Cut(line, "="); found {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+func runLib(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: lib push [--all] | status | list | run <script> [args...] | remove")
+	}
+	switch args[0] {
+	case "list":
+		fmt.Printf("Helper scripts (version %s), installed to %s:\n", libVersion(), libDir)
+		for _, name := range libScripts() {
+			data, _ := deviceLib.ReadFile("devicelib/" + name)
+			description := ""
+			for _, line := range strings.Split(string(data), "\n") {
+				if comment, found := strings.CutPrefix(line, "# "+name+": "); found {
+					description = comment
+				}
+			}
+			fmt.Printf("  %-12s %s\n", name, description)
+		}
+		return nil
+	case "push":
+		fs := flag.NewFlagSet("lib push", flag.ExitOnError)
+		all := fs.Bool("all", false, "Install on every connected device")
+		parseArgs(fs, args[1:])
+		serials := []string{}
+		if *all {
+			var skipped []fleetResult
+			serials, skipped = fleetDevices()
+			for _, s := range skipped {
+				color.New(color.FgYellow).Printf("%s: skipped (%s)\n", s.Device, s.Reason)
+			}
+		} else {
+			serials = append(serials, pickDevice())
+		}
+		failed := 0
+		for _, serial := range serials {
+			if err := pushLib(serial); err != nil {
+				color.New(color.FgRed).Printf("%s: %v\n", serial, err)
+				failed++
+				continue
+			}
+			fmt.Printf("%s: installed %d scripts (version %s) in %s\n", serial, len(libScripts()), libVersion(), libDir)
+		}
+		if failed > 0 {
+			return fmt.Errorf("lib push failed on %d of %d device(s)", failed, len(serials))
+		}
+		return nil
+	case "status":
+		deviceID := pickDevice()
+		switch installed := installedLibVersion(deviceID); installed {
+		case libVersion():
+			fmt.Printf("Installed in %s: version %s (current)\n", libDir, installed)
+		case "":
+			fmt.Printf("Not installed; probes install it on first use, or run 'adbctl lib push'.\n")
+		default:
+			fmt.Printf("Installed in %s: version %s, this adbctl bundles %s; probes update it on first use.\n", libDir, installed, libVersion())
+		}
+		return nil
+	case "run":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: lib run <script> [args...]")
+		}
+		output, err := runLibScript(pickDevice(), args[1], time.Minute, args[2:]...)
+		fmt.Print(output)
+		return err
+	case "remove":
+		deviceID := pickDevice()
+		if _, err := adbShell(deviceID, "rm", "-rf", libDir); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", libDir)
+		return nil
+	}
+	return fmt.Errorf("unknown lib action %q (want push, status, list, run or remove)", args[0])
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Throughput thresholds in MB/s used to classify a link. USB 2.0 adb tops out
+// around 30 MB/s in practice; wireless adb rarely exceeds 10 MB/s.
+const (
+	usb3MinThroughput      = 60.0
+	wifiGoodMinThroughput  = 3.0
+	wifiGoodMaxLatencyMsec = 30.0
+)
+
+type linkSample struct {
+	Latency    time.Duration
+	Throughput float64 // MB/s
+}
+
+func runLink(args []string) error {
+	fs := flag.NewFlagSet("link", flag.ExitOnError)
+	rounds := fs.Int("rounds", 5, "Number of measurement rounds")
+	size := fs.Int("size", 8, "MB transferred per throughput sample")
+	parseArgs(fs, args)
+	if *rounds < 1 || *size < 1 {
+		return fmt.Errorf("--rounds and --size must be positive")
+	}
+
+	deviceID := pickDevice()
+	color.New(color.FgCyan, color.Bold).Printf("Link quality for %s\n", deviceID)
+	fmt.Print(rule("=", 40))
+
+	var samples []linkSample
+	for i := 0; i < *rounds; i++ {
+		sample, err := measureLink(deviceID, *size)
+		if err != nil {
+			return err
+		}
+		samples = append(samples, sample)
+		fmt.Printf("Round %d: latency %v, throughput %.1f MB/s\n", i+1, sample.Latency.Round(time.Millisecond), sample.Throughput)
+	}
+
+	latency, throughput := medianLink(samples)
+	class := classifyLink(deviceID, latency, throughput)
+	fmt.Println()
+	color.New(color.FgGreen).Printf("%-20s : ", "Median Latency")
+	fmt.Println(latency.Round(time.Millisecond))
+	color.New(color.FgGreen).Printf("%-20s : ", "Median Throughput")
+	fmt.Printf("%.1f MB/s\n", throughput)
+	color.New(color.FgGreen).Printf("%-20s : ", "Link")
+	fmt.Println(class)
+
+	if class == "WiFi-poor" {
+		color.New(color.FgYellow, color.Bold).Println("\nWARNING: wireless link is slow or laggy.")
+		fmt.Println("  Screen recording and large pushes or installs will be painful;")
+		fmt.Println("  move closer to the access point, use 5 GHz, or connect over USB.")
+	}
+	return nil
+}
+
+// measureLink times a trivial shell round trip, then streams size MB of zeros
+// from the device to measure throughput.
+func measureLink(deviceID string, size int) (linkSample, error) {
+	start := time.Now()
+	if _, err := adbShellIdempotent(deviceID, "true"); err != nil {
+		return linkSample{}, err
+	}
+	latency := time.Since(start)
+
+	var counter byteCounter
+	start = time.Now()
+	if err := adbExecOut(deviceID, &counter, fmt.Sprintf("dd if=/dev/zero bs=1048576 count=%d 2>/dev/null", size)); err != nil || counter == 0 {
+		return linkSample{}, fmt.Errorf("throughput test failed: %v", err)
+	}
+	elapsed := time.Since(start).Seconds()
+	return linkSample{latency, float64(counter) / 1048576 / elapsed}, nil
+}
+
+// byteCounter is an io.Writer that only counts what is written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
+
+func medianLink(samples []linkSample) (time.Duration, float64) {
+	latencies := make([]time.Duration, len(samples))
+	throughputs := make([]float64, len(samples))
+	for i, s := range samples {
+		latencies[i], throughputs[i] = s.Latency, s.Throughput
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	sort.Float64s(throughputs)
+	return latencies[len(latencies)/2], throughputs[len(throughputs)/2]
+}
+
+// classifyLink uses the serial to tell wireless from USB (ip:port and mDNS
+// serials are TCP transports) and throughput to tell USB generations apart.
+func classifyLink(deviceID string, latency time.Duration, throughput float64) string {
+	if isWirelessSerial(deviceID) {
+		if throughput >= wifiGoodMinThroughput && float64(latency.Milliseconds()) <= wifiGoodMaxLatencyMsec {
+			return "WiFi-good"
+		}
+		return "WiFi-poor"
+	}
+	if throughput >= usb3MinThroughput {
+		return "USB3"
+	}
+	return "USB2"
+}
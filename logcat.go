@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var deviceLabelColors = []color.Attribute{color.FgCyan, color.FgMagenta, color.FgYellow, color.FgGreen, color.FgBlue, color.FgRed}
+
+func runLogcat(args []string) error {
+	fs := flag.NewFlagSet("logcat", flag.ExitOnError)
+	allDevices := fs.Bool("all-devices", false, "Tail every connected device concurrently")
+	merge := fs.Bool("merge", false, "Print all devices into one stream, prefixed with a device label")
+	saveDir := fs.String("save", "", "Also write each device's log to <dir>/<serial>.log")
+	continueOnError := fs.Bool("continue-on-error", false, "Keep tailing the other devices when one stream fails")
+	resultsPath := fs.String("results", "", "With --all-devices, write per-device results as JSON to this file")
+	packageName := fs.String("package", "", "Only show this app's log (by its current process id)")
+	flavor := fs.String("flavor", "", "Only show the log of this flavor's package from the project profile")
+	unity := fs.Bool("unity", false, "Unity games: show Unity, IL2CPP and native crash tags and fold stack traces")
+	symbolsDir := fs.String("symbols", "", "With --unity, symbolize il2cpp/libunity crash frames using this symbols directory")
+	var filters multiFlag
+	fs.Var(&filters, "filter", "Per-device filter spec as serial=spec (e.g. emulator-5554='MyApp:V *:S'); repeatable")
+	filterSpec := parseArgs(fs, args)
+
+	perDevice := make(map[string][]string)
+	for _, f := range filters {
+		serial, spec, found := strings.Cut(f, "=")
+		if !found {
+			return fmt.Errorf("invalid --filter %q, expected serial=spec", f)
+		}
+		perDevice[serial] = strings.Fields(spec)
+	}
+
+	var symbols *symbolizer
+	if *unity {
+		if len(filterSpec) == 0 {
+			filterSpec = unityLogSpec
+		}
+		var err error
+		if symbols, err = newSymbolizer(*symbolsDir); err != nil {
+			return err
+		}
+	} else if *symbolsDir != "" {
+		return fmt.Errorf("--symbols needs --unity; use 'adbctl symbolize' for saved logs")
+	}
+
+	// Inside a project a bare `logcat` follows the project's app. Unity mode
+	// doesn't, as native crashes are reported by other processes.
+	pkg := *packageName
+	if *flavor != "" || (pkg == "" && len(filterSpec) == 0 && len(filters) == 0 && !*allDevices && currentProject() != nil) {
+		var err error
+		if pkg, err = appPackage(nil, *flavor, ""); err != nil {
+			return err
+		}
+	}
+
+	var serials []string
+	var skipped []fleetResult
+	if *allDevices {
+		serials, skipped = fleetDevices()
+		if len(serials) == 0 {
+			return fmt.Errorf("no devices connected")
+		}
+		if !*merge && *saveDir == "" {
+			return fmt.Errorf("--all-devices needs --merge and/or --save <dir>")
+		}
+	} else {
+		serials = []string{pickDevice()}
+	}
+	if *saveDir != "" {
+		if err := os.MkdirAll(*saveDir, 0755); err != nil {
+			return err
+		}
+	}
+
+	labelWidth := 0
+	for _, s := range serials {
+		labelWidth = max(labelWidth, len(s))
+	}
+
+	// Without --continue-on-error the first failing stream stops the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]fleetResult, len(serials))
+	for i, serial := range serials {
+		spec := filterSpec
+		if s, ok := perDevice[serial]; ok {
+			spec = s
+		}
+		label := color.New(deviceLabelColors[i%len(deviceLabelColors)], color.Bold).Sprintf("%-*s", labelWidth, serial)
+		showLabel := len(serials) > 1
+
+		wg.Add(1)
+		go func(i int, serial string, spec []string) {
+			defer wg.Done()
+			start := time.Now()
+			var err error
+			if pkg != "" {
+				spec, err = appendPidFilter(serial, pkg, spec)
+			}
+			if err == nil {
+				show := func(line string) {
+					if len(serials) == 1 || *merge {
+						mu.Lock()
+						if showLabel {
+							fmt.Printf("%s | %s\n", label, line)
+						} else {
+							fmt.Println(line)
+						}
+						mu.Unlock()
+					}
+				}
+				if *unity {
+					show = newUnityLogFolder(symbols, show).line
+				}
+				err = tailDevice(ctx, serial, spec, *saveDir, show)
+			}
+			results[i] = fleetResult{Device: serial, Status: "succeeded", DurationMs: time.Since(start).Milliseconds()}
+			switch {
+			case err != nil && ctx.Err() != nil:
+				results[i].Status, results[i].Reason = "skipped", "stopped after an earlier failure"
+			case err != nil:
+				results[i].Status, results[i].Reason = "failed", err.Error()
+				if !*continueOnError {
+					cancel()
+				}
+			}
+		}(i, serial, spec)
+	}
+	wg.Wait()
+	if !*allDevices {
+		if results[0].Status == "failed" {
+			return fmt.Errorf("%s: %s", serials[0], results[0].Reason)
+		}
+		return nil
+	}
+	return reportFleet(append(skipped, results...), *resultsPath)
+}
+
+// appendPidFilter limits a logcat filter spec to pkg's running process.
+func appendPidFilter(serial, pkg string, spec []string) ([]string, error) {
+	pid := strings.Fields(runAdbCommand(serial, "pidof "+pkg, 5*time.Second))
+	if len(pid) == 0 || pid[0] == "n/a" {
+		return nil, fmt.Errorf("%s is not running; start it with 'adbctl start %s'", pkg, pkg)
+	}
+	return append([]string{"--pid=" + pid[0]}, spec...), nil
+}
+
+// tailDevice streams one device's log to onLine and, when saveDir is set, to
+// <saveDir>/<serial>.log.
+func tailDevice(ctx context.Context, serial string, spec []string, saveDir string, onLine func(string)) error {
+	if saveDir == "" {
+		return streamLogcat(ctx, serial, spec, onLine)
+	}
+	f, err := os.Create(filepath.Join(saveDir, serial+".log"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return streamLogcat(ctx, serial, spec, func(line string) {
+		fmt.Fprintln(f, line)
+		onLine(line)
+	})
+}
+
+// streamLogcat runs `adb logcat` and calls onLine for every line until the
+// stream ends (device disconnect, Ctrl+C or ctx cancellation). When the
+// device drops off because adbd restarted, it waits for the device and picks
+// the log up again from the last line seen, without repeating lines.
+func streamLogcat(ctx context.Context, deviceID string, filterSpec []string, onLine func(string)) error {
+	var lastStamp string
+	seenAtStamp := map[string]bool{}
+	for {
+		args := []string{"logcat", "-v", "threadtime"}
+		resuming := lastStamp != ""
+		if resuming {
+			args = append(args, "-T", lastStamp)
+		}
+		err := adbStream(ctx, deviceID, func(line string) {
+			stamp := logcatTimestamp(line)
+			if resuming && (strings.HasPrefix(line, "--------- beginning of") || stamp != "" && (stamp < lastStamp || stamp == lastStamp && seenAtStamp[line])) {
+				return
+			}
+			if stamp != "" && stamp != lastStamp {
+				lastStamp = stamp
+				clear(seenAtStamp)
+			}
+			seenAtStamp[line] = true
+			onLine(line)
+		}, append(args, filterSpec...)...)
+		if ctx.Err() != nil || adbRetry.ReconnectWait == 0 || adbDeviceStatus(deviceID) == "device" {
+			return err
+		}
+		if !awaitDevice(deviceID, adbRetry.ReconnectWait) {
+			return err
+		}
+	}
+}
+
+// logcatTimestamp returns the "MM-DD hh:mm:ss.mmm" prefix of a threadtime
+// line, or "" for lines without one.
+func logcatTimestamp(line string) string {
+	if len(line) < 18 || line[2] != '-' || line[5] != ' ' || line[14] != '.' {
+		return ""
+	}
+	return line[:18]
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpFile configures report emailing in configDir(). The password is read
+// from the environment variable named by PasswordEnv so it never lands in the
+// config directory (which `config export` shares).
+const smtpFile = "smtp.json"
+
+type smtpConfig struct {
+	Host        string   `json:"host"`
+	Port        int      `json:"port"`
+	Username    string   `json:"username,omitempty"`
+	PasswordEnv string   `json:"password_env,omitempty"`
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+}
+
+func loadSMTPConfig() (smtpConfig, error) {
+	config := smtpConfig{Port: 587, PasswordEnv: "ADBCTL_SMTP_PASSWORD"}
+	if err := loadConfigJSON(smtpFile, &config); err != nil {
+		return config, err
+	}
+	if config.Host == "" || config.From == "" || len(config.To) == 0 {
+		return config, fmt.Errorf("email is not configured; create %s with host, port, from and to", documentPath(smtpFile))
+	}
+	return config, nil
+}
+
+// sendReportEmail sends body as text with the HTML report attached. net/smtp
+// upgrades to STARTTLS whenever the server offers it.
+func sendReportEmail(subject, body, attachmentName string, attachment []byte) error {
+	config, err := loadSMTPConfig()
+	if err != nil {
+		return err
+	}
+
+	var message bytes.Buffer
+	writer := multipart.NewWriter(&message)
+	fmt.Fprintf(&message, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		config.From, strings.Join(config.To, ", "), subject, time.Now().Format(time.RFC1123Z), writer.Boundary())
+
+	part, _ := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	part.Write([]byte(body))
+	part, _ = writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachmentName)},
+	})
+	encoded := base64.StdEncoding.EncodeToString(attachment)
+	for len(encoded) > 76 {
+		part.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	part.Write([]byte(encoded + "\r\n"))
+	writer.Close()
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, os.Getenv(config.PasswordEnv), config.Host)
+	}
+	address := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	if err := smtp.SendMail(address, auth, config.From, config.To, message.Bytes()); err != nil {
+		return fmt.Errorf("sending report to %s: %v", address, err)
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	physicalDensityPattern = regexp.MustCompile(`Physical density: (\d+)`)
+	overrideDensityPattern = regexp.MustCompile(`Override density: (\d+)`)
+)
+
+type matrixCell struct {
+	Locale   string
+	Density  string
+	Timezone string
+}
+
+// matrixSkip is returned when the device would not take a configuration, so
+// running the command under it would prove nothing.
+type matrixSkip struct{ reason string }
+
+func (e matrixSkip) Error() string { return e.reason }
+
+// matrixState is the configuration to restore after a run.
+type matrixState struct {
+	Locale   string
+	Timezone string
+	Density  string // the override density, "" when there was none
+}
+
+func (c matrixCell) String() string {
+	var parts []string
+	for _, p := range []string{c.Locale, c.Density, c.Timezone} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " / ")
+}
+
+type matrixResult struct {
+	Cell     matrixCell
+	Err      error
+	Duration time.Duration
+}
+
+func runMatrix(args []string) error {
+	if len(args) == 0 || args[0] != "run" {
+		return fmt.Errorf("usage: matrix run [--locales a,b] [--densities 1.0,1.3] [--timezones tz,tz] -- <adbctl command>")
+	}
+	fs := flag.NewFlagSet("matrix run", flag.ExitOnError)
+	locales := fs.String("locales", "", "Comma-separated locales, e.g. en-US,de-DE")
+	densities := fs.String("densities", "", "Comma-separated density scale factors, e.g. 1.0,1.3")
+	timezones := fs.String("timezones", "", "Comma-separated Olson time zones, e.g. UTC,Asia/Tokyo")
+	fs.Parse(args[1:])
+	command := fs.Args()
+	if len(command) == 0 {
+		return fmt.Errorf("no command given after --")
+	}
+
+	cells := buildMatrix(splitList(*locales), splitList(*densities), splitList(*timezones))
+	deviceID := pickDevice()
+	original := captureMatrixState(deviceID)
+	defer restoreMatrixState(deviceID, original)
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var results []matrixResult
+	for _, cell := range cells {
+		color.New(color.FgCyan, color.Bold).Printf("\n=== %s ===\n", cell)
+		start := time.Now()
+		err := applyMatrixCell(deviceID, cell)
+		if err == nil {
+			cmd := exec.Command(self, command...)
+			cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+			cmd.Env = append(os.Environ(), "ADBCTL_DEVICE="+deviceID, "ADBCTL_MATRIX_CELL="+cell.String())
+			err = cmd.Run()
+		}
+		results = append(results, matrixResult{cell, err, time.Since(start)})
+	}
+
+	failed, skipped := 0, 0
+	color.New(color.FgCyan, color.Bold).Println("\nMatrix Results")
+	fmt.Print(rule("=", 60))
+	for _, r := range results {
+		fmt.Printf("%-40s %8s  ", r.Cell, r.Duration.Round(time.Second))
+		var skip matrixSkip
+		switch {
+		case errors.As(r.Err, &skip):
+			skipped++
+			color.New(color.FgYellow).Printf("SKIP (%v)\n", r.Err)
+		case r.Err != nil:
+			failed++
+			color.New(color.FgRed).Printf("FAIL (%v)\n", r.Err)
+		default:
+			color.New(color.FgGreen).Println("PASS")
+		}
+	}
+	if failed > 0 || skipped > 0 {
+		return fmt.Errorf("%d of %d configurations failed, %d could not be applied", failed, len(results), skipped)
+	}
+	return nil
+}
+
+// buildMatrix returns the cartesian product of the given dimensions; empty
+// dimensions leave that setting untouched.
+func buildMatrix(locales, densities, timezones []string) []matrixCell {
+	orEmpty := func(values []string) []string {
+		if len(values) == 0 {
+			return []string{""}
+		}
+		return values
+	}
+	var cells []matrixCell
+	for _, l := range orEmpty(locales) {
+		for _, d := range orEmpty(densities) {
+			for _, tz := range orEmpty(timezones) {
+				cells = append(cells, matrixCell{l, d, tz})
+			}
+		}
+	}
+	return cells
+}
+
+func captureMatrixState(deviceID string) matrixState {
+	timeout := 5 * time.Second
+	locale := runAdbCommand(deviceID, "getprop persist.sys.locale", timeout)
+	if locale == "" || locale == "n/a" {
+		locale = runAdbCommand(deviceID, "getprop ro.product.locale", timeout)
+	}
+	state := matrixState{
+		Locale:   locale,
+		Timezone: runAdbCommand(deviceID, "getprop persist.sys.timezone", timeout),
+	}
+	density, _ := adbShellIdempotent(deviceID, "wm", "density")
+	if m := overrideDensityPattern.FindStringSubmatch(density); m != nil {
+		state.Density = m[1]
+	}
+	return state
+}
+
+func restoreMatrixState(deviceID string, original matrixState) {
+	fmt.Println("\nRestoring original configuration...")
+	if original.Density != "" {
+		adbShell(deviceID, "wm", "density", original.Density)
+	} else {
+		adbShell(deviceID, "wm", "density", "reset")
+	}
+	if original.Locale != "" && original.Locale != "n/a" {
+		setLocale(deviceID, original.Locale)
+	}
+	if original.Timezone != "" && original.Timezone != "n/a" {
+		setTimezone(deviceID, original.Timezone)
+	}
+}
+
+func applyMatrixCell(deviceID string, cell matrixCell) error {
+	if cell.Locale != "" {
+		if err := setLocale(deviceID, cell.Locale); err != nil {
+			return err
+		}
+	}
+	if cell.Density != "" {
+		if err := setDensityScale(deviceID, cell.Density); err != nil {
+			return err
+		}
+	}
+	if cell.Timezone != "" {
+		if err := setTimezone(deviceID, cell.Timezone); err != nil {
+			return err
+		}
+	}
+	time.Sleep(2 * time.Second) // let activities recreate after the configuration change
+	return nil
+}
+
+// setLocale switches the system locale. With root the framework is restarted
+// so the change is guaranteed. Without root only the locale list setting can
+// be written, which many builds ignore until the next reboot, so the active
+// configuration is checked and the cell skipped when it didn't change.
+func setLocale(deviceID, locale string) error {
+	if isShellRoot(deviceID) {
+		adbShell(deviceID, "setprop", "persist.sys.locale", shellQuote(locale))
+		adbShell(deviceID, "stop")
+		adbShell(deviceID, "start")
+		time.Sleep(5 * time.Second) // sys.boot_completed is still set until the framework is back down
+		if err := waitForState(deviceID, StateLauncherReady, 2*time.Minute); err != nil {
+			return err
+		}
+	} else if err := putSetting(deviceID, "system", "system_locales", locale); err != nil {
+		return err
+	}
+	if !localeActive(deviceID, locale) {
+		return matrixSkip{fmt.Sprintf("locale %s did not take effect; switching locales needs root on this device", locale)}
+	}
+	return nil
+}
+
+// localeActive reports whether locale is the first locale of the current
+// resource configuration (`am get-config`), falling back to
+// persist.sys.locale on builds without get-config.
+func localeActive(deviceID, locale string) bool {
+	output, err := adbShellIdempotent(deviceID, "am", "get-config")
+	for _, line := range strings.Split(output, "\n") {
+		if config, found := strings.CutPrefix(strings.TrimSpace(line), "config: "); found && err == nil {
+			qualifier := configLocale(locale)
+			// A single locale is a qualifier of its own; a list is written
+			// as [en-rUS,de-rDE], the first entry being the active one.
+			return strings.Contains("-"+config+"-", "-"+qualifier+"-") || strings.Contains(config, "["+qualifier+",") || strings.Contains(config, "["+qualifier+"]")
+		}
+	}
+	current, _ := adbShellIdempotent(deviceID, "getprop", "persist.sys.locale")
+	return strings.EqualFold(current, locale)
+}
+
+// configLocale writes a BCP 47 tag the way resource qualifiers do:
+// de-DE becomes de-rDE, zh-Hans-CN becomes b+zh+Hans+CN.
+func configLocale(locale string) string {
+	parts := strings.Split(locale, "-")
+	switch {
+	case len(parts) == 1:
+		return parts[0]
+	case len(parts) == 2 && len(parts[1]) == 2:
+		return parts[0] + "-r" + strings.ToUpper(parts[1])
+	}
+	return "b+" + strings.Join(parts, "+")
+}
+
+func setDensityScale(deviceID, scale string) error {
+	factor, err := strconv.ParseFloat(scale, 64)
+	if err != nil {
+		return fmt.Errorf("invalid density scale %q", scale)
+	}
+	output, _ := adbShellIdempotent(deviceID, "wm", "density")
+	m := physicalDensityPattern.FindStringSubmatch(output)
+	if m == nil {
+		return fmt.Errorf("could not read physical density: %s", output)
+	}
+	physical, _ := strconv.Atoi(m[1])
+	if output, err := adbShell(deviceID, "wm", "density", strconv.Itoa(int(float64(physical)*factor))); err != nil {
+		return fmt.Errorf("wm density failed: %s", output)
+	}
+	return nil
+}
+
+// setTimezone uses `cmd alarm set-timezone` (Android 12 and later), or the
+// persist.sys.timezone property with root, and checks that it took.
+func setTimezone(deviceID, tz string) error {
+	output, err := adbShell(deviceID, "cmd", "alarm", "set-timezone", shellQuote(tz))
+	if err != nil || strings.Contains(output, "Unknown command") || strings.Contains(output, "Exception") {
+		if !isShellRoot(deviceID) {
+			return matrixSkip{fmt.Sprintf("time zone %s: cmd alarm set-timezone is not available (Android 12+) and the shell is not root", tz)}
+		}
+		if output, err := adbShell(deviceID, "setprop", "persist.sys.timezone", shellQuote(tz)); err != nil {
+			return fmt.Errorf("failed to set time zone %s: %s", tz, output)
+		}
+		adbShell(deviceID, "am", "broadcast", "-a", "android.intent.action.TIMEZONE_CHANGED", "--es", "time-zone", shellQuote(tz))
+	}
+	if current, _ := adbShellIdempotent(deviceID, "getprop", "persist.sys.timezone"); current != tz {
+		return matrixSkip{fmt.Sprintf("time zone %s did not take effect (device reports %q)", tz, current)}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var mediaStoreURIs = map[string]string{
+	"video": "content://media/external/video/media",
+	"image": "content://media/external/images/media",
+	"audio": "content://media/external/audio/media",
+}
+
+var contentRowPattern = regexp.MustCompile(`^Row: \d+ `)
+
+var (
+	sessionStatePattern    = regexp.MustCompile(`state=PlaybackState \{state=(\d+), position=(-?\d+)`)
+	sessionMetadataPattern = regexp.MustCompile(`metadata:.*?description=(.*)$`)
+	audioOutputPattern     = regexp.MustCompile(`AUDIO_DEVICE_OUT_\w+`)
+	streamVolumePattern    = regexp.MustCompile(`volume is (\d+) in range \[(\d+)\.\.(\d+)\]`)
+)
+
+// playbackStateNames follows the PlaybackState.STATE_* constants.
+var playbackStateNames = map[string]string{
+	"0": "none", "1": "stopped", "2": "paused", "3": "playing", "4": "fast-forwarding",
+	"5": "rewinding", "6": "buffering", "7": "error", "8": "connecting",
+	"9": "skipping to previous", "10": "skipping to next", "11": "skipping to queue item",
+}
+
+type mediaSession struct {
+	Package  string
+	Active   bool
+	State    string
+	Position string
+	Metadata string
+}
+
+var (
+	hdrTypesPattern     = regexp.MustCompile(`mSupportedHdrTypes=\[([\d, ]*)\]`)
+	hdrLuminancePattern = regexp.MustCompile(`mMaxLuminance=([\d.]+)`)
+)
+
+// hdrTypeNames follows the Display.HdrCapabilities HDR_TYPE_* constants.
+var hdrTypeNames = map[string]string{
+	"1": "Dolby Vision",
+	"2": "HDR10",
+	"3": "HLG",
+	"4": "HDR10+",
+}
+
+func runMedia(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: media rescan [path] | media list [--type video|image|audio] | media caps | media status")
+	}
+	switch args[0] {
+	case "rescan":
+		devicePath := "/sdcard"
+		if len(args) > 1 {
+			devicePath = args[1]
+		}
+		if err := triggerMediaScan(pickDevice(), devicePath); err != nil {
+			return err
+		}
+		fmt.Printf("Media scan requested for %s.\n", devicePath)
+		return nil
+	case "list":
+		fs := flag.NewFlagSet("media list", flag.ExitOnError)
+		mediaType := fs.String("type", "video", "Media type: video, image or audio")
+		fs.Parse(args[1:])
+		return listMedia(pickDevice(), *mediaType)
+	case "caps":
+		deviceID := pickDevice()
+		color.New(color.FgCyan, color.Bold).Println("Media Capabilities")
+		fmt.Print(rule("=", 30))
+		for _, item := range getMediaCapabilities(deviceID) {
+			color.New(color.FgGreen).Printf("%-22s : ", item.Property)
+			fmt.Println(item.Value)
+		}
+		return nil
+	case "status":
+		return showMediaStatus(pickDevice())
+	default:
+		return fmt.Errorf("unknown media action %q", args[0])
+	}
+}
+
+func listMedia(deviceID, mediaType string) error {
+	uri, ok := mediaStoreURIs[strings.TrimSuffix(mediaType, "s")]
+	if !ok {
+		return fmt.Errorf("unknown media type %q", mediaType)
+	}
+	output, err := adbShellIdempotent(deviceID, "content", "query", "--uri", uri, "--projection", "_id:_display_name:_size:_data")
+	if err != nil {
+		return fmt.Errorf("MediaStore query failed: %s", output)
+	}
+
+	rows := parseContentRows(output)
+	color.New(color.FgCyan, color.Bold).Printf("MediaStore %s entries: %d\n", mediaType, len(rows))
+	for _, row := range rows {
+		size, _ := strconv.Atoi(row["_size"])
+		color.New(color.FgGreen).Printf("%6s  %-40s ", row["_id"], row["_display_name"])
+		fmt.Printf("%10s  %s\n", formatKB(size/1024), row["_data"])
+	}
+	return nil
+}
+
+// parseContentRows parses `content query` output ("Row: 0 a=1, b=2") into
+// maps. Values may themselves contain ", ", so fragments without "=" are
+// appended to the preceding value.
+func parseContentRows(output string) []map[string]string {
+	var rows []map[string]string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !contentRowPattern.MatchString(line) {
+			continue
+		}
+		row := make(map[string]string)
+		lastKey := ""
+		for _, part := range strings.Split(contentRowPattern.ReplaceAllString(line, ""), ", ") {
+			key, value, found := strings.Cut(part, "=")
+			if !found || strings.Contains(key, " ") {
+				if lastKey != "" {
+					row[lastKey] += ", " + part
+				}
+				continue
+			}
+			row[key] = value
+			lastKey = key
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// getMediaCapabilities reports the HDR formats the display accepts and
+// whether a Dolby Vision decoder is present.
+func getMediaCapabilities(deviceID string) []DeviceInfo {
+	timeout := 5 * time.Second
+	display := runAdbCommand(deviceID, "dumpsys display | grep -m 1 mSupportedHdrTypes", timeout)
+	types, luminance := parseHdrCapabilities(display)
+
+	dolbyDecoder := "No"
+	codecs := runAdbCommand(deviceID, "dumpsys media.player | grep -i -m 1 dolby-vision", timeout)
+	if codecs != "" && codecs != "n/a" {
+		dolbyDecoder = "Yes"
+	}
+	return []DeviceInfo{
+		{"HDR Types", types},
+		{"Max Luminance", luminance},
+		{"Dolby Vision Decoder", dolbyDecoder},
+	}
+}
+
+func parseHdrCapabilities(dump string) (string, string) {
+	m := hdrTypesPattern.FindStringSubmatch(dump)
+	if m == nil {
+		return "n/a", "n/a"
+	}
+	var names []string
+	for _, id := range strings.Split(m[1], ",") {
+		id = strings.TrimSpace(id)
+		if name, ok := hdrTypeNames[id]; ok {
+			names = append(names, name)
+		} else if id != "" {
+			names = append(names, "type "+id)
+		}
+	}
+	types := "None (SDR only)"
+	if len(names) > 0 {
+		types = strings.Join(names, ", ")
+	}
+	luminance := "n/a"
+	if l := hdrLuminancePattern.FindStringSubmatch(dump); l != nil {
+		luminance = l[1] + " nits"
+	}
+	return types, luminance
+}
+
+func showMediaStatus(deviceID string) error {
+	dump, err := adbShellIdempotent(deviceID, "dumpsys", "media_session")
+	if err != nil {
+		return err
+	}
+	sessions := parseMediaSessions(dump)
+
+	color.New(color.FgCyan, color.Bold).Println("Playback Sessions")
+	fmt.Print(rule("=", 40))
+	if len(sessions) == 0 {
+		fmt.Println("No media sessions.")
+	}
+	for _, s := range sessions {
+		c := color.New(color.FgWhite)
+		if s.Active && s.State == "playing" {
+			c = color.New(color.FgGreen, color.Bold)
+		}
+		c.Printf("%s (%s)\n", s.Package, s.State)
+		if s.Position != "" {
+			position, _ := strconv.ParseInt(s.Position, 10, 64)
+			fmt.Printf("  %-12s %s\n", "Position", (time.Duration(position) * time.Millisecond).Round(time.Second))
+		}
+		if s.Metadata != "" {
+			fmt.Printf("  %-12s %s\n", "Track", s.Metadata)
+		}
+	}
+
+	audio := runAdbCommand(deviceID, "dumpsys media.audio_flinger", 10*time.Second)
+	var outputs []string
+	for _, device := range audioOutputPattern.FindAllString(audio, -1) {
+		name := strings.ToLower(strings.TrimPrefix(device, "AUDIO_DEVICE_OUT_"))
+		if !containsString(outputs, name) {
+			outputs = append(outputs, name)
+		}
+	}
+	volume := "n/a"
+	if m := streamVolumePattern.FindStringSubmatch(runAdbCommand(deviceID, "cmd media_session volume --stream 3 --get", 5*time.Second)); m != nil {
+		volume = fmt.Sprintf("%s / %s", m[1], m[3])
+	}
+
+	color.New(color.FgCyan, color.Bold).Println("\nAudio")
+	fmt.Print(rule("=", 40))
+	color.New(color.FgGreen).Printf("%-20s : ", "Output Devices")
+	fmt.Println(strings.Join(outputs, ", "))
+	color.New(color.FgGreen).Printf("%-20s : ", "Music Volume")
+	fmt.Println(volume)
+	return nil
+}
+
+// parseMediaSessions reads the per-session blocks of `dumpsys media_session`.
+// Each block starts with a "package=" line followed by its state fields.
+func parseMediaSessions(dump string) []mediaSession {
+	var sessions []mediaSession
+	for _, line := range strings.Split(dump, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if pkg, found := strings.CutPrefix(trimmed, "package="); found {
+			sessions = append(sessions, mediaSession{Package: pkg, State: "none"})
+			continue
+		}
+		if len(sessions) == 0 {
+			continue
+		}
+		s := &sessions[len(sessions)-1]
+		if active, found := strings.CutPrefix(trimmed, "active="); found {
+			s.Active = active == "true"
+		} else if m := sessionStatePattern.FindStringSubmatch(trimmed); m != nil {
+			s.State = playbackStateNames[m[1]]
+			s.Position = m[2]
+		} else if m := sessionMetadataPattern.FindStringSubmatch(trimmed); m != nil {
+			s.Metadata = strings.TrimSuffix(strings.TrimSpace(m[1]), ", null")
+		}
+	}
+	return sessions
+}
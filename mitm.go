@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// pinningLogPattern matches what TrustManager, OkHttp and Conscrypt log when
+// an app refuses the proxy's certificate.
+var pinningLogPattern = regexp.MustCompile(`(?i)certificate pinning failure|CertPathValidatorException|Trust anchor for certification path not found|SSLHandshakeException|pin verification failed`)
+
+func runMitm(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: mitm start [--proxy host:port] | stop | status | check <package>")
+	}
+	switch args[0] {
+	case "start":
+		return runMitmStart(args[1:])
+	case "stop":
+		return runMitmStop()
+	case "status":
+		proxy, _ := getSetting(pickDevice(), "global", "http_proxy")
+		if !proxyEnabled(proxy) {
+			fmt.Println("No proxy set.")
+			return nil
+		}
+		fmt.Printf("Device proxy: %s\n", proxy)
+		return nil
+	case "check":
+		return runMitmCheck(args[1:])
+	}
+	return fmt.Errorf("unknown mitm subcommand %q (want start, stop, status or check)", args[0])
+}
+
+// runMitmStart points the device's global HTTP proxy at a proxy such as
+// mitmproxy. A proxy on this machine is reached through adb reverse, so it
+// works over USB and without knowing the machine's address.
+func runMitmStart(args []string) error {
+	fs := flag.NewFlagSet("mitm start", flag.ExitOnError)
+	proxy := fs.String("proxy", "127.0.0.1:8080", "Proxy address; a loopback address is reverse-forwarded to this machine")
+	parseArgs(fs, args)
+	host, port, err := net.SplitHostPort(*proxy)
+	if err != nil {
+		return fmt.Errorf("invalid --proxy %q: %v", *proxy, err)
+	}
+
+	deviceID := pickDevice()
+	if isLoopbackHost(host) {
+		spec := "tcp:" + port
+		if err := adbHost(deviceID, "reverse", spec, spec); err != nil {
+			return err
+		}
+		host = "127.0.0.1"
+	}
+	if err := putSetting(deviceID, "global", "http_proxy", net.JoinHostPort(host, port)); err != nil {
+		return err
+	}
+	fmt.Printf("Device traffic now goes through %s\n", *proxy)
+	fmt.Println("Install the proxy's CA certificate on the device to decrypt HTTPS; run 'adbctl mitm check <package>' to see whether an app accepts it.")
+	return nil
+}
+
+func runMitmStop() error {
+	deviceID := pickDevice()
+	proxy, _ := getSetting(deviceID, "global", "http_proxy")
+	if host, port, err := net.SplitHostPort(proxy); err == nil && isLoopbackHost(host) {
+		adbHost(deviceID, "reverse", "--remove", "tcp:"+port)
+	}
+	if err := putSetting(deviceID, "global", "http_proxy", ":0"); err != nil {
+		return err
+	}
+	fmt.Println("Device proxy cleared.")
+	return nil
+}
+
+// proxiedHost is what the check relay saw of the connections to one host.
+type proxiedHost struct {
+	Host        string
+	Connections int
+	Intercepted int // connections that carried application data after the handshake
+	BytesUp     int64
+	BytesDown   int64
+}
+
+// mitmRelay sits between the device and the proxy started with mitm start,
+// noting each CONNECT and whether the app went on to send data through the
+// proxy's certificate or aborted the handshake.
+type mitmRelay struct {
+	upstream string
+	listener net.Listener
+	mu       sync.Mutex
+	hosts    map[string]*proxiedHost
+}
+
+func runMitmCheck(args []string) error {
+	fs := flag.NewFlagSet("mitm check", flag.ExitOnError)
+	duration := fs.Duration("duration", 20*time.Second, "How long to watch the app's traffic")
+	args = parseArgs(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("usage: mitm check [package] [--duration 20s]")
+	}
+	pkg, err := appPackage(args, "", "")
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	proxy, _ := getSetting(deviceID, "global", "http_proxy")
+	if !proxyEnabled(proxy) {
+		return fmt.Errorf("no device proxy set; run 'adbctl mitm start' first")
+	}
+	relay, err := startMitmRelay(proxy)
+	if err != nil {
+		return err
+	}
+	defer relay.listener.Close()
+
+	// Route the device through the relay for the duration of the check and
+	// put the proxy back afterwards.
+	_, relayPort, _ := net.SplitHostPort(relay.listener.Addr().String())
+	spec := "tcp:" + relayPort
+	if err := adbHost(deviceID, "reverse", spec, spec); err != nil {
+		return err
+	}
+	defer adbHost(deviceID, "reverse", "--remove", spec)
+	if err := putSetting(deviceID, "global", "http_proxy", "127.0.0.1:"+relayPort); err != nil {
+		return err
+	}
+	defer putSetting(deviceID, "global", "http_proxy", proxy)
+
+	adbShell(deviceID, "am", "force-stop", shellQuote(pkg))
+	adbShell(deviceID, "logcat", "-c")
+	if _, err := startApp(deviceID, pkg); err != nil {
+		return err
+	}
+	fmt.Printf("Watching %s's traffic for %s; use the app so it talks to its servers (Ctrl+C to stop early).\n", pkg, *duration)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	select {
+	case <-ctx.Done():
+	case <-time.After(*duration):
+	}
+
+	log, _ := adbShellIdempotent(deviceID, "logcat", "-d", "-v", "threadtime")
+	var tlsErrors []string
+	for _, line := range strings.Split(log, "\n") {
+		if pinningLogPattern.MatchString(line) {
+			tlsErrors = append(tlsErrors, strings.TrimSpace(line))
+		}
+	}
+	printMitmVerdict(pkg, relay.results(), tlsErrors)
+	return nil
+}
+
+func startMitmRelay(upstream string) (*mitmRelay, error) {
+	if host, port, err := net.SplitHostPort(upstream); err == nil && isLoopbackHost(host) {
+		upstream = net.JoinHostPort("127.0.0.1", port)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	relay := &mitmRelay{upstream: upstream, listener: listener, hosts: map[string]*proxiedHost{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go relay.handle(conn)
+		}
+	}()
+	return relay, nil
+}
+
+func (r *mitmRelay) handle(client net.Conn) {
+	defer client.Close()
+	reader := bufio.NewReader(client)
+	request, err := reader.ReadString('\n')
+	if err != nil {
+		return
+	}
+	host := proxyRequestHost(request)
+	header := request
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		header += line
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+	}
+
+	upstream, err := net.DialTimeout("tcp", r.upstream, 5*time.Second)
+	if err != nil {
+		debugPrint("mitm relay: %v\n", err)
+		return
+	}
+	defer upstream.Close()
+	if _, err := io.WriteString(upstream, header); err != nil {
+		return
+	}
+	debugPrint("mitm relay: %s\n", strings.TrimSpace(request))
+
+	records := &tlsRecordCounter{}
+	hello := &tlsServerHello{}
+	downDone := make(chan int64)
+	go func() {
+		n, _ := io.Copy(io.MultiWriter(client, hello), upstream)
+		client.Close()
+		downDone <- n
+	}()
+	up, _ := io.Copy(io.MultiWriter(upstream, records), reader)
+	upstream.Close()
+	down := <-downDone
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.hosts[host]
+	if stats == nil {
+		stats = &proxiedHost{Host: host}
+		r.hosts[host] = stats
+	}
+	stats.Connections++
+	stats.BytesUp += int64(len(header)) + up
+	stats.BytesDown += down
+	// A plain HTTP request is readable by the proxy as it is. A rejected
+	// certificate ends the handshake in an alert. Under TLS 1.2 the client's
+	// Finished is a handshake record, so any application data is a request;
+	// under TLS 1.3 the Finished is itself an application-data record, so it
+	// takes a second one.
+	requests := 1
+	if hello.tls13 {
+		requests = 2
+	}
+	if !strings.HasPrefix(request, "CONNECT ") || records.notTLS || records.applicationData >= requests {
+		stats.Intercepted++
+	}
+}
+
+func (r *mitmRelay) results() []proxiedHost {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var hosts []proxiedHost
+	for _, h := range r.hosts {
+		hosts = append(hosts, *h)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Host < hosts[j].Host })
+	return hosts
+}
+
+// proxyRequestHost returns the host of "CONNECT host:443 HTTP/1.1" or
+// "GET http://host/path HTTP/1.1".
+func proxyRequestHost(request string) string {
+	fields := strings.Fields(request)
+	if len(fields) < 2 {
+		return "?"
+	}
+	target := fields[1]
+	if fields[0] == "CONNECT" {
+		if host, _, err := net.SplitHostPort(target); err == nil {
+			return host
+		}
+		return target
+	}
+	target = strings.TrimPrefix(strings.TrimPrefix(target, "http://"), "https://")
+	host, _, _ := strings.Cut(target, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// tlsRecordCounter follows the TLS record framing of a stream, counting the
+// application-data records (content type 23) it carries.
+type tlsRecordCounter struct {
+	header          []byte
+	remaining       int
+	applicationData int
+	notTLS          bool
+}
+
+func (c *tlsRecordCounter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && !c.notTLS {
+		if c.remaining > 0 {
+			skip := min(c.remaining, len(p))
+			c.remaining -= skip
+			p = p[skip:]
+			continue
+		}
+		need := min(5-len(c.header), len(p))
+		c.header = append(c.header, p[:need]...)
+		p = p[need:]
+		if len(c.header) < 5 {
+			break
+		}
+		contentType := c.header[0]
+		if contentType < 20 || contentType > 24 || c.header[1] != 3 {
+			c.notTLS = true
+			break
+		}
+		if contentType == 23 {
+			c.applicationData++
+		}
+		c.remaining = int(c.header[3])<<8 | int(c.header[4])
+		c.header = c.header[:0]
+	}
+	return n, nil
+}
+
+// tlsServerHello watches the server side of a stream for the ServerHello and
+// records whether it negotiated TLS 1.3, which is only visible in its
+// supported_versions extension.
+type tlsServerHello struct {
+	record []byte
+	done   bool
+	tls13  bool
+}
+
+func (h *tlsServerHello) Write(p []byte) (int, error) {
+	if h.done {
+		return len(p), nil
+	}
+	h.record = append(h.record, p...)
+	if len(h.record) < 5 {
+		return len(p), nil
+	}
+	length := int(h.record[3])<<8 | int(h.record[4])
+	if h.record[0] != 22 || length > 1<<14+2048 {
+		h.done = true
+		return len(p), nil
+	}
+	if len(h.record) < 5+length {
+		return len(p), nil
+	}
+	h.tls13 = serverHelloIsTLS13(h.record[5 : 5+length])
+	h.done = true
+	h.record = nil
+	return len(p), nil
+}
+
+// serverHelloIsTLS13 parses a ServerHello handshake message far enough to
+// find a supported_versions extension selecting TLS 1.3 (0x0304).
+func serverHelloIsTLS13(msg []byte) bool {
+	if len(msg) < 4 || msg[0] != 2 {
+		return false
+	}
+	// type(1) length(3) version(2) random(32)
+	pos := 4 + 2 + 32
+	if len(msg) <= pos {
+		return false
+	}
+	pos += 1 + int(msg[pos]) // session id
+	pos += 2 + 1             // cipher suite, compression method
+	if len(msg) < pos+2 {
+		return false
+	}
+	end := min(len(msg), pos+2+(int(msg[pos])<<8|int(msg[pos+1])))
+	pos += 2
+	for pos+4 <= end {
+		extType := int(msg[pos])<<8 | int(msg[pos+1])
+		extLen := int(msg[pos+2])<<8 | int(msg[pos+3])
+		pos += 4
+		if extType == 43 && extLen == 2 && pos+2 <= end {
+			return msg[pos] == 3 && msg[pos+1] == 4
+		}
+		pos += extLen
+	}
+	return false
+}
+
+func printMitmVerdict(pkg string, hosts []proxiedHost, tlsErrors []string) {
+	fmt.Println()
+	if len(hosts) == 0 {
+		color.New(color.FgYellow, color.Bold).Println("No traffic reached the proxy")
+		fmt.Println("The app sent nothing during the check or ignores the system proxy (common for Flutter, Unity and")
+		fmt.Println("native networking). Route it with a VPN-based or transparent proxy, or hook it with Frida.")
+		return
+	}
+
+	fmt.Printf("%-40s %6s %12s %10s %10s\n", "HOST", "CONNS", "INTERCEPTED", "UP", "DOWN")
+	fmt.Print(rule("-", 82))
+	pinned := 0
+	for _, h := range hosts {
+		line := fmt.Sprintf("%-40s %6d %12d %10s %10s", h.Host, h.Connections, h.Intercepted, formatKB(int((h.BytesUp+1023)/1024)), formatKB(int((h.BytesDown+1023)/1024)))
+		if h.Intercepted == 0 {
+			pinned++
+			color.New(color.FgRed).Println(line + "  likely pinned")
+		} else {
+			color.New(color.FgGreen).Println(line + "  intercepted")
+		}
+	}
+	if len(tlsErrors) > 0 {
+		fmt.Printf("\nTLS errors in the log (%d):\n", len(tlsErrors))
+		for _, line := range tlsErrors[:min(len(tlsErrors), 5)] {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+
+	fmt.Println()
+	switch {
+	case pinned == 0:
+		color.New(color.FgGreen, color.Bold).Println("Intercepted: the proxy can read this app's traffic.")
+	case pinned == len(hosts):
+		color.New(color.FgRed, color.Bold).Println("Likely pinned: the app rejected the proxy's certificate on every host.")
+	default:
+		color.New(color.FgYellow, color.Bold).Printf("Partly pinned: %d of %d host(s) rejected the proxy's certificate.\n", pinned, len(hosts))
+	}
+	if pinned > 0 {
+		fmt.Println("Apps targeting API 24+ only trust user-installed CAs when their network security config allows it,")
+		fmt.Println("so a rejection can also mean that. Otherwise bypass the pinning with Frida, for example:")
+		fmt.Printf("  objection -g %s explore --startup-command 'android sslpinning disable'\n", pkg)
+	}
+}
+
+// proxyEnabled reports whether an http_proxy setting value points anywhere;
+// "null", "" and ":0" all mean no proxy.
+func proxyEnabled(proxy string) bool {
+	proxy = strings.TrimSpace(proxy)
+	return proxy != "" && proxy != "null" && proxy != ":0"
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func runMonitor(args []string) error {
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	interval := fs.Duration("interval", 30*time.Second, "Sampling interval")
+	duration := fs.Duration("duration", 0, "Stop after this long (default: until interrupted)")
+	publish := fs.Bool("mqtt", false, "Publish samples and events to the broker in mqtt.json")
+	homeAssistant := fs.Bool("ha", false, "Also announce Home Assistant entities and accept wake/launch commands (implies --mqtt)")
+	all := fs.Bool("all", false, "Sample every connected device, including ones connected later")
+	parseArgs(fs, args)
+
+	var sink *mqttSink
+	if *publish || *homeAssistant {
+		var err error
+		if sink, err = newMQTTSink(); err != nil {
+			return err
+		}
+		defer sink.Close()
+	}
+
+	var devices []string
+	if !*all {
+		devices = []string{pickDevice()}
+	}
+	announced := map[string]bool{}
+	last := map[string]map[string]string{}
+	if *all {
+		fmt.Printf("%-22s ", "Serial")
+	}
+	fmt.Printf("%-10s %-15s %-8s %-10s %-8s %s\n", "Time", "State", "Battery", "Mem avail", "Temp", "Foreground")
+	start := time.Now()
+	for *duration == 0 || time.Since(start) < *duration {
+		if *all {
+			// Devices that disconnect stay listed so their state is published.
+			online, _ := fleetDevices()
+			for _, serial := range online {
+				if !containsString(devices, serial) {
+					devices = append(devices, serial)
+				}
+			}
+			if len(devices) == 0 {
+				fmt.Println("No devices connected; waiting.")
+			}
+		}
+		if *homeAssistant {
+			for _, deviceID := range devices {
+				if announced[deviceID] {
+					continue
+				}
+				if err := publishHADiscovery(sink, deviceID); err != nil {
+					return err
+				}
+				if err := subscribeHACommands(sink, deviceID); err != nil {
+					return err
+				}
+				announced[deviceID] = true
+			}
+		}
+
+		samples := make([]map[string]string, len(devices))
+		var wg sync.WaitGroup
+		for i, deviceID := range devices {
+			wg.Add(1)
+			go func(i int, deviceID string) {
+				defer wg.Done()
+				samples[i] = collectMonitorSample(deviceID)
+			}(i, deviceID)
+		}
+		wg.Wait()
+
+		for i, deviceID := range devices {
+			sample := samples[i]
+			if *all {
+				fmt.Printf("%-22s ", deviceID)
+			}
+			fmt.Printf("%-10s %-15s %-8s %-10s %-8s %s\n", time.Now().Format("15:04:05"), sample["state"],
+				sample["battery"], sample["mem_available"], sample["temperature"], sample["foreground"])
+			if sink != nil {
+				publishMonitorSample(sink, deviceID, sample, last[deviceID])
+			}
+			last[deviceID] = sample
+		}
+		time.Sleep(*interval)
+	}
+	return nil
+}
+
+// publishMonitorSample sends each metric to its sample topic, and state or
+// foreground changes since previous as events.
+func publishMonitorSample(sink *mqttSink, deviceID string, sample, previous map[string]string) {
+	for _, metric := range sortedKeys(sample) {
+		if err := sink.PublishSample(deviceID, metric, sample[metric]); err != nil {
+			fmt.Printf("MQTT: %v\n", err)
+			break
+		}
+	}
+	if previous == nil {
+		return
+	}
+	if sample["state"] != previous["state"] {
+		sink.PublishEvent(deviceID, "state_changed", map[string]string{"from": previous["state"], "to": sample["state"]})
+	}
+	if sample["foreground"] != previous["foreground"] {
+		sink.PublishEvent(deviceID, "foreground_changed", map[string]string{"from": previous["foreground"], "to": sample["foreground"]})
+	}
+}
+
+// collectMonitorSample reads the metrics published by monitor. Values are
+// plain strings without units so they graph directly.
+func collectMonitorSample(deviceID string) map[string]string {
+	state := probeDeviceState(deviceID)
+	samples := map[string]string{"state": state.String()}
+	if state < StateAuthorized {
+		return samples
+	}
+	timeout := 5 * time.Second
+	samples["battery"] = runAdbCommand(deviceID, "dumpsys battery | grep level | awk '{print $2}'", timeout)
+	if available, ok := parseMemInfoFields(runAdbCommand(deviceID, "cat /proc/meminfo", timeout))["MemAvailable"]; ok {
+		samples["mem_available"] = strconv.Itoa(available / 1024) // MB
+	}
+	if temp, ok := maxThermalZoneTemp(deviceID); ok {
+		samples["temperature"] = strconv.FormatFloat(temp, 'f', 1, 64)
+	}
+	pkg, _ := foregroundActivity(deviceID)
+	samples["foreground"] = pkg
+	samples["screen_on"] = strconv.FormatBool(strings.Contains(
+		runAdbCommand(deviceID, "dumpsys power | grep -m1 mWakefulness=", timeout), "Awake"))
+	if df := parseDfFields(runAdbCommand(deviceID, "df -k /data", timeout)); df != nil {
+		if free, err := strconv.Atoi(df["free_kb"]); err == nil {
+			samples["storage_free"] = strconv.Itoa(free / 1024) // MB
+		}
+	}
+	if fields := strings.Fields(runAdbCommand(deviceID, "cat /proc/uptime", timeout)); len(fields) > 0 {
+		if seconds, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			samples["uptime"] = strconv.Itoa(int(seconds))
+		}
+	}
+	if link, ok := currentWifiLink(deviceID); ok {
+		samples["wifi_rssi"] = strconv.Itoa(link.RSSI) // dBm
+	}
+	return samples
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mqttFile configures the MQTT sink in configDir(). Topics are templates:
+// {serial} is the device serial, {metric} the sample name and {event} the
+// event name. Devices can override them by serial, for example to publish
+// under the room a TV is in. As with SMTP, the password is read from an
+// environment variable.
+const mqttFile = "mqtt.json"
+
+type mqttConfig struct {
+	Broker      string `json:"broker"` // tcp://host:1883 or ssl://host:8883
+	ClientID    string `json:"client_id,omitempty"`
+	Username    string `json:"username,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"`
+	SampleTopic string `json:"sample_topic,omitempty"`
+	EventTopic  string `json:"event_topic,omitempty"`
+	Retain      bool   `json:"retain,omitempty"`
+	// Home Assistant discovery (monitor --ha).
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+	CommandTopic    string `json:"command_topic,omitempty"`
+	// Devices replaces any of the topics above for individual serials.
+	Devices map[string]mqttTopics `json:"devices,omitempty"`
+}
+
+type mqttTopics struct {
+	SampleTopic  string `json:"sample_topic,omitempty"`
+	EventTopic   string `json:"event_topic,omitempty"`
+	CommandTopic string `json:"command_topic,omitempty"`
+}
+
+// mqttSink publishes samples and events with QoS 0, reconnecting on demand.
+// It implements just enough of MQTT 3.1.1 for that, plus QoS 0 subscriptions
+// to exact topics for Home Assistant commands.
+type mqttSink struct {
+	config mqttConfig
+
+	connMu   sync.Mutex // guards conn, packetID and closed, shared with the receive loop
+	conn     net.Conn
+	packetID uint16
+	closed   bool
+
+	mu            sync.Mutex // guards subscriptions, read by the receive loop
+	subscriptions map[string]func(payload string)
+}
+
+func newMQTTSink() (*mqttSink, error) {
+	host, _ := os.Hostname()
+	config := mqttConfig{
+		ClientID:    "adbctl-" + host,
+		PasswordEnv: "ADBCTL_MQTT_PASSWORD",
+		SampleTopic: "adbctl/{serial}/{metric}",
+		EventTopic:  "adbctl/{serial}/event/{event}",
+
+		DiscoveryPrefix: "homeassistant",
+		CommandTopic:    "adbctl/{serial}/command/{command}",
+	}
+	if err := loadConfigJSON(mqttFile, &config); err != nil {
+		return nil, err
+	}
+	if config.Broker == "" {
+		return nil, fmt.Errorf("MQTT is not configured; create %s with at least a broker", documentPath(mqttFile))
+	}
+	return &mqttSink{config: config}, nil
+}
+
+// topics returns the topic templates for serial, with its overrides applied.
+func (m *mqttSink) topics(serial string) mqttTopics {
+	topics := mqttTopics{m.config.SampleTopic, m.config.EventTopic, m.config.CommandTopic}
+	override := m.config.Devices[serial]
+	if override.SampleTopic != "" {
+		topics.SampleTopic = override.SampleTopic
+	}
+	if override.EventTopic != "" {
+		topics.EventTopic = override.EventTopic
+	}
+	if override.CommandTopic != "" {
+		topics.CommandTopic = override.CommandTopic
+	}
+	return topics
+}
+
+func (m *mqttSink) sampleTopic(serial, metric string) string {
+	return strings.NewReplacer("{serial}", serial, "{metric}", metric).Replace(m.topics(serial).SampleTopic)
+}
+
+func (m *mqttSink) commandTopic(serial, command string) string {
+	return strings.NewReplacer("{serial}", serial, "{command}", command).Replace(m.topics(serial).CommandTopic)
+}
+
+// PublishSample sends value to the sample topic as plain text, which Home
+// Assistant's MQTT sensors read without a value template.
+func (m *mqttSink) PublishSample(serial, metric, value string) error {
+	return m.publish(m.sampleTopic(serial, metric), []byte(value))
+}
+
+// PublishRetained sends payload with the retain flag set regardless of the
+// configuration, as discovery messages must survive broker restarts.
+func (m *mqttSink) PublishRetained(topic string, payload []byte) error {
+	return m.write(mqttPacket(0x31, append(mqttString(topic), payload...)))
+}
+
+// Subscribe calls handler, on a separate goroutine, for every message
+// published to topic. Subscriptions are renewed after a reconnect.
+func (m *mqttSink) Subscribe(topic string, handler func(payload string)) error {
+	m.mu.Lock()
+	if m.subscriptions == nil {
+		m.subscriptions = map[string]func(string){}
+	}
+	m.subscriptions[topic] = handler
+	m.mu.Unlock()
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	if m.conn == nil {
+		return m.connect()
+	}
+	return m.subscribe(m.conn, topic)
+}
+
+func (m *mqttSink) subscribe(conn net.Conn, topic string) error {
+	m.packetID++
+	body := append([]byte{byte(m.packetID >> 8), byte(m.packetID)}, mqttString(topic)...)
+	body = append(body, 0) // QoS 0
+	if _, err := conn.Write(mqttPacket(0x82, body)); err != nil {
+		return fmt.Errorf("mqtt subscribe: %v", err)
+	}
+	return nil
+}
+
+// PublishEvent sends fields as a JSON object to the event topic.
+func (m *mqttSink) PublishEvent(serial, event string, fields map[string]string) error {
+	topic := strings.NewReplacer("{serial}", serial, "{event}", event).Replace(m.topics(serial).EventTopic)
+	payload, _ := json.Marshal(fields)
+	return m.publish(topic, payload)
+}
+
+func (m *mqttSink) publish(topic string, payload []byte) error {
+	header := byte(0x30)
+	if m.config.Retain {
+		header |= 0x01
+	}
+	return m.write(mqttPacket(header, append(mqttString(topic), payload...)))
+}
+
+// write sends a PUBLISH packet, connecting first if needed. A failed write
+// drops the connection so the next one starts afresh.
+func (m *mqttSink) write(packet []byte) error {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	if m.conn == nil {
+		if err := m.connect(); err != nil {
+			return err
+		}
+	}
+	if _, err := m.conn.Write(packet); err != nil {
+		m.conn.Close()
+		m.conn = nil
+		return fmt.Errorf("mqtt publish: %v", err)
+	}
+	return nil
+}
+
+// connect dials the broker, renews the subscriptions and starts the receive
+// loop. The caller holds connMu.
+func (m *mqttSink) connect() error {
+	u, err := url.Parse(m.config.Broker)
+	if err != nil {
+		return fmt.Errorf("invalid MQTT broker %q: %v", m.config.Broker, err)
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		conn, err = dialer.Dial("tcp", hostWithDefaultPort(u.Host, "1883"))
+	case "ssl", "tls", "mqtts":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostWithDefaultPort(u.Host, "8883"), &tls.Config{ServerName: u.Hostname()})
+	default:
+		return fmt.Errorf("unsupported MQTT broker scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return fmt.Errorf("mqtt connect: %v", err)
+	}
+
+	flags := byte(0x02) // clean session
+	payload := mqttString(m.config.ClientID)
+	if m.config.Username != "" {
+		flags |= 0x80 | 0x40
+		payload = append(payload, mqttString(m.config.Username)...)
+		payload = append(payload, mqttString(os.Getenv(m.config.PasswordEnv))...)
+	}
+	// Protocol "MQTT" level 4 (3.1.1); keep-alive 0 since samples may be sparse.
+	variable := append(mqttString("MQTT"), 4, flags, 0, 0)
+	if _, err := conn.Write(mqttPacket(0x10, append(variable, payload...))); err != nil {
+		conn.Close()
+		return fmt.Errorf("mqtt connect: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	ack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, ack); err != nil || ack[0] != 0x20 {
+		conn.Close()
+		return fmt.Errorf("mqtt connect: no CONNACK from %s", m.config.Broker)
+	}
+	if ack[3] != 0 {
+		conn.Close()
+		return fmt.Errorf("mqtt connect: broker refused the connection (code %d)", ack[3])
+	}
+	conn.SetReadDeadline(time.Time{})
+	m.mu.Lock()
+	var topics []string
+	for topic := range m.subscriptions {
+		topics = append(topics, topic)
+	}
+	m.mu.Unlock()
+	for _, topic := range topics {
+		if err := m.subscribe(conn, topic); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	go m.receive(conn)
+	m.conn = conn
+	return nil
+}
+
+// receive dispatches incoming PUBLISH packets until the connection closes,
+// then reconnects so subscriptions keep working without anything to publish.
+func (m *mqttSink) receive(conn net.Conn) {
+	defer m.reconnect(conn)
+	for {
+		header := make([]byte, 1)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		length, multiplier := 0, 1
+		for {
+			b := make([]byte, 1)
+			if _, err := io.ReadFull(conn, b); err != nil {
+				return
+			}
+			length += int(b[0]&0x7F) * multiplier
+			multiplier *= 128
+			if b[0]&0x80 == 0 {
+				break
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+		if header[0]&0xF0 != 0x30 || len(body) < 2 {
+			continue // SUBACK and anything else
+		}
+		topicLen := int(body[0])<<8 | int(body[1])
+		if len(body) < 2+topicLen {
+			continue
+		}
+		offset := 2 + topicLen
+		if header[0]&0x06 != 0 {
+			offset += 2 // packet id, only present for QoS > 0
+		}
+		m.mu.Lock()
+		handler, ok := m.subscriptions[string(body[2:2+topicLen])]
+		m.mu.Unlock()
+		if ok && offset <= len(body) {
+			handler(string(body[offset:]))
+		}
+	}
+}
+
+// reconnect replaces conn after its receive loop ended, backing off while the
+// broker is unreachable. It gives up when the sink was closed or conn was
+// already replaced, for example by a publish that noticed first. Without
+// subscriptions the next publish reconnects instead.
+func (m *mqttSink) reconnect(conn net.Conn) {
+	conn.Close()
+	m.mu.Lock()
+	subscribed := len(m.subscriptions) > 0
+	m.mu.Unlock()
+	if !subscribed {
+		m.connMu.Lock()
+		if m.conn == conn {
+			m.conn = nil
+		}
+		m.connMu.Unlock()
+		return
+	}
+	for delay := time.Second; ; delay = min(2*delay, time.Minute) {
+		m.connMu.Lock()
+		if m.conn == conn {
+			m.conn = nil
+		}
+		if m.closed || m.conn != nil {
+			m.connMu.Unlock()
+			return
+		}
+		err := m.connect()
+		m.connMu.Unlock()
+		if err == nil {
+			return
+		}
+		debugPrint("%v; retrying in %v\n", err, delay)
+		time.Sleep(delay)
+	}
+}
+
+func (m *mqttSink) Close() {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	m.closed = true
+	if m.conn != nil {
+		m.conn.Write([]byte{0xE0, 0x00}) // DISCONNECT
+		m.conn.Close()
+		m.conn = nil
+	}
+}
+
+// mqttPacket prefixes body with the fixed header and variable-length size.
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
+
+func hostWithDefaultPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMQTTPacketRemainingLength(t *testing.T) {
+	// Boundaries of the variable-length encoding, MQTT 3.1.1 section 2.2.3.
+	tests := []struct {
+		length int
+		want   []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7F}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xFF, 0x7F}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+		{2097151, []byte{0xFF, 0xFF, 0x7F}},
+		{2097152, []byte{0x80, 0x80, 0x80, 0x01}},
+	}
+	for _, tt := range tests {
+		packet := mqttPacket(0x30, make([]byte, tt.length))
+		if packet[0] != 0x30 {
+			t.Errorf("length %d: header %#x, want 0x30", tt.length, packet[0])
+		}
+		if got := packet[1 : 1+len(tt.want)]; !bytes.Equal(got, tt.want) {
+			t.Errorf("length %d: remaining length % x, want % x", tt.length, got, tt.want)
+		}
+		if len(packet) != 1+len(tt.want)+tt.length {
+			t.Errorf("length %d: packet is %d bytes, want %d", tt.length, len(packet), 1+len(tt.want)+tt.length)
+		}
+	}
+}
+
+func TestMQTTString(t *testing.T) {
+	if got, want := mqttString("MQTT"), []byte{0x00, 0x04, 'M', 'Q', 'T', 'T'}; !bytes.Equal(got, want) {
+		t.Errorf("mqttString(MQTT) = % x, want % x", got, want)
+	}
+	if got, want := mqttString(""), []byte{0x00, 0x00}; !bytes.Equal(got, want) {
+		t.Errorf("mqttString(\"\") = % x, want % x", got, want)
+	}
+	if got := mqttString(string(make([]byte, 300))); got[0] != 0x01 || got[1] != 0x2C {
+		t.Errorf("mqttString of 300 bytes has length prefix % x, want 01 2c", got[:2])
+	}
+}
+
+// fakeBroker accepts connections one at a time and answers CONNECT with a
+// successful CONNACK.
+type fakeBroker struct {
+	t        *testing.T
+	listener net.Listener
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	return &fakeBroker{t, listener}
+}
+
+func (b *fakeBroker) accept() net.Conn {
+	b.t.Helper()
+	b.listener.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := b.listener.Accept()
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	b.t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// expect reads len(want) bytes from conn and compares them with want.
+func (b *fakeBroker) expect(conn net.Conn, what string, want []byte) {
+	b.t.Helper()
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		b.t.Fatalf("%s: %v", what, err)
+	}
+	if !bytes.Equal(got, want) {
+		b.t.Fatalf("%s = % x, want % x", what, got, want)
+	}
+}
+
+var (
+	testConnect = []byte{
+		0x10, 0x17, // CONNECT, 23 bytes
+		0x00, 0x04, 'M', 'Q', 'T', 'T', 0x04, 0x02, 0x00, 0x00, // MQTT 3.1.1, clean session, no keep-alive
+		0x00, 0x0B, 'a', 'd', 'b', 'c', 't', 'l', '-', 't', 'e', 's', 't',
+	}
+	testConnack = []byte{0x20, 0x02, 0x00, 0x00}
+)
+
+func TestMQTTPublish(t *testing.T) {
+	broker := newFakeBroker(t)
+	sink := &mqttSink{config: mqttConfig{Broker: "tcp://" + broker.listener.Addr().String(), ClientID: "adbctl-test"}}
+	defer sink.Close()
+
+	errs := make(chan error, 1)
+	go func() { errs <- sink.publish("a/b", []byte("on")) }()
+	conn := broker.accept()
+	broker.expect(conn, "CONNECT", testConnect)
+	conn.Write(testConnack)
+	broker.expect(conn, "PUBLISH", []byte{0x30, 0x07, 0x00, 0x03, 'a', '/', 'b', 'o', 'n'})
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { errs <- sink.PublishRetained("a/b", []byte("{}")) }()
+	broker.expect(conn, "retained PUBLISH", []byte{0x31, 0x07, 0x00, 0x03, 'a', '/', 'b', '{', '}'})
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMQTTSubscribeRenewedAfterReconnect(t *testing.T) {
+	broker := newFakeBroker(t)
+	sink := &mqttSink{config: mqttConfig{Broker: "tcp://" + broker.listener.Addr().String(), ClientID: "adbctl-test"}}
+	defer sink.Close()
+	received := make(chan string, 1)
+
+	errs := make(chan error, 1)
+	go func() { errs <- sink.Subscribe("a/b", func(payload string) { received <- payload }) }()
+	conn := broker.accept()
+	broker.expect(conn, "CONNECT", testConnect)
+	conn.Write(testConnack)
+	broker.expect(conn, "SUBSCRIBE", []byte{0x82, 0x08, 0x00, 0x01, 0x00, 0x03, 'a', '/', 'b', 0x00})
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte{0x30, 0x07, 0x00, 0x03, 'a', '/', 'b', 'o', 'n'})
+	if got := <-received; got != "on" {
+		t.Errorf("first payload = %q, want on", got)
+	}
+
+	// The broker drops the connection; the sink comes back on its own and
+	// subscribes again, with nothing published in between.
+	conn.Close()
+	conn = broker.accept()
+	broker.expect(conn, "CONNECT after reconnect", testConnect)
+	conn.Write(testConnack)
+	broker.expect(conn, "SUBSCRIBE after reconnect", []byte{0x82, 0x08, 0x00, 0x02, 0x00, 0x03, 'a', '/', 'b', 0x00})
+	conn.Write([]byte{0x30, 0x08, 0x00, 0x03, 'a', '/', 'b', 'o', 'f', 'f'})
+	select {
+	case got := <-received:
+		if got != "off" {
+			t.Errorf("payload after reconnect = %q, want off", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message delivered after reconnect")
+	}
+}
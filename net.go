@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	netstatsIdentPattern  = regexp.MustCompile(`\buid=(-?\d+) set=\w+ tag=(0x[0-9a-f]+)`)
+	netstatsBucketPattern = regexp.MustCompile(`st=(\d+) rb=(\d+) rp=\d+ tb=(\d+)`)
+	packageUIDPattern     = regexp.MustCompile(`package:(\S+) uid:(\d+)`)
+	pingRTTPattern        = regexp.MustCompile(`= [\d.]+/([\d.]+)/[\d.]+`)
+	pingLossPattern       = regexp.MustCompile(`(\d+)% packet loss`)
+	pingAddressPattern    = regexp.MustCompile(`PING \S+ \(([\d.]+)\)`)
+)
+
+// connectivityCheck is one row of `net test`.
+type connectivityCheck struct {
+	Kind   string
+	Target string
+	OK     bool
+	Detail string
+}
+
+type uidUsage struct {
+	UID     int
+	Name    string
+	RxBytes int64
+	TxBytes int64
+}
+
+func runNet(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: net usage [--window 24h] [--top 15] | net test [--ping hosts] [--dns names] [--http urls]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("net "+action, flag.ExitOnError)
+	window := fs.Duration("window", 24*time.Hour, "Only count traffic from this far back")
+	top := fs.Int("top", 15, "Number of apps to show")
+	pingHosts := fs.String("ping", "8.8.8.8,1.1.1.1", "Comma-separated hosts to ping")
+	dnsNames := fs.String("dns", "amazon.com,google.com", "Comma-separated names to resolve")
+	httpURLs := fs.String("http", "http://connectivitycheck.gstatic.com/generate_204,https://www.amazon.com", "Comma-separated URLs for HTTP HEAD checks")
+	parseArgs(fs, args[1:])
+
+	deviceID := pickDevice()
+	switch action {
+	case "usage":
+		return showNetUsage(deviceID, *window, *top)
+	case "test":
+		return runConnectivityTest(deviceID, splitList(*pingHosts), splitList(*dnsNames), splitList(*httpURLs))
+	default:
+		return fmt.Errorf("unknown net action %q", action)
+	}
+}
+
+func showNetUsage(deviceID string, window time.Duration, top int) error {
+	output, err := adbShellIdempotent(deviceID, "dumpsys", "netstats", "detail")
+	if err != nil {
+		return err
+	}
+	// Bucket start times are device wall-clock seconds.
+	now, err := strconv.ParseInt(runAdbCommand(deviceID, "date +%s", 5*time.Second), 10, 64)
+	if err != nil {
+		now = time.Now().Unix()
+	}
+	usage := parseNetstatsUsage(output, now-int64(window.Seconds()))
+	names := packagesByUID(deviceID)
+	for i := range usage {
+		usage[i].Name = uidName(usage[i].UID, names)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].RxBytes+usage[i].TxBytes > usage[j].RxBytes+usage[j].TxBytes
+	})
+
+	color.New(color.FgCyan, color.Bold).Printf("Network usage over the last %v\n", window)
+	fmt.Print(rule("=", 70))
+	fmt.Printf("%-44s %12s %12s\n", "App", "Received", "Sent")
+	for i, u := range usage {
+		if i == top {
+			break
+		}
+		fmt.Printf("%-44s %12s %12s\n", u.Name, formatBytes(u.RxBytes), formatBytes(u.TxBytes))
+	}
+	if len(usage) == 0 {
+		fmt.Println("No per-app traffic recorded in this window.")
+	}
+	return nil
+}
+
+// runConnectivityTest runs every check from the device shell, so failures
+// point at the device's network rather than at an app.
+func runConnectivityTest(deviceID string, pingHosts, dnsNames, httpURLs []string) error {
+	var checks []connectivityCheck
+	for _, host := range pingHosts {
+		checks = append(checks, pingCheck(deviceID, host))
+	}
+	for _, name := range dnsNames {
+		checks = append(checks, dnsCheck(deviceID, name))
+	}
+	hasCurl := runAdbCommand(deviceID, "command -v curl", 5*time.Second) != "n/a"
+	for _, url := range httpURLs {
+		if !hasCurl {
+			checks = append(checks, connectivityCheck{"http", url, true, "skipped (no curl on device)"})
+			continue
+		}
+		checks = append(checks, httpCheck(deviceID, url))
+	}
+
+	color.New(color.FgCyan, color.Bold).Println("Connectivity Test")
+	fmt.Print(rule("=", 70))
+	failed := 0
+	for _, c := range checks {
+		status := color.New(color.FgGreen).Sprint("OK  ")
+		if !c.OK {
+			status = color.New(color.FgRed).Sprint("FAIL")
+			failed++
+		}
+		fmt.Printf("%s %-5s %-45s %s\n", status, c.Kind, c.Target, c.Detail)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+func pingCheck(deviceID, host string) connectivityCheck {
+	output, _ := adbShellIdempotent(deviceID, "ping", "-c", "3", "-W", "2", shellQuote(host))
+	check := connectivityCheck{Kind: "ping", Target: host}
+	loss := pingLossPattern.FindStringSubmatch(output)
+	rtt := pingRTTPattern.FindStringSubmatch(output)
+	switch {
+	case strings.Contains(output, "unknown host"):
+		check.Detail = "unknown host"
+	case loss == nil || rtt == nil || loss[1] == "100":
+		check.Detail = "no reply"
+	default:
+		check.OK = true
+		check.Detail = fmt.Sprintf("%s ms avg, %s%% loss", rtt[1], loss[1])
+	}
+	return check
+}
+
+// dnsCheck resolves name with a single ping, which fails fast with "unknown
+// host" when resolution fails; Android ships no nslookup.
+func dnsCheck(deviceID, name string) connectivityCheck {
+	start := time.Now()
+	output, _ := adbShellIdempotent(deviceID, "ping", "-c", "1", "-W", "1", shellQuote(name))
+	check := connectivityCheck{Kind: "dns", Target: name}
+	if m := pingAddressPattern.FindStringSubmatch(output); m != nil {
+		check.OK = true
+		check.Detail = fmt.Sprintf("%s (%v incl. adb)", m[1], time.Since(start).Round(time.Millisecond))
+	} else {
+		check.Detail = "resolution failed"
+	}
+	return check
+}
+
+func httpCheck(deviceID, url string) connectivityCheck {
+	output, _ := adbShell(deviceID, "curl", "-sI", "-o", "/dev/null", "-m", "10",
+		"-w", shellQuote("%{http_code} %{time_total}"), shellQuote(url))
+	check := connectivityCheck{Kind: "http", Target: url}
+	fields := strings.Fields(output)
+	if len(fields) != 2 || fields[0] == "000" {
+		check.Detail = "request failed"
+		return check
+	}
+	code, _ := strconv.Atoi(fields[0])
+	seconds, _ := strconv.ParseFloat(fields[1], 64)
+	check.OK = code < 400
+	check.Detail = fmt.Sprintf("HTTP %s in %d ms", fields[0], int(seconds*1000))
+	return check
+}
+
+// parseNetstatsUsage sums the "UID stats" history buckets that start at or
+// after since. Tagged entries (tag other than 0x0) are subsets of the untagged
+// totals and are skipped to avoid double counting.
+func parseNetstatsUsage(output string, since int64) []uidUsage {
+	byUID := map[int]*uidUsage{}
+	inSection, counting := false, false
+	var current *uidUsage
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, "stats:") {
+			inSection = trimmed == "UID stats:"
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if m := netstatsIdentPattern.FindStringSubmatch(trimmed); m != nil {
+			counting = m[2] == "0x0"
+			uid, _ := strconv.Atoi(m[1])
+			if byUID[uid] == nil {
+				byUID[uid] = &uidUsage{UID: uid}
+			}
+			current = byUID[uid]
+			continue
+		}
+		m := netstatsBucketPattern.FindStringSubmatch(trimmed)
+		if m == nil || !counting || current == nil {
+			continue
+		}
+		start, _ := strconv.ParseInt(m[1], 10, 64)
+		if start < since {
+			continue
+		}
+		rx, _ := strconv.ParseInt(m[2], 10, 64)
+		tx, _ := strconv.ParseInt(m[3], 10, 64)
+		current.RxBytes += rx
+		current.TxBytes += tx
+	}
+
+	var usage []uidUsage
+	for _, u := range byUID {
+		if u.RxBytes+u.TxBytes > 0 {
+			usage = append(usage, *u)
+		}
+	}
+	return usage
+}
+
+// packagesByUID maps each app UID to its packages; shared UIDs have several.
+func packagesByUID(deviceID string) map[int][]string {
+	names := map[int][]string{}
+	output, _ := adbShellIdempotent(deviceID, "cmd", "package", "list", "packages", "-U")
+	for _, m := range packageUIDPattern.FindAllStringSubmatch(output, -1) {
+		uid, _ := strconv.Atoi(m[2])
+		names[uid] = append(names[uid], m[1])
+	}
+	return names
+}
+
+func uidName(uid int, names map[int][]string) string {
+	switch uid {
+	case -5:
+		return "(tethering)"
+	case -4:
+		return "(removed apps)"
+	case 0:
+		return "(root)"
+	case 1000:
+		return "(system)"
+	}
+	if pkgs, ok := names[uid]; ok {
+		sort.Strings(pkgs)
+		return strings.Join(pkgs, ",")
+	}
+	return fmt.Sprintf("uid %d", uid)
+}
+
+func formatBytes(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return formatKB(int(n / 1024))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// endpointsFile stores devices paired with `adbctl pair` in configDir().
+const endpointsFile = "endpoints.json"
+
+// pairedEndpoint is a device paired for wireless debugging. The connect port
+// changes whenever wireless debugging is toggled, so it is refreshed from
+// mDNS on connect and only used as a fallback.
+type pairedEndpoint struct {
+	Host        string    `json:"host"`
+	ConnectPort string    `json:"connect_port,omitempty"`
+	Model       string    `json:"model,omitempty"`
+	PairedAt    time.Time `json:"paired_at"`
+}
+
+func (e pairedEndpoint) Address() string {
+	return net.JoinHostPort(e.Host, e.ConnectPort)
+}
+
+func loadEndpoints() ([]pairedEndpoint, error) {
+	var endpoints []pairedEndpoint
+	err := loadConfigJSON(endpointsFile, &endpoints)
+	return endpoints, err
+}
+
+func rememberEndpoint(endpoint pairedEndpoint) error {
+	endpoints, err := loadEndpoints()
+	if err != nil {
+		return err
+	}
+	for i, e := range endpoints {
+		if e.Host == endpoint.Host {
+			endpoints[i] = endpoint
+			return saveConfigJSON(endpointsFile, endpoints)
+		}
+	}
+	return saveConfigJSON(endpointsFile, append(endpoints, endpoint))
+}
+
+// runPair pairs with a device using the code from Settings > Developer
+// options > Wireless debugging > Pair device with pairing code (Android 11+),
+// or with --qr by showing a QR code for "Pair device with QR code".
+func runPair(args []string) error {
+	fs := flag.NewFlagSet("pair", flag.ExitOnError)
+	qr := fs.Bool("qr", false, "Show a QR code to scan on the device instead of typing a code")
+	timeout := fs.Duration("timeout", 2*time.Minute, "How long to wait for the device to scan the QR code")
+	args = parseArgs(fs, args)
+	if *qr {
+		return pairWithQR(*timeout)
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: pair <ip:pairing-port> <code> | pair --qr")
+	}
+	host, _, err := net.SplitHostPort(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid pairing address %q: %v", args[0], err)
+	}
+	if err := adbPair(args[0], args[1]); err != nil {
+		return err
+	}
+	return finishPairing(host)
+}
+
+func adbPair(address, code string) error {
+	output, err := exec.Command(adbPath, "pair", address, code).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Successfully paired") {
+		return normalizeAdbError(string(output), fmt.Errorf("adb pair failed: %s", strings.TrimSpace(string(output))))
+	}
+	fmt.Println(strings.TrimSpace(string(output)))
+	return nil
+}
+
+// finishPairing connects to a freshly paired host and remembers it.
+func finishPairing(host string) error {
+	endpoint := pairedEndpoint{Host: host, ConnectPort: mdnsConnectPort(host), PairedAt: time.Now()}
+	if endpoint.ConnectPort != "" {
+		if err := connectEndpoint(&endpoint); err != nil {
+			fmt.Printf("Paired, but connecting failed: %v\n", err)
+		}
+	}
+	if err := rememberEndpoint(endpoint); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %s; reconnect later with 'adbctl connect %s'\n", host, host)
+	return nil
+}
+
+// runConnect connects to a paired endpoint by host, to any ip:port, or with
+// --recent to an endpoint from the connection history.
+func runConnect(args []string) error {
+	fs := flag.NewFlagSet("connect", flag.ExitOnError)
+	recent := fs.Bool("recent", false, "Pick from recently used devices")
+	args = parseArgs(fs, args)
+	if *recent {
+		return connectRecent()
+	}
+	endpoints, err := loadEndpoints()
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		if len(endpoints) == 0 {
+			return fmt.Errorf("no paired devices; run 'adbctl pair <ip:port> <code>' first")
+		}
+		endpoint, err := chooseEndpoint(endpoints)
+		if err != nil {
+			return err
+		}
+		return connectAndRemember(endpoint)
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: connect [host | ip:port | --recent]")
+	}
+	for _, e := range endpoints {
+		if e.Host == args[0] || e.Address() == args[0] {
+			return connectAndRemember(e)
+		}
+	}
+	host, port, err := net.SplitHostPort(args[0])
+	if err != nil {
+		return fmt.Errorf("%s is not a paired device; give ip:port to connect directly", args[0])
+	}
+	endpoint := pairedEndpoint{Host: host, ConnectPort: port}
+	return connectEndpoint(&endpoint)
+}
+
+func connectAndRemember(endpoint pairedEndpoint) error {
+	if port := mdnsConnectPort(endpoint.Host); port != "" {
+		endpoint.ConnectPort = port
+	}
+	if endpoint.ConnectPort == "" {
+		return fmt.Errorf("no connect port known for %s; is wireless debugging on?", endpoint.Host)
+	}
+	if err := connectEndpoint(&endpoint); err != nil {
+		return err
+	}
+	return rememberEndpoint(endpoint)
+}
+
+// connectEndpoint runs adb connect and records the device model on success.
+func connectEndpoint(endpoint *pairedEndpoint) error {
+	address := endpoint.Address()
+	output, err := exec.Command(adbPath, "connect", address).CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "connected to") {
+		return normalizeAdbError(string(output), fmt.Errorf("adb connect %s: %s", address, strings.TrimSpace(string(output))))
+	}
+	if err := waitForState(address, StateAuthorized, 10*time.Second); err != nil {
+		return err
+	}
+	endpoint.Model = runAdbCommand(address, "getprop ro.product.model", 5*time.Second)
+	color.New(color.FgGreen, color.Bold).Printf("Connected to %s (%s)\n", address, endpoint.Model)
+	recordConnection(address)
+	return nil
+}
+
+// mdnsConnectPort looks up the current wireless debugging port of host in
+// `adb mdns services`, or returns "" when the device is not advertising.
+func mdnsConnectPort(host string) string {
+	output, err := exec.Command(adbPath, "mdns", "services").Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if !strings.Contains(line, "_adb-tls-connect._tcp") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if h, port, err := net.SplitHostPort(field); err == nil && h == host {
+				return port
+			}
+		}
+	}
+	return ""
+}
+
+func chooseEndpoint(endpoints []pairedEndpoint) (pairedEndpoint, error) {
+	if len(endpoints) == 1 {
+		return endpoints[0], nil
+	}
+	if ciMode {
+		return pairedEndpoint{}, fmt.Errorf("%d paired devices; name one", len(endpoints))
+	}
+	fmt.Println("Paired devices:")
+	for i, e := range endpoints {
+		fmt.Printf("%d. %-20s %s (paired %s)\n", i+1, e.Host, e.Model, e.PairedAt.Format("2006-01-02"))
+	}
+	fmt.Print("Enter the number of the device to connect: ")
+	input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	index := 0
+	if _, err := fmt.Sscanf(strings.TrimSpace(input), "%d", &index); err != nil || index < 1 || index > len(endpoints) {
+		return pairedEndpoint{}, fmt.Errorf("invalid selection")
+	}
+	return endpoints[index-1], nil
+}
+
+// pairWithQR shows a QR code in the same format as Android Studio. After the
+// device scans it, it advertises a pairing service named after the QR code's
+// service name over mDNS; adbctl then pairs with it using the QR password.
+func pairWithQR(timeout time.Duration) error {
+	name := "adbctl-" + randomString(6, "0123456789abcdefghijklmnopqrstuvwxyz")
+	password := randomString(10, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	code, err := encodeQR([]byte(fmt.Sprintf("WIFI:T:ADB;S:%s;P:%s;;", name, password)))
+	if err != nil {
+		return err
+	}
+	fmt.Print(code.render())
+	fmt.Println("On the device: Developer options > Wireless debugging > Pair device with QR code.")
+	fmt.Println("Waiting for the device to scan the code...")
+
+	var address string
+	err = pollUntil(timeout, time.Second, func() bool {
+		address = mdnsPairingAddress(name)
+		return address != ""
+	})
+	if err != nil {
+		return fmt.Errorf("the device did not scan the QR code: %v", err)
+	}
+	if err := adbPair(address, password); err != nil {
+		return err
+	}
+	host, _, _ := net.SplitHostPort(address)
+	// The connect service appears shortly after pairing completes.
+	pollUntil(10*time.Second, time.Second, func() bool { return mdnsConnectPort(host) != "" })
+	return finishPairing(host)
+}
+
+// mdnsPairingAddress returns the ip:port of the pairing service named name.
+func mdnsPairingAddress(name string) string {
+	output, err := exec.Command(adbPath, "mdns", "services").Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == name && strings.Contains(fields[1], "_adb-tls-pairing._tcp") {
+			return fields[len(fields)-1]
+		}
+	}
+	return ""
+}
+
+func randomString(length int, alphabet string) string {
+	b := make([]byte, length)
+	for i := range b {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b)
+}
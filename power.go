@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// stayOnAllSources keeps the screen on while powered by AC, USB or wireless charging.
+const stayOnAllSources = "7"
+
+func runPower(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: power status | power stay-awake on|off | power timeout <duration|never>")
+	}
+	deviceID := pickDevice()
+	switch args[0] {
+	case "status":
+		showPowerStatus(deviceID)
+		return nil
+	case "stay-awake":
+		if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+			return fmt.Errorf("usage: power stay-awake on|off")
+		}
+		value := "0"
+		if args[1] == "on" {
+			value = stayOnAllSources
+		}
+		if err := putSetting(deviceID, "global", "stay_on_while_plugged_in", value); err != nil {
+			return err
+		}
+		showPowerStatus(deviceID)
+		return nil
+	case "timeout":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: power timeout <duration|never>, e.g. 30m")
+		}
+		ms, err := parseScreenTimeout(args[1])
+		if err != nil {
+			return err
+		}
+		if err := putSetting(deviceID, "system", "screen_off_timeout", ms); err != nil {
+			return err
+		}
+		showPowerStatus(deviceID)
+		return nil
+	default:
+		return fmt.Errorf("unknown power action %q", args[0])
+	}
+}
+
+func parseScreenTimeout(value string) (string, error) {
+	if value == "never" {
+		return strconv.Itoa(math.MaxInt32), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return "", fmt.Errorf("invalid timeout %q, expected a duration like 30s, 10m or never", value)
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10), nil
+}
+
+func showPowerStatus(deviceID string) {
+	stayOn, _ := getSetting(deviceID, "global", "stay_on_while_plugged_in")
+	timeout, _ := getSetting(deviceID, "system", "screen_off_timeout")
+	sleep, _ := getSetting(deviceID, "secure", "sleep_timeout")
+
+	stayOnText := "off"
+	if stayOn != "0" && stayOn != "null" && stayOn != "" {
+		stayOnText = "on (" + stayOn + ")"
+	}
+
+	color.New(color.FgCyan, color.Bold).Println("Power")
+	fmt.Print(rule("=", 30))
+	rows := [][2]string{
+		{"Stay awake", stayOnText},
+		{"Screen timeout", formatMillis(timeout)},
+		{"Sleep timeout", formatMillis(sleep)},
+	}
+	for _, row := range rows {
+		color.New(color.FgGreen).Printf("%-20s : ", row[0])
+		fmt.Println(row[1])
+	}
+}
+
+func formatMillis(value string) string {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return "n/a"
+	}
+	if ms >= math.MaxInt32 || ms < 0 {
+		return "never"
+	}
+	return (time.Duration(ms) * time.Millisecond).String()
+}
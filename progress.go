@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type progressUnit int
+
+const (
+	progressBytes   progressUnit = iota // done/total are bytes; shows a rate
+	progressPercent                     // done/total are percent points
+	progressTime                        // total is a duration; done follows the clock
+	progressItems                       // done/total count files or steps
+)
+
+// progress shows that a long operation is alive: a spinner with the elapsed
+// time, or, when the total is known, a bar with an ETA. When stdout is not a
+// terminal, in CI and in screen-reader mode only the final line is printed.
+type progress struct {
+	label string
+	total int64
+	unit  progressUnit
+	done  atomic.Int64
+	start time.Time
+	live  bool
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// startProgress begins drawing progress for label; total 0 means unknown.
+func startProgress(label string, total int64, unit progressUnit) *progress {
+	p := &progress{label: label, total: total, unit: unit, start: time.Now(), stop: make(chan struct{})}
+	info, err := os.Stdout.Stat()
+	p.live = err == nil && info.Mode()&os.ModeCharDevice != 0 && !ciMode && !screenReader
+	if !p.live {
+		return p
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(150 * time.Millisecond)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				fmt.Printf("\r%s\033[K", p.render(frame))
+			}
+		}
+	}()
+	return p
+}
+
+// Add records n more units done.
+func (p *progress) Add(n int64) { p.done.Add(n) }
+
+// Set records the units done so far.
+func (p *progress) Set(n int64) { p.done.Store(n) }
+
+// Write counts bytes, so a progress can sit in an io.MultiWriter.
+func (p *progress) Write(b []byte) (int, error) {
+	p.done.Add(int64(len(b)))
+	return len(b), nil
+}
+
+// Finish stops drawing and prints the outcome with the total time taken.
+func (p *progress) Finish(err error) {
+	if p.live {
+		close(p.stop)
+		p.wg.Wait()
+		fmt.Print("\r\033[K")
+	}
+	elapsed := time.Since(p.start).Round(100 * time.Millisecond)
+	if err != nil {
+		color.New(color.FgRed).Printf("%s failed after %v\n", p.label, elapsed)
+		return
+	}
+	summary := ""
+	if p.unit == progressBytes && p.done.Load() > 0 {
+		summary = ", " + formatBytes(p.done.Load())
+	}
+	fmt.Printf("%s done in %v%s\n", p.label, elapsed, summary)
+}
+
+func (p *progress) render(frame int) string {
+	elapsed := time.Since(p.start)
+	done := p.done.Load()
+	if p.unit == progressTime {
+		done = int64(elapsed)
+	}
+	if p.total <= 0 || done > p.total {
+		line := fmt.Sprintf("%s %s  %s", spinnerFrames[frame%len(spinnerFrames)], p.label, formatElapsed(elapsed))
+		if p.unit == progressBytes && done > 0 {
+			line += fmt.Sprintf("  %s  %s/s", formatBytes(done), formatBytes(int64(float64(done)/elapsed.Seconds())))
+		}
+		return line
+	}
+
+	fraction := float64(done) / float64(p.total)
+	width := 30
+	bar := strings.Repeat("#", int(fraction*float64(width))) + strings.Repeat(" ", width-int(fraction*float64(width)))
+	line := fmt.Sprintf("[%s] %3.0f%%  %s", bar, 100*fraction, p.label)
+	switch p.unit {
+	case progressBytes:
+		line += fmt.Sprintf("  %s/s", formatBytes(int64(float64(done)/elapsed.Seconds())))
+	case progressItems:
+		line += fmt.Sprintf("  %d/%d", done, p.total)
+	}
+	if fraction > 0 {
+		remaining := time.Duration(float64(elapsed) / fraction * (1 - fraction))
+		line += "  ETA " + formatElapsed(remaining)
+	}
+	return line
+}
+
+// formatElapsed prints a duration as m:ss (or h:mm:ss).
+func formatElapsed(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectFile is the per-project profile, looked up from the working
+// directory upwards so commands run anywhere inside a checkout pick it up.
+const projectFile = ".adbctl.yaml"
+
+// projectProfile supplies defaults for app-centric commands (start, smoke,
+// logcat) inside a project.
+type projectProfile struct {
+	Package   string            `yaml:"package"`
+	Flavor    string            `yaml:"flavor"`    // default flavor
+	Flavors   map[string]string `yaml:"flavors"`   // flavor -> application id
+	Tags      []string          `yaml:"tags"`      // preferred devices, matched against serial, model, product and device name
+	Artifacts string            `yaml:"artifacts"` // relative to the project root
+
+	Root string `yaml:"-"`
+}
+
+// ignoreProject is set by -no-project.
+var ignoreProject bool
+
+var loadedProject *projectProfile
+var projectLoaded bool
+
+// currentProject returns the nearest project profile, or nil outside a
+// project. A broken profile is reported once and then ignored.
+func currentProject() *projectProfile {
+	if projectLoaded || ignoreProject {
+		return loadedProject
+	}
+	projectLoaded = true
+	path := findProjectFile()
+	if path == "" {
+		return nil
+	}
+	project, err := loadProject(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring %s: %v\n", path, err)
+		return nil
+	}
+	debugPrint("project profile %s\n", path)
+	loadedProject = project
+	return loadedProject
+}
+
+func findProjectFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, projectFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func loadProject(path string) (*projectProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	project := &projectProfile{}
+	if err := yaml.Unmarshal(data, project); err != nil {
+		return nil, err
+	}
+	if project.Flavor != "" {
+		if _, ok := project.Flavors[project.Flavor]; !ok {
+			return nil, fmt.Errorf("default flavor %q is not listed under flavors", project.Flavor)
+		}
+	}
+	project.Root = filepath.Dir(path)
+	return project, nil
+}
+
+// appPackage resolves the package an app command works on: the argument if
+// given, otherwise the project's package for flavor (or its default flavor),
+// otherwise the application id of variant (default debug) of the enclosing
+// Android Gradle project.
+func appPackage(args []string, flavor, variant string) (string, error) {
+	if len(args) > 0 {
+		if flavor != "" {
+			return "", fmt.Errorf("give either a package or --flavor, not both")
+		}
+		return args[0], nil
+	}
+	project := currentProject()
+	if project != nil && (project.Package != "" || len(project.Flavors) > 0) {
+		if flavor == "" {
+			flavor = project.Flavor
+		}
+		if flavor == "" {
+			return project.Package, nil
+		}
+		pkg, ok := project.Flavors[flavor]
+		if !ok {
+			return "", fmt.Errorf("unknown flavor %q; %s defines %s", flavor, projectFile, strings.Join(projectFlavors(project), ", "))
+		}
+		return pkg, nil
+	}
+	if flavor != "" {
+		return "", fmt.Errorf("--flavor needs flavors in a %s project profile", projectFile)
+	}
+	if module := findGradleAppModule(); module != "" {
+		if variant == "" {
+			variant = "debug"
+		}
+		return gradleApplicationID(module, variant)
+	}
+	return "", fmt.Errorf("no package given, and no %s or Android Gradle project found in this directory or its parents", projectFile)
+}
+
+func projectFlavors(project *projectProfile) []string {
+	flavors := make([]string, 0, len(project.Flavors))
+	for flavor := range project.Flavors {
+		flavors = append(flavors, flavor)
+	}
+	sort.Strings(flavors)
+	return flavors
+}
+
+// artifactPath places a default output file in the project's artifact
+// directory; outside a project (or without one configured) name is returned
+// unchanged.
+func artifactPath(name string) string {
+	project := currentProject()
+	if project == nil || project.Artifacts == "" {
+		return name
+	}
+	base := project.Artifacts
+	if !filepath.IsAbs(base) {
+		base = filepath.Join(project.Root, base)
+	}
+	if err := os.MkdirAll(base, 0755); err != nil {
+		debugPrint("artifacts: %v\n", err)
+		return name
+	}
+	return filepath.Join(base, name)
+}
+
+// artifactDir creates a new timestamped directory for a command's output.
+func artifactDir(kind string) (string, error) {
+	dir := artifactPath(fmt.Sprintf("%s-%s", kind, time.Now().Format("20060102-150405")))
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// preferTaggedDevices narrows a device list to those matching the project's
+// tags; the full list is kept when none match.
+func preferTaggedDevices(devices []string) []string {
+	project := currentProject()
+	if project == nil || len(project.Tags) == 0 {
+		return devices
+	}
+	var tagged []string
+	for _, device := range devices {
+		if deviceMatchesTags(device, project.Tags) {
+			tagged = append(tagged, device)
+		}
+	}
+	if len(tagged) == 0 {
+		return devices
+	}
+	return tagged
+}
+
+// deviceMatchesTags matches an `adb devices -l` line's serial, model, product
+// and device name against the tags, case-insensitively.
+func deviceMatchesTags(line string, tags []string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	values := []string{fields[0]}
+	for _, field := range fields[1:] {
+		for _, key := range []string{"model:", "product:", "device:"} {
+			if value, found := strings.CutPrefix(field, key); found {
+				values = append(values, strings.ReplaceAll(value, "_", " "))
+			}
+		}
+	}
+	for _, tag := range tags {
+		for _, value := range values {
+			if strings.Contains(strings.ToLower(value), strings.ToLower(tag)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var getpropLinePattern = regexp.MustCompile(`^\[(.*?)\]: \[(.*)\]$`)
+
+func runProp(args []string) error {
+	fs := flag.NewFlagSet("prop", flag.ExitOnError)
+	filter := fs.String("filter", "", "Only show properties whose name starts with this prefix")
+	output := fs.String("o", "", "Write the listed properties to this JSON file")
+	positional := parseArgs(fs, args)
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: prop list [--filter prefix] [-o file.json] | prop get <name> | prop set <name> <value>")
+	}
+
+	deviceID := pickDevice()
+	switch positional[0] {
+	case "list":
+		props, err := getAllProps(deviceID)
+		if err != nil {
+			return err
+		}
+		for name := range props {
+			if !strings.HasPrefix(name, *filter) {
+				delete(props, name)
+			}
+		}
+		if *output != "" {
+			data, _ := json.MarshalIndent(props, "", "  ")
+			if err := os.WriteFile(*output, data, 0644); err != nil {
+				return err
+			}
+			fmt.Printf("Exported %d properties to %s\n", len(props), *output)
+			return nil
+		}
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			color.New(color.FgGreen).Printf("%-50s ", name)
+			fmt.Println(props[name])
+		}
+		return nil
+	case "get":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: prop get <name>")
+		}
+		value, err := adbShellIdempotent(deviceID, "getprop", shellQuote(positional[1]))
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	case "set":
+		if len(positional) != 3 {
+			return fmt.Errorf("usage: prop set <name> <value>")
+		}
+		return setProp(deviceID, positional[1], positional[2])
+	default:
+		return fmt.Errorf("unknown prop action %q", positional[0])
+	}
+}
+
+// getAllProps returns every system property reported by getprop.
+func getAllProps(deviceID string) (map[string]string, error) {
+	output, err := adbShellIdempotent(deviceID, "getprop")
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if m := getpropLinePattern.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			props[m[1]] = m[2]
+		}
+	}
+	return props, nil
+}
+
+func setProp(deviceID, name, value string) error {
+	if strings.HasPrefix(name, "ro.") {
+		return fmt.Errorf("%s is read-only and can only be changed at boot", name)
+	}
+	// The shell user may only write debug/log properties; everything else needs adbd running as root.
+	if !strings.HasPrefix(name, "debug.") && !strings.HasPrefix(name, "log.") && !isShellRoot(deviceID) {
+		return fmt.Errorf("setting %s requires root; run 'adb root' first", name)
+	}
+	if output, err := adbShell(deviceID, "setprop", shellQuote(name), shellQuote(value)); err != nil {
+		return fmt.Errorf("setprop failed: %s", output)
+	}
+	current, _ := adbShellIdempotent(deviceID, "getprop", shellQuote(name))
+	if current != value {
+		return fmt.Errorf("%s is still %q; the property service rejected the change", name, current)
+	}
+	fmt.Printf("%s = %s\n", name, current)
+	return nil
+}
+
+func isShellRoot(deviceID string) bool {
+	uid, err := adbShellIdempotent(deviceID, "id", "-u")
+	return err == nil && uid == "0"
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var meminfoPSSPattern = regexp.MustCompile(`^\s*([\d,]+)K: (\S+) \(pid (\d+)`)
+
+type processInfo struct {
+	PID   int     `json:"pid"`
+	User  string  `json:"user"`
+	PSSKB int     `json:"pss_kb"` // 0 when dumpsys meminfo doesn't list the process
+	RSSKB int     `json:"rss_kb"`
+	CPU   float64 `json:"cpu_percent"` // -1 when ps can't report it
+	Name  string  `json:"name"`
+}
+
+func runPs(args []string) error {
+	fs := flag.NewFlagSet("ps", flag.ExitOnError)
+	sortBy := fs.String("sort", "mem", "Sort by cpu, mem, pid or name")
+	filter := fs.String("filter", "", "Only show processes whose name or user contains this")
+	top := fs.Int("top", 0, "Only show the first n processes")
+	asJSON := fs.Bool("json", false, "Print the table as JSON")
+	parseArgs(fs, args)
+	if !containsString([]string{"cpu", "mem", "pid", "name"}, *sortBy) {
+		return fmt.Errorf("--sort must be cpu, mem, pid or name")
+	}
+
+	deviceID := pickDevice()
+	processes, err := listProcesses(deviceID)
+	if err != nil {
+		return err
+	}
+	if *filter != "" {
+		needle := strings.ToLower(*filter)
+		var matched []processInfo
+		for _, p := range processes {
+			if strings.Contains(strings.ToLower(p.Name), needle) || strings.Contains(strings.ToLower(p.User), needle) {
+				matched = append(matched, p)
+			}
+		}
+		processes = matched
+	}
+	sortProcesses(processes, *sortBy)
+	if *top > 0 && len(processes) > *top {
+		processes = processes[:*top]
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(processes)
+	}
+	if len(processes) == 0 {
+		fmt.Println("No matching processes.")
+		return nil
+	}
+	color.New(color.FgCyan, color.Bold).Printf("%7s  %-14s %10s %10s %6s  %s\n", "PID", "USER", "PSS", "RSS", "CPU%", "NAME")
+	for _, p := range processes {
+		pss := "-"
+		if p.PSSKB > 0 {
+			pss = formatKB(p.PSSKB)
+		}
+		cpu := "n/a"
+		if p.CPU >= 0 {
+			cpu = fmt.Sprintf("%.1f", p.CPU)
+		}
+		fmt.Printf("%7d  %-14s %10s %10s %6s  %s\n", p.PID, p.User, pss, formatKB(p.RSSKB), cpu, p.Name)
+	}
+	return nil
+}
+
+// listProcesses combines ps (pid, user, RSS, CPU, name) with the PSS dumpsys
+// meminfo reports for app and system processes.
+func listProcesses(deviceID string) ([]processInfo, error) {
+	output, err := adbShellIdempotent(deviceID, "ps", "-A", "-o", "PID,USER,RSS,%CPU,NAME")
+	if err != nil || !strings.Contains(output, "PID") {
+		// Before Android 8 ps is toolbox's: fixed columns, no -A or -o, no CPU.
+		if output, err = adbShellIdempotent(deviceID, "ps"); err != nil {
+			return nil, err
+		}
+	}
+	processes := parsePs(output)
+	if len(processes) == 0 {
+		return nil, fmt.Errorf("could not parse the process list: %s", strings.SplitN(output, "\n", 2)[0])
+	}
+
+	pss := parseMeminfoPSS(runAdbCommand(deviceID, "dumpsys meminfo", 30*time.Second))
+	for i := range processes {
+		processes[i].PSSKB = pss[processes[i].PID]
+	}
+	return processes, nil
+}
+
+// parsePs reads ps output by its header, so both the toybox and the older
+// toolbox column layouts work. NAME is always the last column.
+func parsePs(output string) []processInfo {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+	columns := map[string]int{}
+	header := strings.Fields(lines[0])
+	for i, name := range header {
+		columns[name] = i
+	}
+	pidColumn, ok := columns["PID"]
+	if !ok {
+		return nil
+	}
+	var processes []processInfo
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < len(header) {
+			continue
+		}
+		p := processInfo{CPU: -1, Name: fields[len(fields)-1]}
+		p.PID, _ = strconv.Atoi(fields[pidColumn])
+		if i, ok := columns["USER"]; ok {
+			p.User = fields[i]
+		}
+		if i, ok := columns["RSS"]; ok {
+			p.RSSKB, _ = strconv.Atoi(fields[i])
+		}
+		if i, ok := columns["%CPU"]; ok {
+			if cpu, err := strconv.ParseFloat(fields[i], 64); err == nil {
+				p.CPU = cpu
+			}
+		}
+		if p.PID > 0 && p.Name != "ps" {
+			processes = append(processes, p)
+		}
+	}
+	return processes
+}
+
+// parseMeminfoPSS maps pid to PSS in KB from the "Total PSS by process"
+// section of dumpsys meminfo.
+func parseMeminfoPSS(output string) map[int]int {
+	pss := map[int]int{}
+	inSection := false
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Total ") {
+			inSection = strings.HasPrefix(line, "Total PSS by process")
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if m := meminfoPSSPattern.FindStringSubmatch(line); m != nil {
+			kb, _ := strconv.Atoi(strings.ReplaceAll(m[1], ",", ""))
+			pid, _ := strconv.Atoi(m[3])
+			pss[pid] = kb
+		}
+	}
+	return pss
+}
+
+func sortProcesses(processes []processInfo, by string) {
+	sort.SliceStable(processes, func(i, j int) bool {
+		a, b := processes[i], processes[j]
+		switch by {
+		case "cpu":
+			return a.CPU > b.CPU
+		case "pid":
+			return a.PID < b.PID
+		case "name":
+			return a.Name < b.Name
+		}
+		// PSS where dumpsys knows it; RSS for everything else.
+		return processMemoryKB(a) > processMemoryKB(b)
+	})
+}
+
+func processMemoryKB(p processInfo) int {
+	if p.PSSKB > 0 {
+		return p.PSSKB
+	}
+	return p.RSSKB
+}
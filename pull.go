@@ -0,0 +1,148 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tarExitMarker follows the archive on the stream with tar's exit status;
+// exec-out has no other way to report it.
+const tarExitMarker = "adbctl-tar-exit:"
+
+// runPull copies files from the device. With --compress the device packs
+// them with tar and gzip and streams the archive over exec-out, which is far
+// faster for log directories over wireless adb; it is unpacked locally.
+// Bugreports and adb backup streams are already compressed by adb, so there
+// is no --compress for them.
+func runPull(args []string) error {
+	fs := flag.NewFlagSet("pull", flag.ExitOnError)
+	compress := fs.Bool("compress", false, "gzip on the device and unpack locally")
+	positional := parseArgs(fs, args)
+	if len(positional) < 1 || len(positional) > 2 {
+		return fmt.Errorf("usage: pull <remote path> [local dir] [--compress]")
+	}
+	remote, local := positional[0], "."
+	if len(positional) == 2 {
+		local = positional[1]
+	}
+
+	deviceID := pickDevice()
+	start := time.Now()
+	if !*compress {
+		// A single file is streamed so its bytes can be counted; adb pull
+		// copies directories, with a spinner.
+		if size := remoteFileSize(deviceID, remote); size >= 0 {
+			p := startProgress("Pulling "+remote, size, progressBytes)
+			err := adbPullFile(deviceID, remote, local, size, p)
+			p.Finish(err)
+			return err
+		}
+		p := startProgress("Pulling "+remote, 0, progressTime)
+		err := adbPull(deviceID, remote, local)
+		p.Finish(err)
+		return err
+	}
+
+	if err := os.MkdirAll(local, 0755); err != nil {
+		return err
+	}
+	p := startProgress("Pulling "+remote+" (compressed)", 0, progressBytes)
+	transferred, extracted, err := pullCompressed(deviceID, remote, local, p)
+	p.Finish(err)
+	if err != nil {
+		return err
+	}
+	ratio := 0.0
+	if extracted > 0 {
+		ratio = 100 * float64(transferred) / float64(extracted)
+	}
+	fmt.Printf("Pulled %s to %s in %v: %s transferred for %s (%.0f%%)\n", remote,
+		filepath.Join(local, path.Base(remote)), time.Since(start).Round(time.Millisecond),
+		formatBytes(transferred), formatBytes(extracted), ratio)
+	return nil
+}
+
+// pullCompressed streams remote as a .tar.gz and unpacks it into local,
+// returning the compressed and uncompressed byte counts.
+func pullCompressed(deviceID, remote, local string, p *progress) (int64, int64, error) {
+	pipeReader, writer := io.Pipe()
+	defer pipeReader.Close()
+	var counter byteCounter
+	go func() {
+		writer.CloseWithError(adbExecOutWithin(fastTransport(deviceID), pullBudget(deviceID, remote), io.MultiWriter(writer, &counter, p),
+			fmt.Sprintf("tar -czf - -C %s %s 2>/dev/null; echo %s$?", shellQuote(path.Dir(remote)), shellQuote(path.Base(remote)), tarExitMarker)))
+	}()
+
+	// gzip reads a bufio.Reader without buffering ahead, so the exit status
+	// after the archive is still there once it is unpacked.
+	reader := bufio.NewReader(pipeReader)
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return 0, 0, fmt.Errorf("the device did not send a gzip stream (does its tar support -z?); pull without --compress: %v", err)
+	}
+	gz.Multistream(false)
+	root := filepath.Clean(local)
+	tr := tar.NewReader(gz)
+	var extracted int64
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			io.Copy(io.Discard, gz)
+			return int64(counter), extracted, tarExitStatus(reader, remote)
+		}
+		if err != nil {
+			return int64(counter), extracted, err
+		}
+		target := filepath.Join(root, filepath.FromSlash(header.Name))
+		if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return int64(counter), extracted, fmt.Errorf("refusing to extract %q outside %s", header.Name, local)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return int64(counter), extracted, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return int64(counter), extracted, err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0777|0600)
+			if err != nil {
+				return int64(counter), extracted, err
+			}
+			n, err := io.Copy(f, tr)
+			f.Close()
+			extracted += n
+			if err != nil {
+				return int64(counter), extracted, err
+			}
+			os.Chtimes(target, header.ModTime, header.ModTime)
+		}
+	}
+}
+
+// tarExitStatus reads the exit status the device printed after the archive.
+func tarExitStatus(r io.Reader, remote string) error {
+	trailer, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	status, found := strings.CutPrefix(strings.TrimSpace(string(trailer)), tarExitMarker)
+	if !found {
+		return fmt.Errorf("the device did not report whether tar succeeded; the copy of %s may be incomplete", remote)
+	}
+	if code, _ := strconv.Atoi(status); code != 0 {
+		return fmt.Errorf("tar exited with status %s on the device; the copy of %s may be incomplete (are all files readable?)", status, remote)
+	}
+	return nil
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A minimal QR code encoder (byte mode, error correction level L, versions
+// 1-6) for rendering short payloads such as wireless debugging pairing codes
+// in the terminal, following ISO/IEC 18004.
+
+// qrVersions lists, per version, the total codewords, EC codewords per block,
+// number of blocks and alignment pattern centres at error correction level L.
+var qrVersions = []struct {
+	Total, ECPerBlock, Blocks int
+	Alignment                 []int
+}{
+	{26, 7, 1, nil},
+	{44, 10, 1, []int{6, 18}},
+	{70, 15, 1, []int{6, 22}},
+	{100, 20, 1, []int{6, 26}},
+	{134, 26, 1, []int{6, 30}},
+	{172, 18, 2, []int{6, 34}},
+}
+
+type qrCode struct {
+	Size     int
+	Modules  [][]bool // [y][x], true is dark
+	function [][]bool
+}
+
+// encodeQR returns the QR code for data, using the smallest version that fits.
+func encodeQR(data []byte) (*qrCode, error) {
+	for v, spec := range qrVersions {
+		dataCodewords := spec.Total - spec.ECPerBlock*spec.Blocks
+		if 4+8+8*len(data) > dataCodewords*8 {
+			continue
+		}
+		codewords := qrAddErrorCorrection(qrDataCodewords(data, dataCodewords), spec.ECPerBlock, spec.Blocks)
+		q := newQRCode(v + 1)
+		q.drawCodewords(codewords)
+		q.applyBestMask()
+		return q, nil
+	}
+	return nil, fmt.Errorf("payload of %d bytes is too long for a QR code", len(data))
+}
+
+// qrDataCodewords builds the bit stream: byte mode indicator, 8-bit length,
+// the data, a terminator and alternating pad bytes.
+func qrDataCodewords(data []byte, capacity int) []byte {
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 == 1)
+		}
+	}
+	appendBits(0x4, 4)
+	appendBits(len(data), 8)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	appendBits(0, min(4, capacity*8-len(bits)))
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for _, bit := range bits[i : i+8] {
+			b <<= 1
+			if bit {
+				b |= 1
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// qrAddErrorCorrection splits data into equal blocks, appends Reed-Solomon
+// codewords to each and interleaves the result.
+func qrAddErrorCorrection(data []byte, ecLen, blocks int) []byte {
+	blockLen := len(data) / blocks
+	divisor := rsDivisor(ecLen)
+	var dataBlocks, ecBlocks [][]byte
+	for i := 0; i < blocks; i++ {
+		block := data[i*blockLen : (i+1)*blockLen]
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+	}
+	var result []byte
+	for i := 0; i < blockLen; i++ {
+		for _, block := range dataBlocks {
+			result = append(result, block[i])
+		}
+	}
+	for i := 0; i < ecLen; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		carry := z >> 7
+		z <<= 1
+		if carry == 1 {
+			z ^= 0x1D
+		}
+		if (y>>i)&1 == 1 {
+			z ^= x
+		}
+	}
+	return z
+}
+
+// rsDivisor returns the generator polynomial of the given degree, highest
+// coefficient first with the leading 1 omitted.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= gfMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+func newQRCode(version int) *qrCode {
+	size := version*4 + 17
+	q := &qrCode{Size: size, Modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range q.Modules {
+		q.Modules[y] = make([]bool, size)
+		q.function[y] = make([]bool, size)
+	}
+	for i := 0; i < size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+	q.drawFinder(3, 3)
+	q.drawFinder(size-4, 3)
+	q.drawFinder(3, size-4)
+	align := qrVersions[version-1].Alignment
+	for _, x := range align {
+		for _, y := range align {
+			if (x == 6 && y == 6) || (x == 6 && y == align[len(align)-1]) || (x == align[len(align)-1] && y == 6) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	q.drawFormat(0) // reserves the format areas; redrawn once the mask is chosen
+	return q
+}
+
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.Modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// drawFinder draws a finder pattern centred on (x, y) with its separator.
+func (q *qrCode) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= q.Size || yy < 0 || yy >= q.Size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			q.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+// drawFormat writes both copies of the format information (level L and the
+// mask) plus the always-dark module.
+func (q *qrCode) drawFormat(mask int) {
+	data := 1<<3 | mask // level L is 01
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.Size-15+i, bit(i))
+	}
+	q.setFunction(8, q.Size-8, true)
+}
+
+// drawCodewords fills the non-function modules in the standard zigzag order,
+// two columns at a time from the bottom right.
+func (q *qrCode) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if q.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				q.Modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+func qrMaskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if !q.function[y][x] && qrMaskBit(mask, x, y) {
+				q.Modules[y][x] = !q.Modules[y][x]
+			}
+		}
+	}
+}
+
+// applyBestMask tries every mask and keeps the one with the lowest penalty.
+func (q *qrCode) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		q.applyMask(mask) // XOR again to undo
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+}
+
+// penalty scores runs of same-coloured modules, 2x2 blocks and dark/light
+// imbalance (rules 1, 2 and 4 of the standard).
+func (q *qrCode) penalty() int {
+	penalty, dark := 0, 0
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return q.Modules[x][y]
+		}
+		return q.Modules[y][x]
+	}
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < q.Size; y++ {
+			run := 1
+			for x := 1; x <= q.Size; x++ {
+				if x < q.Size && at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += run - 2
+				}
+				run = 1
+			}
+		}
+	}
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.Modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				c := q.Modules[y][x]
+				if c == q.Modules[y-1][x] && c == q.Modules[y][x-1] && c == q.Modules[y-1][x-1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := q.Size * q.Size
+	penalty += abs(dark*20-total*10) / total * 10
+	return penalty
+}
+
+// render draws the code with half-block characters, two rows per line, with
+// explicit colours so it scans on light and dark terminal themes alike.
+func (q *qrCode) render() string {
+	const quiet = 4
+	dark := func(x, y int) bool {
+		x, y = x-quiet, y-quiet
+		return x >= 0 && y >= 0 && x < q.Size && y < q.Size && q.Modules[y][x]
+	}
+	var b strings.Builder
+	size := q.Size + 2*quiet
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			fg, bg := "97", "107" // white
+			if dark(x, y) {
+				fg = "30"
+			}
+			if dark(x, y+1) {
+				bg = "40"
+			}
+			fmt.Fprintf(&b, "\x1b[%s;%sm▀", fg, bg)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
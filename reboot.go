@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var rebootModes = []string{"recovery", "bootloader", "sideload", "userspace"}
+
+func runReboot(args []string) error {
+	fs := flag.NewFlagSet("reboot", flag.ExitOnError)
+	wait := fs.Bool("wait", false, "Block until the device is back and responsive")
+	timeout := fs.Duration("timeout", 3*time.Minute, "How long --wait waits")
+	positional := parseArgs(fs, args)
+	mode := ""
+	if len(positional) > 1 || (len(positional) == 1 && !containsString(rebootModes, positional[0])) {
+		return fmt.Errorf("usage: reboot [%s] [--wait]", strings.Join(rebootModes, "|"))
+	}
+	if len(positional) == 1 {
+		mode = positional[0]
+	}
+
+	deviceID := pickDevice()
+	if mode == "userspace" {
+		if sdk, err := strconv.Atoi(runAdbCommand(deviceID, "getprop ro.build.version.sdk", 5*time.Second)); err == nil && sdk < 30 {
+			fmt.Println("Userspace reboot needs Android 11; the device will do a full reboot instead.")
+		}
+	}
+	if err := rebootDevice(deviceID, mode); err != nil {
+		return err
+	}
+	if !*wait {
+		return nil
+	}
+
+	// The device stays listed for a moment after adb reboot returns; wait
+	// for it to go away so the old session is not mistaken for the new one.
+	pollUntil(30*time.Second, 500*time.Millisecond, func() bool {
+		return adbDeviceStatus(deviceID) != "device"
+	})
+	start := time.Now()
+	// A wireless device drops off adb when it reboots and is not reconnected
+	// by the host on its own.
+	reconnect := wirelessReconnector(deviceID)
+	var err error
+	switch mode {
+	case "", "userspace":
+		state := StateDisconnected
+		err = pollUntil(*timeout, time.Second, func() bool {
+			state = probeDeviceState(deviceID)
+			switch state {
+			case StateDisconnected:
+				reconnect("")
+			case StateConnected:
+				reconnect(adbDeviceStatus(deviceID))
+			}
+			return state >= StateBooted
+		})
+		if err != nil {
+			err = fmt.Errorf("%s is %s: %v", deviceID, state, err)
+		}
+	case "recovery", "sideload":
+		err = pollUntil(*timeout, time.Second, func() bool {
+			status := adbDeviceStatus(deviceID)
+			if status == mode {
+				return true
+			}
+			reconnect(status)
+			return false
+		})
+	case "bootloader":
+		err = pollUntil(*timeout, time.Second, func() bool {
+			return inFastboot(deviceID)
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("%s did not come back in %s mode: %v", deviceID, rebootModeName(mode), err)
+	}
+	fmt.Printf("%s is back in %s mode after %v\n", deviceID, rebootModeName(mode), time.Since(start).Round(time.Second))
+	return nil
+}
+
+func rebootModeName(mode string) string {
+	if mode == "" || mode == "userspace" {
+		return "normal"
+	}
+	return mode
+}
+
+// inFastboot reports whether serial is listed by `fastboot devices`. fastboot
+// is looked up next to adb first, as both ship in platform-tools.
+func inFastboot(serial string) bool {
+	fastboot := "fastboot"
+	if dir := filepath.Dir(adbPath); dir != "." {
+		sibling := filepath.Join(dir, strings.Replace(adbExecutable(), "adb", "fastboot", 1))
+		if _, err := os.Stat(sibling); err == nil {
+			fastboot = sibling
+		}
+	}
+	output, err := exec.Command(fastboot, "devices").Output()
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == serial {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// screenrecord refuses time limits above three minutes.
+const maxScreenrecord = 3 * time.Minute
+
+func runRecord(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ExitOnError)
+	output := fs.String("o", "", "Output file (default recording-<time>.mp4/.gif/.webp)")
+	limit := fs.Duration("max", 30*time.Second, "Stop recording after this long (up to 3m); Ctrl+C stops earlier")
+	gif := fs.Bool("gif", false, "Convert to an animated GIF")
+	webp := fs.Bool("webp", false, "Convert to an animated WebP")
+	fps := fs.Int("fps", 10, "Frame rate of the GIF/WebP")
+	width := fs.Int("width", 480, "Width of the GIF/WebP in pixels")
+	parseArgs(fs, args)
+	if *limit <= 0 || *limit > maxScreenrecord {
+		return fmt.Errorf("--max must be between 1s and %v", maxScreenrecord)
+	}
+	if *gif && *webp {
+		return fmt.Errorf("choose one of --gif and --webp")
+	}
+	format := "mp4"
+	if *gif {
+		format = "gif"
+	} else if *webp {
+		format = "webp"
+	}
+	if format != "mp4" {
+		if _, err := exec.LookPath("ffmpeg"); err != nil {
+			return fmt.Errorf("--%s needs ffmpeg on this machine: %v", format, err)
+		}
+	}
+	if *output == "" {
+		*output = artifactPath(fmt.Sprintf("recording-%s.%s", time.Now().Format("20060102-150405"), format))
+	}
+
+	deviceID := pickDevice()
+	remote := "/sdcard/adbctl-record.mp4"
+	if err := screenrecord(deviceID, remote, *limit); err != nil {
+		return err
+	}
+	defer adbShell(deviceID, "rm", "-f", remote)
+
+	local := *output
+	if format != "mp4" {
+		tmp, err := os.MkdirTemp("", "adbctl-record")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		local = filepath.Join(tmp, "record.mp4")
+	}
+	var err error
+	if size := remoteFileSize(deviceID, remote); size >= 0 {
+		p := startProgress("Pulling the recording", size, progressBytes)
+		err = adbPullFile(deviceID, remote, local, size, p)
+		p.Finish(err)
+	} else {
+		p := startProgress("Pulling the recording", 0, progressTime)
+		err = adbPull(deviceID, remote, local)
+		p.Finish(err)
+	}
+	if err != nil {
+		return err
+	}
+	if format != "mp4" {
+		if err := convertRecording(local, *output, format, *fps, *width); err != nil {
+			return err
+		}
+	}
+	if info, err := os.Stat(*output); err == nil {
+		fmt.Printf("Saved %s (%s)\n", *output, formatBytes(info.Size()))
+	}
+	return nil
+}
+
+// screenrecord records until limit elapses or the user presses Ctrl+C, in
+// which case screenrecord is interrupted on the device so it finalizes the file.
+func screenrecord(deviceID, remote string, limit time.Duration) error {
+	seconds := strconv.Itoa(int(limit.Round(time.Second).Seconds()))
+	cmd := exec.Command(adbPath, "-s", deviceID, "shell", "screenrecord", "--time-limit", seconds, remote)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	fmt.Printf("Recording for up to %v, press Ctrl+C to stop...\n", limit)
+	p := startProgress("Recording", int64(limit), progressTime)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		p.Finish(err)
+		if err != nil {
+			return fmt.Errorf("screenrecord failed: %v", err)
+		}
+	case <-interrupt:
+		adbShell(deviceID, "pkill", "-INT", "screenrecord")
+		<-done
+		p.Finish(nil)
+	}
+	// screenrecord writes the MP4 index after it exits.
+	time.Sleep(time.Second)
+	return nil
+}
+
+// convertRecording turns an MP4 into a small animated GIF (with a generated
+// palette, which keeps UI colours clean) or WebP using the local ffmpeg.
+func convertRecording(input, output, format string, fps, width int) error {
+	scale := fmt.Sprintf("fps=%d,scale=%d:-1:flags=lanczos", fps, width)
+	args := []string{"-y", "-loglevel", "error", "-i", input}
+	if format == "gif" {
+		args = append(args, "-vf", scale+",split[a][b];[a]palettegen[p];[b][p]paletteuse", "-loop", "0", output)
+	} else {
+		args = append(args, "-vf", scale, "-c:v", "libwebp", "-quality", "70", "-loop", "0", output)
+	}
+	out, err := exec.Command("ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// lowRemoteBattery is where Fire TV starts warning about the remote.
+const lowRemoteBattery = 20
+
+var (
+	// Android 13+ masks the first four bytes of addresses as XX.
+	bondedDevicePattern   = regexp.MustCompile(`^\s*([0-9A-Fa-fX]{2}(?::[0-9A-Fa-fX]{2}){5})\s+(?:\[[^\]]*\]\s+)?(.+?)\s*$`)
+	remoteBatteryPattern  = regexp.MustCompile(`(?i)battery\s*(?:level|percent(?:age)?)?\s*[:=]?\s*(\d{1,3})\s*%?`)
+	remoteFirmwarePattern = regexp.MustCompile(`(?i)(?:firmware|fw|software)[ _-]?(?:version|ver|rev(?:ision)?)?\s*[:=]\s*([\w.\-]+)`)
+	// Vendor services that know more about the remote than the Bluetooth stack.
+	remoteServicePattern = regexp.MustCompile(`(?i)remote|rcu|btle|amazon.*bluetooth`)
+)
+
+type remoteStatus struct {
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+	Connected bool   `json:"connected"`
+	Battery   int    `json:"battery_percent"` // -1 when nothing reports it
+	Firmware  string `json:"firmware,omitempty"`
+	Source    string `json:"source,omitempty"` // where the battery or firmware came from
+}
+
+func runRemote(args []string) error {
+	if len(args) == 0 || args[0] != "status" {
+		return fmt.Errorf("usage: remote status [--json]")
+	}
+	fs := flag.NewFlagSet("remote status", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the remotes as JSON")
+	parseArgs(fs, args[1:])
+
+	deviceID := pickDevice()
+	remotes, err := readRemoteStatus(deviceID)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(remotes)
+	}
+	if len(remotes) == 0 {
+		fmt.Println("No paired Bluetooth remote. IR-only remotes report neither battery nor firmware.")
+		return nil
+	}
+	for _, r := range remotes {
+		state := "not connected"
+		if r.Connected {
+			state = "connected"
+		}
+		color.New(color.FgCyan, color.Bold).Printf("%s (%s, %s)\n", r.Name, r.Address, state)
+		switch {
+		case r.Battery < 0:
+			fmt.Println("  Battery:  not reported")
+		case r.Battery <= lowRemoteBattery:
+			color.New(color.FgRed, color.Bold).Printf("  Battery:  %d%% - replace the batteries\n", r.Battery)
+		default:
+			fmt.Printf("  Battery:  %d%%\n", r.Battery)
+		}
+		firmware := r.Firmware
+		if firmware == "" {
+			firmware = "not reported"
+		}
+		fmt.Printf("  Firmware: %s\n", firmware)
+		if r.Source != "" {
+			fmt.Printf("  Source:   %s\n", r.Source)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// readRemoteStatus lists the bonded remotes from the Bluetooth stack, then
+// fills in battery and firmware from the kernel's HID battery, the Bluetooth
+// dump and the vendor remote services, in that order.
+func readRemoteStatus(deviceID string) ([]remoteStatus, error) {
+	bluetooth, err := adbShellIdempotent(deviceID, "dumpsys", "bluetooth_manager")
+	if err != nil {
+		return nil, fmt.Errorf("dumpsys bluetooth_manager failed: %s", bluetooth)
+	}
+	remotes := parseBondedRemotes(bluetooth)
+	if len(remotes) == 0 {
+		return nil, nil
+	}
+
+	inputDump, _ := adbShellIdempotent(deviceID, "dumpsys", "input")
+	for i := range remotes {
+		r := &remotes[i]
+		r.Connected = strings.Contains(inputDump, r.Name)
+		// HID over GATT remotes get a power_supply node named after their address.
+		capacity, err := adbShellIdempotent(deviceID, "cat", "/sys/class/power_supply/hid-"+strings.ToLower(r.Address)+"-battery/capacity")
+		if n, convErr := strconv.Atoi(strings.TrimSpace(capacity)); err == nil && convErr == nil {
+			r.Battery, r.Source = n, "kernel HID battery"
+		}
+	}
+	fillRemoteDetails(remotes, bluetooth, "bluetooth_manager")
+
+	services, _ := adbShellIdempotent(deviceID, "dumpsys", "-l")
+	for _, service := range strings.Fields(services) {
+		if service == "bluetooth_manager" || !remoteServicePattern.MatchString(service) {
+			continue
+		}
+		if dump, err := adbShellIdempotent(deviceID, "dumpsys", service); err == nil {
+			fillRemoteDetails(remotes, dump, service)
+		}
+	}
+	return remotes, nil
+}
+
+// parseBondedRemotes reads the "Bonded devices:" list of dumpsys
+// bluetooth_manager, keeping devices named like remotes (or every device
+// when none is).
+func parseBondedRemotes(dump string) []remoteStatus {
+	var bonded []remoteStatus
+	inBonded := false
+	for _, line := range strings.Split(dump, "\n") {
+		if strings.Contains(line, "Bonded devices:") {
+			inBonded = true
+			continue
+		}
+		if !inBonded {
+			continue
+		}
+		m := bondedDevicePattern.FindStringSubmatch(line)
+		if m == nil {
+			if strings.TrimSpace(line) == "" || !strings.HasPrefix(line, " ") {
+				inBonded = false
+			}
+			continue
+		}
+		bonded = append(bonded, remoteStatus{Name: m[2], Address: strings.ToUpper(m[1]), Battery: -1})
+	}
+	var remotes []remoteStatus
+	for _, d := range bonded {
+		name := strings.ToLower(d.Name)
+		if strings.Contains(name, "remote") || strings.Contains(name, "rcu") {
+			remotes = append(remotes, d)
+		}
+	}
+	if len(remotes) == 0 {
+		return bonded
+	}
+	return remotes
+}
+
+// fillRemoteDetails looks for battery and firmware values in a dump,
+// attributing each to the remote whose address or name was mentioned last,
+// or to the only remote when there is one.
+func fillRemoteDetails(remotes []remoteStatus, dump, source string) {
+	current := -1
+	if len(remotes) == 1 {
+		current = 0
+	}
+	for _, line := range strings.Split(dump, "\n") {
+		upper := strings.ToUpper(line)
+		for i, r := range remotes {
+			if strings.Contains(upper, r.Address) || strings.Contains(line, r.Name) {
+				current = i
+			}
+		}
+		if current < 0 {
+			continue
+		}
+		r := &remotes[current]
+		if m := remoteBatteryPattern.FindStringSubmatch(line); m != nil && r.Battery < 0 {
+			if n, _ := strconv.Atoi(m[1]); n <= 100 {
+				r.Battery = n
+				r.Source = addSource(r.Source, source)
+			}
+		}
+		if m := remoteFirmwarePattern.FindStringSubmatch(line); m != nil && r.Firmware == "" {
+			r.Firmware = m[1]
+			r.Source = addSource(r.Source, source)
+		}
+	}
+}
+
+func addSource(sources, source string) string {
+	if sources == "" {
+		return source
+	}
+	if strings.Contains(sources, source) {
+		return sources
+	}
+	return sources + ", " + source
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deviceHealth is one row of the fleet health report.
+type deviceHealth struct {
+	Serial    string
+	Model     string
+	State     string
+	Battery   string
+	FreeData  string
+	MemAvail  string
+	Uptime    string
+	Problems  []string
+	Reachable bool
+}
+
+// Thresholds below which a device is flagged in the report.
+const (
+	lowFreeDataKB = 1024 * 1024 // 1 GB
+	lowMemAvailKB = 200 * 1024
+)
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>adbctl fleet health {{.Generated}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f0f0f0; }
+tr.problem td { background: #fff3cd; }
+tr.down td { background: #f8d7da; }
+</style></head><body>
+<h1>Fleet health</h1>
+<p>Generated {{.Generated}} on {{.Host}}: {{.Healthy}} of {{len .Devices}} device(s) healthy.</p>
+<table>
+<tr><th>Serial</th><th>Model</th><th>State</th><th>Battery</th><th>Free /data</th><th>Available memory</th><th>Uptime</th><th>Problems</th></tr>
+{{range .Devices}}<tr class="{{if not .Reachable}}down{{else if .Problems}}problem{{end}}">
+<td>{{.Serial}}</td><td>{{.Model}}</td><td>{{.State}}</td><td>{{.Battery}}</td><td>{{.FreeData}}</td><td>{{.MemAvail}}</td><td>{{.Uptime}}</td><td>{{range $i, $p := .Problems}}{{if $i}}; {{end}}{{$p}}{{end}}</td>
+</tr>
+{{end}}</table>
+</body></html>
+`))
+
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	output := fs.String("o", "", "Write the HTML report to this file (default fleet-health-<date>.html)")
+	email := fs.Bool("email", false, "Email the report to the recipients in smtp.json")
+	at := fs.String("at", "", "Run every day at this local time (HH:MM) instead of once; use with --email")
+	upload := fs.String("upload", "", "Also upload the report to s3://, gs:// or an HTTP PUT URL and print a download link")
+	parseArgs(fs, args)
+
+	if *at == "" {
+		return generateReport(*output, *email, *upload)
+	}
+	clock, err := time.Parse("15:04", *at)
+	if err != nil {
+		return fmt.Errorf("invalid --at %q, expected HH:MM", *at)
+	}
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		fmt.Printf("Next report at %s\n", next.Format("2006-01-02 15:04"))
+		time.Sleep(time.Until(next))
+		if err := generateReport(*output, *email, *upload); err != nil {
+			// Keep the schedule alive; a failed night should not stop the next one.
+			fmt.Printf("Report failed: %v\n", err)
+		}
+	}
+}
+
+func generateReport(output string, email bool, upload string) error {
+	serials, skipped := fleetDevices()
+	var devices []deviceHealth
+	for _, serial := range serials {
+		devices = append(devices, collectDeviceHealth(serial))
+	}
+	for _, s := range skipped {
+		devices = append(devices, deviceHealth{Serial: s.Device, State: s.Reason, Problems: []string{"not usable over adb"}})
+	}
+	healthy := 0
+	for _, d := range devices {
+		if d.Reachable && len(d.Problems) == 0 {
+			healthy++
+		}
+	}
+
+	host, _ := os.Hostname()
+	var html bytes.Buffer
+	err := reportTemplate.Execute(&html, map[string]any{
+		"Generated": time.Now().Format("2006-01-02 15:04"),
+		"Host":      host,
+		"Healthy":   healthy,
+		"Devices":   devices,
+	})
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("fleet-health-%s.html", time.Now().Format("2006-01-02"))
+	if output == "" {
+		output = name
+	}
+	if err := os.WriteFile(output, html.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s: %d of %d device(s) healthy\n", output, healthy, len(devices))
+	if err := uploadAndReport(output, upload); err != nil {
+		return err
+	}
+
+	if email {
+		subject := fmt.Sprintf("adbctl fleet health: %d of %d healthy", healthy, len(devices))
+		body := fmt.Sprintf("Fleet health report generated on %s. %d of %d device(s) are healthy; details are attached.\n", host, healthy, len(devices))
+		if err := sendReportEmail(subject, body, name, html.Bytes()); err != nil {
+			return err
+		}
+		fmt.Println("Report emailed.")
+	}
+	return nil
+}
+
+func collectDeviceHealth(serial string) deviceHealth {
+	timeout := 5 * time.Second
+	state := probeDeviceState(serial)
+	d := deviceHealth{Serial: serial, State: state.String(), Reachable: state >= StateAuthorized}
+	if !d.Reachable {
+		d.Problems = append(d.Problems, "shell not available")
+		return d
+	}
+	d.Model = mapFireOSModel(runAdbCommand(serial, "getprop ro.product.model", timeout))
+
+	battery := runAdbCommand(serial, "dumpsys battery | grep level | awk '{print $2}'", timeout)
+	d.Battery = battery
+	if level, err := strconv.Atoi(battery); err == nil {
+		d.Battery = battery + "%"
+		if level < 20 {
+			d.Problems = append(d.Problems, "battery low")
+		}
+	}
+
+	d.FreeData = "n/a"
+	if df := parseDfFields(runAdbCommand(serial, "df -k /data", timeout)); df != nil {
+		free, _ := strconv.Atoi(df["free_kb"])
+		d.FreeData = formatKB(free)
+		if free < lowFreeDataKB {
+			d.Problems = append(d.Problems, "low storage")
+		}
+	}
+
+	d.MemAvail = "n/a"
+	if available, ok := parseMemInfoFields(runAdbCommand(serial, "cat /proc/meminfo", timeout))["MemAvailable"]; ok {
+		d.MemAvail = formatKB(available)
+		if available < lowMemAvailKB {
+			d.Problems = append(d.Problems, "low memory")
+		}
+	}
+
+	d.Uptime = "n/a"
+	if fields := strings.Fields(runAdbCommand(serial, "cat /proc/uptime", timeout)); len(fields) > 0 {
+		if seconds, err := strconv.ParseFloat(fields[0], 64); err == nil {
+			d.Uptime = (time.Duration(seconds) * time.Second).Round(time.Minute).String()
+		}
+	}
+	if state < StateBooted {
+		d.Problems = append(d.Problems, "not booted")
+	}
+	return d
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// retryPolicy retries adb invocations that failed because the transport
+// blipped (device offline, connection closed), doubling the wait each time.
+// Failures of the command itself are never retried. When the device has
+// dropped off adb altogether, as it does while adbd restarts (adb tcpip,
+// adb root, an OTA), the first retry waits up to ReconnectWait for it to
+// come back instead.
+type retryPolicy struct {
+	Attempts      int
+	Backoff       time.Duration
+	ReconnectWait time.Duration
+}
+
+// adbRetry is configured by -retries/-retry-backoff/-reconnect-wait or
+// ADBCTL_RETRIES, ADBCTL_RETRY_BACKOFF and ADBCTL_RECONNECT_WAIT.
+var adbRetry = retryPolicy{Attempts: 3, Backoff: 500 * time.Millisecond, ReconnectWait: time.Minute}
+
+var (
+	// lostDevices are devices that did not come back within ReconnectWait;
+	// later commands fail fast instead of waiting again.
+	lostDevices   = map[string]bool{}
+	lostDevicesMu sync.Mutex
+)
+
+func init() {
+	if n, err := strconv.Atoi(os.Getenv("ADBCTL_RETRIES")); err == nil && n >= 1 {
+		adbRetry.Attempts = n
+	}
+	if d, err := time.ParseDuration(os.Getenv("ADBCTL_RETRY_BACKOFF")); err == nil && d >= 0 {
+		adbRetry.Backoff = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("ADBCTL_RECONNECT_WAIT")); err == nil && d >= 0 {
+		adbRetry.ReconnectWait = d
+	}
+}
+
+// transientAdbErrors are the normalized kinds worth retrying.
+var transientAdbErrors = []string{"offline", "no-device", "protocol-fault"}
+
+func isTransientAdbError(output string, err error) bool {
+	if err == nil {
+		return false
+	}
+	return containsString(transientAdbErrors, errorKind(normalizeAdbError(output, err), ""))
+}
+
+// run calls op until it succeeds, fails for a non-transient reason, or the
+// attempts are used up, and returns op's last result. op must be safe to
+// repeat: a read, a copy or a port forward, never an action such as am start
+// or input (adbShell runs those once). deviceID is the device op talks to; "" skips waiting for it to
+// reconnect, for callers with their own short deadline.
+func (p retryPolicy) run(deviceID string, op func() ([]byte, error)) ([]byte, error) {
+	backoff := p.Backoff
+	waited := false
+	for attempt := 1; ; attempt++ {
+		output, err := op()
+		if attempt >= p.Attempts || !isTransientAdbError(string(output), err) {
+			return output, err
+		}
+		if !waited && deviceID != "" && p.ReconnectWait > 0 && adbDeviceStatus(deviceID) != "device" {
+			waited = true
+			if awaitDevice(deviceID, p.ReconnectWait) {
+				continue
+			}
+			return output, err
+		}
+		debugPrint("adb transport error (attempt %d of %d), retrying in %v: %v\n", attempt, p.Attempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// awaitDevice waits for a device that went offline mid-command to be usable
+// again, reconnecting network devices whose connection adbd dropped when it
+// restarted. It reports whether the device came back.
+func awaitDevice(deviceID string, timeout time.Duration) bool {
+	lostDevicesMu.Lock()
+	lost := lostDevices[deviceID]
+	lostDevicesMu.Unlock()
+	if lost {
+		return false
+	}
+	color.New(color.FgYellow).Fprintf(os.Stderr, "Device %s went offline (adbd restarting?); waiting up to %v for it to return...\n", deviceID, timeout)
+	start := time.Now()
+	reconnect := wirelessReconnector(deviceID)
+	err := pollUntil(timeout, time.Second, func() bool {
+		status := adbDeviceStatus(deviceID)
+		if status == "device" {
+			return true
+		}
+		reconnect(status)
+		return false
+	})
+	if err != nil {
+		lostDevicesMu.Lock()
+		lostDevices[deviceID] = true
+		lostDevicesMu.Unlock()
+		color.New(color.FgRed).Fprintf(os.Stderr, "Device %s did not come back: %v\n", deviceID, err)
+		return false
+	}
+	color.New(color.FgGreen).Fprintf(os.Stderr, "Device %s is back after %v; resuming.\n", deviceID, time.Since(start).Round(time.Second))
+	return true
+}
+
+// wirelessReconnector returns a function for polling loops that re-issues
+// adb connect for a wireless serial at most every five seconds. adbd comes
+// back listening on the same port after a reboot, adb tcpip or adb root, but
+// the host side may keep a dead connection or forget it. For USB serials the
+// function does nothing.
+func wirelessReconnector(deviceID string) func(status string) {
+	lastConnect := time.Now()
+	return func(status string) {
+		if !isWirelessSerial(deviceID) || time.Since(lastConnect) < 5*time.Second {
+			return
+		}
+		lastConnect = time.Now()
+		if status == "offline" {
+			exec.Command(adbPath, "disconnect", deviceID).Run()
+		}
+		exec.Command(adbPath, "connect", deviceID).Run()
+	}
+}
+
+func validateRetryPolicy(p retryPolicy) error {
+	if p.Attempts < 1 {
+		return fmt.Errorf("-retries must be at least 1")
+	}
+	if p.ReconnectWait < 0 {
+		return fmt.Errorf("-reconnect-wait must not be negative")
+	}
+	return nil
+}
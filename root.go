@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rootManagers are the packages of root solutions, by display name.
+var rootManagers = map[string]string{
+	"com.topjohnwu.magisk":       "Magisk",
+	"io.github.huskydg.magisk":   "Kitsune Magisk",
+	"io.github.vvb2060.magisk":   "Magisk Alpha",
+	"me.weishu.kernelsu":         "KernelSU",
+	"me.bmax.apatch":             "APatch",
+	"eu.chainfire.supersu":       "SuperSU",
+	"com.koushikdutta.superuser": "Superuser",
+	"com.noshufou.android.su":    "Superuser",
+	"com.kingroot.kinguser":      "KingRoot",
+}
+
+// rootStatus summarizes whether the device is rooted: su binaries, root
+// manager apps and adbd running as root, followed by the build properties
+// that allow adb root at all.
+func rootStatus(deviceID string) string {
+	timeout := 5 * time.Second
+	var evidence []string
+
+	// root.sh checks where the usual root methods install su.
+	sections := libSections("")
+	if output, err := runLibScript(deviceID, "root.sh", timeout); err == nil {
+		sections = libSections(output)
+	}
+	if found := strings.Fields(sections["su"]); len(found) > 0 {
+		evidence = append(evidence, "su at "+strings.Join(found, ", "))
+	}
+
+	packages := runAdbCommand(deviceID, "pm list packages", timeout)
+	var managers []string
+	for _, line := range strings.Split(packages, "\n") {
+		if name, ok := rootManagers[strings.TrimPrefix(strings.TrimSpace(line), "package:")]; ok && !containsString(managers, name) {
+			managers = append(managers, name)
+		}
+	}
+	evidence = append(evidence, managers...)
+
+	if isShellRoot(deviceID) {
+		evidence = append(evidence, "adbd running as root")
+	}
+
+	props := "ro.debuggable=n/a, ro.secure=n/a"
+	if values := libValues(sections["props"]); len(values) > 0 {
+		props = fmt.Sprintf("ro.debuggable=%s, ro.secure=%s", values["debuggable"], values["secure"])
+	}
+	if len(evidence) == 0 {
+		return fmt.Sprintf("Not rooted (%s)", props)
+	}
+	return fmt.Sprintf("Rooted: %s (%s)", strings.Join(evidence, "; "), props)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// maxScanHosts keeps a mistyped prefix (e.g. /8) from probing millions of hosts.
+const maxScanHosts = 4096
+
+type scanHit struct {
+	Address string
+	Model   string
+	State   DeviceState
+}
+
+func runScan(args []string) error {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	port := fs.Int("port", 5555, "adb TCP port to probe")
+	timeout := fs.Duration("timeout", 500*time.Millisecond, "Connect timeout per host")
+	workers := fs.Int("workers", 64, "Concurrent probes")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: scan <cidr>, e.g. scan 192.168.1.0/24")
+	}
+	prefix, err := netip.ParsePrefix(positional[0])
+	if err != nil || !prefix.Addr().Is4() {
+		return fmt.Errorf("invalid IPv4 subnet %q", positional[0])
+	}
+	prefix = prefix.Masked()
+	if bits := 32 - prefix.Bits(); bits > 12 {
+		return fmt.Errorf("%s has more than %d hosts; scan a smaller subnet", prefix, maxScanHosts)
+	}
+
+	fmt.Printf("Probing %s port %d...\n", prefix, *port)
+	open := probeSubnet(prefix, *port, *timeout, *workers)
+	if len(open) == 0 {
+		fmt.Println("No hosts with an open adb port found.")
+		return nil
+	}
+
+	before := connectedSerials()
+	var hits []scanHit
+	for _, address := range open {
+		hits = append(hits, identifyEndpoint(address))
+	}
+
+	color.New(color.FgCyan, color.Bold).Println("ADB Endpoints")
+	fmt.Print(rule("=", 60))
+	for i, h := range hits {
+		fmt.Printf("%d. %-22s %-14s %s\n", i+1, h.Address, h.State, h.Model)
+	}
+
+	keep := map[string]bool{}
+	if !ciMode {
+		fmt.Print("Numbers to stay connected to (e.g. 1,3 or all; Enter for none): ")
+		input, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		input = strings.TrimSpace(input)
+		for i, h := range hits {
+			if input == "all" || containsString(splitList(input), strconv.Itoa(i+1)) {
+				keep[h.Address] = true
+			}
+		}
+	}
+	for _, h := range hits {
+		if !keep[h.Address] && !containsString(before, h.Address) {
+			exec.Command(adbPath, "disconnect", h.Address).Run()
+		}
+	}
+	if len(keep) > 0 {
+		fmt.Printf("Connected to %d device(s); see 'adb devices'.\n", len(keep))
+	}
+	return nil
+}
+
+// probeSubnet returns the ip:port of every host in prefix accepting TCP
+// connections on port, in address order.
+func probeSubnet(prefix netip.Prefix, port int, timeout time.Duration, workers int) []string {
+	addresses := make(chan netip.Addr)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var open []netip.Addr
+	for i := 0; i < max(1, workers); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range addresses {
+				conn, err := net.DialTimeout("tcp", netip.AddrPortFrom(addr, uint16(port)).String(), timeout)
+				if err != nil {
+					continue
+				}
+				conn.Close()
+				mu.Lock()
+				open = append(open, addr)
+				mu.Unlock()
+			}
+		}()
+	}
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		addresses <- addr
+	}
+	close(addresses)
+	wg.Wait()
+
+	sort.Slice(open, func(i, j int) bool { return open[i].Less(open[j]) })
+	var result []string
+	for _, addr := range open {
+		result = append(result, netip.AddrPortFrom(addr, uint16(port)).String())
+	}
+	return result
+}
+
+// identifyEndpoint connects to address and reads the model. Unauthorized
+// devices show the RSA prompt on screen and report no model until accepted.
+func identifyEndpoint(address string) scanHit {
+	hit := scanHit{Address: address, Model: "-"}
+	exec.Command(adbPath, "connect", address).Run()
+	hit.State = probeDeviceState(address)
+	if hit.State >= StateAuthorized {
+		hit.Model = mapFireOSModel(runAdbCommand(address, "getprop ro.product.model", 5*time.Second))
+	} else if hit.State == StateConnected {
+		hit.Model = "(accept the debugging prompt on the device)"
+	}
+	return hit
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deviceSchema is the structured view of a device used by assertions and
+// exports: namespace -> key -> raw value, e.g. props -> ro.build.version.sdk -> 30.
+type deviceSchema map[string]map[string]string
+
+// schemaCollectors produce one namespace of the device schema each.
+var schemaCollectors = map[string]func(deviceID string) map[string]string{
+	"props": func(deviceID string) map[string]string {
+		props, _ := getAllProps(deviceID)
+		return props
+	},
+	"meminfo": func(deviceID string) map[string]string {
+		values := make(map[string]string)
+		for key, kb := range parseMemInfoFields(runAdbCommand(deviceID, "cat /proc/meminfo", 5*time.Second)) {
+			values[key] = strconv.Itoa(kb)
+		}
+		return values
+	},
+	"battery": func(deviceID string) map[string]string {
+		return parseColonFields(runAdbCommand(deviceID, "dumpsys battery", 5*time.Second))
+	},
+	"storage": func(deviceID string) map[string]string {
+		return parseDfFields(runAdbCommand(deviceID, "df -k /data", 5*time.Second))
+	},
+	"global": func(deviceID string) map[string]string {
+		values, _ := listSettings(deviceID, "global")
+		return values
+	},
+	"system": func(deviceID string) map[string]string {
+		values, _ := listSettings(deviceID, "system")
+		return values
+	},
+	"secure": func(deviceID string) map[string]string {
+		values, _ := listSettings(deviceID, "secure")
+		return values
+	},
+}
+
+// collectDeviceSchema gathers the requested namespaces (all when none are given).
+func collectDeviceSchema(deviceID string, namespaces ...string) deviceSchema {
+	if len(namespaces) == 0 {
+		for ns := range schemaCollectors {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	schema := make(deviceSchema)
+	for _, ns := range namespaces {
+		if collect, ok := schemaCollectors[ns]; ok {
+			schema[ns] = collect(deviceID)
+		}
+	}
+	return schema
+}
+
+// lookup resolves "namespace.key", where the key may itself contain dots.
+func (s deviceSchema) lookup(path string) (string, bool) {
+	ns, key, found := strings.Cut(path, ".")
+	if !found {
+		return "", false
+	}
+	value, ok := s[ns][key]
+	return value, ok
+}
+
+// parseColonFields parses "key: value" dumpsys output into a map with
+// lower-cased, underscore-separated keys ("AC powered" -> "ac_powered").
+func parseColonFields(output string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found || key == "" {
+			continue
+		}
+		key = strings.ReplaceAll(strings.ToLower(key), " ", "_")
+		values[key] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+func parseDfFields(dfOutput string) map[string]string {
+	lines := strings.Split(dfOutput, "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+	fields := strings.Fields(lines[1])
+	if len(fields) < 4 {
+		return nil
+	}
+	return map[string]string{"total_kb": fields[1], "used_kb": fields[2], "free_kb": fields[3]}
+}
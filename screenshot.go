@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// minRowMatch is the fraction of overlapping rows that must be identical for
+// two captures to be considered aligned; focus highlights on TV UIs move
+// between captures, so an exact match is too strict.
+const minRowMatch = 0.9
+
+func runScreenshot(args []string) error {
+	fs := flag.NewFlagSet("screenshot", flag.ExitOnError)
+	output := fs.String("o", "", "Output PNG (default screenshot-<time>.png)")
+	scroll := fs.Bool("scroll", false, "Scroll and stitch captures into one tall image")
+	pages := fs.Int("pages", 10, "Maximum number of captures when scrolling")
+	method := fs.String("method", "swipe", "How to scroll: swipe (touch UIs) or dpad (TV UIs)")
+	steps := fs.Int("steps", 5, "D-pad presses per page with --method dpad")
+	settle := fs.Duration("settle", 700*time.Millisecond, "Wait after each scroll before capturing")
+	parseArgs(fs, args)
+	if *method != "swipe" && *method != "dpad" {
+		return fmt.Errorf("--method must be swipe or dpad")
+	}
+	if *output == "" {
+		*output = artifactPath(fmt.Sprintf("screenshot-%s.png", time.Now().Format("20060102-150405")))
+	}
+
+	deviceID := pickDevice()
+	if !*scroll {
+		if err := captureScreenshot(deviceID, *output); err != nil {
+			return err
+		}
+		fmt.Printf("Saved %s\n", *output)
+		return nil
+	}
+
+	var frames []*image.RGBA
+	for len(frames) < *pages {
+		frame, err := captureFrame(deviceID)
+		if err != nil {
+			return err
+		}
+		if n := len(frames); n > 0 && bytes.Equal(frames[n-1].Pix, frame.Pix) {
+			break // scrolling no longer changes the screen: end of content
+		}
+		frames = append(frames, frame)
+		fmt.Printf("Captured page %d\n", len(frames))
+		if err := scrollPage(deviceID, *method, *steps, frame.Bounds().Dx(), frame.Bounds().Dy()); err != nil {
+			return err
+		}
+		time.Sleep(*settle)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stitched := stitchCaptures(frames)
+	if err := png.Encode(f, stitched); err != nil {
+		return err
+	}
+	fmt.Printf("Saved %s (%d captures, %dx%d)\n", *output, len(frames), stitched.Bounds().Dx(), stitched.Bounds().Dy())
+	return nil
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// screencapPNG returns a PNG of the current screen. exec-out is used so the
+// binary stream is not mangled by the shell's line-ending conversion. Devices
+// without exec-out (before Android 5) fall back to adb shell, whose "\n" to
+// "\r\n" rewriting is undone; the result is checked to be a PNG either way.
+func screencapPNG(deviceID string) ([]byte, error) {
+	budget := scaledTimeout(deviceID, screencapBudget)
+	var buf bytes.Buffer
+	err := adbExecOutWithin(deviceID, budget, &buf, "screencap", "-p")
+	if err == nil && bytes.HasPrefix(buf.Bytes(), pngSignature) {
+		return buf.Bytes(), nil
+	}
+	ctx, cancel := budgetContext(budget)
+	defer cancel()
+	output, shellErr := exec.CommandContext(ctx, adbPath, "-s", deviceID, "shell", "screencap", "-p").Output()
+	if shellErr != nil {
+		if err != nil {
+			return nil, err
+		}
+		return nil, normalizeAdbError(string(output), shellErr)
+	}
+	output = bytes.ReplaceAll(output, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(output, pngSignature) {
+		return nil, fmt.Errorf("screencap did not return a PNG (is the screen secure or the display off?)")
+	}
+	return output, nil
+}
+
+func captureFrame(deviceID string) (*image.RGBA, error) {
+	data, err := screencapPNG(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding screencap: %v", err)
+	}
+	frame := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(frame, frame.Bounds(), img, img.Bounds().Min, draw.Src)
+	return frame, nil
+}
+
+// scrollPage moves the content by roughly half a screen, so consecutive
+// captures overlap enough to be aligned.
+func scrollPage(deviceID, method string, steps, width, height int) error {
+	if method == "dpad" {
+		for i := 0; i < steps; i++ {
+			if _, err := adbShell(deviceID, "input", "keyevent", "KEYCODE_DPAD_DOWN"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	x := strconv.Itoa(width / 2)
+	_, err := adbShell(deviceID, "input", "swipe", x, strconv.Itoa(height*3/4), x, strconv.Itoa(height/4), "600")
+	return err
+}
+
+// stitchCaptures joins overlapping captures into one tall image. Rows that
+// stay put in every capture (app bars, navigation) are treated as a fixed
+// header and footer and emitted once; the scrolling region in between is
+// aligned pairwise by finding the largest overlap of identical rows.
+func stitchCaptures(frames []*image.RGBA) *image.RGBA {
+	hashes := make([][]uint64, len(frames))
+	for i, f := range frames {
+		hashes[i] = rowHashes(f)
+	}
+	height := len(hashes[0])
+	header, footer := height/3, height/3
+	for i := 1; i < len(frames); i++ {
+		header = min(header, commonRows(hashes[i-1], hashes[i], false))
+		footer = min(footer, commonRows(hashes[i-1], hashes[i], true))
+	}
+
+	type rowRef struct{ frame, row int }
+	var rows []rowRef
+	for r := 0; r < height-footer; r++ {
+		rows = append(rows, rowRef{0, r})
+	}
+	for i := 1; i < len(frames); i++ {
+		prev := hashes[i-1][header : height-footer]
+		next := hashes[i][header : height-footer]
+		overlap := findOverlap(prev, next)
+		for r := overlap; r < len(next); r++ {
+			rows = append(rows, rowRef{i, header + r})
+		}
+	}
+	last := len(frames) - 1
+	for r := height - footer; r < height; r++ {
+		rows = append(rows, rowRef{last, r})
+	}
+
+	width := frames[0].Bounds().Dx()
+	out := image.NewRGBA(image.Rect(0, 0, width, len(rows)))
+	for y, ref := range rows {
+		src := frames[ref.frame]
+		copy(out.Pix[y*out.Stride:y*out.Stride+width*4], src.Pix[ref.row*src.Stride:ref.row*src.Stride+width*4])
+	}
+	return out
+}
+
+func rowHashes(img *image.RGBA) []uint64 {
+	width := img.Bounds().Dx()
+	hashes := make([]uint64, img.Bounds().Dy())
+	for y := range hashes {
+		h := fnv.New64a()
+		h.Write(img.Pix[y*img.Stride : y*img.Stride+width*4])
+		hashes[y] = h.Sum64()
+	}
+	return hashes
+}
+
+// commonRows counts identical rows at the same position, from the top or
+// (fromBottom) from the bottom.
+func commonRows(a, b []uint64, fromBottom bool) int {
+	n := 0
+	for n < len(a) {
+		i := n
+		if fromBottom {
+			i = len(a) - 1 - n
+		}
+		if a[i] != b[i] {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// findOverlap returns how many leading rows of next repeat the trailing rows
+// of prev, preferring the largest overlap that matches; 0 when none does.
+// Runs of repeated rows (plain backgrounds) match at any offset, so only rows
+// that differ from the row above count towards the match.
+func findOverlap(prev, next []uint64) int {
+	for overlap := len(next) - 1; overlap >= 10; overlap-- {
+		offset := len(prev) - overlap
+		considered, matched := 0, 0
+		for r := 1; r < overlap; r++ {
+			if next[r] == next[r-1] {
+				continue
+			}
+			considered++
+			if prev[offset+r] == next[r] {
+				matched++
+			}
+		}
+		if considered >= 5 && float64(matched) >= minRowMatch*float64(considered) {
+			return overlap
+		}
+	}
+	return 0
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const seedMediaDir = "/sdcard/DCIM/adbctl-seed"
+
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	media := fs.String("media", "", "Local directory of photos/videos to push")
+	contacts := fs.String("contacts", "", "vCard file to import into Contacts")
+	calendar := fs.String("calendar", "", "iCalendar file to import into Calendar")
+	fs.Parse(args)
+
+	if *media == "" && *contacts == "" && *calendar == "" {
+		return fmt.Errorf("nothing to seed: pass --media, --contacts and/or --calendar")
+	}
+	deviceID := pickDevice()
+
+	if *media != "" {
+		count, err := seedMedia(deviceID, *media)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Pushed %d media files to %s and triggered a media scan.\n", count, seedMediaDir)
+	}
+	if *contacts != "" {
+		if err := importFixture(deviceID, *contacts, "text/x-vcard"); err != nil {
+			return err
+		}
+		fmt.Println("Contacts import started; confirm the prompt on the device if one appears.")
+	}
+	if *calendar != "" {
+		if err := importFixture(deviceID, *calendar, "text/calendar"); err != nil {
+			return err
+		}
+		fmt.Println("Calendar import started; confirm the prompt on the device if one appears.")
+	}
+	return nil
+}
+
+// seedMedia pushes every file under dir, keeping its subdirectories, so an
+// album laid out in folders arrives the same way.
+func seedMedia(deviceID, dir string) (int, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			files = append(files, file)
+		} else if !entry.IsDir() {
+			fmt.Printf("Skipping %s: not a regular file\n", file)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	adbShell(deviceID, "mkdir", "-p", seedMediaDir)
+
+	p := startProgress("Pushing media", int64(len(files)), progressItems)
+	count := 0
+	for _, file := range files {
+		rel, _ := filepath.Rel(dir, file)
+		remote := path.Join(seedMediaDir, filepath.ToSlash(rel))
+		if err := adbPush(deviceID, file, remote); err != nil {
+			p.Finish(err)
+			return count, err
+		}
+		count++
+		p.Add(1)
+	}
+	p.Finish(nil)
+	return count, triggerMediaScan(deviceID, seedMediaDir)
+}
+
+// importFixture pushes a file to Downloads and opens it with the handler for
+// mimeType, which hands it to the Contacts/Calendar import flow.
+func importFixture(deviceID, local, mimeType string) error {
+	remote := path.Join("/sdcard/Download", filepath.Base(local))
+	if err := adbPush(deviceID, local, remote); err != nil {
+		return err
+	}
+	output, err := adbShell(deviceID, "am", "start", "-a", "android.intent.action.VIEW", "-d", shellQuote("file://"+remote), "-t", mimeType)
+	if err != nil || strings.Contains(output, "Error") {
+		return fmt.Errorf("no app on the device can import %s: %s", mimeType, output)
+	}
+	return nil
+}
+
+// triggerMediaScan asks MediaProvider to index files under devicePath. The
+// per-file scanner broadcast is ignored from Android 10, so newer releases
+// call MediaProvider's scan_file, which also walks directories, and fall
+// back to rescanning the whole external volume where that is refused.
+func triggerMediaScan(deviceID, devicePath string) error {
+	sdk, _ := strconv.Atoi(runAdbCommand(deviceID, "getprop ro.build.version.sdk", 5*time.Second))
+	if sdk >= 29 {
+		output, err := adbShell(deviceID, "content", "call", "--uri", "content://media", "--method", "scan_file", "--arg", shellQuote(devicePath))
+		if err == nil && !strings.Contains(output, "Exception") {
+			return nil
+		}
+		debugPrint("scan_file %s: %v %s\n", devicePath, err, output)
+		fmt.Printf("The device refused to scan %s alone; rescanning all of shared storage instead.\n", devicePath)
+		_, err = adbShell(deviceID, "content", "call", "--uri", "content://media", "--method", "scan_volume", "--arg", "external_primary")
+		return err
+	}
+	// The legacy broadcast only accepts files, so expand directories first.
+	files, err := adbShellIdempotent(deviceID, "find", shellQuote(devicePath), "-type", "f")
+	if err != nil {
+		return err
+	}
+	for _, file := range strings.Split(files, "\n") {
+		if file = strings.TrimSpace(file); file == "" {
+			continue
+		}
+		if _, err := adbShell(deviceID, "am", "broadcast", "-a", "android.intent.action.MEDIA_SCANNER_SCAN_FILE", "-d", shellQuote("file://"+file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
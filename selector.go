@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// selectorTimeout bounds how long the device list waits for any one device;
+// a slow or unauthorized device is listed without details.
+const selectorTimeout = 2 * time.Second
+
+// deviceSummaries describes each `adb devices -l` line for the device picker
+// (model, Android version, the device's alias and its power state), querying
+// all devices at once. Devices that don't answer in time are described by
+// their alias alone.
+func deviceSummaries(lines []string) []string {
+	aliases := loadDeviceAliases()
+	summaries := make([]string, len(lines))
+	var wg sync.WaitGroup
+	for i, line := range lines {
+		wg.Add(1)
+		serial := strings.Fields(line)[0]
+		go func(i int, serial string) {
+			defer wg.Done()
+			summaries[i] = deviceSummary(serial, aliases[serial])
+		}(i, serial)
+	}
+	wg.Wait()
+	return summaries
+}
+
+// deviceSummary waits selectorTimeout unscaled: the picker is interactive,
+// and a slow device is better listed without details than holding it up.
+func deviceSummary(serial, alias string) string {
+	var parts []string
+	if alias != "" {
+		parts = append(parts, fmt.Sprintf("%q", alias))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), selectorTimeout)
+	defer cancel()
+	// One round trip: two properties, then the battery dump.
+	output, err := exec.CommandContext(ctx, adbPath, "-s", serial, "shell",
+		"getprop ro.product.model; getprop ro.build.version.release; dumpsys battery").Output()
+	if err != nil {
+		debugPrint("device summary %s: %v\n", serial, err)
+		return strings.Join(parts, " · ")
+	}
+	lines := strings.SplitN(strings.ReplaceAll(string(output), "\r", ""), "\n", 3)
+	if len(lines) < 3 {
+		return strings.Join(parts, " · ")
+	}
+	model, _, _ := strings.Cut(mapFireOSModel(strings.TrimSpace(lines[0])), " (http")
+	if model != "" {
+		parts = append(parts, model)
+	}
+	if version := strings.TrimSpace(lines[1]); version != "" {
+		parts = append(parts, "Android "+version)
+	}
+	if power := powerSummary(parseKeyValueLines(lines[2])); power != "" {
+		parts = append(parts, power)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// powerSummary turns dumpsys battery fields into "battery 80%, charging" or,
+// for streaming sticks and TVs without a battery, "mains powered".
+func powerSummary(fields map[string]string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	plugged := fields["AC powered"] == "true" || fields["USB powered"] == "true" || fields["Wireless powered"] == "true"
+	if fields["present"] == "false" {
+		return "mains powered"
+	}
+	level, ok := fields["level"]
+	if !ok {
+		return ""
+	}
+	if plugged {
+		return fmt.Sprintf("battery %s%%, charging", level)
+	}
+	return fmt.Sprintf("battery %s%%", level)
+}
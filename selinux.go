@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var (
+	avcDeniedPattern = regexp.MustCompile(`avc:\s+denied\s+\{\s*([^}]*?)\s*\}\s+for\s+(.*)$`)
+	avcFieldPattern  = regexp.MustCompile(`(\w+)=("[^"]*"|\S+)`)
+)
+
+// avcDenial is one distinct SELinux denial; repeats are counted.
+type avcDenial struct {
+	Source     string // scontext type, e.g. untrusted_app
+	Target     string // tcontext type
+	Class      string
+	Perms      string
+	Comm       string
+	Name       string
+	Permissive bool
+	Count      int
+}
+
+// selinuxMode returns getenforce's answer: Enforcing, Permissive or Disabled.
+func selinuxMode(deviceID string) string {
+	return runAdbCommand(deviceID, "getenforce", 5*time.Second)
+}
+
+func runSelinux(args []string) error {
+	if len(args) == 0 || args[0] != "denials" {
+		return fmt.Errorf("usage: selinux denials [--source context] [--allow]")
+	}
+	fs := flag.NewFlagSet("selinux denials", flag.ExitOnError)
+	source := fs.String("source", "", "Only show denials whose source context contains this")
+	allow := fs.Bool("allow", false, "Also print the allow rules that would silence them (audit2allow style)")
+	parseArgs(fs, args[1:])
+
+	deviceID := pickDevice()
+	fmt.Printf("SELinux: %s\n\n", selinuxMode(deviceID))
+
+	// auditd forwards denials to logcat; the kernel log has the ones logged
+	// before logd started. The same denial is in both, so take the larger
+	// count rather than the sum.
+	logcat, err := adbShellIdempotent(deviceID, "logcat", "-d", "-b", "all")
+	if err != nil {
+		// Builds before Android 7 have no "all" buffer.
+		logcat, _ = adbShellIdempotent(deviceID, "logcat", "-d")
+	}
+	denials := parseAVCDenials(logcat)
+	if _, kernel, err := readKernelLog(deviceID); err == nil {
+		for key, d := range parseAVCDenials(kernel) {
+			if existing, ok := denials[key]; !ok || existing.Count < d.Count {
+				denials[key] = d
+			}
+		}
+	} else {
+		debugPrint("kernel log: %v\n", err)
+	}
+
+	groups := map[string][]avcDenial{}
+	totals := map[string]int{}
+	for _, d := range denials {
+		if *source != "" && !strings.Contains(d.Source, *source) {
+			continue
+		}
+		groups[d.Source] = append(groups[d.Source], d)
+		totals[d.Source] += d.Count
+	}
+	if len(groups) == 0 {
+		fmt.Println("No avc denials in the log.")
+		return nil
+	}
+	sources := make([]string, 0, len(groups))
+	for s := range groups {
+		sources = append(sources, s)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if totals[sources[i]] != totals[sources[j]] {
+			return totals[sources[i]] > totals[sources[j]]
+		}
+		return sources[i] < sources[j]
+	})
+
+	for _, s := range sources {
+		list := groups[s]
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Count != list[j].Count {
+				return list[i].Count > list[j].Count
+			}
+			return list[i].Target+list[i].Class < list[j].Target+list[j].Class
+		})
+		color.New(color.FgCyan, color.Bold).Printf("%s (%d denial(s))\n", s, totals[s])
+		for _, d := range list {
+			line := fmt.Sprintf("  %4dx { %s } %s:%s", d.Count, d.Perms, d.Target, d.Class)
+			if d.Comm != "" {
+				line += " comm=" + d.Comm
+			}
+			if d.Name != "" {
+				line += " name=" + d.Name
+			}
+			if d.Permissive {
+				line += " (permissive)"
+			}
+			fmt.Println(line)
+		}
+		if *allow {
+			for _, allowRule := range avcAllowRules(list) {
+				color.New(color.FgYellow).Printf("  %s\n", allowRule)
+			}
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// parseAVCDenials collects the distinct denials in a log, keyed by everything
+// but the audit timestamp, pid and inode.
+func parseAVCDenials(log string) map[string]avcDenial {
+	denials := map[string]avcDenial{}
+	for _, line := range strings.Split(log, "\n") {
+		m := avcDeniedPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		fields := map[string]string{}
+		for _, f := range avcFieldPattern.FindAllStringSubmatch(m[2], -1) {
+			fields[f[1]] = f[2]
+		}
+		d := avcDenial{
+			Source:     contextType(fields["scontext"]),
+			Target:     contextType(fields["tcontext"]),
+			Class:      fields["tclass"],
+			Perms:      m[1],
+			Comm:       fields["comm"],
+			Name:       fields["name"],
+			Permissive: fields["permissive"] == "1",
+		}
+		key := strings.Join([]string{d.Source, d.Target, d.Class, d.Perms, d.Comm, d.Name}, "|")
+		existing := denials[key]
+		d.Count = existing.Count + 1
+		denials[key] = d
+	}
+	return denials
+}
+
+// contextType returns the type of an SELinux context
+// ("u:r:untrusted_app:s0:c12,c257" is untrusted_app).
+func contextType(context string) string {
+	parts := strings.Split(context, ":")
+	if len(parts) < 3 {
+		return context
+	}
+	return parts[2]
+}
+
+// avcAllowRules merges the permissions of each source, target and class into
+// one rule, as audit2allow does.
+func avcAllowRules(denials []avcDenial) []string {
+	perms := map[string][]string{}
+	for _, d := range denials {
+		key := fmt.Sprintf("allow %s %s:%s", d.Source, d.Target, d.Class)
+		for _, p := range strings.Fields(d.Perms) {
+			if !containsString(perms[key], p) {
+				perms[key] = append(perms[key], p)
+			}
+		}
+	}
+	var rules []string
+	for key, list := range perms {
+		sort.Strings(list)
+		rules = append(rules, fmt.Sprintf("%s { %s };", key, strings.Join(list, " ")))
+	}
+	sort.Strings(rules)
+	return rules
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var serviceRecordPattern = regexp.MustCompile(`ServiceRecord\{\w+ u\d+ ([\w.]+)/([\w.$]+)\}`)
+
+func runServices(args []string) error {
+	fs := flag.NewFlagSet("services", flag.ExitOnError)
+	pkg := fs.String("package", "", "Only show this package")
+	parseArgs(fs, args)
+
+	deviceID := pickDevice()
+	output, err := adbShellIdempotent(deviceID, "dumpsys", "activity", "services")
+	if err != nil {
+		return err
+	}
+	running := parseRunningServices(output)
+	receivers := bootReceivers(deviceID)
+	thirdParty := map[string]bool{}
+	packages, _ := adbShellIdempotent(deviceID, "pm", "list", "packages", "-3")
+	for _, line := range strings.Split(packages, "\n") {
+		if name, found := strings.CutPrefix(strings.TrimSpace(line), "package:"); found {
+			thirdParty[name] = true
+		}
+	}
+
+	names := map[string]bool{}
+	for name := range running {
+		names[name] = true
+	}
+	for name := range receivers {
+		names[name] = true
+	}
+	var sorted []string
+	for name := range names {
+		if *pkg == "" || name == *pkg {
+			sorted = append(sorted, name)
+		}
+	}
+	// Third-party packages first, since those are the ones you can remove.
+	sort.Slice(sorted, func(i, j int) bool {
+		if thirdParty[sorted[i]] != thirdParty[sorted[j]] {
+			return thirdParty[sorted[i]]
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	color.New(color.FgCyan, color.Bold).Println("Running Services and Boot Receivers")
+	fmt.Print(rule("=", 60))
+	autoStart := 0
+	for _, name := range sorted {
+		c := color.New(color.FgWhite, color.Bold)
+		label := ""
+		if thirdParty[name] {
+			label = " [third-party]"
+			if len(running[name]) > 0 && len(receivers[name]) > 0 {
+				c = color.New(color.FgYellow, color.Bold)
+				label = " [third-party, auto-starts]"
+				autoStart++
+			}
+		}
+		c.Printf("%s%s\n", name, label)
+		for _, service := range running[name] {
+			fmt.Printf("  service   %s\n", service)
+		}
+		for _, receiver := range receivers[name] {
+			fmt.Printf("  boot      %s\n", receiver)
+		}
+	}
+	if len(sorted) == 0 {
+		fmt.Println("No running services or boot receivers found.")
+	}
+	if autoStart > 0 {
+		fmt.Printf("\n%d third-party package(s) start at boot and keep services running.\n", autoStart)
+	}
+	return nil
+}
+
+// parseRunningServices groups the ServiceRecords of `dumpsys activity
+// services` by package.
+func parseRunningServices(output string) map[string][]string {
+	services := map[string][]string{}
+	for _, m := range serviceRecordPattern.FindAllStringSubmatch(output, -1) {
+		if !containsString(services[m[1]], m[2]) {
+			services[m[1]] = append(services[m[1]], m[2])
+		}
+	}
+	return services
+}
+
+// bootReceivers lists the BOOT_COMPLETED receivers of every package, using
+// query-receivers where available and the package dump's resolver table on
+// older releases.
+func bootReceivers(deviceID string) map[string][]string {
+	receivers := map[string][]string{}
+	add := func(component string) {
+		pkg, class, found := strings.Cut(strings.TrimSpace(component), "/")
+		if found && !containsString(receivers[pkg], class) {
+			receivers[pkg] = append(receivers[pkg], class)
+		}
+	}
+	output, err := adbShellIdempotent(deviceID, "cmd", "package", "query-receivers", "--brief", "-a", "android.intent.action.BOOT_COMPLETED")
+	if err == nil && !strings.Contains(output, "Unknown command") {
+		for _, line := range strings.Split(output, "\n") {
+			add(line)
+		}
+		return receivers
+	}
+
+	output, _ = adbShellIdempotent(deviceID, "dumpsys", "package", "r")
+	inBoot := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "android.intent.action.BOOT_COMPLETED:"):
+			inBoot = true
+		case strings.HasSuffix(trimmed, ":"):
+			inBoot = false
+		case inBoot:
+			// e.g. "1234abc com.example.app/.BootReceiver filter 5678def"
+			for _, field := range strings.Fields(trimmed) {
+				if strings.Contains(field, "/") {
+					add(field)
+				}
+			}
+		}
+	}
+	return receivers
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var settingsNamespaces = []string{"system", "secure", "global"}
+
+// settingTypes lists the value type of commonly tweaked settings so typos like
+// "screen_off_timeout 30s" are rejected before they reach the device.
+var settingTypes = map[string]string{
+	"screen_off_timeout":           "int",
+	"screen_brightness":            "int",
+	"stay_on_while_plugged_in":     "int",
+	"location_mode":                "int",
+	"zen_mode":                     "int",
+	"window_animation_scale":       "float",
+	"transition_animation_scale":   "float",
+	"animator_duration_scale":      "float",
+	"font_scale":                   "float",
+	"adb_enabled":                  "bool",
+	"development_settings_enabled": "bool",
+	"accelerometer_rotation":       "bool",
+	"screen_brightness_mode":       "bool",
+	"lock_to_app_enabled":          "bool",
+	"install_non_market_apps":      "bool",
+	"sysui_demo_allowed":           "bool",
+	"always_finish_activities":     "bool",
+	"show_touches":                 "bool",
+	"pointer_location":             "bool",
+	"auto_time":                    "bool",
+	"auto_time_zone":               "bool",
+	"airplane_mode_on":             "bool",
+	"wifi_on":                      "bool",
+	"bluetooth_on":                 "bool",
+}
+
+func runSettings(args []string) error {
+	if len(args) > 0 && args[0] == "apply" {
+		return runSettingsApply(args[1:])
+	}
+	if len(args) > 0 && args[0] == "export" {
+		return runSettingsExport(args[1:])
+	}
+
+	fs := flag.NewFlagSet("settings", flag.ExitOnError)
+	search := fs.String("search", "", "Only show settings whose name or value contains this text")
+	force := fs.Bool("force", false, "Skip type validation when writing")
+	positional := parseArgs(fs, args)
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: settings get|put|list|apply|export [system|secure|global] ...")
+	}
+	action, rest := positional[0], positional[1:]
+
+	switch action {
+	case "list":
+		namespaces := settingsNamespaces
+		if len(rest) > 0 {
+			if err := validateNamespace(rest[0]); err != nil {
+				return err
+			}
+			namespaces = rest[:1]
+		}
+		deviceID := pickDevice()
+		for _, ns := range namespaces {
+			values, err := listSettings(deviceID, ns)
+			if err != nil {
+				return err
+			}
+			printSettings(ns, values, *search)
+		}
+		return nil
+	case "get":
+		deviceID := pickDevice()
+		if len(rest) == 1 {
+			// No namespace given: look the key up everywhere.
+			for _, ns := range settingsNamespaces {
+				if value, err := getSetting(deviceID, ns, rest[0]); err == nil && value != "null" {
+					fmt.Printf("%s/%s = %s\n", ns, rest[0], value)
+				}
+			}
+			return nil
+		}
+		if len(rest) != 2 {
+			return fmt.Errorf("usage: settings get [namespace] <key>")
+		}
+		if err := validateNamespace(rest[0]); err != nil {
+			return err
+		}
+		value, err := getSetting(deviceID, rest[0], rest[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	case "put":
+		if len(rest) != 3 {
+			return fmt.Errorf("usage: settings put <namespace> <key> <value>")
+		}
+		if err := validateNamespace(rest[0]); err != nil {
+			return err
+		}
+		if !*force {
+			if err := validateSettingValue(rest[1], rest[2]); err != nil {
+				return err
+			}
+		}
+		deviceID := pickDevice()
+		previous, _ := getSetting(deviceID, rest[0], rest[1])
+		if err := putSetting(deviceID, rest[0], rest[1], rest[2]); err != nil {
+			return err
+		}
+		fmt.Printf("%s/%s: %s -> %s\n", rest[0], rest[1], previous, rest[2])
+		return nil
+	default:
+		return fmt.Errorf("unknown settings action %q", action)
+	}
+}
+
+func validateNamespace(ns string) error {
+	if !containsString(settingsNamespaces, ns) {
+		return fmt.Errorf("invalid namespace %q (expected system, secure or global)", ns)
+	}
+	return nil
+}
+
+func validateSettingValue(key, value string) error {
+	var err error
+	switch settingTypes[key] {
+	case "int":
+		_, err = strconv.Atoi(value)
+	case "float":
+		_, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		if value != "0" && value != "1" {
+			err = fmt.Errorf("not 0 or 1")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s expects a %s value, got %q (use --force to override)", key, settingTypes[key], value)
+	}
+	return nil
+}
+
+func getSetting(deviceID, ns, key string) (string, error) {
+	return adbShellIdempotent(deviceID, "settings", "get", ns, shellQuote(key))
+}
+
+func putSetting(deviceID, ns, key, value string) error {
+	// adb joins the arguments into one remote shell command line, so the
+	// key and value (a proxy list, a URL) are quoted to reach settings as
+	// they are.
+	output, err := adbShell(deviceID, "settings", "put", ns, shellQuote(key), shellQuote(value))
+	if err != nil {
+		return fmt.Errorf("failed to set %s/%s: %s", ns, key, output)
+	}
+	return nil
+}
+
+func listSettings(deviceID, ns string) (map[string]string, error) {
+	output, err := adbShellIdempotent(deviceID, "settings", "list", ns)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, found := strings.Cut(strings.TrimSpace(line), "="); found {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func printSettings(ns string, values map[string]string, search string) {
+	keys := make([]string, 0, len(values))
+	for key, value := range values {
+		if search == "" || strings.Contains(key, search) || strings.Contains(value, search) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	color.New(color.FgYellow, color.Bold).Printf("[ %s ]\n", ns)
+	for _, key := range keys {
+		color.New(color.FgGreen).Printf("  %-45s ", key)
+		fmt.Println(values[key])
+	}
+	fmt.Println()
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+// settingsBundle is namespace -> key -> value, stored as YAML or JSON:
+//
+//	global:
+//	  window_animation_scale: "0"
+//	system:
+//	  screen_off_timeout: "600000"
+//	secure:
+//	  show_ime_with_hard_keyboard: null
+//
+// A null value, which is what settings get prints for an unset key, deletes
+// the key so the platform default applies again.
+type settingsBundle map[string]map[string]string
+
+// unsetSetting is the value of a key that is not set.
+const unsetSetting = "null"
+
+func runSettingsApply(args []string) error {
+	fs := flag.NewFlagSet("settings apply", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Only show what would change")
+	rollbackFile := fs.String("save-rollback", "", "Write the previous values to this bundle file")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: settings apply <bundle.yaml> [--dry-run] [--save-rollback old.yaml]")
+	}
+
+	bundle, err := loadSettingsBundle(positional[0])
+	if err != nil {
+		return err
+	}
+	for ns, values := range bundle {
+		if err := validateNamespace(ns); err != nil {
+			return err
+		}
+		for key, value := range values {
+			if value == unsetSetting {
+				continue
+			}
+			if err := validateSettingValue(key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	deviceID := pickDevice()
+	previous := make(settingsBundle)
+	for ns, values := range bundle {
+		previous[ns] = make(map[string]string)
+		for key := range values {
+			// A failed read must not be recorded as unset: rolling back
+			// would then delete the key.
+			old, err := getSetting(deviceID, ns, key)
+			if err != nil {
+				return fmt.Errorf("could not read the current %s/%s, nothing was changed: %v", ns, key, err)
+			}
+			if old == "" {
+				old = unsetSetting
+			}
+			previous[ns][key] = old
+		}
+	}
+
+	for _, ns := range settingsNamespaces {
+		for _, key := range sortedKeys(bundle[ns]) {
+			if previous[ns][key] != bundle[ns][key] {
+				fmt.Printf("%s/%s: %s -> %s\n", ns, key, previous[ns][key], bundle[ns][key])
+			}
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+	if *rollbackFile != "" {
+		if err := saveSettingsBundle(*rollbackFile, previous); err != nil {
+			return err
+		}
+	}
+
+	var applied [][2]string
+	for _, ns := range settingsNamespaces {
+		for _, key := range sortedKeys(bundle[ns]) {
+			if err := applySetting(deviceID, ns, key, bundle[ns][key]); err != nil {
+				color.New(color.FgRed).Printf("%v, rolling back %d change(s)\n", err, len(applied))
+				rollbackSettings(deviceID, applied, previous)
+				return fmt.Errorf("bundle was not applied")
+			}
+			applied = append(applied, [2]string{ns, key})
+		}
+	}
+	color.New(color.FgGreen).Printf("Applied %d setting(s) from %s\n", len(applied), positional[0])
+	return nil
+}
+
+// applySetting puts value, or deletes the key when value is unsetSetting.
+func applySetting(deviceID, ns, key, value string) error {
+	if value != unsetSetting {
+		return putSetting(deviceID, ns, key, value)
+	}
+	output, err := adbShell(deviceID, "settings", "delete", ns, shellQuote(key))
+	if err != nil {
+		return fmt.Errorf("failed to delete %s/%s: %s", ns, key, output)
+	}
+	return nil
+}
+
+// rollbackSettings restores previous values in reverse order; keys that did
+// not exist before are deleted so the platform default applies again.
+func rollbackSettings(deviceID string, applied [][2]string, previous settingsBundle) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		ns, key := applied[i][0], applied[i][1]
+		if err := applySetting(deviceID, ns, key, previous[ns][key]); err != nil {
+			fmt.Printf("  failed to restore %s/%s: %v\n", ns, key, err)
+		}
+	}
+}
+
+func runSettingsExport(args []string) error {
+	fs := flag.NewFlagSet("settings export", flag.ExitOnError)
+	output := fs.String("o", "", "Write the bundle to this file (.yaml or .json) instead of stdout")
+	positional := parseArgs(fs, args)
+
+	namespaces := settingsNamespaces
+	if len(positional) > 0 {
+		for _, ns := range positional {
+			if err := validateNamespace(ns); err != nil {
+				return err
+			}
+		}
+		namespaces = positional
+	}
+
+	deviceID := pickDevice()
+	bundle := make(settingsBundle)
+	for _, ns := range namespaces {
+		values, err := listSettings(deviceID, ns)
+		if err != nil {
+			return err
+		}
+		bundle[ns] = values
+	}
+
+	if *output == "" {
+		data, err := yaml.Marshal(bundle)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+	if err := saveSettingsBundle(*output, bundle); err != nil {
+		return err
+	}
+	fmt.Printf("Exported settings to %s\n", *output)
+	return nil
+}
+
+func loadSettingsBundle(path string) (settingsBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Decode loosely so unquoted YAML numbers like `screen_off_timeout: 600000` work.
+	var raw map[string]map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	} else {
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid settings bundle %s: %v", path, err)
+	}
+	bundle := make(settingsBundle)
+	for ns, values := range raw {
+		bundle[ns] = make(map[string]string)
+		for key, value := range values {
+			if value == nil {
+				bundle[ns][key] = unsetSetting
+				continue
+			}
+			bundle[ns][key] = fmt.Sprint(value)
+		}
+	}
+	return bundle, nil
+}
+
+func saveSettingsBundle(path string, bundle settingsBundle) error {
+	// Unset keys are written as null rather than the string "null".
+	raw := make(map[string]map[string]*string)
+	for ns, values := range bundle {
+		raw[ns] = make(map[string]*string)
+		for key, value := range values {
+			raw[ns][key] = nil
+			if value != unsetSetting {
+				raw[ns][key] = &value
+			}
+		}
+	}
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(raw, "", "  ")
+	} else {
+		data, err = yaml.Marshal(raw)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
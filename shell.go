@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+)
+
+// runShell runs a shell command on the device and prints its output line by
+// line as it is produced, so long-running commands such as top or monkey show
+// progress. Ctrl+C stops the command; its exit status becomes adbctl's.
+// Only flags before the command (or before "--") are adbctl's; the command
+// and its own flags are passed on as they are.
+func runShell(args []string) error {
+	fs := flag.NewFlagSet("shell", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 0, "Stop the command after this long (default: run until it exits)")
+	fs.Parse(args)
+	command := fs.Args()
+	if len(command) == 0 {
+		return fmt.Errorf("usage: shell [--timeout 1m] -- <command> [args...]")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	deviceID := pickDevice()
+	// adb shell joins its arguments into one command line for the device shell.
+	err := adbStream(ctx, deviceID, func(line string) { fmt.Println(line) }, "shell", strings.Join(command, " "))
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &exitCodeError{Code: exitErr.ExitCode(), Err: fmt.Errorf("%s exited with status %d", command[0], exitErr.ExitCode())}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgsStopsAtDoubleDash(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	verbose := fs.Bool("v", false, "")
+	got := parseArgs(fs, []string{"a", "-v", "--", "ls", "-l", "--", "-x"})
+	if want := []string{"a", "ls", "-l", "--", "-x"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseArgs = %q, want %q", got, want)
+	}
+	if !*verbose {
+		t.Error("-v before -- was not parsed")
+	}
+}
+
+func TestShellPassesCommandFlags(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	fake := filepath.Join(dir, "adb")
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n"
+	if err := os.WriteFile(fake, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	defer func(path string, probe bool) { adbPath, probeDevice = path, probe }(adbPath, probeDevice)
+	adbPath, probeDevice = fake, false
+	t.Setenv("ADBCTL_DEVICE", "emu1")
+
+	for _, args := range [][]string{{"--", "ls", "-l"}, {"--timeout", "1m", "ls", "-l"}} {
+		os.Remove(log)
+		if err := runShell(args); err != nil {
+			t.Fatalf("shell %q: %v", args, err)
+		}
+		calls, _ := os.ReadFile(log)
+		if !strings.Contains(string(calls), "-s emu1 shell ls -l\n") {
+			t.Errorf("shell %q ran %q, want adb -s emu1 shell ls -l", args, calls)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var sideloadProgress = regexp.MustCompile(`\(~?(\d+)%\)`)
+
+// runSideload installs an OTA package: reboot into sideload mode (unless the
+// device is already there), stream the package with a progress bar and report
+// what recovery said about it.
+func runSideload(args []string) error {
+	fs := flag.NewFlagSet("sideload", flag.ExitOnError)
+	reboot := fs.Bool("reboot", false, "Reboot into Android after a successful install and wait for boot")
+	timeout := fs.Duration("timeout", 3*time.Minute, "How long to wait for sideload mode")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: sideload <update.zip> [--reboot]")
+	}
+	pkg := positional[0]
+	info, err := os.Stat(pkg)
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	if adbDeviceStatus(deviceID) != "sideload" {
+		if !confirm(fmt.Sprintf("Type 'yes' to reboot %s into sideload mode and install %s: ", deviceID, pkg)) {
+			return fmt.Errorf("aborted")
+		}
+		if err := rebootDevice(deviceID, "sideload"); err != nil {
+			return err
+		}
+		fmt.Println("Waiting for sideload mode...")
+		if err := pollUntil(*timeout, time.Second, func() bool { return adbDeviceStatus(deviceID) == "sideload" }); err != nil {
+			return fmt.Errorf("%s did not enter sideload mode; on some devices it must be started from the recovery menu (Apply update from ADB): %v", deviceID, err)
+		}
+	}
+
+	fmt.Printf("Sideloading %s (%s)\n", pkg, formatBytes(info.Size()))
+	output, err := streamSideload(deviceID, pkg)
+	if err != nil {
+		color.New(color.FgRed, color.Bold).Println("Sideload failed.")
+		if output != "" {
+			fmt.Println(output)
+		}
+		fmt.Println("Recovery shows the verification error on screen; common causes are a package signed for another device or build, or a downgrade.")
+		return normalizeAdbError(output, fmt.Errorf("adb sideload: %v", err))
+	}
+	color.New(color.FgGreen, color.Bold).Println("Package transferred and accepted by recovery.")
+	if output != "" {
+		fmt.Println(output)
+	}
+
+	if !*reboot {
+		fmt.Println("Reboot from the recovery menu, or run 'adbctl reboot --wait' once it shows the device.")
+		return nil
+	}
+	pollUntil(time.Minute, time.Second, func() bool { return adbDeviceStatus(deviceID) == "recovery" })
+	if err := rebootDevice(deviceID, ""); err != nil {
+		return err
+	}
+	return waitForState(deviceID, StateBooted, 10*time.Minute)
+}
+
+// streamSideload runs adb sideload, showing its "(~NN%)" progress as a bar,
+// and returns the remaining (non-progress) output.
+func streamSideload(deviceID, pkg string) (string, error) {
+	cmd := exec.Command(adbPath, "-s", deviceID, "sideload", pkg)
+	reader, writer := io.Pipe()
+	cmd.Stdout, cmd.Stderr = writer, writer
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+	go func() {
+		writer.CloseWithError(cmd.Wait())
+	}()
+
+	p := startProgress("Sideloading "+filepath.Base(pkg), 100, progressPercent)
+	scanner := bufio.NewScanner(reader)
+	scanner.Split(scanProgressLines)
+	var messages []string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := sideloadProgress.FindStringSubmatch(line); m != nil {
+			percent, _ := strconv.Atoi(m[1])
+			p.Set(int64(percent))
+			continue
+		}
+		if line != "" {
+			messages = append(messages, line)
+		}
+	}
+	p.Finish(scanner.Err())
+	return strings.Join(messages, "\n"), scanner.Err()
+}
+
+// scanProgressLines splits on \r as well as \n, since adb redraws its
+// progress in place.
+func scanProgressLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var launchTotalTimePattern = regexp.MustCompile(`TotalTime: (\d+)`)
+
+func runStart(args []string) error {
+	fs := flag.NewFlagSet("start", flag.ExitOnError)
+	flavor := fs.String("flavor", "", "Start this flavor's package from the project profile")
+	variant := fs.String("variant", "", "In a Gradle project, start this build variant's application id (default debug)")
+	restart := fs.Bool("restart", false, "Force-stop the app first for a cold start")
+	args = parseArgs(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("usage: start [package] [--flavor name | --variant name] [--restart]")
+	}
+	pkg, err := appPackage(args, *flavor, *variant)
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	if *restart {
+		adbShell(deviceID, "am", "force-stop", shellQuote(pkg))
+	}
+	launchTime, err := startApp(deviceID, pkg)
+	if err != nil {
+		return err
+	}
+	if launchTime > 0 {
+		fmt.Printf("Started %s in %s\n", pkg, launchTime)
+	} else {
+		fmt.Printf("Started %s\n", pkg)
+	}
+	return nil
+}
+
+// startApp starts pkg's launcher activity and waits for it to draw, returning
+// the launch time reported by the activity manager (0 when the app was
+// already in front).
+func startApp(deviceID, pkg string) (time.Duration, error) {
+	activity, err := resolveLauncherActivity(deviceID, pkg)
+	if err != nil {
+		return 0, err
+	}
+	output, err := adbShell(deviceID, "am", "start", "-W", "-n", shellQuote(activity))
+	if err != nil || strings.Contains(output, "Error:") {
+		return 0, fmt.Errorf("failed to start %s: %s", pkg, output)
+	}
+	var ms int
+	if m := launchTotalTimePattern.FindStringSubmatch(output); m != nil {
+		fmt.Sscanf(m[1], "%d", &ms)
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// runSmoke cold-starts the app, lets it run and checks that it is still alive
+// and in front without crashes or ANRs, keeping a screenshot and the log.
+func runSmoke(args []string) error {
+	fs := flag.NewFlagSet("smoke", flag.ExitOnError)
+	flavor := fs.String("flavor", "", "Test this flavor's package from the project profile")
+	variant := fs.String("variant", "", "In a Gradle project, test this build variant's application id (default debug)")
+	duration := fs.Duration("duration", 10*time.Second, "How long the app must keep running")
+	upload := fs.String("upload", "", "Also upload the artifacts as a .tar.gz to s3://, gs:// or an HTTP PUT URL")
+	args = parseArgs(fs, args)
+	if len(args) > 1 {
+		return fmt.Errorf("usage: smoke [package] [--flavor name | --variant name] [--duration 10s] [--upload dest]")
+	}
+	pkg, err := appPackage(args, *flavor, *variant)
+	if err != nil {
+		return err
+	}
+
+	deviceID := pickDevice()
+	dir, err := artifactDir("smoke")
+	if err != nil {
+		return err
+	}
+	adbShell(deviceID, "am", "force-stop", shellQuote(pkg))
+	adbShell(deviceID, "logcat", "-c")
+	fmt.Printf("Smoke testing %s on %s for %s\n", pkg, deviceID, *duration)
+	launchTime, err := startApp(deviceID, pkg)
+	if err != nil {
+		return err
+	}
+	time.Sleep(*duration)
+
+	ok := smokeCheck("Launch", fmt.Sprintf("%d ms", launchTime.Milliseconds()), true)
+	pid := runAdbCommand(deviceID, "pidof "+shellQuote(pkg), 5*time.Second)
+	ok = smokeCheck("Process", pid, pid != "" && pid != "n/a") && ok
+	foreground, _ := foregroundActivity(deviceID)
+	ok = smokeCheck("Foreground", foreground, foreground == pkg) && ok
+
+	log, _ := adbShellIdempotent(deviceID, "logcat", "-d", "-v", "threadtime")
+	crashes := 0
+	for _, line := range strings.Split(log, "\n") {
+		// A crash logs "FATAL EXCEPTION" followed by "Process: <pkg>, PID: n".
+		if strings.Contains(line, "ANR in "+pkg) || (strings.Contains(line, "AndroidRuntime") && strings.Contains(line, "Process: "+pkg+",")) {
+			crashes++
+		}
+	}
+	ok = smokeCheck("Crashes and ANRs", fmt.Sprint(crashes), crashes == 0) && ok
+
+	if err := os.WriteFile(filepath.Join(dir, "logcat.txt"), []byte(log), 0644); err != nil {
+		return err
+	}
+	if err := captureScreenshot(deviceID, filepath.Join(dir, "screen.png")); err != nil {
+		debugPrint("smoke screenshot: %v\n", err)
+	}
+	fmt.Printf("Artifacts saved to %s\n", dir)
+	// Failed runs are the ones worth archiving, so upload before judging.
+	if err := uploadAndReport(dir, *upload); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("smoke test failed for %s", pkg)
+	}
+	color.New(color.FgGreen, color.Bold).Println("Smoke test passed.")
+	return nil
+}
+
+func smokeCheck(label, value string, ok bool) bool {
+	fmt.Printf("%-20s : %s ", label, value)
+	if ok {
+		color.New(color.FgGreen).Println("OK")
+	} else {
+		color.New(color.FgRed).Println("FAIL")
+	}
+	return ok
+}
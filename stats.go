@@ -0,0 +1,119 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func runStats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	since := fs.Duration("since", 30*24*time.Hour, "Only include usage from this far back")
+	top := fs.Int("top", 10, "Number of commands to show")
+	positional := parseArgs(fs, args)
+
+	if len(positional) > 0 {
+		switch positional[0] {
+		case "enable":
+			if err := os.MkdirAll(configDir(), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(auditLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return err
+			}
+			f.Close()
+			fmt.Printf("Local usage recording enabled (%s). Nothing is sent anywhere.\n", auditLogPath())
+			return nil
+		case "disable":
+			if err := os.Remove(auditLogPath()); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			fmt.Println("Local usage recording disabled and the audit log deleted.")
+			return nil
+		default:
+			return fmt.Errorf("unknown stats action %q", positional[0])
+		}
+	}
+
+	entries, err := readAuditLog()
+	if os.IsNotExist(err) {
+		return fmt.Errorf("usage recording is off; run 'adbctl stats enable' to opt in")
+	} else if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-*since)
+	commandCounts := make(map[string]int)
+	deviceCounts := make(map[string]int)
+	deviceLastSeen := make(map[string]time.Time)
+	infoTotal := make(map[string]time.Duration)
+	infoCount := make(map[string]int)
+	failures, total := 0, 0
+	for _, e := range entries {
+		if e.Time.Before(cutoff) {
+			continue
+		}
+		total++
+		commandCounts[e.Command]++
+		if e.Error != "" {
+			failures++
+		}
+		if e.Device == "" {
+			continue
+		}
+		deviceCounts[e.Device]++
+		if e.Time.After(deviceLastSeen[e.Device]) {
+			deviceLastSeen[e.Device] = e.Time
+		}
+		if e.Command == "info" && e.Error == "" {
+			infoTotal[e.Device] += time.Duration(e.DurationMs) * time.Millisecond
+			infoCount[e.Device]++
+		}
+	}
+	if total == 0 {
+		fmt.Println("No recorded usage in this period.")
+		return nil
+	}
+
+	color.New(color.FgCyan, color.Bold).Printf("Usage over the last %s: %d commands, %d failed\n\n", *since, total, failures)
+
+	color.New(color.FgYellow, color.Bold).Println("[ Most used commands ]")
+	for i, name := range sortByCount(commandCounts) {
+		if i >= *top {
+			break
+		}
+		fmt.Printf("  %-25s %5d\n", name, commandCounts[name])
+	}
+
+	color.New(color.FgYellow, color.Bold).Println("\n[ Devices ]")
+	fmt.Printf("  %-25s %5s  %-12s %s\n", "DEVICE", "USES", "AVG INFO", "LAST USED")
+	for _, device := range sortByCount(deviceCounts) {
+		avg := "n/a"
+		if infoCount[device] > 0 {
+			avg = (infoTotal[device] / time.Duration(infoCount[device])).Round(10 * time.Millisecond).String()
+		}
+		fmt.Printf("  %-25s %5d  %-12s %s\n", device, deviceCounts[device], avg, deviceLastSeen[device].Format("2006-01-02 15:04"))
+	}
+	fmt.Print("\n" + rule("-", 40))
+	fmt.Println("Slow AVG INFO values usually point at a weak WiFi link or an overloaded device.")
+	return nil
+}
+
+func sortByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	storeFile        = "store.json"
+	boltFile         = "store.db"
+	sqliteFile       = "store.sqlite"
+	boltBucket       = "documents"
+	boltStampBucket  = "updated"       // name -> RFC 3339 time the document was last saved
+	storeLockTimeout = 5 * time.Second // how long to wait for another adbctl holding the database
+)
+
+// storeDocuments are the documents kept in the store, copied over when
+// switching backends.
+var storeDocuments = []string{endpointsFile, historyFile, aliasesFile, deviceAliasesFile, shortcutsFile, smtpFile, mqttFile}
+
+// localOnlyFiles describe this machine and never leave configDir().
+var localOnlyFiles = []string{adbFile, storeFile}
+
+// storeNamePattern keeps remote requests to plain document names.
+var storeNamePattern = regexp.MustCompile(`^[\w.-]+\.json$`)
+
+// configStore holds adbctl's named JSON documents: paired endpoints,
+// connection history, aliases, shortcuts and the like. The bolt store (the
+// default) and the sqlite store keep them in one database file in
+// configDir(); the file store keeps one file per document there; the remote
+// store keeps them on a shared lab server (another adbctl running `store
+// serve`, or any REST service with the same GET/PUT interface) so every
+// laptop sees the same device metadata.
+type configStore interface {
+	// Load returns nil and no error when the document doesn't exist.
+	Load(name string) ([]byte, error)
+	Save(name string, data []byte) error
+	String() string
+}
+
+type storeConfig struct {
+	Backend string `json:"backend"` // bolt (default), sqlite, file or remote
+	URL     string `json:"url,omitempty"`
+}
+
+type fileStore struct{ dir string }
+
+func (s fileStore) Load(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s fileStore) Save(name string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.dir, name), data, 0644)
+}
+
+func (s fileStore) String() string { return "file (" + s.dir + ")" }
+
+// importNewerDocuments copies the per-document files in dir into a database
+// when the database has no copy or an older one, so the JSON files a file
+// store left behind, and hand edits to them (smtp.json, mqtt.json,
+// shortcuts.json, ...), are picked up. stored returns when the database last
+// saved a document.
+func importNewerDocuments(dir string, stored func(name string) (time.Time, bool), save func(name string, data []byte) error) error {
+	for _, name := range storeDocuments {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		if updated, ok := stored(name); ok && !info.ModTime().After(updated) {
+			continue
+		}
+		data, err := fileStore{dir}.Load(name)
+		if err != nil {
+			return err
+		}
+		debugPrint("store: importing %s\n", name)
+		if err := save(name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltStore keeps the documents in a bbolt database. It is opened for each
+// call, so several adbctl processes can take turns with it.
+type boltStore struct{ path string }
+
+func (s boltStore) open() (*bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(s.path, 0644, &bolt.Options{Timeout: storeLockTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %v", s.path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(boltBucket))
+		if err != nil {
+			return err
+		}
+		stamps, err := tx.CreateBucketIfNotExists([]byte(boltStampBucket))
+		if err != nil {
+			return err
+		}
+		// Databases from before the stamps were kept count as current.
+		err = bucket.ForEach(func(name, _ []byte) error {
+			if stamps.Get(name) != nil {
+				return nil
+			}
+			return stamps.Put(name, []byte(time.Now().UTC().Format(time.RFC3339Nano)))
+		})
+		if err != nil {
+			return err
+		}
+		return importNewerDocuments(filepath.Dir(s.path), func(name string) (time.Time, bool) {
+			updated, err := time.Parse(time.RFC3339Nano, string(stamps.Get([]byte(name))))
+			return updated, err == nil
+		}, func(name string, data []byte) error {
+			return boltPut(tx, name, data)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("store %s: %v", s.path, err)
+	}
+	return db, nil
+}
+
+func (s boltStore) Load(name string) ([]byte, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var data []byte
+	err = db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket([]byte(boltBucket)).Get([]byte(name)); value != nil {
+			// value is only valid inside the transaction.
+			data = append([]byte{}, value...)
+		}
+		return nil
+	})
+	return data, err
+}
+
+func (s boltStore) Save(name string, data []byte) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		return boltPut(tx, name, data)
+	})
+}
+
+func boltPut(tx *bolt.Tx, name string, data []byte) error {
+	if err := tx.Bucket([]byte(boltBucket)).Put([]byte(name), data); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(boltStampBucket)).Put([]byte(name), []byte(time.Now().UTC().Format(time.RFC3339Nano)))
+}
+
+func (s boltStore) String() string { return "bolt (" + s.path + ")" }
+
+// remoteStore reads and writes documents at <url>/<name>, sending
+// ADBCTL_STORE_TOKEN as a bearer token when it is set.
+type remoteStore struct {
+	url    string
+	client *http.Client
+}
+
+func (s remoteStore) request(method, name string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(s.url, "/")+"/"+name, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("ADBCTL_STORE_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.client.Do(req)
+}
+
+func (s remoteStore) Load(name string) ([]byte, error) {
+	resp, err := s.request(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("store %s: %v", s.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("store %s: GET %s: %s", s.url, name, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s remoteStore) Save(name string, data []byte) error {
+	resp, err := s.request(http.MethodPut, name, data)
+	if err != nil {
+		return fmt.Errorf("store %s: %v", s.url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("store %s: PUT %s: %s", s.url, name, resp.Status)
+	}
+	return nil
+}
+
+func (s remoteStore) String() string { return "remote (" + s.url + ")" }
+
+var (
+	activeStore     configStore
+	activeStoreOnce sync.Once
+)
+
+// newStore returns the store a storeConfig describes.
+func newStore(config storeConfig) (configStore, error) {
+	dir := configDir()
+	switch config.Backend {
+	case "", "bolt":
+		return boltStore{filepath.Join(dir, boltFile)}, nil
+	case "sqlite":
+		return sqliteStore{filepath.Join(dir, sqliteFile)}, nil
+	case "file":
+		return fileStore{dir}, nil
+	case "remote":
+		if config.URL == "" {
+			return nil, fmt.Errorf("the remote store needs a URL")
+		}
+		return remoteStore{url: config.URL, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	}
+	return nil, fmt.Errorf("unknown store backend %q (want bolt, sqlite, file or remote)", config.Backend)
+}
+
+// documentPath tells users where to edit the named document by hand: its
+// file in configDir(), which the local stores read when it is newer than
+// their copy, or the same file on the server of a remote store.
+func documentPath(name string) string {
+	if remote, ok := storeFor(name).(remoteStore); ok {
+		return fmt.Sprintf("%s in the config directory of the store server (%s)", name, remote.url)
+	}
+	return filepath.Join(configDir(), name)
+}
+
+// storeFor returns the store a document lives in. ADBCTL_STORE (bolt,
+// sqlite, file or a URL) overrides store.json.
+func storeFor(name string) configStore {
+	local := fileStore{configDir()}
+	if containsString(localOnlyFiles, name) {
+		return local
+	}
+	activeStoreOnce.Do(func() {
+		var config storeConfig
+		if data, err := local.Load(storeFile); err == nil && data != nil {
+			if err := json.Unmarshal(data, &config); err != nil {
+				debugPrint("%s: %v\n", storeFile, err)
+			}
+		}
+		if env := os.Getenv("ADBCTL_STORE"); env != "" {
+			config = storeConfig{Backend: env}
+			if strings.HasPrefix(env, "http://") || strings.HasPrefix(env, "https://") {
+				config = storeConfig{Backend: "remote", URL: env}
+			}
+		}
+		store, err := newStore(config)
+		if err != nil {
+			printError(err)
+			os.Exit(exitError)
+		}
+		activeStore = store
+	})
+	return activeStore
+}
+
+// switchStore makes config the store, copying over the documents the new
+// store doesn't have yet.
+func switchStore(config storeConfig) error {
+	next, err := newStore(config)
+	if err != nil {
+		return err
+	}
+	current := storeFor("")
+	if current.String() != next.String() {
+		copied := 0
+		for _, name := range storeDocuments {
+			data, err := current.Load(name)
+			if err != nil {
+				return err
+			}
+			if existing, err := next.Load(name); err != nil {
+				return err
+			} else if data == nil || existing != nil {
+				continue
+			}
+			if err := next.Save(name, data); err != nil {
+				return err
+			}
+			copied++
+		}
+		if copied > 0 {
+			fmt.Printf("Copied %d document(s) from the %s store\n", copied, current)
+		}
+	}
+	if err := saveConfigJSON(storeFile, config); err != nil {
+		return err
+	}
+	fmt.Printf("Using the %s store\n", next)
+	return nil
+}
+
+// runStore shows or switches the store, and serves a local store to others.
+func runStore(args []string) error {
+	if len(args) == 0 {
+		fmt.Printf("Store: %s\n", storeFor(""))
+		return nil
+	}
+	switch args[0] {
+	case "bolt", "sqlite", "file":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: store %s", args[0])
+		}
+		return switchStore(storeConfig{Backend: args[0]})
+	case "remote":
+		if len(args) != 2 || (!strings.HasPrefix(args[1], "http://") && !strings.HasPrefix(args[1], "https://")) {
+			return fmt.Errorf("usage: store remote <http(s)://server/path>")
+		}
+		probe := remoteStore{url: args[1], client: &http.Client{Timeout: 10 * time.Second}}
+		if _, err := probe.Load(endpointsFile); err != nil {
+			return err
+		}
+		return switchStore(storeConfig{Backend: "remote", URL: args[1]})
+	case "serve":
+		return serveStore(args[1:])
+	}
+	return fmt.Errorf("unknown store action %q (want bolt, sqlite, file, remote or serve)", args[0])
+}
+
+// serveStore shares this machine's store over HTTP for `store remote`.
+func serveStore(args []string) error {
+	fs := flag.NewFlagSet("store serve", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8750", "Address to listen on")
+	parseArgs(fs, args)
+	// The store holds the SMTP and MQTT settings among the rest, so it is
+	// only shared beyond this machine with a token.
+	token := os.Getenv("ADBCTL_STORE_TOKEN")
+	if host, _, err := net.SplitHostPort(*listen); token == "" && (err != nil || !isLoopbackHost(host)) {
+		return fmt.Errorf("set ADBCTL_STORE_TOKEN to serve the store on %s; without a token it is only served on 127.0.0.1", *listen)
+	}
+	store := storeFor("")
+	if _, isRemote := store.(remoteStore); isRemote {
+		return fmt.Errorf("this machine uses the %s store; serve from the machine that keeps the data", store)
+	}
+	var mu sync.Mutex
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		name := filepath.Base(r.URL.Path)
+		if !storeNamePattern.MatchString(name) || containsString(localOnlyFiles, name) {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			data, err := store.Load(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else if data == nil {
+				http.NotFound(w, r)
+			} else {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(data)
+			}
+		case http.MethodPut:
+			data, err := io.ReadAll(io.LimitReader(r.Body, 16<<20))
+			if err == nil && !json.Valid(data) {
+				err = fmt.Errorf("body is not JSON")
+			}
+			if err == nil {
+				err = store.Save(name, data)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			debugPrint("store: %s saved by %s\n", name, r.RemoteAddr)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	fmt.Printf("Serving the %s store on %s\n", store, *listen)
+	return http.ListenAndServe(*listen, handler)
+}
@@ -0,0 +1,19 @@
+//go:build !cgo
+
+package main
+
+import "fmt"
+
+// sqliteStore stands in for the SQLite store in builds without cgo, which
+// the driver needs; build.sh cross-compiles that way.
+type sqliteStore struct{ path string }
+
+func (s sqliteStore) err() error {
+	return fmt.Errorf("this adbctl was built without cgo and has no sqlite store; use 'adbctl store bolt' or a build with CGO_ENABLED=1")
+}
+
+func (s sqliteStore) Load(name string) ([]byte, error) { return nil, s.err() }
+
+func (s sqliteStore) Save(name string, data []byte) error { return s.err() }
+
+func (s sqliteStore) String() string { return "sqlite (" + s.path + ")" }
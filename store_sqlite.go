@@ -0,0 +1,84 @@
+//go:build cgo
+
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteStore keeps the documents in a SQLite database, for labs that want
+// to query or back it up with the usual SQLite tools. The driver needs cgo;
+// builds without it get store_nosqlite.go instead.
+type sqliteStore struct{ path string }
+
+func (s sqliteStore) open() (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=%d", s.path, storeLockTimeout.Milliseconds()))
+	if err == nil {
+		_, err = db.Exec(`CREATE TABLE IF NOT EXISTS documents (
+			name TEXT PRIMARY KEY,
+			data BLOB NOT NULL,
+			updated TEXT NOT NULL
+		)`)
+	}
+	if err == nil {
+		err = importNewerDocuments(filepath.Dir(s.path), func(name string) (time.Time, bool) {
+			var stamp string
+			if db.QueryRow(`SELECT updated FROM documents WHERE name = ?`, name).Scan(&stamp) != nil {
+				return time.Time{}, false
+			}
+			updated, err := time.Parse(time.RFC3339Nano, stamp)
+			return updated, err == nil
+		}, func(name string, data []byte) error {
+			return sqliteSave(db, name, data)
+		})
+	}
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
+		return nil, fmt.Errorf("store %s: %v", s.path, err)
+	}
+	return db, nil
+}
+
+func sqliteSave(db *sql.DB, name string, data []byte) error {
+	_, err := db.Exec(`INSERT INTO documents (name, data, updated) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET data = excluded.data, updated = excluded.updated`,
+		name, data, time.Now().UTC().Format(time.RFC3339Nano))
+	return err
+}
+
+func (s sqliteStore) Load(name string) ([]byte, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var data []byte
+	err = db.QueryRow(`SELECT data FROM documents WHERE name = ?`, name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s sqliteStore) Save(name string, data []byte) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return sqliteSave(db, name, data)
+}
+
+func (s sqliteStore) String() string { return "sqlite (" + s.path + ")" }
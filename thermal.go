@@ -0,0 +1,195 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// PowerManager/Temperature constants as printed by dumpsys thermalservice.
+var thermalStatusNames = []string{"None", "Light", "Moderate", "Severe", "Critical", "Emergency", "Shutdown"}
+var thermalTypeNames = map[string]string{"0": "CPU", "1": "GPU", "2": "Battery", "3": "Skin", "4": "USB port",
+	"5": "Power amplifier", "6": "BCL voltage", "7": "BCL current", "8": "BCL percentage", "9": "NPU"}
+
+var thermalTemperaturePattern = regexp.MustCompile(`Temperature\{mValue=([-\d.]+), mType=(\d+), mName=([^,]+), mStatus=(\d+)`)
+
+type thermalSensor struct {
+	Name   string
+	Type   string
+	Temp   float64
+	Status int
+}
+
+type thermalSnapshot struct {
+	Status   int // -1 when thermalservice is unavailable (before Android 10)
+	Sensors  []thermalSensor
+	Cooling  []string // active cooling devices as "name cur/max"
+	CPUCapPc int      // scaling_max_freq as a percentage of cpuinfo_max_freq, lowest policy
+}
+
+func runThermal(args []string) error {
+	fs := flag.NewFlagSet("thermal", flag.ExitOnError)
+	watch := fs.Bool("watch", false, "Keep sampling and print one line per interval")
+	interval := fs.Duration("interval", 5*time.Second, "Sampling interval with --watch")
+	parseArgs(fs, args)
+
+	deviceID := pickDevice()
+	if !*watch {
+		printThermal(collectThermal(deviceID))
+		return nil
+	}
+	fmt.Printf("%-10s %-10s %-10s %-8s %s\n", "Time", "Status", "Hottest", "CPU cap", "Cooling")
+	for {
+		s := collectThermal(deviceID)
+		hottest := "n/a"
+		if len(s.Sensors) > 0 {
+			h := s.Sensors[0]
+			for _, sensor := range s.Sensors {
+				if sensor.Temp > h.Temp {
+					h = sensor
+				}
+			}
+			hottest = fmt.Sprintf("%.1f°C", h.Temp)
+		}
+		fmt.Printf("%-10s %-10s %-10s %-8s %s\n", time.Now().Format("15:04:05"), thermalStatusName(s.Status),
+			hottest, fmt.Sprintf("%d%%", s.CPUCapPc), strings.Join(s.Cooling, ", "))
+		time.Sleep(*interval)
+	}
+}
+
+func thermalStatusName(status int) string {
+	if status < 0 || status >= len(thermalStatusNames) {
+		return "n/a"
+	}
+	return thermalStatusNames[status]
+}
+
+// collectThermal prefers the thermal HAL's view from thermalservice and falls
+// back to the raw thermal zones, which older Fire OS builds only have. The
+// sysfs readings come from the thermal.sh helper in one round trip.
+func collectThermal(deviceID string) thermalSnapshot {
+	timeout := 5 * time.Second
+	snapshot := thermalSnapshot{Status: -1, CPUCapPc: 100}
+	output := runAdbCommand(deviceID, "dumpsys thermalservice", timeout)
+	sysfs, err := runLibScript(deviceID, "thermal.sh", timeout)
+	if err != nil {
+		debugPrint("thermal.sh: %v\n", err)
+	}
+	sections := libSections(sysfs)
+	if status, ok := parseThermalStatus(output); ok {
+		snapshot.Status = status
+		snapshot.Sensors = parseThermalTemperatures(output)
+	}
+	if len(snapshot.Sensors) == 0 {
+		for _, line := range strings.Split(sections["zones"], "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			value, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				continue
+			}
+			if value > 1000 { // millidegrees
+				value /= 1000
+			}
+			snapshot.Sensors = append(snapshot.Sensors, thermalSensor{Name: fields[0], Type: "zone", Temp: value, Status: -1})
+		}
+	}
+
+	for _, line := range strings.Split(sections["cooling"], "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 && fields[1] != "0" {
+			snapshot.Cooling = append(snapshot.Cooling, fmt.Sprintf("%s %s/%s", fields[0], fields[1], fields[2]))
+		}
+	}
+
+	for _, line := range strings.Split(sections["cpufreq"], "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		current, err1 := strconv.Atoi(fields[0])
+		maxFreq, err2 := strconv.Atoi(fields[1])
+		if err1 == nil && err2 == nil && maxFreq > 0 && current*100/maxFreq < snapshot.CPUCapPc {
+			snapshot.CPUCapPc = current * 100 / maxFreq
+		}
+	}
+	return snapshot
+}
+
+func parseThermalStatus(output string) (int, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), "Thermal Status: "); found {
+			status, err := strconv.Atoi(value)
+			return status, err == nil
+		}
+	}
+	return 0, false
+}
+
+// parseThermalTemperatures reads the "Current temperatures from HAL" block,
+// or the cached temperatures when the HAL section is missing.
+func parseThermalTemperatures(output string) []thermalSensor {
+	section := output
+	if i := strings.Index(output, "Current temperatures from HAL:"); i >= 0 {
+		section = output[i:]
+		if j := strings.Index(section, "Current cooling devices"); j >= 0 {
+			section = section[:j]
+		}
+	}
+	var sensors []thermalSensor
+	seen := map[string]bool{}
+	for _, m := range thermalTemperaturePattern.FindAllStringSubmatch(section, -1) {
+		if seen[m[3]] {
+			continue
+		}
+		seen[m[3]] = true
+		temp, _ := strconv.ParseFloat(m[1], 64)
+		status, _ := strconv.Atoi(m[4])
+		sensors = append(sensors, thermalSensor{Name: m[3], Type: lookupOr(thermalTypeNames, m[2]), Temp: temp, Status: status})
+	}
+	return sensors
+}
+
+func printThermal(s thermalSnapshot) {
+	color.New(color.FgCyan, color.Bold).Println("Thermal")
+	fmt.Print(rule("=", 40))
+	status := thermalStatusName(s.Status)
+	statusColor := color.New(color.FgGreen)
+	if s.Status >= 2 {
+		statusColor = color.New(color.FgRed, color.Bold)
+	} else if s.Status == 1 {
+		statusColor = color.New(color.FgYellow)
+	}
+	color.New(color.FgGreen).Printf("%-20s : ", "Throttling Status")
+	statusColor.Println(status)
+	color.New(color.FgGreen).Printf("%-20s : ", "CPU Frequency Cap")
+	if s.CPUCapPc < 100 {
+		color.New(color.FgYellow).Printf("%d%% of maximum (throttled)\n", s.CPUCapPc)
+	} else {
+		fmt.Println("none")
+	}
+	color.New(color.FgGreen).Printf("%-20s : ", "Active Cooling")
+	if len(s.Cooling) == 0 {
+		fmt.Println("none")
+	} else {
+		fmt.Println(strings.Join(s.Cooling, ", "))
+	}
+
+	fmt.Println()
+	for _, sensor := range s.Sensors {
+		fmt.Printf("  %-24s %-10s %6.1f°C", sensor.Name, sensor.Type, sensor.Temp)
+		if sensor.Status > 0 {
+			color.New(color.FgYellow).Printf("  %s", thermalStatusName(sensor.Status))
+		}
+		fmt.Println()
+	}
+	if len(s.Sensors) == 0 {
+		fmt.Println("  No temperature sensors readable on this device.")
+	}
+}
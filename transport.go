@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// forcedTransport is "usb", "wifi" or "" (no preference), from -transport or
+// ADBCTL_TRANSPORT.
+var forcedTransport = os.Getenv("ADBCTL_TRANSPORT")
+
+// transportGroups maps every adb serial to all serials of the same physical
+// device, keyed by ro.serialno. Filled lazily by deviceTransports.
+var transportGroups map[string][]string
+
+// isWirelessSerial reports whether serial is a TCP transport, either
+// ip:port from `adb connect` or an mDNS name from wireless debugging.
+func isWirelessSerial(serial string) bool {
+	return strings.Contains(serial, ":") || strings.Contains(serial, "._adb-tls-")
+}
+
+func transportName(serial string) string {
+	if isWirelessSerial(serial) {
+		return "wifi"
+	}
+	return "usb"
+}
+
+// deviceTransports returns every serial that reaches the same device as serial.
+func deviceTransports(serial string) []string {
+	if transportGroups == nil {
+		groupTransports(connectedSerials())
+	}
+	if group, ok := transportGroups[serial]; ok {
+		return group
+	}
+	return []string{serial}
+}
+
+func groupTransports(serials []string) {
+	transportGroups = map[string][]string{}
+	byIdentity := map[string][]string{}
+	var order []string
+	for _, serial := range serials {
+		identity := serialNumber(serial)
+		if identity == "" {
+			identity = serial
+		}
+		if _, seen := byIdentity[identity]; !seen {
+			order = append(order, identity)
+		}
+		byIdentity[identity] = append(byIdentity[identity], serial)
+	}
+	for _, identity := range order {
+		for _, serial := range byIdentity[identity] {
+			transportGroups[serial] = byIdentity[identity]
+		}
+	}
+}
+
+// serialNumber reads ro.serialno with a fixed timeout. Grouping probes every
+// listed device, and a scaled timeout would sample each wireless link first;
+// only the device finally chosen is measured, on its first scaled command.
+func serialNumber(serial string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, adbPath, "-s", serial, "shell", "getprop", "ro.serialno").Output()
+	if err != nil {
+		debugPrint("serial number of %s: %v\n", serial, err)
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// dedupeDevices collapses `adb devices -l` lines that reach the same device
+// over USB and WiFi into one line, annotated with the available transports.
+func dedupeDevices(lines []string) []string {
+	var serials []string
+	for _, line := range lines {
+		serials = append(serials, strings.Fields(line)[0])
+	}
+	groupTransports(serials)
+
+	var unique []string
+	shown := map[string]bool{}
+	for i, line := range lines {
+		group := transportGroups[serials[i]]
+		if shown[group[0]] {
+			continue
+		}
+		shown[group[0]] = true
+		if len(group) > 1 {
+			var names []string
+			for _, serial := range group {
+				names = append(names, transportName(serial))
+			}
+			line += " [" + strings.Join(names, "+") + "]"
+		}
+		unique = append(unique, line)
+	}
+	return unique
+}
+
+// withTransport switches serial to the transport forced by -transport, and
+// exits when the device is not reachable that way.
+func withTransport(serial string) string {
+	if forcedTransport == "" {
+		return serial
+	}
+	for _, candidate := range deviceTransports(serial) {
+		if transportName(candidate) == forcedTransport {
+			return candidate
+		}
+	}
+	if ciMode {
+		ciFail(exitDevice, "no-transport", fmt.Errorf("device %s is not connected over %s", serial, forcedTransport))
+	}
+	fmt.Printf("Device %s is not connected over %s.\n", serial, forcedTransport)
+	os.Exit(1)
+	return ""
+}
+
+// fastTransport returns the USB serial for a device reachable over both USB
+// and WiFi, for transfers such as pushes, installs, recordings and bug
+// reports. A forced transport always wins.
+func fastTransport(serial string) string {
+	if forcedTransport != "" || !isWirelessSerial(serial) {
+		return serial
+	}
+	for _, candidate := range deviceTransports(serial) {
+		if !isWirelessSerial(candidate) {
+			debugPrint("Using USB transport %s instead of %s\n", candidate, serial)
+			return candidate
+		}
+	}
+	return serial
+}
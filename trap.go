@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+const trapLogLines = 500
+
+func runTrap(args []string) error {
+	fs := flag.NewFlagSet("trap", flag.ExitOnError)
+	pattern := fs.String("on", "FATAL EXCEPTION", "Regular expression to watch for in logcat")
+	actions := fs.String("do", "screenshot,logcat", "Artifacts to capture: screenshot, logcat, bugreport")
+	outDir := fs.String("out", "traps", "Directory to store captured artifacts")
+	once := fs.Bool("once", false, "Exit after the first capture")
+	cooldown := fs.Duration("cooldown", 30*time.Second, "Ignore further matches for this long after a capture")
+	fs.Parse(args)
+
+	re, err := regexp.Compile(*pattern)
+	if err != nil {
+		return fmt.Errorf("invalid --on pattern: %v", err)
+	}
+	for _, action := range splitList(*actions) {
+		if !containsString([]string{"screenshot", "logcat", "bugreport"}, action) {
+			return fmt.Errorf("unknown --do action %q", action)
+		}
+	}
+
+	deviceID := pickDevice()
+	// Start from an empty buffer so old crashes don't trigger immediately.
+	adbShell(deviceID, "logcat", "-c")
+	fmt.Printf("Watching %s for /%s/ (Ctrl+C to stop)...\n", deviceID, *pattern)
+
+	var mu sync.Mutex
+	var recent []string
+	var lastCapture time.Time
+	done := make(chan struct{})
+	var stop sync.Once
+
+	go func() {
+		streamLogcat(context.Background(), deviceID, nil, func(line string) {
+			mu.Lock()
+			recent = append(recent, line)
+			if len(recent) > trapLogLines {
+				recent = recent[1:]
+			}
+			if !re.MatchString(line) || time.Since(lastCapture) < *cooldown {
+				mu.Unlock()
+				return
+			}
+			lastCapture = time.Now()
+			snapshot := append([]string(nil), recent...)
+			mu.Unlock()
+
+			color.New(color.FgRed, color.Bold).Printf("Trap triggered: %s\n", strings.TrimSpace(line))
+			// Give the crash a moment to finish logging and render before capturing.
+			time.Sleep(500 * time.Millisecond)
+			captureTrapArtifacts(deviceID, *outDir, splitList(*actions), snapshot)
+			if *once {
+				stop.Do(func() { close(done) })
+			}
+		})
+		stop.Do(func() { close(done) })
+	}()
+	<-done
+	return nil
+}
+
+func captureTrapArtifacts(deviceID, outDir string, actions []string, logLines []string) {
+	dir := filepath.Join(outDir, time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Printf("Error creating %s: %v\n", dir, err)
+		return
+	}
+	for _, action := range actions {
+		var err error
+		var path string
+		switch action {
+		case "screenshot":
+			path = filepath.Join(dir, "screenshot.png")
+			err = captureScreenshot(deviceID, path)
+		case "logcat":
+			path = filepath.Join(dir, "logcat.txt")
+			err = os.WriteFile(path, []byte(strings.Join(logLines, "\n")+"\n"), 0644)
+		case "bugreport":
+			path = filepath.Join(dir, "bugreport.zip")
+			p := startProgress("Capturing bugreport", 0, progressTime)
+			err = exec.Command(adbPath, "-s", fastTransport(deviceID), "bugreport", path).Run()
+			p.Finish(err)
+		}
+		if err != nil {
+			fmt.Printf("  %s failed: %v\n", action, err)
+		} else {
+			fmt.Printf("  saved %s\n", path)
+		}
+	}
+}
+
+// captureScreenshot saves a PNG of the current screen.
+func captureScreenshot(deviceID, path string) error {
+	data, err := screencapPNG(deviceID)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// unityLogSpec keeps Unity's own tags plus the native crash reporters, which
+// log il2cpp and libunity backtraces from other processes.
+var unityLogSpec = []string{"Unity:V", "IL2CPP:V", "CRASH:V", "DEBUG:V", "libc:F", "AndroidRuntime:E", "*:S"}
+
+var (
+	threadtimePattern = regexp.MustCompile(`^\S+ \S+\s+\d+\s+\d+ [VDIWEF] (.*?)\s*: (.*)$`)
+	// Managed frames ("UnityEngine.Debug:Log(Object)", "at Foo.Bar ()") and
+	// native ones ("#01 pc 0001a2b3  /data/app/.../libil2cpp.so").
+	unityStackFramePattern = regexp.MustCompile(`^\s*(?:at |#\d+ +pc |[\w.<>` + "`" + `]+[:.][\w<>.` + "`" + `]+ ?\(.*\)(?: \(at .*\))?$)`)
+	nativeFramePattern     = regexp.MustCompile(`#\d+ +pc +([0-9a-fA-F]+) +(\S*/(lib\w+\.so))`)
+)
+
+// unityLogFolder collapses stack traces in a Unity log to their first frame
+// and a count, keeping native frames of symbolized libraries visible.
+type unityLogFolder struct {
+	print   func(string)
+	symbols *symbolizer
+	folded  int
+}
+
+func newUnityLogFolder(symbols *symbolizer, print func(string)) *unityLogFolder {
+	return &unityLogFolder{print: print, symbols: symbols}
+}
+
+func (f *unityLogFolder) line(line string) {
+	message := line
+	if m := threadtimePattern.FindStringSubmatch(line); m != nil {
+		message = m[2]
+	}
+	if strings.HasPrefix(message, "(Filename:") {
+		f.flush()
+		return
+	}
+	if !unityStackFramePattern.MatchString(message) {
+		f.flush()
+		f.print(line)
+		return
+	}
+	if symbol := f.symbols.frame(message); symbol != "" {
+		f.print(line + "  " + symbol)
+		return
+	}
+	if f.folded == 0 {
+		f.print(line)
+	}
+	f.folded++
+}
+
+// flush prints how many frames were hidden after the first.
+func (f *unityLogFolder) flush() {
+	if f.folded > 1 {
+		f.print(fmt.Sprintf("    ... %d more frames", f.folded-1))
+	}
+	f.folded = 0
+}
+
+// symbolizer resolves native backtrace frames with addr2line against the
+// unstripped libraries Unity exports with a build (symbols.zip).
+type symbolizer struct {
+	addr2line string
+	libraries map[string][]string // library name -> symbol files
+}
+
+// newSymbolizer indexes the symbol files below dir: Unity names them
+// libil2cpp.sym.so, libil2cpp.dbg.so or keeps the library name, one
+// directory per ABI.
+func newSymbolizer(dir string) (*symbolizer, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	addr2line := findAddr2line()
+	if addr2line == "" {
+		return nil, fmt.Errorf("llvm-addr2line not found; install the Android NDK or set ADDR2LINE")
+	}
+	s := &symbolizer{addr2line: addr2line, libraries: map[string][]string{}}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		name := d.Name()
+		for _, suffix := range []string{".sym.so", ".dbg.so", ".so.debug", ".so"} {
+			if base, found := strings.CutSuffix(name, suffix); found {
+				s.libraries[base+".so"] = append(s.libraries[base+".so"], path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(s.libraries) == 0 {
+		return nil, fmt.Errorf("no symbol files (*.sym.so, *.dbg.so, *.so) in %s", dir)
+	}
+	return s, nil
+}
+
+// frame returns "function at file:line" for a native frame of a library with
+// symbols, or "" otherwise.
+func (s *symbolizer) frame(message string) string {
+	if s == nil {
+		return ""
+	}
+	m := nativeFramePattern.FindStringSubmatch(message)
+	if m == nil {
+		return ""
+	}
+	file := s.symbolFile(m[3], m[2])
+	if file == "" {
+		return ""
+	}
+	output, err := exec.Command(s.addr2line, "-C", "-f", "-e", file, "0x"+m[1]).Output()
+	if err != nil {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) == 0 || lines[0] == "??" {
+		return ""
+	}
+	if len(lines) > 1 && !strings.HasPrefix(lines[1], "??") {
+		return lines[0] + " at " + lines[1]
+	}
+	return lines[0]
+}
+
+// symbolFile picks the symbol file for library, preferring the one in the ABI
+// directory that matches the device path (lib/arm64/ is arm64-v8a).
+func (s *symbolizer) symbolFile(library, devicePath string) string {
+	files := s.libraries[library]
+	if len(files) == 0 {
+		return ""
+	}
+	abi := map[string]string{"arm64": "arm64-v8a", "arm": "armeabi-v7a", "x86_64": "x86_64", "x86": "x86"}
+	for deviceABI, dir := range abi {
+		if !strings.Contains(devicePath, "/lib/"+deviceABI+"/") && !strings.Contains(devicePath, "/"+dir+"/") {
+			continue
+		}
+		for _, file := range files {
+			if strings.Contains(filepath.ToSlash(file), "/"+dir+"/") {
+				return file
+			}
+		}
+	}
+	return files[0]
+}
+
+// findAddr2line looks in ADDR2LINE, PATH and the NDKs installed in the SDK.
+func findAddr2line() string {
+	if tool := os.Getenv("ADDR2LINE"); tool != "" {
+		return tool
+	}
+	if binary, err := exec.LookPath("llvm-addr2line"); err == nil {
+		return binary
+	}
+	var ndks []string
+	for _, env := range []string{"ANDROID_NDK_HOME", "ANDROID_NDK_ROOT"} {
+		if dir := os.Getenv(env); dir != "" {
+			ndks = append(ndks, dir)
+		}
+	}
+	for _, sdk := range androidSDKDirs() {
+		versions, _ := filepath.Glob(filepath.Join(sdk, "ndk", "*"))
+		sort.Sort(sort.Reverse(sort.StringSlice(versions)))
+		ndks = append(ndks, versions...)
+	}
+	for _, ndk := range ndks {
+		matches, _ := filepath.Glob(filepath.Join(ndk, "toolchains", "llvm", "prebuilt", "*", "bin", "llvm-addr2line"+filepath.Ext(adbExecutable())))
+		if len(matches) > 0 {
+			return matches[0]
+		}
+	}
+	if binary, err := exec.LookPath("addr2line"); err == nil {
+		return binary
+	}
+	return ""
+}
+
+// runSymbolize symbolizes the native frames in a saved log or tombstone.
+func runSymbolize(args []string) error {
+	fs := flag.NewFlagSet("symbolize", flag.ExitOnError)
+	symbolsDir := fs.String("symbols", "", "Directory with the build's symbol files (unzipped symbols.zip)")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 || *symbolsDir == "" {
+		return fmt.Errorf("usage: symbolize <log or tombstone file | -> --symbols <dir>")
+	}
+	symbols, err := newSymbolizer(*symbolsDir)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if positional[0] != "-" {
+		f, err := os.Open(positional[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	resolved := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if symbol := symbols.frame(line); symbol != "" {
+			line += "  " + symbol
+			resolved++
+		}
+		fmt.Println(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Symbolized %d frame(s)\n", resolved)
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// uploadURLExpiry is how long the printed download links stay valid; seven
+// days is the longest S3 and GCS V4 signatures allow.
+const uploadURLExpiry = 7 * 24 * time.Hour
+
+// uploadArtifact copies a file, or a directory as a .tar.gz bundle, to
+// s3://bucket/key, gs://bucket/key or an HTTP(S) URL taking a PUT, and
+// returns a link to download it. A destination ending in "/" gets the file's
+// name appended. Buckets go through the aws and gcloud CLIs, so their usual
+// credentials apply.
+func uploadArtifact(local, dest string) (string, error) {
+	info, err := os.Stat(local)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		tmp, err := os.MkdirTemp("", "adbctl-upload")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(tmp)
+		bundle := filepath.Join(tmp, filepath.Base(filepath.Clean(local))+".tar.gz")
+		if _, err := writeTarGz(local, bundle); err != nil {
+			return "", err
+		}
+		local = bundle
+	}
+
+	parsed, err := url.Parse(dest)
+	if err != nil || parsed.Scheme == "" {
+		return "", fmt.Errorf("invalid --upload destination %q (want s3://, gs:// or an http(s) URL)", dest)
+	}
+	if strings.HasSuffix(parsed.Path, "/") || (parsed.Path == "" && parsed.Scheme != "http" && parsed.Scheme != "https") {
+		parsed.Path = path.Join("/", parsed.Path, filepath.Base(local))
+		dest = parsed.String()
+	}
+
+	switch parsed.Scheme {
+	case "s3":
+		if err := runUploadCLI("aws", "s3", "cp", "--only-show-errors", local, dest); err != nil {
+			return "", err
+		}
+		link, err := exec.Command("aws", "s3", "presign", dest, "--expires-in", fmt.Sprint(int(uploadURLExpiry.Seconds()))).Output()
+		if err != nil {
+			debugPrint("aws s3 presign: %v\n", err)
+			return dest, nil
+		}
+		return strings.TrimSpace(string(link)), nil
+	case "gs":
+		if err := runUploadCLI("gcloud", "storage", "cp", local, dest); err != nil {
+			return "", err
+		}
+		// Signing needs a service account key or impersonation; without one
+		// the gs:// URL is the best link there is.
+		signed, err := exec.Command("gcloud", "storage", "sign-url", dest, "--duration", "7d").Output()
+		if err != nil {
+			debugPrint("gcloud storage sign-url: %v\n", err)
+			return dest, nil
+		}
+		for _, line := range strings.Split(string(signed), "\n") {
+			if link, found := strings.CutPrefix(strings.TrimSpace(line), "signed_url:"); found {
+				return strings.TrimSpace(link), nil
+			}
+		}
+		return dest, nil
+	case "http", "https":
+		return httpPutArtifact(local, dest)
+	}
+	return "", fmt.Errorf("unsupported --upload scheme %q (want s3, gs, http or https)", parsed.Scheme)
+}
+
+func runUploadCLI(tool string, args ...string) error {
+	if _, err := exec.LookPath(tool); err != nil {
+		return fmt.Errorf("uploading to %s:// needs the %s CLI: %v", map[string]string{"aws": "s3", "gcloud": "gs"}[tool], tool, err)
+	}
+	output, err := exec.Command(tool, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %v: %s", tool, strings.Join(args[:2], " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// httpPutArtifact PUTs the file, which suits pre-signed upload URLs and
+// simple artifact servers. The link returned is the server's Location when it
+// sends one, otherwise the URL without its query (the upload signature).
+func httpPutArtifact(local, dest string) (string, error) {
+	f, err := os.Open(local)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPut, dest, f)
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = info.Size()
+	if contentType := mime.TypeByExtension(filepath.Ext(local)); contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := &http.Client{Timeout: 10 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("PUT %s: %s", redactQuery(dest), resp.Status)
+	}
+	if location := resp.Header.Get("Location"); location != "" {
+		return location, nil
+	}
+	return redactQuery(dest), nil
+}
+
+func redactQuery(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.RawQuery = ""
+	return u.String()
+}
+
+// uploadAndReport uploads local when dest is set and prints the link.
+func uploadAndReport(local, dest string) error {
+	if dest == "" {
+		return nil
+	}
+	link, err := uploadArtifact(local, dest)
+	if err != nil {
+		return fmt.Errorf("upload failed: %v", err)
+	}
+	fmt.Printf("Uploaded %s: %s\n", filepath.Base(local), link)
+	return nil
+}
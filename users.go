@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// userInfoPattern matches `pm list users` lines: UserInfo{10:Kids:410} running
+var userInfoPattern = regexp.MustCompile(`UserInfo\{(\d+):([^:]*):([0-9a-fA-F]+)\}(\s+running)?`)
+
+type androidUser struct {
+	ID      string
+	Name    string
+	Running bool
+}
+
+func runUsers(args []string) error {
+	deviceID := pickDevice()
+	users, err := listUsers(deviceID)
+	if err != nil {
+		return err
+	}
+	current, _ := adbShell(deviceID, "am", "get-current-user")
+	color.New(color.FgCyan, color.Bold).Println("Users")
+	fmt.Print(rule("=", 40))
+	for _, u := range users {
+		var notes []string
+		if u.ID == current {
+			notes = append(notes, "current")
+		}
+		if u.Running {
+			notes = append(notes, "running")
+		}
+		fmt.Printf("  %-4s %-25s %s\n", u.ID, u.Name, strings.Join(notes, ", "))
+	}
+	return nil
+}
+
+func listUsers(deviceID string) ([]androidUser, error) {
+	output, err := adbShellIdempotent(deviceID, "pm", "list", "users")
+	if err != nil {
+		return nil, err
+	}
+	var users []androidUser
+	for _, m := range userInfoPattern.FindAllStringSubmatch(output, -1) {
+		users = append(users, androidUser{ID: m[1], Name: m[2], Running: m[4] != ""})
+	}
+	return users, nil
+}
+
+// checkUser fails early with the valid ids when user does not exist, rather
+// than letting pm report a bare "Unknown user".
+func checkUser(deviceID, user string) error {
+	if user == "" {
+		return nil
+	}
+	users, err := listUsers(deviceID)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, u := range users {
+		if u.ID == user {
+			return nil
+		}
+		ids = append(ids, u.ID+" ("+u.Name+")")
+	}
+	return fmt.Errorf("no user %s on this device; users: %s", user, strings.Join(ids, ", "))
+}
+
+func runUninstall(args []string) error {
+	fs := flag.NewFlagSet("uninstall", flag.ExitOnError)
+	user := fs.String("user", "", "Only remove the app for this user id (see 'adbctl users')")
+	keepData := fs.Bool("keep-data", false, "Keep the app's data and cache")
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: uninstall <package> [--user id] [--keep-data]")
+	}
+	pkg := positional[0]
+	deviceID := pickDevice()
+	if err := checkUser(deviceID, *user); err != nil {
+		return err
+	}
+
+	var output []byte
+	var err error
+	if *user == "" {
+		cmdArgs := []string{"-s", deviceID, "uninstall"}
+		if *keepData {
+			cmdArgs = append(cmdArgs, "-k")
+		}
+		output, err = exec.Command(adbPath, append(cmdArgs, pkg)...).CombinedOutput()
+	} else {
+		cmdArgs := []string{"-s", deviceID, "shell", "pm", "uninstall", "--user", *user}
+		if *keepData {
+			cmdArgs = append(cmdArgs, "-k")
+		}
+		output, err = exec.Command(adbPath, append(cmdArgs, pkg)...).CombinedOutput()
+	}
+	if err != nil || !strings.Contains(string(output), "Success") {
+		return normalizeAdbError(string(output), fmt.Errorf("uninstall %s failed: %s", pkg, strings.TrimSpace(string(output))))
+	}
+	if *user != "" {
+		fmt.Printf("Uninstalled %s for user %s\n", pkg, *user)
+	} else {
+		fmt.Printf("Uninstalled %s\n", pkg)
+	}
+	return nil
+}
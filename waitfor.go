@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var focusPattern = regexp.MustCompile(`(?:mResumedActivity|topResumedActivity|mCurrentFocus|mFocusedApp)[=:].*?\s([\w.]+)/([\w.$]+)`)
+
+func runWaitFor(args []string) error {
+	fs := flag.NewFlagSet("wait-for", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 2*time.Minute, "Give up after this long")
+	interval := fs.Duration("interval", time.Second, "Polling interval")
+	serial := fs.String("serial", os.Getenv("ADBCTL_DEVICE"), "Wait for this device, which need not be connected yet")
+	positional := parseArgs(fs, args)
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: wait-for boot | state <level> | package-foreground <pkg> | property <key>=<value> | port <n> [--serial s] [--timeout 2m]")
+	}
+
+	// A named device is waited for even while adb doesn't list it, as after
+	// a reboot or before it is plugged in; picking one needs it listed.
+	deviceID := *serial
+	if deviceID == "" {
+		deviceID = pickDevice()
+	}
+	var check func() bool
+	switch positional[0] {
+	case "boot":
+		check = func() bool { return probeDeviceState(deviceID) >= StateBooted }
+	case "state":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: wait-for state %s", strings.Join(deviceStateNames, "|"))
+		}
+		target, err := parseDeviceState(positional[1])
+		if err != nil {
+			return err
+		}
+		check = func() bool { return probeDeviceState(deviceID) >= target }
+	case "package-foreground":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: wait-for package-foreground <pkg>")
+		}
+		check = func() bool {
+			pkg, _ := foregroundActivity(deviceID)
+			return pkg == positional[1]
+		}
+	case "property":
+		if len(positional) != 2 || !strings.Contains(positional[1], "=") {
+			return fmt.Errorf("usage: wait-for property <key>=<value>")
+		}
+		key, value, _ := strings.Cut(positional[1], "=")
+		check = func() bool {
+			return runAdbCommand(deviceID, "getprop "+shellQuote(key), 5*time.Second) == value
+		}
+	case "port":
+		if len(positional) != 2 {
+			return fmt.Errorf("usage: wait-for port <n>")
+		}
+		port, err := strconv.Atoi(positional[1])
+		if err != nil {
+			return fmt.Errorf("invalid port %q", positional[1])
+		}
+		check = func() bool {
+			return isDevicePortListening(deviceID, port)
+		}
+	default:
+		return fmt.Errorf("unknown condition %q", positional[0])
+	}
+	if positional[0] != "boot" && positional[0] != "state" {
+		// The device states cover a missing device; the other conditions
+		// are only checked once it is online.
+		condition := check
+		check = func() bool { return adbDeviceStatus(deviceID) == "device" && condition() }
+	}
+
+	if err := pollUntil(*timeout, *interval, check); err != nil {
+		return fmt.Errorf("%s: %v", strings.Join(positional, " "), err)
+	}
+	fmt.Printf("%s: ready\n", strings.Join(positional, " "))
+	return nil
+}
+
+// pollUntil calls check every interval until it succeeds or timeout elapses.
+func pollUntil(timeout, interval time.Duration, check func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if check() {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func isBootCompleted(deviceID string) bool {
+	return runAdbCommand(deviceID, "getprop sys.boot_completed", 5*time.Second) == "1"
+}
+
+// foregroundActivity returns the package and activity currently in front.
+func foregroundActivity(deviceID string) (string, string) {
+	output := runAdbCommand(deviceID, "dumpsys activity activities | grep -E 'mResumedActivity|topResumedActivity'", 5*time.Second)
+	if m := focusPattern.FindStringSubmatch(output); m != nil {
+		return m[1], m[2]
+	}
+	// Older releases (and some Fire OS builds) only expose the window focus.
+	output = runAdbCommand(deviceID, "dumpsys window | grep -E 'mCurrentFocus|mFocusedApp'", 5*time.Second)
+	if m := focusPattern.FindStringSubmatch(output); m != nil {
+		return m[1], m[2]
+	}
+	return "", ""
+}
+
+// isDevicePortListening reports whether a TCP socket on the device is
+// listening on port, based on /proc/net/tcp{,6} (state 0A is LISTEN).
+func isDevicePortListening(deviceID string, port int) bool {
+	output := runAdbCommand(deviceID, "cat /proc/net/tcp /proc/net/tcp6", 5*time.Second)
+	suffix := fmt.Sprintf(":%04X", port)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 3 && strings.HasSuffix(fields[1], suffix) && fields[3] == "0A" {
+			return true
+		}
+	}
+	return false
+}
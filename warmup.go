@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func runWarmup(args []string) error {
+	fs := flag.NewFlagSet("warmup", flag.ExitOnError)
+	packagesFlag := fs.String("packages", "", "Comma-separated list of packages to pre-launch")
+	settle := fs.Duration("settle", 3*time.Second, "Time to let each app start before moving on")
+	minFreeMB := fs.Int("min-free", 500, "Minimum available memory (MB) expected after warm-up")
+	maxTemp := fs.Float64("max-temp", 60, "Maximum thermal zone temperature (°C) considered safe")
+	fs.Parse(args)
+
+	packages := splitList(*packagesFlag)
+	if len(packages) == 0 {
+		return fmt.Errorf("--packages is required")
+	}
+
+	deviceID := pickDevice()
+
+	fmt.Println("Stopping background apps...")
+	stopped := stopCompetingApps(deviceID, packages)
+	for _, pkg := range stopped {
+		fmt.Printf("  force-stopped %s\n", pkg)
+	}
+
+	fmt.Println("Pre-launching apps...")
+	for _, pkg := range packages {
+		_, err := adbShell(deviceID, "monkey", "-p", pkg, "-c", "android.intent.category.LAUNCHER", "1")
+		if err != nil {
+			color.New(color.FgRed).Printf("  %s: failed to launch (%v)\n", pkg, err)
+			continue
+		}
+		fmt.Printf("  launched %s\n", pkg)
+		time.Sleep(*settle)
+	}
+	adbShell(deviceID, "input", "keyevent", "KEYCODE_HOME")
+
+	fmt.Println()
+	ok := true
+	availableKB := parseMemInfoFields(runAdbCommand(deviceID, "cat /proc/meminfo", 5*time.Second))["MemAvailable"]
+	ok = reportCheck("Available memory", fmt.Sprintf("%d MB", availableKB/1024), availableKB/1024 >= *minFreeMB) && ok
+
+	if temp, found := maxThermalZoneTemp(deviceID); found {
+		ok = reportCheck("Max temperature", fmt.Sprintf("%.1f °C", temp), temp <= *maxTemp) && ok
+	} else {
+		fmt.Printf("%-20s : n/a\n", "Max temperature")
+	}
+
+	if !ok {
+		return fmt.Errorf("device is not ready for the demo")
+	}
+	color.New(color.FgGreen, color.Bold).Println("Device is warmed up.")
+	return nil
+}
+
+// stopCompetingApps force-stops running third-party apps that are not in keep.
+func stopCompetingApps(deviceID string, keep []string) []string {
+	thirdParty, err := adbShellIdempotent(deviceID, "pm", "list", "packages", "-3")
+	if err != nil {
+		return nil
+	}
+	running := make(map[string]bool)
+	ps, _ := adbShellIdempotent(deviceID, "ps", "-A", "-o", "NAME")
+	for _, name := range strings.Fields(ps) {
+		running[strings.SplitN(name, ":", 2)[0]] = true
+	}
+
+	var stopped []string
+	for _, line := range strings.Split(thirdParty, "\n") {
+		pkg := strings.TrimSpace(strings.TrimPrefix(line, "package:"))
+		if pkg == "" || !running[pkg] || containsString(keep, pkg) {
+			continue
+		}
+		if _, err := adbShell(deviceID, "am", "force-stop", pkg); err == nil {
+			stopped = append(stopped, pkg)
+		}
+	}
+	return stopped
+}
+
+// maxThermalZoneTemp returns the hottest thermal zone in °C.
+func maxThermalZoneTemp(deviceID string) (float64, bool) {
+	output := runAdbCommand(deviceID, "cat /sys/class/thermal/thermal_zone*/temp", 5*time.Second)
+	var maxTemp float64
+	found := false
+	for _, field := range strings.Fields(output) {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			continue
+		}
+		if value > 1000 { // millidegrees
+			value /= 1000
+		}
+		if !found || value > maxTemp {
+			maxTemp = value
+			found = true
+		}
+	}
+	return maxTemp, found
+}
+
+func reportCheck(label, value string, ok bool) bool {
+	fmt.Printf("%-20s : %s ", label, value)
+	if ok {
+		color.New(color.FgGreen).Println("OK")
+	} else {
+		color.New(color.FgRed).Println("LOW HEADROOM")
+	}
+	return ok
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"connectrpc.com/connect"
+)
+
+//go:embed web/index.html
+var webIndex []byte
+
+func runWeb(args []string) error {
+	fs := flag.NewFlagSet("web", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:8752", "Address to listen on")
+	parseArgs(fs, args)
+	host, port, err := net.SplitHostPort(*listen)
+	if err != nil {
+		return fmt.Errorf("invalid --listen address %q: %v", *listen, err)
+	}
+	loopback := host != "" && isLoopbackHost(host)
+	if !loopback {
+		fmt.Println("Warning: the dashboard has no login; anyone who can reach this port can reboot devices and install apps.")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(webIndex)
+	})
+	mux.HandleFunc("/api/devices", webDevices)
+	mux.HandleFunc("/api/screenshot", webScreenshot)
+	mux.HandleFunc("/api/reboot", webReboot)
+	mux.HandleFunc("/api/install", webInstall)
+	mux.HandleFunc("/api/logcat", webLogcat)
+
+	url := "http://" + *listen + "/"
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		url = "http://localhost:" + port + "/"
+	}
+	fmt.Printf("Dashboard at %s (Ctrl+C to stop)\n", url)
+	return http.ListenAndServe(*listen, webGuard(mux, loopback))
+}
+
+// webGuard protects the dashboard from other web pages. Actions must carry the
+// X-Adbctl header, which a cross-origin page can't send, and on a loopback
+// address the Host must be local too, so a DNS-rebound name can't reach it.
+func webGuard(next http.Handler, loopback bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if loopback {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if !isLoopbackHost(strings.Trim(host, "[]")) {
+				http.Error(w, "forbidden host", http.StatusForbidden)
+				return
+			}
+		}
+		if r.Method != http.MethodGet && r.Header.Get("X-Adbctl") != "1" {
+			http.Error(w, "missing X-Adbctl header", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeWebJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// webErrorStatus is the HTTP status for each API error code.
+var webErrorStatus = map[connect.Code]int{
+	connect.CodeInvalidArgument: http.StatusBadRequest,
+	connect.CodeNotFound:        http.StatusNotFound,
+	connect.CodeUnavailable:     http.StatusServiceUnavailable,
+}
+
+func writeWebError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var apiErr *connect.Error
+	if errors.As(err, &apiErr) {
+		if s, found := webErrorStatus[apiErr.Code()]; found {
+			status = s
+		}
+		err = fmt.Errorf("%s", apiErr.Message())
+	}
+	writeWebJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// webDevice checks the request's serial and method, writing the error
+// response itself when they are wrong.
+func webDevice(w http.ResponseWriter, r *http.Request, method string) (string, bool) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeWebJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return "", false
+	}
+	serial := r.URL.Query().Get("serial")
+	if err := apiRequireDevice(serial); err != nil {
+		writeWebError(w, err)
+		return "", false
+	}
+	return serial, true
+}
+
+// webDevices lists every device with the fields inventory --all collects.
+func webDevices(w http.ResponseWriter, r *http.Request) {
+	rows := collectFleetInventory()
+	if rows == nil {
+		rows = []fleetInventoryRow{}
+	}
+	writeWebJSON(w, http.StatusOK, rows)
+}
+
+func webScreenshot(w http.ResponseWriter, r *http.Request) {
+	serial, ok := webDevice(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+	data, err := screencapPNG(serial)
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
+
+func webReboot(w http.ResponseWriter, r *http.Request) {
+	serial, ok := webDevice(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	if err := rebootDevice(serial, ""); err != nil {
+		writeWebError(w, err)
+		return
+	}
+	writeWebJSON(w, http.StatusOK, map[string]string{})
+}
+
+func webInstall(w http.ResponseWriter, r *http.Request) {
+	serial, ok := webDevice(w, r, http.MethodPost)
+	if !ok {
+		return
+	}
+	upload, header, err := r.FormFile("apk")
+	if err != nil {
+		writeWebJSON(w, http.StatusBadRequest, map[string]string{"error": "no APK uploaded: " + err.Error()})
+		return
+	}
+	defer upload.Close()
+	tmp, err := os.MkdirTemp("", "adbctl-web")
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	defer os.RemoveAll(tmp)
+	apk := filepath.Join(tmp, filepath.Base(header.Filename))
+	f, err := os.Create(apk)
+	if err == nil {
+		_, err = io.Copy(f, upload)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		err = adbInstall(serial, apk, "")
+	}
+	if err != nil {
+		writeWebError(w, err)
+		return
+	}
+	debugPrint("web: installed %s on %s\n", header.Filename, serial)
+	writeWebJSON(w, http.StatusOK, map[string]string{})
+}
+
+// webLogcat streams the device log as server-sent events until the browser
+// closes the panel. An "end" event says why a stream stopped on its own.
+func webLogcat(w http.ResponseWriter, r *http.Request) {
+	serial, ok := webDevice(w, r, http.MethodGet)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	controller := http.NewResponseController(w)
+	err := streamLogcat(r.Context(), serial, strings.Fields(r.URL.Query().Get("filter")), func(line string) {
+		fmt.Fprintf(w, "data: %s\n\n", line)
+		controller.Flush()
+	})
+	if r.Context().Err() != nil {
+		return
+	}
+	message := "logcat ended"
+	if err != nil {
+		message = "logcat stopped: " + strings.ReplaceAll(err.Error(), "\n", " ")
+	}
+	fmt.Fprintf(w, "event: end\ndata: %s\n\n", message)
+	controller.Flush()
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Fields of the mWifiInfo line in `dumpsys wifi`.
+var (
+	wifiSSIDPattern      = regexp.MustCompile(`SSID: "?([^",]*)"?,`)
+	wifiBSSIDPattern     = regexp.MustCompile(`BSSID: ([0-9a-fA-F:]{17})`)
+	wifiRSSIPattern      = regexp.MustCompile(`RSSI: (-?\d+)`)
+	wifiLinkSpeedPattern = regexp.MustCompile(`(?:^|, )Link speed: (\d+)Mbps`)
+	wifiFrequencyPattern = regexp.MustCompile(`Frequency: (\d+)MHz`)
+)
+
+// weakRSSI is the level below which video streaming typically starts to buffer.
+const weakRSSI = -70
+
+type wifiLink struct {
+	SSID      string
+	BSSID     string
+	RSSI      int
+	LinkSpeed int // Mbps
+	Frequency int // MHz
+}
+
+func runWifi(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: wifi connect --ssid <name> [--pass <passphrase>] | wifi status | wifi forget <ssid> | wifi monitor [--interval 1s]")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("wifi "+action, flag.ExitOnError)
+	ssid := fs.String("ssid", "", "Network name")
+	pass := fs.String("pass", "", "Passphrase (omit for open networks)")
+	security := fs.String("security", "", "open, wpa2 or wpa3 (defaults to wpa2 when --pass is given)")
+	interval := fs.Duration("interval", time.Second, "Sampling interval for monitor")
+	duration := fs.Duration("duration", 0, "Stop monitoring after this long (default: until interrupted)")
+	positional := parseArgs(fs, args[1:])
+
+	deviceID := pickDevice()
+	switch action {
+	case "connect":
+		if *ssid == "" {
+			return fmt.Errorf("usage: wifi connect --ssid <name> [--pass <passphrase>]")
+		}
+		if *security == "" {
+			*security = "open"
+			if *pass != "" {
+				*security = "wpa2"
+			}
+		}
+		return wifiConnect(deviceID, *ssid, *pass, *security)
+	case "status":
+		showWifiStatus(deviceID)
+		return nil
+	case "forget":
+		if len(positional) != 1 {
+			return fmt.Errorf("usage: wifi forget <ssid>")
+		}
+		return wifiForget(deviceID, positional[0])
+	case "monitor":
+		return monitorWifi(deviceID, *interval, *duration)
+	default:
+		return fmt.Errorf("unknown wifi action %q", action)
+	}
+}
+
+// wifiConnect uses `cmd wifi connect-network` (Android 11+) and falls back to
+// wpa_cli, which needs a rooted shell, on older builds such as Fire OS 6/7.
+func wifiConnect(deviceID, ssid, pass, security string) error {
+	if security != "open" && pass == "" {
+		return fmt.Errorf("--pass is required for %s networks", security)
+	}
+	adbShell(deviceID, "svc", "wifi", "enable")
+
+	cmdArgs := []string{"cmd", "wifi", "connect-network", shellQuote(ssid), security}
+	if security != "open" {
+		cmdArgs = append(cmdArgs, shellQuote(pass))
+	}
+	output, err := adbShell(deviceID, cmdArgs...)
+	if err != nil || strings.Contains(output, "Unknown command") || strings.Contains(output, "Exception") {
+		debugPrint("cmd wifi connect-network failed: %s\n", output)
+		if !isShellRoot(deviceID) {
+			return fmt.Errorf("this device does not support `cmd wifi connect-network`; run `adb root` to use the wpa_cli fallback")
+		}
+		if err := wpaCliConnect(deviceID, ssid, pass); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Connecting to %s...\n", ssid)
+	err = pollUntil(30*time.Second, time.Second, func() bool {
+		return currentSSID(deviceID) == ssid
+	})
+	if err != nil {
+		return fmt.Errorf("not connected to %s: %v", ssid, err)
+	}
+	showWifiStatus(deviceID)
+	return nil
+}
+
+func wpaCliConnect(deviceID, ssid, pass string) error {
+	wpa := func(args ...string) (string, error) {
+		return adbShell(deviceID, append([]string{"wpa_cli", "-i", "wlan0"}, args...)...)
+	}
+	id, err := wpa("add_network")
+	if err != nil {
+		return fmt.Errorf("wpa_cli add_network: %s", id)
+	}
+	id = strings.TrimSpace(id[strings.LastIndex(id, "\n")+1:])
+	settings := [][]string{{"ssid", shellQuote(`"` + ssid + `"`)}}
+	if pass == "" {
+		settings = append(settings, []string{"key_mgmt", "NONE"})
+	} else {
+		settings = append(settings, []string{"psk", shellQuote(`"` + pass + `"`)})
+	}
+	for _, s := range settings {
+		if output, err := wpa("set_network", id, s[0], s[1]); err != nil || !strings.Contains(output, "OK") {
+			return fmt.Errorf("wpa_cli set_network %s: %s", s[0], output)
+		}
+	}
+	if output, err := wpa("enable_network", id); err != nil {
+		return fmt.Errorf("wpa_cli enable_network: %s", output)
+	}
+	wpa("save_config")
+	return nil
+}
+
+func wifiForget(deviceID, ssid string) error {
+	output, err := adbShellIdempotent(deviceID, "cmd", "wifi", "list-networks")
+	if err != nil {
+		return fmt.Errorf("listing saved networks failed: %s", output)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		// Rows are "<id> <ssid> <security type>"; the SSID may contain spaces.
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.Join(fields[1:len(fields)-1], " ") != ssid {
+			continue
+		}
+		if output, err := adbShell(deviceID, "cmd", "wifi", "forget-network", fields[0]); err != nil {
+			return fmt.Errorf("forget failed: %s", output)
+		}
+		fmt.Printf("Forgot %s\n", ssid)
+		return nil
+	}
+	return fmt.Errorf("no saved network named %q", ssid)
+}
+
+func showWifiStatus(deviceID string) {
+	timeout := 5 * time.Second
+	enabled := "No"
+	if runAdbCommand(deviceID, "settings get global wifi_on", timeout) != "0" {
+		enabled = "Yes"
+	}
+	color.New(color.FgCyan, color.Bold).Println("WiFi")
+	fmt.Print(rule("=", 30))
+	color.New(color.FgGreen).Printf("%-20s : ", "WiFi Enabled")
+	fmt.Println(enabled)
+	color.New(color.FgGreen).Printf("%-20s : ", "IP Address")
+	fmt.Println(wlanIP(deviceID))
+	for _, item := range getWifiLinkInfo(deviceID) {
+		color.New(color.FgGreen).Printf("%-20s : ", item.Property)
+		fmt.Println(item.Value)
+	}
+}
+
+// wlanIP returns the IPv4 address of wlan0, or "n/a".
+func wlanIP(deviceID string) string {
+	return runAdbCommand(deviceID, "ip addr show wlan0 | grep 'inet ' | awk '{print $2}' | cut -d/ -f1", 5*time.Second)
+}
+
+// getWifiLinkInfo reports signal and link details of the current connection.
+func getWifiLinkInfo(deviceID string) []DeviceInfo {
+	link, ok := currentWifiLink(deviceID)
+	if !ok {
+		return []DeviceInfo{{"WiFi SSID", "not connected"}}
+	}
+	return []DeviceInfo{
+		{"WiFi SSID", link.SSID},
+		{"WiFi BSSID", link.BSSID},
+		{"WiFi RSSI", fmt.Sprintf("%d dBm (%s)", link.RSSI, signalQuality(link.RSSI))},
+		{"WiFi Link Speed", fmt.Sprintf("%d Mbps", link.LinkSpeed)},
+		{"WiFi Frequency", fmt.Sprintf("%d MHz (%s)", link.Frequency, wifiBand(link.Frequency))},
+	}
+}
+
+func monitorWifi(deviceID string, interval, duration time.Duration) error {
+	color.New(color.FgCyan, color.Bold).Printf("Monitoring WiFi on %s (Ctrl+C to stop)\n", deviceID)
+	fmt.Printf("%-10s %-10s %-12s %s\n", "Time", "RSSI", "Link Speed", "Signal")
+	start := time.Now()
+	for duration == 0 || time.Since(start) < duration {
+		link, ok := currentWifiLink(deviceID)
+		now := time.Now().Format("15:04:05")
+		if !ok {
+			color.New(color.FgRed).Printf("%-10s not connected\n", now)
+		} else {
+			c := color.New(color.FgGreen)
+			if link.RSSI < weakRSSI {
+				c = color.New(color.FgYellow)
+			}
+			bar := ""
+			if !screenReader {
+				// -100 dBm maps to an empty bar, -30 dBm to a full one.
+				bar = strings.Repeat("#", max(0, min(14, (link.RSSI+100)/5)))
+			}
+			c.Printf("%-10s %-10s %-12s %s %s\n", now, fmt.Sprintf("%d dBm", link.RSSI),
+				fmt.Sprintf("%d Mbps", link.LinkSpeed), signalQuality(link.RSSI), bar)
+		}
+		time.Sleep(interval)
+	}
+	return nil
+}
+
+func currentWifiLink(deviceID string) (wifiLink, bool) {
+	output := runAdbCommand(deviceID, "dumpsys wifi | grep -m1 'mWifiInfo'", 5*time.Second)
+	return parseWifiLink(output)
+}
+
+// parseWifiLink reads the connection fields of an mWifiInfo line.
+func parseWifiLink(line string) (wifiLink, bool) {
+	m := wifiSSIDPattern.FindStringSubmatch(line)
+	if m == nil || m[1] == "<unknown ssid>" {
+		return wifiLink{}, false
+	}
+	link := wifiLink{SSID: m[1]}
+	if m := wifiBSSIDPattern.FindStringSubmatch(line); m != nil {
+		link.BSSID = m[1]
+	}
+	if m := wifiRSSIPattern.FindStringSubmatch(line); m != nil {
+		link.RSSI, _ = strconv.Atoi(m[1])
+	}
+	if m := wifiLinkSpeedPattern.FindStringSubmatch(line); m != nil {
+		link.LinkSpeed, _ = strconv.Atoi(m[1])
+	}
+	if m := wifiFrequencyPattern.FindStringSubmatch(line); m != nil {
+		link.Frequency, _ = strconv.Atoi(m[1])
+	}
+	return link, true
+}
+
+func signalQuality(rssi int) string {
+	switch {
+	case rssi >= -55:
+		return "excellent"
+	case rssi >= -65:
+		return "good"
+	case rssi >= weakRSSI:
+		return "fair"
+	default:
+		return "weak"
+	}
+}
+
+func wifiBand(frequency int) string {
+	switch {
+	case frequency >= 2400 && frequency < 2500:
+		return "2.4 GHz"
+	case frequency >= 4900 && frequency < 5900:
+		return "5 GHz"
+	case frequency >= 5925 && frequency <= 7125:
+		return "6 GHz"
+	default:
+		return "unknown band"
+	}
+}
+
+// currentSSID returns the connected network, or "" when disconnected.
+func currentSSID(deviceID string) string {
+	link, _ := currentWifiLink(deviceID)
+	return link.SSID
+}
+
+// shellQuote single-quotes s for the device shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+func runWireless(args []string) error {
+	if len(args) == 0 || args[0] != "enable" {
+		return fmt.Errorf("usage: wireless enable [--port 5555]")
+	}
+	fs := flag.NewFlagSet("wireless enable", flag.ExitOnError)
+	port := fs.Int("port", 5555, "TCP port adbd listens on")
+	parseArgs(fs, args[1:])
+
+	deviceID := pickDevice()
+	if isWirelessSerial(deviceID) {
+		return fmt.Errorf("%s is already connected over the network", deviceID)
+	}
+	ip := wlanIP(deviceID)
+	if ip == "n/a" || ip == "" {
+		return fmt.Errorf("no wlan0 address; connect the device to WiFi first (see 'adbctl wifi connect')")
+	}
+	endpoint := ip + ":" + strconv.Itoa(*port)
+
+	fmt.Printf("Restarting adbd in TCP mode on port %d...\n", *port)
+	if output, err := exec.Command(adbPath, "-s", deviceID, "tcpip", strconv.Itoa(*port)).CombinedOutput(); err != nil {
+		return normalizeAdbError(string(output), fmt.Errorf("adb tcpip: %v: %s", err, strings.TrimSpace(string(output))))
+	}
+	// adbd restarts, so the USB transport drops briefly.
+	pollUntil(15*time.Second, 500*time.Millisecond, func() bool {
+		return containsString(connectedSerials(), deviceID)
+	})
+
+	fmt.Printf("Connecting to %s...\n", endpoint)
+	err := pollUntil(20*time.Second, time.Second, func() bool {
+		output, err := exec.Command(adbPath, "connect", endpoint).CombinedOutput()
+		return err == nil && strings.Contains(string(output), "connected to")
+	})
+	if err != nil {
+		return fmt.Errorf("adb connect %s: %v", endpoint, err)
+	}
+	if err := waitForState(endpoint, StateAuthorized, 10*time.Second); err != nil {
+		return err
+	}
+
+	color.New(color.FgGreen, color.Bold).Printf("Wireless adb ready: %s\n", endpoint)
+	fmt.Println("You can unplug the USB cable. Reconnect later with:")
+	fmt.Printf("  adb connect %s\n", endpoint)
+	return nil
+}